@@ -0,0 +1,65 @@
+package armed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrAssertionFailed is returned by CLI.Run when --assert is enabled and
+// the evaluated result reports a failure.
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// errAssertionResultType is returned for an --assert result of another type
+var errAssertionResultType = errors.New("assert: result must be a boolean or an object {ok: bool, message: string}")
+
+// assertion is the object form of an --assert result.
+type assertion struct {
+	OK      *bool  `json:"ok"`
+	Message string `json:"message"`
+}
+
+// parseAssertion interprets an evaluation result as an assertion.
+// The result must be a boolean or an object with a boolean "ok" field
+// and an optional string "message" field.
+func parseAssertion(jsonStr string) (ok bool, message string, err error) {
+	trimmed := strings.TrimSpace(jsonStr)
+	// null unmarshals into both forms without an error
+	if trimmed == "null" {
+		return false, "", errAssertionResultType
+	}
+	if err := json.Unmarshal([]byte(trimmed), &ok); err == nil {
+		return ok, "", nil
+	}
+	var a assertion
+	if err := json.Unmarshal([]byte(trimmed), &a); err != nil {
+		return false, "", errAssertionResultType
+	}
+	if a.OK == nil {
+		return false, "", fmt.Errorf("assert: result object must have a boolean \"ok\" field")
+	}
+	return *a.OK, a.Message, nil
+}
+
+// assert checks the evaluation result in --assert mode. On success the
+// message (if any) is written to the output writer; on failure an error
+// wrapping ErrAssertionFailed is returned with the message.
+func (cli *CLI) assert(jsonStr string) error {
+	ok, message, err := parseAssertion(jsonStr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if message == "" {
+			return ErrAssertionFailed
+		}
+		return fmt.Errorf("%w: %s", ErrAssertionFailed, message)
+	}
+	if message != "" {
+		_, err := io.WriteString(cli.writer, message+"\n")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package armed_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunWithCLIAssert(t *testing.T) {
+	ctx := t.Context()
+
+	tests := []struct {
+		name         string
+		jsonnet      string
+		expected     string
+		expectFailed bool
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			name:     "true",
+			jsonnet:  `1 + 1 == 2`,
+			expected: "",
+		},
+		{
+			name:         "false",
+			jsonnet:      `1 + 1 == 3`,
+			expectFailed: true,
+			errorMessage: "assertion failed",
+		},
+		{
+			name:     "object ok with message",
+			jsonnet:  `{ ok: true, message: "all good" }`,
+			expected: "all good\n",
+		},
+		{
+			name:         "object not ok with message",
+			jsonnet:      `{ ok: false, message: "disk is full" }`,
+			expectFailed: true,
+			errorMessage: "assertion failed: disk is full",
+		},
+		{
+			name:        "object without ok",
+			jsonnet:     `{ message: "missing ok" }`,
+			expectError: true,
+		},
+		{
+			name:        "non boolean result",
+			jsonnet:     `"yes"`,
+			expectError: true,
+		},
+		{
+			name:         "null result",
+			jsonnet:      `null`,
+			expectError:  true,
+			errorMessage: "result must be a boolean or an object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+			if err := os.WriteFile(jsonnetFile, []byte(tt.jsonnet), 0644); err != nil {
+				t.Fatalf("failed to write jsonnet file: %v", err)
+			}
+
+			var output bytes.Buffer
+			cli := &armed.CLI{
+				Filename: jsonnetFile,
+				Assert:   true,
+			}
+			cli.SetWriter(&output)
+
+			err := cli.Run(ctx)
+			switch {
+			case tt.expectFailed:
+				if !errors.Is(err, armed.ErrAssertionFailed) {
+					t.Fatalf("expected ErrAssertionFailed, got %v", err)
+				}
+				if err.Error() != tt.errorMessage {
+					t.Errorf("error message: got %q, want %q", err.Error(), tt.errorMessage)
+				}
+				return
+			case tt.expectError:
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				if errors.Is(err, armed.ErrAssertionFailed) {
+					t.Fatalf("expected non-assertion error, got %v", err)
+				}
+				if !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Errorf("error %q does not contain %q", err.Error(), tt.errorMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, output.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
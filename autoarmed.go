@@ -0,0 +1,10 @@
+package armed
+
+// armedLibVar is the name of the local variable and the ext code variable
+// bound to the armed library by --auto-armed.
+const armedLibVar = "armed"
+
+// autoArmedPrelude binds the armed library to a local variable. It is
+// prepended to the first line of the main input so that line numbers in
+// error messages are unchanged. Imported files can use std.extVar("armed").
+const autoArmedPrelude = "local " + armedLibVar + " = import 'armed.libsonnet'; "
@@ -0,0 +1,108 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIAutoArmed(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `{
+		a: armed.base64("a"),
+		lib: (import 'lib.libsonnet').b,
+	}`)
+	writeFile(t, filepath.Join(tmpDir, "lib.libsonnet"), `{
+		b: std.extVar('armed').base64("b"),
+	}`)
+
+	t.Run("file", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile, AutoArmed: true}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, output.String(), `{"a": "YQ==", "lib": "Yg=="}`)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		t.Chdir(tmpDir)
+		content, err := os.ReadFile(jsonnetFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldStdin := os.Stdin
+		os.Stdin = r
+		t.Cleanup(func() { os.Stdin = oldStdin })
+		go func() {
+			w.Write(content)
+			w.Close()
+		}()
+
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: "-", AutoArmed: true}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, output.String(), `{"a": "YQ==", "lib": "Yg=="}`)
+	})
+
+	t.Run("line numbers are preserved", func(t *testing.T) {
+		errFile := filepath.Join(tmpDir, "error.jsonnet")
+		writeFile(t, errFile, "{\n  a: error 'boom',\n}\n")
+		cli := &armed.CLI{Filename: errFile, AutoArmed: true}
+		cli.SetWriter(&bytes.Buffer{})
+		err := cli.Run(t.Context())
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		if !strings.Contains(err.Error(), "error.jsonnet:2:6") {
+			t.Errorf("error does not point to line 2: %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cli := &armed.CLI{Filename: jsonnetFile}
+		cli.SetWriter(&bytes.Buffer{})
+		if err := cli.Run(t.Context()); err == nil {
+			t.Fatal("expected error without --auto-armed")
+		}
+	})
+}
+
+func TestArmedLibImportFromMultipleFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `local armed = import 'armed.libsonnet';
+	{
+		a: armed.base64("a"),
+		b: (import 'sub/lib.libsonnet').b,
+		c: (import 'common.libsonnet').c,
+	}`)
+	writeFile(t, filepath.Join(subDir, "lib.libsonnet"), `local armed = import 'armed.libsonnet';
+	{ b: armed.base64("b") + (import '../common.libsonnet').c }`)
+	writeFile(t, filepath.Join(tmpDir, "common.libsonnet"), `{ c: "c" }`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: jsonnetFile}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, output.String(), `{"a": "YQ==", "b": "Yg==c", "c": "c"}`)
+}
@@ -0,0 +1,19 @@
+package armed
+
+import "github.com/fujiwara/jsonnet-armed/functions"
+
+// AWSFlags configures the AWS SDK shared by the aws_* native functions and S3 outputs
+type AWSFlags struct {
+	AWSProfile    string `name:"aws-profile" placeholder:"NAME" help:"AWS shared config profile for AWS native functions and S3 outputs."`
+	AWSRegion     string `name:"aws-region" placeholder:"REGION" help:"AWS region for AWS native functions and S3 outputs."`
+	AWSAssumeRole string `name:"aws-assume-role" placeholder:"ARN" help:"Assume the IAM role ARN for AWS native functions and S3 outputs."`
+}
+
+// awsOptions returns the options passed to the functions package
+func (f AWSFlags) awsOptions() functions.AWSOptions {
+	return functions.AWSOptions{
+		Profile:       f.AWSProfile,
+		Region:        f.AWSRegion,
+		AssumeRoleARN: f.AWSAssumeRole,
+	}
+}
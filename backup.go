@@ -0,0 +1,72 @@
+package armed
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultBackupSuffix is the suffix of the backups of --backup
+const defaultBackupSuffix = ".bak"
+
+// checkBackupFlags rejects invalid --backup-suffix and --backup-keep values
+func (cli *CLI) checkBackupFlags() error {
+	if !cli.Backup && (cli.BackupSuffix != "" || cli.BackupKeep != 0) {
+		return fmt.Errorf("--backup-suffix and --backup-keep require --backup")
+	}
+	if cli.BackupKeep < 0 {
+		return fmt.Errorf("--backup-keep must not be negative")
+	}
+	if strings.ContainsRune(cli.BackupSuffix, filepath.Separator) || strings.ContainsRune(cli.BackupSuffix, '/') {
+		return fmt.Errorf("invalid --backup-suffix %q", cli.BackupSuffix)
+	}
+	return nil
+}
+
+// backupName returns the name of the n-th backup of filename: FILE.bak for
+// the latest one, then FILE.bak.1, FILE.bak.2, ...
+func (cli *CLI) backupName(filename string, n int) string {
+	name := filename + cmp.Or(cli.BackupSuffix, defaultBackupSuffix)
+	if n > 0 {
+		name += "." + strconv.Itoa(n)
+	}
+	return name
+}
+
+// backupOutput keeps the current content of the output file filename as its
+// latest backup before it is replaced, rotating the older backups to keep
+// at most --backup-keep of them (default 1). The file is linked, so it
+// exists under its own name all the time.
+func (cli *CLI) backupOutput(filename string) error {
+	if _, err := os.Lstat(filename); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	keep := max(cli.BackupKeep, 1)
+	if err := os.Remove(cli.backupName(filename, keep-1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for n := keep - 2; n >= 0; n-- {
+		if err := os.Rename(cli.backupName(filename, n), cli.backupName(filename, n+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	backup := cli.backupName(filename, 0)
+	if err := os.Link(filename, backup); err == nil {
+		return nil
+	}
+	// Copy on file systems without hard links, keeping the permissions
+	st, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(backup, data, st.Mode().Perm())
+}
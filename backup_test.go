@@ -0,0 +1,90 @@
+package armed_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `{v: std.extVar("v")}`)
+	out := filepath.Join(dir, "out.json")
+
+	run := func(t *testing.T, v string, cli *armed.CLI) {
+		t.Helper()
+		cli.Filename = jsonnetFile
+		cli.Output = []string{out}
+		cli.CompactOutput = true
+		cli.ExtStr = map[string]string{"v": v}
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertFiles := func(t *testing.T, want map[string]string) {
+		t.Helper()
+		for name, content := range want {
+			b, err := os.ReadFile(filepath.Join(dir, name))
+			if content == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("%s must not exist: %v", name, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("failed to read %s: %v", name, err)
+				continue
+			}
+			if got := strings.TrimSpace(string(b)); got != content {
+				t.Errorf("%s: got %s, want %s", name, got, content)
+			}
+		}
+	}
+
+	t.Run("rotate", func(t *testing.T) {
+		backup := func() *armed.CLI { return &armed.CLI{Backup: true, BackupKeep: 2} }
+		run(t, "1", backup())
+		assertFiles(t, map[string]string{"out.json": `{"v":"1"}`, "out.json.bak": ""})
+		run(t, "2", backup())
+		run(t, "3", backup())
+		assertFiles(t, map[string]string{"out.json": `{"v":"3"}`, "out.json.bak": `{"v":"2"}`, "out.json.bak.1": `{"v":"1"}`})
+		// Unchanged outputs do not rotate the backups
+		run(t, "3", backup())
+		run(t, "4", backup())
+		assertFiles(t, map[string]string{"out.json": `{"v":"4"}`, "out.json.bak": `{"v":"3"}`, "out.json.bak.1": `{"v":"2"}`, "out.json.bak.2": ""})
+	})
+
+	t.Run("suffix", func(t *testing.T) {
+		run(t, "5", &armed.CLI{Backup: true, BackupSuffix: ".prev"})
+		assertFiles(t, map[string]string{"out.json": `{"v":"5"}`, "out.json.prev": `{"v":"4"}`})
+	})
+}
+
+func TestBackupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cli  armed.CLI
+		want string
+	}{
+		{"keep without backup", armed.CLI{BackupKeep: 3}, "require --backup"},
+		{"negative keep", armed.CLI{Backup: true, BackupKeep: -1}, "--backup-keep must not be negative"},
+		{"suffix with a directory", armed.CLI{Backup: true, BackupSuffix: "/bak"}, `invalid --backup-suffix "/bak"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			jsonnetFile := filepath.Join(dir, "test.jsonnet")
+			writeFile(t, jsonnetFile, `{a: 1}`)
+			cli := tt.cli
+			cli.Filename = jsonnetFile
+			cli.Output = []string{filepath.Join(dir, "out.json")}
+			if err := cli.Run(t.Context()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
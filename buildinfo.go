@@ -0,0 +1,81 @@
+package armed
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"slices"
+)
+
+// Commit and BuildDate are set at build time with -ldflags "-X ...". When
+// they are empty, the VCS information embedded by the Go toolchain is used.
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// versionInfo is the output of --version --json
+type versionInfo struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit"`
+	BuildDate string   `json:"build_date"`
+	GoVersion string   `json:"go_version"`
+	Functions []string `json:"functions"`
+}
+
+// buildVCSInfo returns the commit and date recorded by the Go toolchain
+func buildVCSInfo() (commit, date string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.time":
+			date = s.Value
+		}
+	}
+	return commit, date
+}
+
+// printVersion prints the version, or the build metadata and the registered
+// native function names as JSON with --json
+func (cli *CLI) printVersion(ctx context.Context) error {
+	if !cli.VersionJSON {
+		_, err := fmt.Fprintf(cli.writer, "jsonnet-armed %s\n", Version)
+		return err
+	}
+	info := versionInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit == "" || info.BuildDate == "" {
+		commit, date := buildVCSInfo()
+		info.Commit = cmp.Or(info.Commit, commit)
+		info.BuildDate = cmp.Or(info.BuildDate, date)
+	}
+	nctx, err := cli.nativeContext(ctx)
+	if err != nil {
+		return err
+	}
+	funcs, err := cli.nativeFunctions(nctx)
+	if err != nil {
+		return err
+	}
+	for _, f := range funcs {
+		if !slices.Contains(info.Functions, f.Name) {
+			info.Functions = append(info.Functions, f.Name)
+		}
+	}
+	slices.Sort(info.Functions)
+	enc := json.NewEncoder(cli.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
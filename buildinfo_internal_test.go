@@ -0,0 +1,58 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+)
+
+func TestPrintVersion(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := &CLI{Version: true, writer: &buf}
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := buf.String(), "jsonnet-armed "+Version+"\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		cli := &CLI{Version: true, VersionJSON: true, writer: &buf}
+		cli.AddFunctions(&jsonnet.NativeFunction{Name: "custom_func"})
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var info versionInfo
+		if err := json.Unmarshal(buf.Bytes(), &info); err != nil {
+			t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+		}
+		if info.Version != Version {
+			t.Errorf("version: got %q, want %q", info.Version, Version)
+		}
+		if info.GoVersion != runtime.Version() {
+			t.Errorf("go_version: got %q, want %q", info.GoVersion, runtime.Version())
+		}
+		for _, name := range []string{"env", "sha256_file", "custom_func"} {
+			if !slices.Contains(info.Functions, name) {
+				t.Errorf("functions do not contain %s: %v", name, info.Functions)
+			}
+		}
+		if !slices.IsSorted(info.Functions) {
+			t.Errorf("functions are not sorted: %v", info.Functions)
+		}
+	})
+
+	t.Run("json without version", func(t *testing.T) {
+		cli := &CLI{VersionJSON: true, Filename: "testdata/server/static.jsonnet", writer: &bytes.Buffer{}}
+		if err := cli.run(t.Context()); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -67,31 +67,83 @@ func (c *Cache) GenerateCacheKey(cli *CLI, content []byte) (string, error) {
 
 // generateCacheKey creates a unique cache key based on input parameters and content
 func generateCacheKey(cli *CLI, content []byte) (string, error) {
+	parts, err := cacheKeyParts(cli, content)
+	if err != nil {
+		return "", err
+	}
 	hasher := sha256.New()
+	for _, p := range parts {
+		hasher.Write(p.data)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// cacheKeyPart is an input of the cache key
+type cacheKeyPart struct {
+	name string // flags, path, content, overlay:<filename>, var-file:<filename>, mock:<filename> or replay:<filename>
+	data []byte
+}
 
+// cacheKeyParts returns the inputs of the cache key, in the order they are hashed
+func cacheKeyParts(cli *CLI, content []byte) ([]cacheKeyPart, error) {
 	// Marshal the CLI configuration to JSON for consistent hashing
 	// Private fields (writer, cacheKey) are automatically ignored
 	cliJSON, err := json.Marshal(cli)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal CLI for cache key: %w", err)
+		return nil, fmt.Errorf("failed to marshal CLI for cache key: %w", err)
 	}
+	parts := []cacheKeyPart{{name: "flags", data: cliJSON}}
 
-	// Hash the CLI configuration
-	hasher.Write(cliJSON)
-
-	// Add absolute path separately for files (not stdin) to ensure uniqueness
-	if cli.Filename != "-" {
+	// Add absolute path separately for files (not stdin, nor remote inputs
+	// whose URLs are in the flags) to ensure uniqueness. For -e/--exec the
+	// filename is empty, so the working directory its imports are relative
+	// to is added.
+	if cli.Filename != "-" && !isRemoteInput(cli.Filename) {
 		absPath, err := filepath.Abs(cli.Filename)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		hasher.Write([]byte(absPath))
+		parts = append(parts, cacheKeyPart{name: "path", data: []byte(absPath)})
 	}
 
-	// Hash the content
-	hasher.Write(content)
+	parts = append(parts, cacheKeyPart{name: "content", data: content})
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	// Overlay files are merged into the result, so their contents matter too
+	for _, overlay := range cli.Overlays {
+		b, err := os.ReadFile(overlay)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, cacheKeyPart{name: "overlay:" + overlay, data: b})
+	}
+
+	// So are the files read into external variables and top-level arguments
+	for _, f := range cli.varFiles() {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, cacheKeyPart{name: "var-file:" + f, data: b})
+	}
+
+	// And the mocked results of native functions
+	if cli.Mock != "" {
+		b, err := os.ReadFile(cli.Mock)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, cacheKeyPart{name: "mock:" + cli.Mock, data: b})
+	}
+
+	// And the recorded results served by --replay
+	if cli.Replay != "" {
+		b, err := os.ReadFile(cli.Replay)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, cacheKeyPart{name: "replay:" + cli.Replay, data: b})
+	}
+	return parts, nil
 }
 
 // Get retrieves a cached result if it exists and is not expired (deprecated)
@@ -256,6 +308,13 @@ func (c *memoryCache) Clean() error {
 	return nil
 }
 
+// clear removes all entries
+func (c *memoryCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
 // maxAge returns the maximum duration an entry may be kept
 func (c *memoryCache) maxAge() time.Duration {
 	if c.staleTTL > c.ttl {
@@ -148,6 +148,42 @@ func TestCacheKeyGeneration(t *testing.T) {
 			},
 			shouldDiff: true,
 		},
+		{
+			name: "different TLAStr generates different key",
+			cli1: armed.CLI{
+				Filename: "test.jsonnet",
+				TLAStr:   map[string]string{"env": "dev"},
+			},
+			cli2: armed.CLI{
+				Filename: "test.jsonnet",
+				TLAStr:   map[string]string{"env": "prod"},
+			},
+			shouldDiff: true,
+		},
+		{
+			name: "different TLACode generates different key",
+			cli1: armed.CLI{
+				Filename: "test.jsonnet",
+				TLACode:  map[string]string{"replicas": "1"},
+			},
+			cli2: armed.CLI{
+				Filename: "test.jsonnet",
+				TLACode:  map[string]string{"replicas": "3"},
+			},
+			shouldDiff: true,
+		},
+		{
+			name: "same value as TLAStr and ExtStr generates different key",
+			cli1: armed.CLI{
+				Filename: "test.jsonnet",
+				TLAStr:   map[string]string{"env": "dev"},
+			},
+			cli2: armed.CLI{
+				Filename: "test.jsonnet",
+				ExtStr:   map[string]string{"env": "dev"},
+			},
+			shouldDiff: true,
+		},
 		{
 			name: "different filename generates different key",
 			cli1: armed.CLI{
@@ -224,3 +260,39 @@ func TestCacheWithStdin(t *testing.T) {
 	// reads it during cache key generation
 	t.Skip("Stdin caching requires special handling")
 }
+
+func TestCacheKeyFileContents(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(cli *armed.CLI, filename string)
+	}{
+		{name: "mock file", set: func(cli *armed.CLI, filename string) { cli.Mock = filename }},
+		{name: "replay cassette", set: func(cli *armed.CLI, filename string) { cli.Replay = filename }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+			inputFile := filepath.Join(tmpDir, "input.json")
+			content := []byte(`{ test: "value" }`)
+			writeFile(t, jsonnetFile, string(content))
+
+			cache := armed.NewCache(time.Minute, 0)
+			key := func(data string) string {
+				t.Helper()
+				writeFile(t, inputFile, data)
+				cli := &armed.CLI{Filename: jsonnetFile}
+				tt.set(cli, inputFile)
+				k, err := cache.GenerateCacheKey(cli, content)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return k
+			}
+			// Editing the file in place must invalidate the cache
+			if key(`{"a": 1}`) == key(`{"a": 2}`) {
+				t.Error("keys should differ when the file content changes")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CacheCmd manages the on-disk cache of --cache and --incremental
+type CacheCmd struct {
+	Path  CachePathCmd  `cmd:"" help:"Print the cache directory"`
+	Clear CacheClearCmd `cmd:"" help:"Remove cached results and --incremental state"`
+}
+
+// CachePathCmd prints the cache directory
+type CachePathCmd struct {
+	// writer for the path (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+}
+
+// Run prints the cache directory
+func (c *CachePathCmd) Run(ctx context.Context) error {
+	if c.writer == nil {
+		c.writer = os.Stdout
+	}
+	_, err := fmt.Fprintln(c.writer, getCacheDir())
+	return err
+}
+
+// CacheClearCmd removes cached results and --incremental state
+type CacheClearCmd struct{}
+
+// Run removes the cache entries. Files not written by jsonnet-armed are kept.
+func (c *CacheClearCmd) Run(ctx context.Context) error {
+	dir := getCacheDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var removed int
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.Name() == "incremental" && entry.IsDir():
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+			removed++
+		case strings.HasSuffix(entry.Name(), ".json") && entry.Type().IsRegular():
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+		}
+	}
+	slog.Info("Cache cleared", "dir", dir, "removed", removed)
+	return nil
+}
@@ -0,0 +1,52 @@
+package armed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheCmd(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := getCacheDir()
+
+	var buf bytes.Buffer
+	if err := (&CachePathCmd{writer: &buf}).Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != dir {
+		t.Errorf("expected %s, got %s", dir, got)
+	}
+
+	// Clearing a missing cache directory is not an error
+	if err := (&CacheClearCmd{}).Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]bool{
+		"0123.json":              false,
+		"incremental/abcd.json":  false,
+		"not-written-by-us.txt":  true,
+		"other/nested-file.json": true,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (&CacheClearCmd{}).Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, kept := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: expected exists=%v, got %v", name, kept, exists)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package armed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+)
+
+// cassetteFunctions are the side-effecting native functions captured by
+// --record and served by --replay.
+var cassetteFunctions = map[string]bool{
+	"http_get":           true,
+	"http_request":       true,
+	"dns_lookup":         true,
+	"exec":               true,
+	"exec_with_env":      true,
+	"github_api":         true,
+	"github_release":     true,
+	"oidc_token":         true,
+	"aws_cfn_output":     true,
+	"aws_cfn_outputs":    true,
+	"aws_dynamodb_get":   true,
+	"ldap_search":        true,
+	"sql_query":          true,
+	"redis_get":          true,
+	"redis_hgetall":      true,
+	"net_port_listening": true,
+}
+
+// nonHermeticFunctions return random results that must not be recorded,
+// such as passwords. They fail under --replay.
+var nonHermeticFunctions = map[string]bool{
+	"password_generate": true,
+}
+
+// interaction is a recorded native function call.
+type interaction struct {
+	Name   string          `json:"name"`
+	Args   json.RawMessage `json:"args"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// cassette holds recorded interactions keyed by function name and arguments.
+// It is safe for concurrent use.
+type cassette struct {
+	mu           sync.Mutex
+	Interactions []*interaction `json:"interactions"`
+	index        map[string]*interaction
+}
+
+func newCassette() *cassette {
+	return &cassette{index: make(map[string]*interaction)}
+}
+
+// loadCassette reads a cassette file written by --record
+func loadCassette(filename string) (*cassette, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := newCassette()
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", filename, err)
+	}
+	for i, in := range c.Interactions {
+		var args []any
+		if err := json.Unmarshal(in.Args, &args); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: interaction #%d: %w", filename, i, err)
+		}
+		// Cassettes recorded before redaction was added still match
+		key, _, err := interactionKey(in.Name, redactArgs(in.Name, args))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := c.index[key]; !ok {
+			c.index[key] = in
+		}
+	}
+	return c, nil
+}
+
+// credentialHeaders are the request headers whose values are not recorded
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// redactArgs returns a copy of the arguments of a call of the function name
+// with the credentials replaced by functions.RedactedValue, so that they are
+// not saved in cassettes, which are often committed: the values of
+// credentialHeaders, the environment variables of exec_with_env and any
+// registered secret. Calls are matched by the redacted arguments.
+func redactArgs(name string, args []any) []any {
+	redacted, _ := redactValue(args).([]any)
+	switch name {
+	case "http_get", "http_request":
+		i := 1
+		if name == "http_request" {
+			i = 2
+		}
+		if headers, ok := argAt(redacted, i).(map[string]any); ok {
+			for k := range headers {
+				if slices.ContainsFunc(credentialHeaders, func(h string) bool { return strings.EqualFold(h, k) }) {
+					headers[k] = functions.RedactedValue
+				}
+			}
+		}
+	case "exec_with_env":
+		if env, ok := argAt(redacted, 2).(map[string]any); ok {
+			for k := range env {
+				env[k] = functions.RedactedValue
+			}
+		}
+	}
+	return redacted
+}
+
+// redactValue returns a copy of v with the registered secrets in strings
+// replaced by functions.RedactedValue
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return functions.RedactSecrets(v)
+	case []any:
+		r := make([]any, len(v))
+		for i, e := range v {
+			r[i] = redactValue(e)
+		}
+		return r
+	case map[string]any:
+		r := make(map[string]any, len(v))
+		for k, e := range v {
+			r[k] = redactValue(e)
+		}
+		return r
+	default:
+		return v
+	}
+}
+
+// interactionKey returns the key of a call; encoding/json sorts object
+// keys, so equal arguments always produce the same key.
+func interactionKey(name string, args []any) (string, json.RawMessage, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to encode arguments: %w", name, err)
+	}
+	return name + string(b), b, nil
+}
+
+// record returns copies of funcs that record calls of cassetteFunctions.
+// Only the first result of identical calls is recorded.
+func (c *cassette) record(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	return c.wrap(funcs, cassetteFunctions, func(f *jsonnet.NativeFunction, args []any) (any, error) {
+		result, callErr := f.Func(args)
+		key, encodedArgs, err := interactionKey(f.Name, redactArgs(f.Name, args))
+		if err != nil {
+			return nil, err
+		}
+		in := &interaction{Name: f.Name, Args: encodedArgs}
+		if callErr != nil {
+			in.Error = functions.RedactSecrets(callErr.Error())
+		} else if in.Result, err = json.Marshal(redactValue(result)); err != nil {
+			return nil, fmt.Errorf("%s: failed to record result: %w", f.Name, err)
+		}
+		c.mu.Lock()
+		if _, ok := c.index[key]; !ok {
+			c.index[key] = in
+			c.Interactions = append(c.Interactions, in)
+		}
+		c.mu.Unlock()
+		return result, callErr
+	})
+}
+
+// replay returns copies of funcs where calls of cassetteFunctions are
+// answered from the cassette. A call that was not recorded fails, as do
+// calls of nonHermeticFunctions.
+func (c *cassette) replay(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	funcs = c.wrap(funcs, nonHermeticFunctions, func(f *jsonnet.NativeFunction, args []any) (any, error) {
+		return nil, fmt.Errorf("%s: disabled with --replay, as its result is random", f.Name)
+	})
+	return c.wrap(funcs, cassetteFunctions, func(f *jsonnet.NativeFunction, args []any) (any, error) {
+		key, _, err := interactionKey(f.Name, redactArgs(f.Name, args))
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		in, ok := c.index[key]
+		c.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("%s: no recorded interaction for the arguments %s", f.Name, formatArgs(redactArgs(f.Name, args)))
+		}
+		if in.Error != "" {
+			return nil, errors.New(in.Error)
+		}
+		var result any
+		if err := json.Unmarshal(in.Result, &result); err != nil {
+			return nil, fmt.Errorf("%s: invalid recorded result: %w", f.Name, err)
+		}
+		return result, nil
+	})
+}
+
+// wrap returns copies of funcs where the functions in names are replaced by call
+func (c *cassette) wrap(funcs []*jsonnet.NativeFunction, names map[string]bool, call func(*jsonnet.NativeFunction, []any) (any, error)) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		if !names[f.Name] {
+			wrapped[i] = f
+			continue
+		}
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				return call(f, args)
+			},
+		}
+	}
+	return wrapped
+}
+
+// save writes the recorded interactions to filename, readable only by the
+// owner as the results may still contain credentials
+func (c *cassette) save(filename string) error {
+	c.mu.Lock()
+	b, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := writeFileAtomic(filename, b, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package armed
+
+import "testing"
+
+func TestCassetteFunctionsCoverSandboxGroups(t *testing.T) {
+	// Functions running commands or accessing the network must be replayed,
+	// so that --replay is hermetic
+	for _, flag := range []string{"--no-exec", "--no-net"} {
+		for _, name := range sandboxGroups[flag] {
+			if !cassetteFunctions[name] {
+				t.Errorf("%s is disabled by %s, but not recorded by --record", name, flag)
+			}
+		}
+	}
+}
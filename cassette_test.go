@@ -0,0 +1,169 @@
+package armed_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIRecordReplay(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, fmt.Sprintf(`
+	local armed = import 'armed.libsonnet';
+	{
+		a: armed.http_get("%[1]s/a").body,
+		b: armed.http_get("%[1]s/b").body,
+		again: armed.http_get("%[1]s/a").body,
+		echo: armed.exec("echo", ["recorded"]).stdout,
+		hash: armed.sha256("not recorded"),
+	}`, ts.URL))
+	cassetteFile := filepath.Join(tmpDir, "cassette.json")
+
+	var recorded bytes.Buffer
+	cli := &armed.CLI{Filename: jsonnetFile, Record: cassetteFile}
+	cli.SetWriter(&recorded)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls == 0 {
+		t.Fatal("server was not called while recording")
+	}
+
+	// Replay without the server
+	ts.Close()
+	calls = 0
+	var replayed bytes.Buffer
+	cli = &armed.CLI{Filename: jsonnetFile, Replay: cassetteFile}
+	cli.SetWriter(&replayed)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("server was called %d time(s) while replaying", calls)
+	}
+	compareJSON(t, recorded.String(), replayed.String())
+}
+
+func TestRunWithCLIReplayUnrecorded(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `std.native("exec")("echo", ["other"])`)
+	cassetteFile := filepath.Join(tmpDir, "cassette.json")
+	writeFile(t, cassetteFile, `{"interactions": [
+		{"name": "exec", "args": ["echo", ["recorded"]], "result": {"stdout": "recorded\n", "stderr": "", "exit_code": 0}}
+	]}`)
+
+	cli := &armed.CLI{Filename: jsonnetFile, Replay: cassetteFile}
+	cli.SetWriter(&bytes.Buffer{})
+	err := cli.Run(t.Context())
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	want := `exec: no recorded interaction for the arguments ["echo",["other"]]`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err.Error(), want)
+	}
+}
+
+func TestRunWithCLIReplayRecordedError(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `std.native("dns_lookup")("example.invalid", "A")`)
+	cassetteFile := filepath.Join(tmpDir, "cassette.json")
+	writeFile(t, cassetteFile, `{"interactions": [
+		{"name": "dns_lookup", "args": ["example.invalid", "A"], "error": "dns_lookup: no such host"}
+	]}`)
+
+	cli := &armed.CLI{Filename: jsonnetFile, Replay: cassetteFile}
+	cli.SetWriter(&bytes.Buffer{})
+	err := cli.Run(t.Context())
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if !strings.Contains(err.Error(), "dns_lookup: no such host") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunWithCLIReplayNonHermetic(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `(import 'armed.libsonnet').password_generate()`)
+	cassetteFile := filepath.Join(tmpDir, "cassette.json")
+	writeFile(t, cassetteFile, `{"interactions": []}`)
+
+	cli := &armed.CLI{Filename: jsonnetFile, Replay: cassetteFile}
+	cli.SetWriter(&bytes.Buffer{})
+	err := cli.Run(t.Context())
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if !strings.Contains(err.Error(), "password_generate: disabled with --replay") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunWithCLIRecordRedactsCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, fmt.Sprintf(`
+	local armed = import 'armed.libsonnet';
+	{
+		get: armed.http_get("%s/", { authorization: "Bearer cassette-token-1234" }).body,
+		env: armed.exec_with_env("sh", ["-c", "printf %%s \"$TOKEN\""], { TOKEN: "cassette-secret-5678" }).stdout,
+	}`, ts.URL))
+	cassetteFile := filepath.Join(tmpDir, "cassette.json")
+
+	cli := &armed.CLI{Filename: jsonnetFile, Record: cassetteFile}
+	cli.SetWriter(&bytes.Buffer{})
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st, err := os.Stat(cassetteFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && st.Mode().Perm() != 0600 {
+		t.Errorf("cassette mode: got %#o, want 0600", st.Mode().Perm())
+	}
+	b, err := os.ReadFile(cassetteFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"cassette-token-1234", "cassette-secret-5678"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	// The calls with the credentials still match the redacted interactions
+	ts.Close()
+	var replayed bytes.Buffer
+	cli = &armed.CLI{Filename: jsonnetFile, Replay: cassetteFile}
+	cli.SetWriter(&replayed)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, replayed.String(), `{"get": "ok", "env": "***"}`)
+}
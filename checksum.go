@@ -0,0 +1,33 @@
+package armed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// checksumRecord is a line of --print-checksum
+type checksumRecord struct {
+	Output  string `json:"output"`
+	SHA256  string `json:"sha256"`
+	Changed *bool  `json:"changed,omitempty"`
+}
+
+// printChecksum writes a --print-checksum line for the content written to
+// out ("-" for stdout), and records it for --meta-out. changed is nil for
+// outputs whose previous content is unknown.
+func (cli *CLI) printChecksum(out string, content string, changed *bool) {
+	if !cli.PrintChecksum && cli.meta == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(content))
+	record := checksumRecord{Output: out, SHA256: hex.EncodeToString(sum[:]), Changed: changed}
+	if cli.meta != nil {
+		cli.meta.addOutput(record)
+	}
+	if cli.PrintChecksum {
+		b, _ := json.Marshal(record)
+		fmt.Fprintln(cli.checksums, string(b))
+	}
+}
@@ -0,0 +1,90 @@
+package armed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintChecksum(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outFile := filepath.Join(dir, "out.json")
+
+	// run returns the --print-checksum records of a run
+	run := func(cli *CLI) []checksumRecord {
+		t.Helper()
+		var checksums bytes.Buffer
+		cli.Filename = jsonnetFile
+		cli.PrintChecksum = true
+		cli.writer = &bytes.Buffer{}
+		cli.checksums = &checksums
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var records []checksumRecord
+		for _, line := range strings.Split(strings.TrimSpace(checksums.String()), "\n") {
+			var r checksumRecord
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatalf("invalid line %q: %v", line, err)
+			}
+			records = append(records, r)
+		}
+		return records
+	}
+	want := func(t *testing.T, r checksumRecord, output string, changed *bool) {
+		t.Helper()
+		if r.Output != output {
+			t.Errorf("expected output %q, got %q", output, r.Output)
+		}
+		if (r.Changed == nil) != (changed == nil) || (changed != nil && *r.Changed != *changed) {
+			t.Errorf("expected changed %v, got %v", changed, r.Changed)
+		}
+	}
+	changed, unchanged := true, false
+
+	t.Run("stdout", func(t *testing.T) {
+		records := run(&CLI{})
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %v", records)
+		}
+		want(t, records[0], "-", nil)
+		sum := sha256.Sum256([]byte("{\n   \"a\": 1\n}\n"))
+		if records[0].SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("unexpected sha256 %s", records[0].SHA256)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		records := run(&CLI{Output: []string{outFile}, Stdout: true})
+		if len(records) != 2 {
+			t.Fatalf("expected 2 records, got %v", records)
+		}
+		want(t, records[0], "-", nil)
+		want(t, records[1], outFile, &changed)
+		b, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		if records[1].SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("checksum %s does not match the written file", records[1].SHA256)
+		}
+	})
+
+	t.Run("unchanged file", func(t *testing.T) {
+		records := run(&CLI{Output: []string{outFile}, WriteIfChanged: true})
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %v", records)
+		}
+		want(t, records[0], outFile, &unchanged)
+	})
+}
@@ -5,33 +5,139 @@ import (
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/fujiwara/jsonnet-armed/functions"
 	"github.com/google/go-jsonnet"
 )
 
 // rootCLI is the top-level kong structure. Eval is the default command so
 // that `jsonnet-armed <filename>` keeps working without a subcommand.
+// Each command is a struct with a Run(context.Context) error method.
 type rootCLI struct {
-	Eval  CLI      `cmd:"" default:"withargs" help:"Evaluate a jsonnet file (default command)"`
-	Serve ServeCmd `cmd:"" help:"Serve evaluated jsonnet files over HTTP"`
+	Eval    CLI        `cmd:"" default:"withargs" help:"Evaluate a jsonnet file (default command)"`
+	Serve   ServeCmd   `cmd:"" help:"Serve evaluated jsonnet files over HTTP"`
+	Test    TestCmd    `cmd:"" help:"Run *_test.jsonnet test cases against golden files"`
+	EvalDir EvalDirCmd `cmd:"" name:"eval-dir" help:"Evaluate the *.jsonnet files under a directory into a mirrored output tree"`
+	Diff    DiffCmd    `cmd:"" help:"Compare the results of two evaluations structurally"`
+	Fmt     FmtCmd     `cmd:"" help:"Format jsonnet files"`
+	Lint    LintCmd    `cmd:"" help:"Report problems in jsonnet files"`
+	Deps    DepsCmd    `cmd:"" help:"Print the files the result of a jsonnet file depends on"`
+	Lambda  LambdaCmd  `cmd:"" help:"Run as an AWS Lambda function handler"`
+	Cron    CronCmd    `cmd:"" help:"Keep running and evaluate a jsonnet file on a cron schedule"`
+	Daemon  DaemonCmd  `cmd:"" help:"Keep a warm process that evaluates for invocations with --use-daemon"`
+	Cache   CacheCmd   `cmd:"" help:"Manage the cache of --cache and --incremental"`
+	Docs    DocsCmd    `cmd:"" help:"Print the documentation"`
+
+	Config    kong.ConfigFlag `name:"config" placeholder:"FILE" help:"Load the default values of flags from a YAML file (default: .jsonnet-armed.yaml in the current directory, if any)."`
+	LogLevel  string          `name:"log-level" enum:"debug,info,warn,error" default:"info" help:"Minimum level of the logs written to stderr: debug, info, warn or error."`
+	LogFormat string          `name:"log-format" enum:",text,json" default:"" help:"Format of the logs written to stderr: text (key=value pairs) or json (one object per line); human-readable lines by default."`
 }
 
 type CLI struct {
-	Output         []string          `short:"o" name:"output" help:"Write to the output file(s) or http(s) URL(s) rather than stdout (can be repeated)"`
-	Stdout         bool              `short:"S" name:"stdout" help:"Also write to stdout when using -o/--output" negatable:""`
-	WriteIfChanged bool              `name:"write-if-changed" help:"Write output file only if content has changed"`
-	ExtStr         map[string]string `short:"V" name:"ext-str" help:"Set external string variable (can be repeated)."`
-	ExtCode        map[string]string `name:"ext-code" help:"Set external code variable (can be repeated)."`
-	CompactOutput  bool              `short:"c" name:"compact-output" help:"Output compact JSON (no indentation)."`
-	RawOutput      bool              `short:"r" name:"raw-output" help:"Output raw strings (unquoted) for string values."`
-	Timeout        time.Duration     `short:"t" name:"timeout" help:"Timeout for evaluation (e.g., 30s, 5m, 1h)"`
-	Cache          time.Duration     `name:"cache" help:"Cache evaluation results for specified duration (e.g., 5m, 1h)"`
-	Stale          time.Duration     `name:"stale" help:"Maximum duration to use stale cache when evaluation fails (e.g., 10m, 2h)"`
-	Version        kong.VersionFlag  `short:"v" help:"Show version and exit."`
-	Document       bool              `name:"document" help:"Print full documentation and exit."`
-	DocumentToc    bool              `name:"document-toc" help:"Print documentation table of contents and exit."`
-	DocumentSearch string            `name:"document-search" help:"Search documentation by keyword and print matching sections."`
-
-	Filename string `arg:"" name:"filename" help:"Filename or code to execute" type:"path" optional:""`
+	Output            []string                 `short:"o" name:"output" help:"Write to the output file(s) or http(s) URL(s) rather than stdout (can be repeated)"`
+	Stdout            bool                     `short:"S" name:"stdout" help:"Also write to stdout when using -o/--output" negatable:""`
+	WriteIfChanged    bool                     `name:"write-if-changed" help:"Write output file only if content has changed"`
+	OutputMode        string                   `name:"output-mode" placeholder:"MODE" help:"Permissions of the output files in octal, e.g. 0600 for files containing secrets (default 0644)."`
+	OutputOwner       string                   `name:"output-owner" placeholder:"USER[:GROUP]" help:"Change the owner (and group) of the output files, by name or numeric ID."`
+	Backup            bool                     `name:"backup" help:"Keep the previous content of a changed output file as FILE.bak."`
+	BackupSuffix      string                   `name:"backup-suffix" placeholder:"SUFFIX" help:"Suffix of the backups of --backup (default .bak)."`
+	BackupKeep        int                      `name:"backup-keep" placeholder:"N" help:"Keep N backups of --backup, rotated as FILE.bak, FILE.bak.1, ... (default 1)."`
+	ExtStr            map[string]string        `short:"V" name:"ext-str" help:"Set external string variable (can be repeated)."`
+	ExtCode           map[string]string        `name:"ext-code" help:"Set external code variable (can be repeated)."`
+	ExtStrFile        map[string]string        `name:"ext-str-file" placeholder:"NAME=FILE" help:"Set external string variable NAME to the content of FILE (can be repeated)."`
+	ExtCodeFile       map[string]string        `name:"ext-code-file" placeholder:"NAME=FILE" help:"Set external code variable NAME to the code in FILE (can be repeated)."`
+	TLAStr            map[string]string        `short:"A" name:"tla-str" help:"Set top-level argument string (can be repeated)."`
+	TLACode           map[string]string        `name:"tla-code" help:"Set top-level argument code (can be repeated)."`
+	TLAStrFile        map[string]string        `name:"tla-str-file" placeholder:"NAME=FILE" help:"Set top-level argument NAME to the content of FILE as a string (can be repeated)."`
+	TLACodeFile       map[string]string        `name:"tla-code-file" placeholder:"NAME=FILE" help:"Set top-level argument NAME to the code in FILE (can be repeated)."`
+	ExtJSON           map[string]string        `name:"ext-json" placeholder:"NAME=FILE" help:"Set external code variable NAME to the content of a JSON or YAML file (can be repeated)."`
+	ExtStrStdin       string                   `name:"ext-str-stdin" placeholder:"NAME" help:"Set external string variable NAME to the content of stdin."`
+	Exec              string                   `short:"e" name:"exec" placeholder:"CODE" help:"Evaluate CODE given on the command line instead of <filename>."`
+	CompactOutput     bool                     `short:"c" name:"compact-output" aliases:"compact" help:"Output compact JSON (no indentation)."`
+	Indent            int                      `name:"indent" placeholder:"N" help:"Indent the JSON output by N spaces instead of 3."`
+	SortKeys          bool                     `name:"sort-keys" json:"-" help:"Sort the keys of objects. Accepted for compatibility with jq; the keys are always sorted."`
+	RawOutput         bool                     `short:"r" name:"raw-output" help:"Output raw strings (unquoted) for string values."`
+	OutputBinary      bool                     `name:"output-binary" help:"Decode the result, which must be a base64 string, and output the raw bytes."`
+	Format            string                   `name:"format" enum:"json,msgpack,cbor,toml" default:"json" help:"Output format: json, msgpack, cbor or toml."`
+	Set               []string                 `name:"set" placeholder:"PATH=VALUE" sep:"none" help:"Set the string VALUE at PATH of the result (e.g., spec.containers[0].image) before the output (can be repeated)."`
+	SetJSON           []string                 `name:"set-json" placeholder:"PATH=JSON" sep:"none" help:"Set the JSON value at PATH of the result, after --set (can be repeated)."`
+	ErrorFormat       string                   `name:"error-format" enum:"text,json" default:"text" json:"-" help:"Format of the error written to stderr on failure: text, or json (message, file, line, column and stack trace) for editors and CI annotations."`
+	Color             string                   `name:"color" enum:"auto,always,never" default:"auto" help:"Highlight the JSON output on stdout: auto (when stdout is a terminal and no -o/--output is given), always or never."`
+	Query             string                   `name:"query" placeholder:"FILTER" help:"Apply a jq filter to the result before the output (e.g., '.services[] | select(.enabled)')."`
+	OutputTemplate    string                   `name:"output-template" placeholder:"FILE" type:"path" help:"Render the result with a Go template file instead of outputting JSON."`
+	Timeout           time.Duration            `short:"t" name:"timeout" help:"Timeout for evaluation (e.g., 30s, 5m, 1h)"`
+	MaxStack          int                      `name:"max-stack" placeholder:"N" help:"Maximum depth of the jsonnet stack, for deeply recursive libraries (default 500)."`
+	MaxTrace          int                      `name:"max-trace" placeholder:"N" help:"Maximum number of stack frames shown in error messages (default 20)."`
+	MaxCPU            time.Duration            `name:"max-cpu" placeholder:"DURATION" help:"Abort the evaluation when it uses more CPU time than DURATION (e.g., 10s), independently of --timeout."`
+	Incremental       bool                     `name:"incremental" help:"Skip the evaluation when the input files, imports and the data read by native functions are unchanged since the last run."`
+	Cache             time.Duration            `name:"cache" help:"Cache evaluation results for specified duration (e.g., 5m, 1h)"`
+	Stale             time.Duration            `name:"stale" help:"Maximum duration to use stale cache when evaluation fails (e.g., 10m, 2h)"`
+	ExplainCache      bool                     `name:"explain-cache" json:"-" help:"Print why the cache was hit, missed or used stale to stderr."`
+	OutputRetry       int                      `name:"output-retry" placeholder:"N" help:"Retry writing to HTTP(S) and S3 outputs up to N times on network errors and 5xx or 429 responses."`
+	OutputRetryWait   time.Duration            `name:"output-retry-wait" placeholder:"DURATION" help:"Wait before the first --output-retry retry, doubled for each further retry (default 1s)."`
+	OutputMethod      string                   `name:"output-method" enum:",POST,PUT,PATCH" default:"" placeholder:"METHOD" help:"HTTP method for HTTP(S) outputs: POST (default), PUT or PATCH."`
+	OutputHeader      []string                 `name:"output-header" placeholder:"'NAME: VALUE'" help:"Add a header to the requests of HTTP(S) outputs, e.g. 'Authorization: Bearer ...' (can be repeated)."`
+	OutputStatus      []int                    `name:"output-expect-status" placeholder:"CODE" help:"Status codes of HTTP(S) outputs that are successful (can be repeated; default any 2xx)."`
+	PrintChecksum     bool                     `name:"print-checksum" json:"-" help:"Print a JSON line with the sha256 of each written output (and whether output files changed) to stderr."`
+	MetaOut           string                   `name:"meta-out" placeholder:"FILE" help:"Write a JSON summary of each run (duration, cache status, inputs read and output checksums) to FILE ('-' for stderr)."`
+	ReportFunctions   string                   `name:"report-functions" placeholder:"FILE" help:"Write a JSON report of native function calls to FILE ('-' for stderr)."`
+	Lib               []string                 `short:"J" name:"lib" aliases:"jpath" placeholder:"DIR" type:"path" help:"Make the files in DIR importable by their names, e.g. import 'strings.libsonnet' (can be repeated; later directories take precedence)."`
+	LibHelpers        bool                     `name:"lib-helpers" help:"Bind the *.libsonnet files of --lib directories to std.extVar('helpers'), keyed by their names without the extension."`
+	Prompt            bool                     `name:"prompt" json:"-" help:"When stdin is a terminal, ask for the values of missing ext vars and must_env variables instead of failing."`
+	AutoArmed         bool                     `name:"auto-armed" help:"Make the armed library available as 'armed' without importing armed.libsonnet."`
+	VerifyNatives     bool                     `name:"verify-natives" help:"Check that std.native() calls refer to registered functions before evaluation."`
+	ValidateK8s       bool                     `name:"validate-k8s" help:"Validate the Kubernetes objects in the result against the Kubernetes API schema."`
+	K8sVersion        string                   `name:"k8s-version" placeholder:"VERSION" help:"With --validate-k8s, use the schema of Kubernetes VERSION (e.g., 1.30) fetched from GitHub instead of the built-in one."`
+	Schema            string                   `name:"schema" placeholder:"FILE" type:"path" help:"Validate the result against the JSON Schema (draft 2020-12 unless $schema says otherwise) in FILE before writing the output."`
+	StrictJSON        bool                     `name:"strict-json" help:"Fail when the result has NaN, Infinity or numbers beyond ±(2^53-1), which some JSON parsers cannot read exactly."`
+	RedisURL          string                   `name:"redis-url" placeholder:"URL" env:"JSONNET_ARMED_REDIS_URL" help:"Redis server for redis_get and redis_hgetall (e.g., redis://localhost:6379/0)."`
+	Resolve           []functions.ResolveEntry `name:"resolve" placeholder:"HOST:PORT:ADDRESS" help:"Connect to ADDRESS for HOST:PORT in http functions, and return ADDRESS for HOST in dns_lookup (can be repeated)."`
+	HostsFile         string                   `name:"hosts-file" placeholder:"FILE" type:"path" help:"Resolve the host names in FILE (in the /etc/hosts format) to its addresses in network functions."`
+	FuncTimeout       map[string]time.Duration `name:"func-timeout" placeholder:"FUNC=DURATION" mapsep:"," help:"Override the timeout of exec, exec_with_env, http_get, http_request and dns_lookup (e.g., exec=10s,http_get=5s)."`
+	ExecMaxParallel   int                      `name:"exec-max-parallel" placeholder:"N" help:"Run at most N exec commands at the same time (default unlimited)."`
+	StrictWarnings    bool                     `name:"strict-warnings" help:"Fail the evaluation when native functions emit warnings (e.g., deprecations)."`
+	Heartbeat         time.Duration            `name:"heartbeat" placeholder:"DURATION" help:"Log the elapsed time and the running native function every DURATION while an evaluation takes longer than DURATION (e.g., 5s)."`
+	Trace             bool                     `name:"trace" help:"Log import cache statistics after each evaluation in --watch and cron modes and in the daemon."`
+	Assert            bool                     `name:"assert" help:"Treat the result as an assertion: true or {ok: bool, message: string} controls the exit status."`
+	Mock              string                   `name:"mock" placeholder:"FILE" type:"path" help:"Replace native functions with canned results defined in a JSON or Jsonnet mock file."`
+	Record            string                   `name:"record" placeholder:"FILE" type:"path" xor:"cassette" help:"Record the native function calls running commands or accessing the network, and their results, to a cassette file."`
+	Redact            bool                     `name:"redact" help:"Mask values marked with secret() as *** in stdout output; -o/--output targets get the real values."`
+	Sandbox           bool                     `name:"sandbox" help:"Disable the native functions running commands, accessing the network or reading files (all of --no-exec, --no-net and --no-fs)."`
+	NoExec            bool                     `name:"no-exec" help:"Disable the exec native functions."`
+	NoNet             bool                     `name:"no-net" help:"Disable the native functions accessing the network (http, dns, AWS, databases, ...)."`
+	NoFS              bool                     `name:"no-fs" help:"Disable the native functions reading files (file_* and *_file)."`
+	AllowFn           []string                 `name:"allow-fn" placeholder:"PATTERN" help:"Allow only the native functions whose names match the glob PATTERN, e.g. 'sha*' (can be repeated)."`
+	DenyFn            []string                 `name:"deny-fn" placeholder:"PATTERN" help:"Disable the native functions whose names match the glob PATTERN, e.g. 'http_*' (can be repeated)."`
+	DryRun            bool                     `name:"dry-run" help:"Do not run exec and http native functions or write outputs; report the planned side effects to stderr instead."`
+	Diff              bool                     `name:"diff" help:"Compare the result with the current content of the -o/--output files instead of writing them; print the differences and fail if they differ."`
+	DiffFormat        string                   `name:"diff-format" enum:"unified,structural" default:"unified" help:"Format of the --diff output: unified (line diff) or structural (changes at JSON paths)."`
+	Check             bool                     `name:"check" help:"Evaluate and compare the result with the -o/--output files without writing them; exit 0 if nothing would change, 1 if an output would change and 2 on errors."`
+	ListDeps          bool                     `name:"list-deps" help:"Evaluate and print the files the result depends on as a Make rule for the -o/--output files, instead of writing them."`
+	Replay            string                   `name:"replay" placeholder:"FILE" type:"path" xor:"cassette" help:"Serve the native function calls recorded in a cassette file by --record."`
+	MergeStrategy     string                   `name:"merge-strategy" enum:"deep,append,shallow" default:"deep" help:"How to merge the results of overlay files: deep, append (deep, concatenating arrays) or shallow."`
+	ExitCodeError     int                      `name:"exit-code-error" placeholder:"N" help:"Exit status when the evaluation or writing fails (default 1)."`
+	ExitCodeTimeout   int                      `name:"exit-code-timeout" placeholder:"N" help:"Exit status when the evaluation times out (default: --exit-code-error)."`
+	ExitCodeAssert    int                      `name:"exit-code-assert" placeholder:"N" help:"Exit status when an --assert assertion fails (default: --exit-code-error)."`
+	ExitCodeChanged   int                      `name:"exit-code-changed" placeholder:"N" help:"Exit status when an output file was created or its content changed (default 0)."`
+	ExitCodeUnchanged int                      `name:"exit-code-unchanged" placeholder:"N" help:"Exit status when all output files already had the same content (default 0)."`
+	Watch             bool                     `name:"watch" help:"Keep running and evaluate again whenever the input files or their imports change."`
+	WatchInterval     time.Duration            `name:"watch-interval" placeholder:"DURATION" help:"Interval for checking the input files in --watch mode (default 1s)."`
+	OnChange          string                   `name:"on-change" placeholder:"COMMAND" help:"In --watch mode, run COMMAND with sh after each regeneration that changed the output; {} is replaced by the output file path."`
+	MetricsDest       string                   `name:"metrics-destination" enum:",cloudwatch,datadog,statsd" default:"" help:"Publish evaluation metrics to cloudwatch, datadog (DogStatsD) or statsd after each run."`
+	MetricsAddress    string                   `name:"metrics-address" placeholder:"HOST:PORT" help:"Address of the statsd or DogStatsD agent (default 127.0.0.1:8125)."`
+	MetricsTag        map[string]string        `name:"metrics-tag" placeholder:"KEY=VALUE" help:"Add a tag (CloudWatch dimension) to the metrics (can be repeated)."`
+	NotifyURL         string                   `name:"notify-url" placeholder:"URL" help:"Post a message to a Slack-compatible webhook URL when the evaluation or writing fails."`
+	UseDaemon         bool                     `name:"use-daemon" env:"JSONNET_ARMED_USE_DAEMON" help:"Delegate the evaluation to a running 'jsonnet-armed daemon'; evaluate in process if it is not running."`
+	DaemonSocket      string                   `name:"daemon-socket" placeholder:"PATH" env:"JSONNET_ARMED_DAEMON_SOCKET" help:"Unix socket of the daemon for --use-daemon (default $XDG_RUNTIME_DIR/jsonnet-armed.sock)."`
+	Version           bool                     `short:"v" name:"version" help:"Show version and exit."`
+	VersionJSON       bool                     `name:"json" help:"With --version, print the version, build metadata and native function names as JSON."`
+	Document          bool                     `name:"document" help:"Print full documentation and exit."`
+	DocumentToc       bool                     `name:"document-toc" help:"Print documentation table of contents and exit."`
+	DocumentSearch    string                   `name:"document-search" help:"Search documentation by keyword and print matching sections."`
+
+	AWSFlags `embed:""`
+
+	Filename string   `arg:"" name:"filename" help:"Filename or code to execute, or an http(s) or s3:// URL to fetch it from" type:"input" optional:""`
+	Overlays []string `arg:"" name:"overlay" help:"Files whose results are merged over the result of <filename>, left to right" type:"path" optional:""`
 
 	// writer for output (not exposed to CLI, used internally)
 	writer io.Writer `kong:"-"`
@@ -41,4 +147,60 @@ type CLI struct {
 
 	// functions holds additional native functions to be added to the Jsonnet VM
 	functions []*jsonnet.NativeFunction `kong:"-"`
+
+	// mocks holds mock rules for native functions (used by the test command)
+	mocks []mockRule `kong:"-"`
+
+	// secrets collects values marked with secret() for --redact
+	secrets *functions.SecretCollector `kong:"-"`
+
+	// plan collects side effects skipped by --dry-run
+	plan *dryRunPlan `kong:"-"`
+
+	// inputs are the files read by the last evaluation, watched by --watch
+	inputs []string `kong:"-"`
+
+	// meta collects the summary of the current run for --meta-out
+	meta *runMeta `kong:"-"`
+
+	// hooks set by the Go API (OnNativeCall, OnCacheHit and OnResult)
+	onNativeCall func(NativeCall)               `kong:"-"`
+	onCacheHit   func(key string, stale bool)   `kong:"-"`
+	onResult     func(result string, err error) `kong:"-"`
+
+	// outputChanged and outputUnchanged count file outputs (and HTTP(S)
+	// outputs with --write-if-changed) whose content changed or stayed the
+	// same, for --exit-code-changed/unchanged
+	outputChanged   int `kong:"-"`
+	outputUnchanged int `kong:"-"`
+
+	// tracker records the inputs of the evaluation for --incremental
+	tracker *inputTracker `kong:"-"`
+
+	// explain receives the diagnostics of --explain-cache (stderr by default)
+	explain io.Writer `kong:"-"`
+
+	// checksums receives the lines of --print-checksum (stderr by default)
+	checksums io.Writer `kong:"-"`
+
+	// errorOutput receives the error of --error-format json (stderr by default)
+	errorOutput io.Writer `kong:"-"`
+
+	// cacheStatus is the cache status of the last run (hit, stale or miss), for --metrics-destination
+	cacheStatus string `kong:"-"`
+
+	// vms keeps VMs with parsed imports between the evaluations of long-lived modes
+	vms *vmCache `kong:"-"`
+
+	// k8sSchema is the schema loaded for --validate-k8s
+	k8sSchema *k8sSchema `kong:"-"`
+
+	// stdoutTerminal is set when stdout is a TTY, for --color auto
+	stdoutTerminal bool `kong:"-"`
+
+	// prompter asks for missing variables with --prompt (set when stdin is a terminal)
+	prompter *prompter `kong:"-"`
+
+	// prettyErrors enables error reports with source excerpts (set when stderr is a TTY)
+	prettyErrors bool `kong:"-"`
 }
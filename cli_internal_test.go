@@ -1,6 +1,9 @@
 package armed
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/alecthomas/kong"
@@ -15,9 +18,24 @@ func TestRootCLIDispatch(t *testing.T) {
 		{"filename only", []string{"testdata/server/static.jsonnet"}, "eval <filename>"},
 		{"flag before filename", []string{"-c", "testdata/server/static.jsonnet"}, "eval <filename>"},
 		{"stdin", []string{"-"}, "eval <filename>"},
+		{"exec", []string{"-e", `std.native("sha256")("x")`}, "eval"},
+		{"overlays", []string{"base.jsonnet", "overlay.jsonnet", "prod.jsonnet"}, "eval <filename> <overlay>"},
 		{"document flag only", []string{"--document"}, "eval"},
 		{"serve", []string{"serve", "testdata/server"}, "serve <dir>"},
 		{"serve with listen", []string{"serve", "--listen", "127.0.0.1:0", "testdata/server"}, "serve <dir>"},
+		{"test", []string{"test"}, "test"},
+		{"test with path", []string{"test", "--update", "testdata/testcmd"}, "test <path>"},
+		{"diff", []string{"diff", "a.jsonnet", "b.jsonnet"}, "diff <file>"},
+		{"diff with ext vars", []string{"diff", "--a-ext-str", "env=dev", "--b-ext-str", "env=prod", "app.jsonnet"}, "diff <file>"},
+		{"fmt", []string{"fmt", "--check", "testdata"}, "fmt <path>"},
+		{"lint", []string{"lint", "--format", "json", "testdata"}, "lint <path>"},
+		{"deps", []string{"deps", "-o", "out.json", "app.jsonnet"}, "deps <filename>"},
+		{"sort keys", []string{"--sort-keys", "--indent", "2", "app.jsonnet"}, "eval <filename>"},
+		{"log flags", []string{"--log-level", "debug", "--log-format", "json", "testdata/server/static.jsonnet"}, "eval <filename>"},
+		{"log flags after a command", []string{"lint", "--log-format", "text", "testdata"}, "lint <path>"},
+		{"docs", []string{"docs", "--search", "hash"}, "docs"},
+		{"cache clear", []string{"cache", "clear"}, "cache clear"},
+		{"version as json", []string{"--version", "--json"}, "eval"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -36,3 +54,36 @@ func TestRootCLIDispatch(t *testing.T) {
 		})
 	}
 }
+
+func TestLibFlagAliases(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(wd, "a"), filepath.Join(wd, "b"), filepath.Join(wd, "c")}
+
+	tests := []struct {
+		name string
+		args []string
+		lib  func(*rootCLI) []string
+	}{
+		{"eval", []string{"-J", "a", "--jpath", "b", "--lib", "c", "app.jsonnet"}, func(r *rootCLI) []string { return r.Eval.Lib }},
+		{"eval-dir", []string{"eval-dir", "-o", "out", "-J", "a", "--jpath", "b", "--lib", "c", "testdata"}, func(r *rootCLI) []string { return r.EvalDir.Lib }},
+		{"lint", []string{"lint", "-J", "a", "--jpath", "b", "--lib", "c", "testdata"}, func(r *rootCLI) []string { return r.Lint.Lib }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &rootCLI{}
+			parser, err := kong.New(root, kong.Vars{"version": "test"}, kong.NamedMapper("input", inputMapper))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.lib(root); !slices.Equal(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
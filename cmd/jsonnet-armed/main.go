@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -13,6 +14,14 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), signals()...)
 	defer stop()
 	if err := run(ctx); err != nil {
+		var exitErr *app.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil && !exitErr.Reported {
+				slog.Error(exitErr.Err.Error())
+			}
+			stop()
+			os.Exit(exitErr.Code)
+		}
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
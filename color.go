@@ -0,0 +1,83 @@
+package armed
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiGray   = "\x1b[90m"
+)
+
+// Modes of --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor reports whether the JSON output on stdout is highlighted. In the
+// auto mode, it is when stdout is a terminal, no -o/--output is given and
+// NO_COLOR is not set.
+func (cli *CLI) useColor() bool {
+	if cli.isNonJSONFormat() || cli.OutputTemplate != "" || cli.OutputBinary || cli.RawOutput {
+		return false
+	}
+	switch cli.Color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return cli.stdoutTerminal && len(cli.Output) == 0 && os.Getenv("NO_COLOR") == ""
+}
+
+// colorizeJSON highlights JSON text with ANSI colors, keeping its layout:
+// object keys in bold blue, strings in green, numbers in cyan, booleans in
+// yellow and null in gray.
+func colorizeJSON(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(s)) // closing quote
+			color := ansiGreen
+			if strings.HasPrefix(strings.TrimLeft(s[end:], " \t\r\n"), ":") {
+				color = ansiBold + ansiBlue
+			}
+			b.WriteString(color + s[i:end] + ansiReset)
+			i = end
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(s) && strings.IndexByte("0123456789.eE+-", s[end]) >= 0 {
+				end++
+			}
+			b.WriteString(ansiCyan + s[i:end] + ansiReset)
+			i = end
+		case strings.HasPrefix(s[i:], "true"):
+			b.WriteString(ansiYellow + "true" + ansiReset)
+			i += len("true")
+		case strings.HasPrefix(s[i:], "false"):
+			b.WriteString(ansiYellow + "false" + ansiReset)
+			i += len("false")
+		case strings.HasPrefix(s[i:], "null"):
+			b.WriteString(ansiGray + "null" + ansiReset)
+			i += len("null")
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package armed
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestColorizeJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "object",
+			input:    `{"a": "x", "b": 1}`,
+			expected: "{\x1b[1m\x1b[34m\"a\"\x1b[0m: \x1b[32m\"x\"\x1b[0m, \x1b[1m\x1b[34m\"b\"\x1b[0m: \x1b[36m1\x1b[0m}",
+		},
+		{
+			name:     "literals",
+			input:    `[true, false, null, -2.5e+3]`,
+			expected: "[\x1b[33mtrue\x1b[0m, \x1b[33mfalse\x1b[0m, \x1b[90mnull\x1b[0m, \x1b[36m-2.5e+3\x1b[0m]",
+		},
+		{
+			name:     "escaped quotes and colons in strings",
+			input:    `{"k\"1": "v: \"true\""}`,
+			expected: "{\x1b[1m\x1b[34m\"k\\\"1\"\x1b[0m: \x1b[32m\"v: \\\"true\\\"\"\x1b[0m}",
+		},
+	}
+	ansi := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := colorizeJSON(tt.input)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if plain := ansi.ReplaceAllString(got, ""); plain != tt.input {
+				t.Errorf("layout changed: %q", plain)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		cli      CLI
+		noColor  string
+		expected bool
+	}{
+		{name: "auto on terminal", cli: CLI{Color: colorAuto, stdoutTerminal: true}, expected: true},
+		{name: "auto not on terminal", cli: CLI{Color: colorAuto}, expected: false},
+		{name: "auto with output", cli: CLI{Color: colorAuto, stdoutTerminal: true, Output: []string{"out.json"}}, expected: false},
+		{name: "auto with NO_COLOR", cli: CLI{Color: colorAuto, stdoutTerminal: true}, noColor: "1", expected: false},
+		{name: "always", cli: CLI{Color: colorAlways}, expected: true},
+		{name: "never", cli: CLI{Color: colorNever, stdoutTerminal: true}, expected: false},
+		{name: "raw output", cli: CLI{Color: colorAlways, RawOutput: true}, expected: false},
+		{name: "binary format", cli: CLI{Color: colorAlways, Format: formatCBOR}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			if got := tt.cli.useColor(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/alecthomas/kong"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultConfigFile is loaded from the current directory when it exists
+const defaultConfigFile = ".jsonnet-armed.yaml"
+
+// configResolver provides the default values of flags from a YAML config
+// file. Top-level keys are flag names applied to every command; a key
+// naming a command holds the flags for that command only, e.g.
+//
+//	lib: [lib]
+//	cache: 5m
+//	lint:
+//	  format: json
+//
+// Flags given on the command line take precedence.
+type configResolver struct {
+	// dir is the directory of the config file, to which relative paths are resolved
+	dir    string
+	values map[string]any
+}
+
+var _ kong.Resolver = (*configResolver)(nil)
+
+// loadConfig is the kong.ConfigurationLoader of the config files
+func loadConfig(r io.Reader) (kong.Resolver, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	c := &configResolver{values: map[string]any{}}
+	if err := json.Unmarshal(j, &c.values); err != nil && string(j) != "null" {
+		return nil, fmt.Errorf("config must be a mapping of flag names to values")
+	}
+	if f, ok := r.(interface{ Name() string }); ok {
+		if abs, err := filepath.Abs(f.Name()); err == nil {
+			c.dir = filepath.Dir(abs)
+		}
+	}
+	return c, nil
+}
+
+// Validate rejects unknown flag names, so that typos are not ignored
+func (c *configResolver) Validate(app *kong.Application) error {
+	flags := map[string]bool{}
+	commands := map[string]*kong.Node{}
+	var walk func(n *kong.Node)
+	walk = func(n *kong.Node) {
+		for _, f := range n.Flags {
+			flags[f.Name] = true
+		}
+		for _, child := range n.Children {
+			if child.Type == kong.CommandNode {
+				commands[child.Name] = child
+			}
+			walk(child)
+		}
+	}
+	walk(app.Node)
+
+	for _, key := range slices.Sorted(maps.Keys(c.values)) {
+		section, isMap := c.values[key].(map[string]any)
+		cmd, isCommand := commands[key]
+		if isCommand && isMap {
+			known := map[string]bool{}
+			for _, group := range cmd.AllFlags(false) {
+				for _, f := range group {
+					known[f.Name] = true
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(section)) {
+				if !known[name] {
+					return fmt.Errorf("config: unknown flag %q for the %s command", name, key)
+				}
+			}
+			continue
+		}
+		if !flags[key] {
+			return fmt.Errorf("config: unknown flag or command %q", key)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the value of flag in the section of its command, or at
+// the top level
+func (c *configResolver) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	if parent.Command != nil {
+		if section, ok := c.values[parent.Command.Name].(map[string]any); ok {
+			if v, ok := section[flag.Name]; ok {
+				return c.resolvePaths(flag, v), nil
+			}
+		}
+	}
+	v, ok := c.values[flag.Name]
+	if !ok {
+		return nil, nil
+	}
+	if _, isMap := v.(map[string]any); isMap && flag.Target.Kind() != reflect.Map {
+		// A section of a command with the same name as the flag
+		return nil, nil
+	}
+	return c.resolvePaths(flag, v), nil
+}
+
+// resolvePaths makes the relative paths of a path flag relative to the
+// directory of the config file instead of the current directory
+func (c *configResolver) resolvePaths(flag *kong.Flag, v any) any {
+	if c.dir == "" || flag.Tag.Type != "path" {
+		return v
+	}
+	abs := func(p any) any {
+		if s, ok := p.(string); ok && s != "" && s != "-" && !filepath.IsAbs(s) {
+			return filepath.Join(c.dir, s)
+		}
+		return p
+	}
+	if list, ok := v.([]any); ok {
+		resolved := make([]any, len(list))
+		for i, p := range list {
+			resolved[i] = abs(p)
+		}
+		return resolved
+	}
+	return abs(v)
+}
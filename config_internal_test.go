@@ -0,0 +1,111 @@
+package armed
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte(`
+lib: [lib, /opt/jsonnet]
+ext-str:
+  env: prod
+cache: 5m
+compact-output: true
+output-retry: 3
+lint:
+  format: json
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(t *testing.T, args ...string) *rootCLI {
+		t.Helper()
+		root := &rootCLI{}
+		parser, err := kong.New(root, kong.Vars{"version": "test"}, kong.NamedMapper("input", inputMapper), kong.Configuration(loadConfig, config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		root := parse(t, "app.jsonnet")
+		if want := []string{filepath.Join(dir, "lib"), "/opt/jsonnet"}; !slices.Equal(root.Eval.Lib, want) {
+			t.Errorf("lib: got %q, want %q", root.Eval.Lib, want)
+		}
+		if root.Eval.ExtStr["env"] != "prod" {
+			t.Errorf("ext-str: got %v", root.Eval.ExtStr)
+		}
+		if root.Eval.Cache != 5*time.Minute || !root.Eval.CompactOutput || root.Eval.OutputRetry != 3 {
+			t.Errorf("got cache=%v compact-output=%v output-retry=%d", root.Eval.Cache, root.Eval.CompactOutput, root.Eval.OutputRetry)
+		}
+		if root.Eval.Format != "json" {
+			t.Errorf("a section of another command must not apply: format=%q", root.Eval.Format)
+		}
+	})
+
+	t.Run("command line takes precedence", func(t *testing.T) {
+		root := parse(t, "-J", "mylib", "-V", "env=dev", "--cache", "1m", "app.jsonnet")
+		if want := []string{filepath.Join(wd, "mylib")}; !slices.Equal(root.Eval.Lib, want) {
+			t.Errorf("lib: got %q, want %q", root.Eval.Lib, want)
+		}
+		if root.Eval.ExtStr["env"] != "dev" || root.Eval.Cache != time.Minute {
+			t.Errorf("got ext-str=%v cache=%v", root.Eval.ExtStr, root.Eval.Cache)
+		}
+	})
+
+	t.Run("command section", func(t *testing.T) {
+		root := parse(t, "lint", "testdata")
+		if root.Lint.Format != "json" {
+			t.Errorf("format: got %q, want json", root.Lint.Format)
+		}
+		if want := []string{filepath.Join(dir, "lib"), "/opt/jsonnet"}; !slices.Equal(root.Lint.Lib, want) {
+			t.Errorf("lib: got %q, want %q", root.Lint.Lib, want)
+		}
+	})
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown flag", "no-such-flag: true\n", `config: unknown flag or command "no-such-flag"`},
+		{"unknown flag of a command", "fmt:\n  format: json\n", `config: unknown flag "format" for the fmt command`},
+		{"not a mapping", "- lib\n", "config must be a mapping"},
+		{"invalid value", "cache: soon\n", "--cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(config, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			root := &rootCLI{}
+			parser, err := kong.New(root, kong.Vars{"version": "test"}, kong.Configuration(loadConfig, config))
+			if err == nil {
+				_, err = parser.Parse([]string{"app.jsonnet"})
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
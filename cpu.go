@@ -0,0 +1,42 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCPULimit is returned by CLI.Run when the evaluation exceeds --max-cpu.
+var ErrCPULimit = errors.New("evaluation exceeded the CPU time limit")
+
+// cpuCheckInterval is the interval for checking the CPU time used by the process
+const cpuCheckInterval = 100 * time.Millisecond
+
+// watchCPUTime returns a channel that is closed when the process has used
+// more than limit of CPU time since the call, until ctx is done. Only one
+// evaluation runs at a time in the CLI, so the CPU time of the process is
+// that of the evaluation.
+func watchCPUTime(ctx context.Context, limit time.Duration) (<-chan struct{}, error) {
+	start, err := processCPUTime()
+	if err != nil {
+		return nil, err
+	}
+	exceeded := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(min(cpuCheckInterval, limit))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now, err := processCPUTime()
+				if err == nil && now-start > limit {
+					close(exceeded)
+					return
+				}
+			}
+		}
+	}()
+	return exceeded, nil
+}
@@ -0,0 +1,60 @@
+package armed
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+func TestMaxCPU(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "busy.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ result: std.native('spin')() }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// spin busy-loops until the test ends, as a runaway template would
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	spin := &jsonnet.NativeFunction{
+		Name: "spin",
+		Func: func(args []any) (any, error) {
+			for {
+				select {
+				case <-stop:
+					return nil, nil
+				default:
+				}
+			}
+		},
+	}
+
+	cli := &CLI{
+		Filename:        jsonnetFile,
+		MaxCPU:          200 * time.Millisecond,
+		Timeout:         time.Minute,
+		ExitCodeTimeout: 4,
+		functions:       []*jsonnet.NativeFunction{spin},
+		// the aborted evaluation completes in background after the test ends
+		writer: io.Discard,
+	}
+	start := time.Now()
+	err := cli.run(t.Context())
+	if !errors.Is(err, ErrCPULimit) {
+		t.Fatalf("expected ErrCPULimit, got %v", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("CPU time limit must be distinct from the timeout: %v", err)
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 4 {
+		t.Errorf("expected exit status 4, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("evaluation was aborted after %v", elapsed)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package armed
+
+import (
+	"fmt"
+	"time"
+)
+
+func processCPUTime() (time.Duration, error) {
+	return 0, fmt.Errorf("--max-cpu is not supported on this platform")
+}
@@ -0,0 +1,98 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronCmd keeps running and evaluates a file on a cron schedule.
+// It accepts the flags of the eval command.
+type CronCmd struct {
+	Schedule string `name:"schedule" required:"" placeholder:"SPEC" help:"Cron schedule (e.g. \"*/5 * * * *\", \"@hourly\", \"@every 10m\"; prefix with CRON_TZ=<zone> for a time zone)."`
+
+	CLI `embed:""`
+}
+
+// Run evaluates the file on the schedule until ctx is canceled
+func (c *CronCmd) Run(ctx context.Context) error {
+	if c.writer == nil {
+		c.writer = os.Stdout
+	}
+	schedule, err := cron.ParseStandard(c.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid --schedule %q: %w", c.Schedule, err)
+	}
+	if c.Filename == "" {
+		return fmt.Errorf("<filename> is required")
+	}
+	if c.Filename == "-" || c.ExtStrStdin != "" {
+		return fmt.Errorf("cron cannot be used with stdin")
+	}
+	if c.Watch || c.OnChange != "" {
+		return fmt.Errorf("cron cannot be used with --watch or --on-change")
+	}
+	if err := c.prepare(); err != nil {
+		return err
+	}
+	return c.cron(ctx, schedule)
+}
+
+// cron runs the evaluation at each activation of schedule until ctx is
+// canceled. Runs never overlap: activations missed while a run is in
+// progress are skipped. Failed runs are logged and do not stop the loop.
+func (cli *CLI) cron(ctx context.Context, schedule cron.Schedule) error {
+	if cli.vms == nil {
+		cli.vms = newVMCache()
+	}
+	probe := make(loopProbe)
+	stopping := systemdReady(ctx, "Scheduled "+cli.Filename, probe.alive)
+	defer stopping()
+	for {
+		next := schedule.Next(time.Now())
+		slog.Info("Next run scheduled", "filename", cli.Filename, "at", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-probe:
+			case <-timer.C:
+				break wait
+			}
+		}
+
+		cli.outputChanged, cli.outputUnchanged = 0, 0
+		start := time.Now()
+		// A run in progress completes on shutdown (bounded by --timeout),
+		// so that outputs are not left half written
+		err := cli.runOnce(context.WithoutCancel(ctx))
+		// Exit statuses do not apply to scheduled runs
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			err = exitErr.Err
+		}
+		attrs := []any{
+			"filename", cli.Filename,
+			"duration", time.Since(start).String(),
+		}
+		if outputs := cli.outputChanged + cli.outputUnchanged; outputs > 0 {
+			attrs = append(attrs, "changed", cli.outputChanged, "unchanged", cli.outputUnchanged)
+		}
+		if cli.cacheStatus != "" {
+			attrs = append(attrs, "cache", cli.cacheStatus)
+		}
+		if err != nil {
+			slog.Error("Run failed", append(attrs, "error", err.Error())...)
+			continue
+		}
+		slog.Info("Run succeeded", attrs...)
+	}
+}
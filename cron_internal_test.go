@@ -0,0 +1,108 @@
+package armed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// intervalSchedule activates every interval; cron schedules have a resolution of one second
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+func TestCron(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	outputFile := filepath.Join(tmpDir, "out.json")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ v: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cli := &CLI{Filename: jsonnetFile, Output: []string{outputFile}, WriteIfChanged: true}
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.cron(ctx, intervalSchedule(20*time.Millisecond))
+	}()
+
+	readOutput := func() string {
+		b, _ := os.ReadFile(outputFile)
+		return string(b)
+	}
+	waitForOutput := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(readOutput(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %q, got %q", want, readOutput())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForOutput(`"v": 1`)
+
+	// A failed run does not stop the schedule
+	if err := os.WriteFile(jsonnetFile, []byte(`{ v: `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(jsonnetFile, []byte(`{ v: 2 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(`"v": 2`)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cron did not stop after cancel")
+	}
+}
+
+func TestCronCmdErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  CronCmd
+		want string
+	}{
+		{name: "invalid schedule", cmd: CronCmd{Schedule: "* * *", CLI: CLI{Filename: "a.jsonnet"}}, want: "invalid --schedule"},
+		{name: "no filename", cmd: CronCmd{Schedule: "@hourly"}, want: "<filename> is required"},
+		{name: "stdin", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "-"}}, want: "cannot be used with stdin"},
+		{name: "watch", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet", Watch: true}}, want: "cannot be used with --watch"},
+		{name: "invalid output header", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet", OutputHeader: []string{"Authorization"}}}, want: "invalid --output-header"},
+		{name: "negative backup keep", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet", Backup: true, BackupKeep: -1}}, want: "--backup-keep must not be negative"},
+		{name: "negative max stack", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet", MaxStack: -1}}, want: "--max-stack must not be negative"},
+		{name: "invalid query", cmd: CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet", Query: ".["}}, want: "invalid --query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cmd.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCronCmdExtVarsFromEnv(t *testing.T) {
+	t.Setenv("JSONNET_ARMED_EXT_STR_env", "prod")
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	cmd := CronCmd{Schedule: "@hourly", CLI: CLI{Filename: "a.jsonnet"}}
+	if err := cmd.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cmd.ExtStr["env"]; got != "prod" {
+		t.Errorf("ext-str env: got %q, want prod", got)
+	}
+}
@@ -0,0 +1,314 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+// DaemonCmd keeps a warm process that evaluates on behalf of CLI
+// invocations with --use-daemon, sparing them the process startup and the
+// initialization of native functions.
+type DaemonCmd struct {
+	Socket string `name:"socket" placeholder:"PATH" help:"Unix socket path to listen on (default $XDG_RUNTIME_DIR/jsonnet-armed.sock)."`
+
+	// mu serializes evaluations, which run in the client's working directory and environment
+	mu sync.Mutex
+	// vms keeps VMs with parsed imports between evaluations
+	vms *vmCache
+}
+
+// Reload discards the in-memory state of the daemon: VMs with parsed
+// imports, AWS credentials and database and Redis connections. It waits for
+// the evaluation in progress.
+func (d *DaemonCmd) Reload() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.vms = newVMCache()
+	resetSharedState()
+	slog.Info("jsonnet-armed daemon reloaded")
+}
+
+// daemonRequest is an evaluation delegated by a client
+type daemonRequest struct {
+	Dir string   `json:"dir"`
+	Env []string `json:"env"`
+	CLI *CLI     `json:"cli"`
+}
+
+// daemonResponse is the result of a delegated evaluation
+type daemonResponse struct {
+	Stdout   []byte `json:"stdout,omitempty"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+// defaultDaemonSocket returns the socket path shared by the daemon and its
+// clients. Without XDG_RUNTIME_DIR, the socket is in a directory of its own
+// under the temporary directory, which the daemon keeps private to the user.
+func defaultDaemonSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "jsonnet-armed.sock")
+	}
+	return filepath.Join(os.TempDir(), "jsonnet-armed-"+strconv.Itoa(os.Getuid()), "daemon.sock")
+}
+
+// ensurePrivateDir creates dir with mode 0700 unless it exists, and fails
+// unless it is a directory owned by the user and closed to others
+func ensurePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by the current user", dir, uid)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is accessible by other users (mode %s)", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// checkDaemonSocket fails when the socket is owned by another user, who
+// would receive the environment of the client
+func checkDaemonSocket(socket string) error {
+	fi, err := os.Stat(socket)
+	if err != nil {
+		// Connecting reports a missing socket
+		return nil
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by the current user", socket, uid)
+	}
+	return nil
+}
+
+// Run listens on the socket and serves evaluations until ctx is canceled
+func (d *DaemonCmd) Run(ctx context.Context) error {
+	socket := d.Socket
+	if socket == "" {
+		socket = defaultDaemonSocket()
+		if err := ensurePrivateDir(filepath.Dir(socket)); err != nil {
+			return fmt.Errorf("insecure socket directory: %w", err)
+		}
+	}
+	// Remove a socket left by a previous daemon, unless it is alive
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("daemon is already running on %s", socket)
+	}
+	os.Remove(socket)
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	defer os.Remove(socket)
+	// Evaluations run with the daemon's privileges; allow only the owner
+	if err := os.Chmod(socket, 0600); err != nil {
+		ln.Close()
+		return err
+	}
+	return d.Serve(ctx, ln)
+}
+
+// Serve serves evaluations on the listener until ctx is canceled
+func (d *DaemonCmd) Serve(ctx context.Context, ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /eval", d.handleEval)
+	srv := &http.Server{Handler: mux}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+	slog.Info("jsonnet-armed daemon starting", "socket", ln.Addr().String())
+	go reloadOnSignal(ctx, d.Reload)
+	stopping := systemdReady(ctx, "Listening on "+ln.Addr().String(), httpAlive(ln))
+	select {
+	case <-ctx.Done():
+		stopping()
+		sctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			srv.Close()
+			return err
+		}
+		return nil
+	case err := <-errCh:
+		stopping()
+		return err
+	}
+}
+
+func (d *DaemonCmd) handleEval(w http.ResponseWriter, r *http.Request) {
+	var req daemonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CLI == nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	res := d.evaluate(r.Context(), &req)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// evaluate runs the delegated evaluation in the client's working directory
+// and environment
+func (d *DaemonCmd) evaluate(ctx context.Context, req *daemonRequest) daemonResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	restore, err := enterClientContext(req.Dir, req.Env)
+	if err != nil {
+		return daemonResponse{Error: err.Error(), ExitCode: 1}
+	}
+	defer restore()
+	// AWS configurations hold the credentials of the environment they were
+	// loaded in; do not serve them to another client
+	functions.ResetAWSConfigs()
+
+	var stdout bytes.Buffer
+	cli := req.CLI
+	cli.writer = &stdout
+	if d.vms == nil {
+		d.vms = newVMCache()
+	}
+	cli.vms = d.vms
+	start := time.Now()
+	err = cli.runOnce(ctx)
+	slog.Info("Evaluated", "filename", cli.Filename, "dir", req.Dir, "duration", time.Since(start).String())
+
+	res := daemonResponse{Stdout: stdout.Bytes()}
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.Code
+		if exitErr.Err != nil {
+			res.Error = exitErr.Err.Error()
+		}
+	case err != nil:
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// enterClientContext switches the working directory and the environment
+// to the client's. The returned function restores the daemon's.
+func enterClientContext(dir string, env []string) (func(), error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("failed to change directory to %s: %w", dir, err)
+	}
+	saved := os.Environ()
+	setEnviron(env)
+	return func() {
+		setEnviron(saved)
+		os.Chdir(wd)
+	}, nil
+}
+
+func setEnviron(env []string) {
+	os.Clearenv()
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+// delegatable reports whether the evaluation can run in the daemon.
+// Reading stdin, -e/--exec (whose imports are relative to the working
+// directory), watching, dry runs, prompts, --explain-cache and
+// --print-checksum stay in the client process.
+func (cli *CLI) delegatable() bool {
+	return cli.Filename != "-" && cli.Exec == "" && cli.ExtStrStdin == "" && !cli.Watch && !cli.DryRun && !cli.Prompt &&
+		!cli.ExplainCache && !cli.PrintChecksum && !cli.ListDeps && cli.ErrorFormat != errorFormatJSON &&
+		len(cli.functions) == 0 && len(cli.mocks) == 0
+}
+
+// delegate runs the evaluation in the daemon. handled is false when the
+// daemon is not running, and the caller evaluates by itself.
+func (cli *CLI) delegate(ctx context.Context) (handled bool, err error) {
+	socket := cli.DaemonSocket
+	if socket == "" {
+		socket = defaultDaemonSocket()
+	}
+	if err := checkDaemonSocket(socket); err != nil {
+		slog.Warn("Refusing to delegate to the daemon, evaluating in process", "error", err.Error())
+		return false, nil
+	}
+	var dialErr error
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				conn, err := d.DialContext(ctx, "unix", socket)
+				dialErr = err
+				return conn, err
+			},
+		},
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return true, err
+	}
+	body, err := json.Marshal(daemonRequest{Dir: dir, Env: os.Environ(), CLI: cli})
+	if err != nil {
+		return true, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://daemon/eval", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if dialErr != nil && ctx.Err() == nil {
+			slog.Debug("Daemon is not available, evaluating in process", "socket", socket, "error", dialErr.Error())
+			return false, nil
+		}
+		// The daemon may have started the evaluation; do not run it twice
+		return true, fmt.Errorf("daemon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return true, fmt.Errorf("daemon request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(b))
+	}
+	var res daemonResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return true, fmt.Errorf("invalid daemon response: %w", err)
+	}
+	if _, err := cli.writer.Write(res.Stdout); err != nil {
+		return true, err
+	}
+	var resErr error
+	if res.Error != "" {
+		resErr = errors.New(res.Error)
+	}
+	if res.ExitCode != 0 {
+		return true, &ExitError{Code: res.ExitCode, Err: resErr}
+	}
+	return true, resErr
+}
@@ -0,0 +1,244 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+)
+
+// startDaemon serves a daemon on a temporary socket and returns its path
+func startDaemon(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		(&DaemonCmd{}).Serve(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("daemon did not stop")
+		}
+	})
+	return socket
+}
+
+func TestDaemonDelegate(t *testing.T) {
+	socket := startDaemon(t)
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ env: std.extVar("env") }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	cli := &CLI{Filename: jsonnetFile, ExtStr: map[string]string{"env": "prod"}, CompactOutput: true, writer: &buf, DaemonSocket: socket}
+	handled, err := cli.delegate(t.Context())
+	if !handled || err != nil {
+		t.Fatalf("unexpected result: handled=%v err=%v", handled, err)
+	}
+	if diff := cmp.Diff(`{"env":"prod"}`+"\n", buf.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	// Errors and exit statuses are returned to the client
+	cli = &CLI{Filename: filepath.Join(tmpDir, "missing.jsonnet"), ExitCodeError: 3, writer: &bytes.Buffer{}, DaemonSocket: socket}
+	handled, err = cli.delegate(t.Context())
+	var exitErr *ExitError
+	if !handled || !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit status 3, got handled=%v err=%v", handled, err)
+	}
+	if exitErr.Err == nil || !strings.Contains(exitErr.Err.Error(), "missing.jsonnet") {
+		t.Errorf("unexpected error: %v", exitErr.Err)
+	}
+}
+
+func TestDaemonNotRunning(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cli := &CLI{Filename: jsonnetFile, CompactOutput: true, UseDaemon: true, DaemonSocket: filepath.Join(tmpDir, "none.sock"), writer: &buf}
+	if handled, _ := cli.delegate(t.Context()); handled {
+		t.Fatal("expected fallback when the daemon is not running")
+	}
+	// run evaluates in process
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != `{"a":1}`+"\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestDaemonClientContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "data.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.jsonnet"), []byte(`
+	local armed = import 'armed.libsonnet';
+	{ data: armed.file_content("data.txt"), env: armed.env("ARMED_DAEMON_TEST", "") }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	t.Setenv("ARMED_DAEMON_TEST", "daemon")
+
+	// Relative paths and the environment are resolved as in the client
+	d := &DaemonCmd{}
+	res := d.evaluate(t.Context(), &daemonRequest{
+		Dir: tmpDir,
+		Env: []string{"ARMED_DAEMON_TEST=client"},
+		CLI: &CLI{Filename: "main.jsonnet", CompactOutput: true},
+	})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if diff := cmp.Diff(`{"data":"hello","env":"client"}`+"\n", string(res.Stdout)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	// The daemon's own directory and environment are restored
+	if got, _ := os.Getwd(); got != wd {
+		t.Errorf("working directory was not restored: %s", got)
+	}
+	if got := os.Getenv("ARMED_DAEMON_TEST"); got != "daemon" {
+		t.Errorf("environment was not restored: %q", got)
+	}
+}
+
+func TestDelegatable(t *testing.T) {
+	tests := []struct {
+		cli  CLI
+		want bool
+	}{
+		{cli: CLI{Filename: "a.jsonnet"}, want: true},
+		{cli: CLI{Filename: "-"}, want: false},
+		{cli: CLI{Filename: "a.jsonnet", ExtStrStdin: "input"}, want: false},
+		{cli: CLI{Filename: "a.jsonnet", Watch: true}, want: false},
+		{cli: CLI{Filename: "a.jsonnet", DryRun: true}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.cli.delegatable(); got != tt.want {
+			t.Errorf("delegatable(%+v) = %v, want %v", tt.cli, got, tt.want)
+		}
+	}
+}
+
+func TestDaemonReload(t *testing.T) {
+	vms := newVMCache()
+	d := &DaemonCmd{vms: vms}
+	d.Reload()
+	if d.vms == nil || d.vms == vms {
+		t.Error("expected Reload to discard the cached VMs")
+	}
+}
+
+func TestDaemonSocketDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := filepath.Dir(defaultDaemonSocket())
+	if err := ensurePrivateDir(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0700 {
+		t.Errorf("expected mode 0700, got %s", fi.Mode().Perm())
+	}
+
+	// A directory opened to others, e.g. created by another user, is rejected
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensurePrivateDir(dir); err == nil || !strings.Contains(err.Error(), "accessible by other users") {
+		t.Errorf("expected an error for mode 0777, got %v", err)
+	}
+}
+
+func TestDaemonSocketOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of the socket requires root")
+	}
+	socket := startDaemon(t)
+	if err := checkDaemonSocket(socket); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chown(socket, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkDaemonSocket(socket); err == nil {
+		t.Error("expected an error for a socket owned by another user")
+	}
+
+	// The client evaluates in process instead of sending its environment
+	cli := &CLI{Filename: "testdata/simple.jsonnet", writer: &bytes.Buffer{}, DaemonSocket: socket}
+	if handled, _ := cli.delegate(t.Context()); handled {
+		t.Error("expected the client to refuse a socket owned by another user")
+	}
+}
+
+func TestDaemonAWSCredentialsPerClient(t *testing.T) {
+	var credentials []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Authorization: AWS4-HMAC-SHA256 Credential=<access key>/<scope>, ...
+		_, cred, _ := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+		key, _, _ := strings.Cut(cred, "/")
+		credentials = append(credentials, key)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+	t.Cleanup(functions.ResetAWSConfigs)
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.jsonnet"), []byte(`
+	local armed = import 'armed.libsonnet';
+	{ item: armed.aws_dynamodb_get("tenants", { id: "a" }) }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &DaemonCmd{}
+	for _, key := range []string{"AKIACLIENTA", "AKIACLIENTB"} {
+		res := d.evaluate(t.Context(), &daemonRequest{
+			Dir: tmpDir,
+			Env: []string{
+				"AWS_ENDPOINT_URL_DYNAMODB=" + ts.URL,
+				"AWS_ACCESS_KEY_ID=" + key,
+				"AWS_SECRET_ACCESS_KEY=secret",
+				"AWS_REGION=us-east-1",
+			},
+			CLI: &CLI{Filename: "main.jsonnet", CompactOutput: true},
+		})
+		if res.Error != "" {
+			t.Fatalf("unexpected error: %s", res.Error)
+		}
+	}
+	if diff := cmp.Diff([]string{"AKIACLIENTA", "AKIACLIENTB"}, credentials); diff != "" {
+		t.Errorf("credentials mismatch (-want +got):\n%s", diff)
+	}
+}
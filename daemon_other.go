@@ -0,0 +1,10 @@
+//go:build !unix
+
+package armed
+
+import "os"
+
+// fileOwner is not available on this platform; ownership is not checked
+func fileOwner(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,89 @@
+package armed
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// checkListDepsFlags checks that --list-deps is used with an evaluation
+// reading its inputs every time
+func (cli *CLI) checkListDepsFlags() error {
+	if !cli.ListDeps {
+		return nil
+	}
+	switch {
+	case cli.Watch:
+		return fmt.Errorf("--list-deps cannot be used with --watch")
+	case cli.DryRun:
+		return fmt.Errorf("--list-deps cannot be used with --dry-run")
+	case cli.Incremental:
+		return fmt.Errorf("--list-deps cannot be used with --incremental")
+	case cli.Diff || cli.Check:
+		return fmt.Errorf("--list-deps cannot be used with --diff or --check")
+	}
+	return nil
+}
+
+// dependencies returns the local files read by the evaluation: the input
+// files and their imports, the files read by native functions, and the
+// files given by flags. Files that do not exist (e.g. checked by
+// file_exists) are left out, since make cannot build them.
+func (cli *CLI) dependencies() []string {
+	var files []string
+	if cli.meta != nil {
+		cli.meta.mu.Lock()
+		files = slices.Clone(cli.meta.files)
+		cli.meta.mu.Unlock()
+	}
+	files = append(files, cli.varFiles()...)
+	files = append(files, cli.Mock, cli.Schema, cli.OutputTemplate)
+
+	var deps []string
+	for _, f := range files {
+		if f == "" || isRemoteInput(f) || slices.Contains(deps, f) {
+			continue
+		}
+		if st, err := os.Stat(f); err != nil || st.IsDir() {
+			continue
+		}
+		deps = append(deps, f)
+	}
+	slices.Sort(deps)
+	return deps
+}
+
+// writeDeps prints the dependencies of the evaluation for --list-deps: a
+// Make rule with the -o/--output files as targets, or one file per line
+// without them.
+func (cli *CLI) writeDeps() error {
+	deps := cli.dependencies()
+	targets := cli.fileOutputs()
+	if len(targets) == 0 {
+		for _, d := range deps {
+			if _, err := fmt.Fprintln(cli.writer, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	rule := make([]string, 0, len(targets))
+	for _, t := range targets {
+		rule = append(rule, makeEscape(t))
+	}
+	line := strings.Join(rule, " ") + ":"
+	for _, d := range deps {
+		line += " " + makeEscape(d)
+	}
+	_, err := io.WriteString(cli.writer, line+"\n")
+	return err
+}
+
+// makeEscape escapes a path for a rule of make (and ninja depfiles)
+var makeEscape = strings.NewReplacer(
+	" ", `\ `,
+	"#", `\#`,
+	"$", "$$",
+).Replace
@@ -0,0 +1,150 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIListDeps(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "lib dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	libFile := filepath.Join(dir, "lib dir", "common.libsonnet")
+	dataFile := filepath.Join(dir, "data.txt")
+	varFile := filepath.Join(dir, "env.txt")
+	writeFile(t, jsonnetFile, `
+local common = import 'common.libsonnet';
+local file_content = std.native('file_content');
+local file_exists = std.native('file_exists');
+{
+  name: common.name,
+  data: file_content(std.extVar('data')),
+  env: std.extVar('env'),
+  missing: file_exists(std.extVar('missing')),
+}`)
+	writeFile(t, libFile, `{ name: 'app' }`)
+	writeFile(t, dataFile, "data")
+	writeFile(t, varFile, "prod")
+	outFile := filepath.Join(dir, "app.json")
+
+	newCLI := func() *armed.CLI {
+		return &armed.CLI{
+			Filename:   jsonnetFile,
+			Lib:        []string{filepath.Join(dir, "lib dir")},
+			ExtStr:     map[string]string{"data": dataFile, "missing": filepath.Join(dir, "missing.txt")},
+			ExtStrFile: map[string]string{"env": varFile},
+			ListDeps:   true,
+		}
+	}
+
+	t.Run("make rule", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := newCLI()
+		cli.Output = []string{outFile}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		escapedLib := strings.ReplaceAll(libFile, " ", `\ `)
+		want := outFile + ": " + jsonnetFile + " " + dataFile + " " + varFile + " " + escapedLib + "\n"
+		if output.String() != want {
+			t.Errorf("got %q, want %q", output.String(), want)
+		}
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+			t.Fatalf("--list-deps must not write the output: %v", err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := newCLI()
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := strings.Join([]string{jsonnetFile, dataFile, varFile, libFile}, "\n") + "\n"
+		if output.String() != want {
+			t.Errorf("got %q, want %q", output.String(), want)
+		}
+	})
+
+	t.Run("deps command", func(t *testing.T) {
+		var output bytes.Buffer
+		cmd := &armed.DepsCmd{CLI: *newCLI()}
+		cmd.ListDeps = false
+		cmd.Output = []string{outFile}
+		cmd.SetWriter(&output)
+		if err := cmd.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		escapedLib := strings.ReplaceAll(libFile, " ", `\ `)
+		want := outFile + ": " + jsonnetFile + " " + dataFile + " " + varFile + " " + escapedLib + "\n"
+		if output.String() != want {
+			t.Errorf("got %q, want %q", output.String(), want)
+		}
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+			t.Fatalf("deps must not write the output: %v", err)
+		}
+	})
+
+	t.Run("output template", func(t *testing.T) {
+		templateFile := filepath.Join(dir, "app.gotmpl")
+		writeFile(t, templateFile, `name={{ .name }}`)
+		var output bytes.Buffer
+		cli := newCLI()
+		cli.OutputTemplate = templateFile
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := strings.Join([]string{templateFile, jsonnetFile, dataFile, varFile, libFile}, "\n") + "\n"
+		if output.String() != want {
+			t.Errorf("got %q, want %q", output.String(), want)
+		}
+	})
+
+	t.Run("with cache", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		for range 2 {
+			var output bytes.Buffer
+			cli := newCLI()
+			cli.Cache = time.Minute
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(output.String(), libFile+"\n") {
+				t.Errorf("dependencies must be listed without the cache, got %q", output.String())
+			}
+		}
+	})
+}
+
+func TestRunWithCLIListDepsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cli  *armed.CLI
+		want string
+	}{
+		{"watch", &armed.CLI{ListDeps: true, Watch: true}, "--list-deps cannot be used with --watch"},
+		{"dry-run", &armed.CLI{ListDeps: true, DryRun: true}, "--list-deps cannot be used with --dry-run"},
+		{"check", &armed.CLI{ListDeps: true, Check: true, Output: []string{"out.json"}}, "--list-deps cannot be used with --diff or --check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.Filename = "testdata/server/static.jsonnet"
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package armed
+
+import "context"
+
+// DepsCmd prints the files an evaluation depends on, as --list-deps does.
+// It accepts the flags of the eval command.
+type DepsCmd struct {
+	CLI `embed:""`
+}
+
+// Run evaluates the file and prints its dependencies
+func (c *DepsCmd) Run(ctx context.Context) error {
+	c.ListDeps = true
+	return c.CLI.Run(ctx)
+}
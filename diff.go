@@ -0,0 +1,168 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// lineDiff returns a unified-style line diff between a and b.
+// Lines only in a are prefixed with "-", lines only in b with "+" and
+// common lines with a space. It returns an empty string when a and b are equal.
+func lineDiff(nameA, nameB, a, b string) string {
+	if a == b {
+		return ""
+	}
+	al := splitLines(a)
+	bl := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", nameA, nameB)
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			fmt.Fprintf(&buf, " %s\n", al[i])
+			i++
+			j++
+		case j < len(bl) && (i == len(al) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&buf, "+%s\n", bl[j])
+			j++
+		default:
+			fmt.Fprintf(&buf, "-%s\n", al[i])
+			i++
+		}
+	}
+	return buf.String()
+}
+
+// splitLines splits s into lines without the trailing newline
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// valueChange is a difference between two JSON values at a path.
+// Op is "+" (added), "-" (removed) or "~" (changed).
+type valueChange struct {
+	Op   string
+	Path string
+	Old  any
+	New  any
+}
+
+func (c valueChange) String() string {
+	switch c.Op {
+	case "+":
+		return fmt.Sprintf("+ %s: %s", c.Path, compactJSON(c.New))
+	case "-":
+		return fmt.Sprintf("- %s: %s", c.Path, compactJSON(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, compactJSON(c.Old), compactJSON(c.New))
+	}
+}
+
+// structuralDiff returns the differences between two decoded JSON values.
+// Objects are compared key by key and arrays element by element, so that
+// each change is reported at the deepest path where the values differ.
+func structuralDiff(a, b any) []valueChange {
+	var changes []valueChange
+	diffValues("", a, b, &changes)
+	return changes
+}
+
+func diffValues(path string, a, b any, changes *[]valueChange) {
+	switch av := a.(type) {
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			keys := make([]string, 0, len(av)+len(bv))
+			for k := range av {
+				keys = append(keys, k)
+			}
+			for k := range bv {
+				if _, ok := av[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				p := path + pathKey(k)
+				x, inA := av[k]
+				y, inB := bv[k]
+				switch {
+				case !inA:
+					*changes = append(*changes, valueChange{Op: "+", Path: p, New: y})
+				case !inB:
+					*changes = append(*changes, valueChange{Op: "-", Path: p, Old: x})
+				default:
+					diffValues(p, x, y, changes)
+				}
+			}
+			return
+		}
+	case []any:
+		if bv, ok := b.([]any); ok {
+			for i := 0; i < max(len(av), len(bv)); i++ {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(av):
+					*changes = append(*changes, valueChange{Op: "+", Path: p, New: bv[i]})
+				case i >= len(bv):
+					*changes = append(*changes, valueChange{Op: "-", Path: p, Old: av[i]})
+				default:
+					diffValues(p, av[i], bv[i], changes)
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "."
+		}
+		*changes = append(*changes, valueChange{Op: "~", Path: path, Old: a, New: b})
+	}
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pathKey formats an object key as a path element: .key for identifiers, ["key"] otherwise
+func pathKey(k string) string {
+	if identifierPattern.MatchString(k) {
+		return "." + k
+	}
+	b, _ := json.Marshal(k)
+	return "[" + string(b) + "]"
+}
+
+// compactJSON formats a decoded JSON value on a single line
+func compactJSON(v any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,121 @@
+package armed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// ErrDifferencesFound is returned by the diff command with --exit-code
+// when the evaluations differ, and by --diff when an output file differs
+// from the result.
+var ErrDifferencesFound = errors.New("differences found")
+
+// DiffCmd evaluates two jsonnet files (or one file with two sets of
+// external variables) and prints a structural diff of the results.
+type DiffCmd struct {
+	ExtStr   map[string]string `short:"V" name:"ext-str" help:"Set external string variable for both evaluations (can be repeated)."`
+	ExtCode  map[string]string `name:"ext-code" help:"Set external code variable for both evaluations (can be repeated)."`
+	AExtStr  map[string]string `name:"a-ext-str" help:"Set external string variable for the first evaluation (can be repeated)."`
+	AExtCode map[string]string `name:"a-ext-code" help:"Set external code variable for the first evaluation (can be repeated)."`
+	BExtStr  map[string]string `name:"b-ext-str" help:"Set external string variable for the second evaluation (can be repeated)."`
+	BExtCode map[string]string `name:"b-ext-code" help:"Set external code variable for the second evaluation (can be repeated)."`
+	Timeout  time.Duration     `short:"t" name:"timeout" help:"Timeout for each evaluation (e.g., 30s, 5m)"`
+	ExitCode bool              `name:"exit-code" help:"Exit with a non-zero status if the evaluations differ."`
+	Files    []string          `arg:"" name:"file" help:"Files to compare: a.jsonnet b.jsonnet, or a single file evaluated with --a-* and --b-* variables" type:"path"`
+
+	// writer for the diff (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+
+	// functions holds additional native functions to be added to the Jsonnet VM
+	functions []*jsonnet.NativeFunction `kong:"-"`
+}
+
+// SetWriter sets the writer for the diff
+func (d *DiffCmd) SetWriter(w io.Writer) {
+	d.writer = w
+}
+
+// AddFunctions adds custom native functions to the evaluations
+func (d *DiffCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
+	d.functions = append(d.functions, funcs...)
+}
+
+// Run evaluates both sides and prints the differences
+func (d *DiffCmd) Run(ctx context.Context) error {
+	if d.writer == nil {
+		d.writer = os.Stdout
+	}
+	var fileA, fileB string
+	switch len(d.Files) {
+	case 1:
+		fileA, fileB = d.Files[0], d.Files[0]
+	case 2:
+		fileA, fileB = d.Files[0], d.Files[1]
+	default:
+		return fmt.Errorf("diff requires one or two files, got %d", len(d.Files))
+	}
+
+	a, err := d.evaluate(ctx, fileA, d.AExtStr, d.AExtCode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fileA, err)
+	}
+	b, err := d.evaluate(ctx, fileB, d.BExtStr, d.BExtCode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fileB, err)
+	}
+
+	changes := structuralDiff(a, b)
+	if len(changes) == 0 {
+		return nil
+	}
+	nameA, nameB := fileA, fileB
+	if fileA == fileB {
+		nameA, nameB = fileA+" (a)", fileB+" (b)"
+	}
+	fmt.Fprintf(d.writer, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, c := range changes {
+		fmt.Fprintln(d.writer, c.String())
+	}
+	if d.ExitCode {
+		return fmt.Errorf("%w: %d change(s)", ErrDifferencesFound, len(changes))
+	}
+	return nil
+}
+
+// evaluate evaluates a file with the common and side-specific external
+// variables and decodes the result.
+func (d *DiffCmd) evaluate(ctx context.Context, filename string, extStr, extCode map[string]string) (any, error) {
+	cli := &CLI{
+		Filename:  filename,
+		ExtStr:    merged(d.ExtStr, extStr),
+		ExtCode:   merged(d.ExtCode, extCode),
+		functions: d.functions,
+	}
+	jsonStr, err := evaluateWithTimeout(ctx, cli, d.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal([]byte(jsonStr), &v); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %w", err)
+	}
+	return v, nil
+}
+
+// merged returns a copy of base overridden by override
+func merged(base, override map[string]string) map[string]string {
+	m := maps.Clone(base)
+	if m == nil {
+		m = make(map[string]string, len(override))
+	}
+	maps.Copy(m, override)
+	return m
+}
@@ -0,0 +1,93 @@
+package armed_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffCmd(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.jsonnet")
+	writeFile(t, app, `
+	local env = std.extVar("env");
+	{
+		name: "app",
+		replicas: if env == "prod" then 3 else 1,
+		[if env == "dev" then "debug"]: true,
+		containers: [{ image: "app:" + std.extVar("tag") }] + (if env == "prod" then [{ image: "sidecar" }] else []),
+		"app.kubernetes.io/env": env,
+	}`)
+
+	tests := []struct {
+		name     string
+		cmd      armed.DiffCmd
+		expected string
+	}{
+		{
+			name: "same file with different ext vars",
+			cmd: armed.DiffCmd{
+				ExtStr:  map[string]string{"tag": "v1"},
+				AExtStr: map[string]string{"env": "dev"},
+				BExtStr: map[string]string{"env": "prod", "tag": "v2"},
+				Files:   []string{app},
+			},
+			expected: `--- ` + app + ` (a)
++++ ` + app + ` (b)
+~ ["app.kubernetes.io/env"]: "dev" -> "prod"
+~ .containers[0].image: "app:v1" -> "app:v2"
++ .containers[1]: {"image":"sidecar"}
+- .debug: true
+~ .replicas: 1 -> 3
+`,
+		},
+		{
+			name: "no differences",
+			cmd: armed.DiffCmd{
+				ExtStr: map[string]string{"tag": "v1", "env": "dev"},
+				Files:  []string{app, app},
+			},
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			tt.cmd.SetWriter(&output)
+			if err := tt.cmd.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, output.String()); diff != "" {
+				t.Errorf("diff output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffCmdTwoFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.jsonnet")
+	b := filepath.Join(tmpDir, "b.jsonnet")
+	writeFile(t, a, `{ x: 1, y: [1, 2] }`)
+	writeFile(t, b, `{ x: "1", y: [1] }`)
+
+	var output bytes.Buffer
+	cmd := &armed.DiffCmd{Files: []string{a, b}, ExitCode: true}
+	cmd.SetWriter(&output)
+	err := cmd.Run(t.Context())
+	if !errors.Is(err, armed.ErrDifferencesFound) {
+		t.Errorf("expected ErrDifferencesFound, got %v", err)
+	}
+	expected := `--- ` + a + `
++++ ` + b + `
+~ .x: 1 -> "1"
+- .y[1]: 2
+`
+	if diff := cmp.Diff(expected, output.String()); diff != "" {
+		t.Errorf("diff output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,13 +1,48 @@
 package armed
 
 import (
+	"context"
 	_ "embed"
+	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
 //go:embed README.md
 var readmeContent string
 
+// DocsCmd prints the documentation (this README) embedded in the binary
+type DocsCmd struct {
+	Toc    bool   `name:"toc" help:"Print the table of contents."`
+	Search string `name:"search" placeholder:"KEYWORD" help:"Print the sections matching KEYWORD."`
+
+	// writer for the documentation (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+}
+
+// Run prints the full documentation, its table of contents or the matching sections
+func (d *DocsCmd) Run(ctx context.Context) error {
+	if d.writer == nil {
+		d.writer = os.Stdout
+	}
+	switch {
+	case d.Toc:
+		_, err := io.WriteString(d.writer, extractTOC(readmeContent))
+		return err
+	case d.Search != "":
+		results := searchSections(readmeContent, d.Search)
+		if results == "" {
+			_, err := fmt.Fprintf(d.writer, "No sections found matching: %s\n", d.Search)
+			return err
+		}
+		_, err := io.WriteString(d.writer, results)
+		return err
+	}
+	_, err := io.WriteString(d.writer, readmeContent)
+	return err
+}
+
 // extractTOC extracts table of contents from markdown content.
 // It finds all heading lines (starting with #) and formats them with indentation.
 // Lines inside fenced code blocks (```) are skipped.
@@ -175,3 +175,50 @@ MIT
 		})
 	}
 }
+
+func TestDocsCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      DocsCmd
+		contains string
+		excludes string
+	}{
+		{
+			name:     "full documentation",
+			cmd:      DocsCmd{},
+			contains: "## Native Functions",
+		},
+		{
+			name:     "table of contents",
+			cmd:      DocsCmd{Toc: true},
+			contains: "  Native Functions\n",
+			excludes: "```",
+		},
+		{
+			name:     "search",
+			cmd:      DocsCmd{Search: "sha256_file"},
+			contains: "### Hash Functions",
+			excludes: "### UUID Functions",
+		},
+		{
+			name:     "no match",
+			cmd:      DocsCmd{Search: "nonexistent-keyword-xyz-12345"},
+			contains: "No sections found matching: nonexistent-keyword-xyz-12345",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			tt.cmd.writer = &buf
+			if err := tt.cmd.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.contains) {
+				t.Errorf("output does not contain %q", tt.contains)
+			}
+			if tt.excludes != "" && strings.Contains(buf.String(), tt.excludes) {
+				t.Errorf("output contains %q", tt.excludes)
+			}
+		})
+	}
+}
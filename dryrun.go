@@ -0,0 +1,183 @@
+package armed
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// dryRunStub describes a planned call of a side-effecting native function
+// and returns a placeholder result of the same shape as the real one.
+// An empty description means that the call has no side effect, and the
+// function is called as usual.
+type dryRunStub func(args []any) (description string, result any)
+
+var dryRunStubs = map[string]dryRunStub{
+	"exec": func(args []any) (string, any) {
+		return commandLine(args), execPlaceholder()
+	},
+	"exec_with_env": func(args []any) (string, any) {
+		line := commandLine(args)
+		if env, ok := argAt(args, 2).(map[string]any); ok && len(env) > 0 {
+			keys := make([]string, 0, len(env))
+			for k := range env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			vars := make([]string, len(keys))
+			for i, k := range keys {
+				vars[i] = k + "=" + shellQuote(fmt.Sprint(env[k]))
+			}
+			line = strings.Join(vars, " ") + " " + line
+		}
+		return line, execPlaceholder()
+	},
+	"http_get": func(args []any) (string, any) {
+		return "GET " + fmt.Sprint(argAt(args, 0)), httpPlaceholder()
+	},
+	"http_request": func(args []any) (string, any) {
+		description := fmt.Sprintf("%v %v", argAt(args, 0), argAt(args, 1))
+		if body, ok := argAt(args, 3).(string); ok && body != "" {
+			description += fmt.Sprintf(" (%d bytes body)", len(body))
+		}
+		return description, httpPlaceholder()
+	},
+	"github_api": func(args []any) (string, any) {
+		// GET requests only read, and the template needs their results
+		options, _ := argAt(args, 1).(map[string]any)
+		method, _ := options["method"].(string)
+		if method == "" || strings.EqualFold(method, http.MethodGet) {
+			return "", nil
+		}
+		return strings.ToUpper(method) + " " + fmt.Sprint(argAt(args, 0)), map[string]any{}
+	},
+}
+
+func execPlaceholder() any {
+	return map[string]any{"stdout": "", "stderr": "", "exit_code": 0}
+}
+
+func httpPlaceholder() any {
+	return map[string]any{"status_code": 200, "status": "200 OK", "headers": map[string]any{}, "body": ""}
+}
+
+// plannedEffect is a side effect that --dry-run did not perform
+type plannedEffect struct {
+	kind        string
+	description string
+}
+
+// dryRunPlan collects the side effects skipped by --dry-run.
+// It is safe for concurrent use.
+type dryRunPlan struct {
+	w       io.Writer
+	mu      sync.Mutex
+	effects []plannedEffect
+	seen    map[plannedEffect]bool
+}
+
+func newDryRunPlan(w io.Writer) *dryRunPlan {
+	return &dryRunPlan{w: w, seen: make(map[plannedEffect]bool)}
+}
+
+// add records a planned side effect; identical effects are recorded once
+func (p *dryRunPlan) add(kind, description string) {
+	e := plannedEffect{kind: kind, description: description}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.seen[e] {
+		return
+	}
+	p.seen[e] = true
+	p.effects = append(p.effects, e)
+}
+
+// wrap returns copies of funcs where side-effecting functions are replaced
+// by stubs that record the planned call instead of performing it.
+func (p *dryRunPlan) wrap(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		stub, ok := dryRunStubs[f.Name]
+		if !ok {
+			wrapped[i] = f
+			continue
+		}
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				description, result := stub(args)
+				if description == "" {
+					return f.Func(args)
+				}
+				p.add(f.Name, description)
+				return result, nil
+			},
+		}
+	}
+	return wrapped
+}
+
+// addOutput records a skipped write to an output destination
+func (p *dryRunPlan) addOutput(out string) {
+	if u, err := url.Parse(out); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		p.add("output", "POST "+out)
+		return
+	}
+	if _, _, ok := parseS3URL(out); ok {
+		p.add("output", "PUT "+out)
+		return
+	}
+	p.add("output", "write "+out)
+}
+
+// report writes the planned side effects
+func (p *dryRunPlan) report() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.effects) == 0 {
+		_, err := fmt.Fprintln(p.w, "Dry run: no side effects planned")
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Dry run: %d planned side effect(s)\n", len(p.effects))
+	for _, e := range p.effects {
+		fmt.Fprintf(&b, "  %s: %s\n", e.kind, e.description)
+	}
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+// argAt returns the i-th argument, or nil if it is missing
+func argAt(args []any, i int) any {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+// commandLine formats the command and args arguments of exec functions
+func commandLine(args []any) string {
+	parts := []string{shellQuote(fmt.Sprint(argAt(args, 0)))}
+	if cmdArgs, ok := argAt(args, 1).([]any); ok {
+		for _, a := range cmdArgs {
+			parts = append(parts, shellQuote(fmt.Sprint(a)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s if it is empty or contains characters that a shell would interpret
+func shellQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n'\"\\$`;&|<>()*?[]#~") {
+		return strconv.Quote(s)
+	}
+	return s
+}
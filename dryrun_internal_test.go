@@ -0,0 +1,116 @@
+package armed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	marker := filepath.Join(tmpDir, "marker")
+	code := `
+	local armed = import 'armed.libsonnet';
+	{
+		touch: armed.exec("touch", ["` + marker + `"]).exit_code,
+		deploy: armed.exec_with_env("deploy", ["--env", "prod env"], { TOKEN: "x", A: "1" }).stdout,
+		get: armed.http_get("http://127.0.0.1:1/status").status_code,
+		again: armed.http_get("http://127.0.0.1:1/status").status_code,
+		post: armed.http_request("POST", "http://127.0.0.1:1/hook", {}, "hello").body,
+		hash: armed.sha256(""),
+	}`
+	if err := os.WriteFile(jsonnetFile, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(tmpDir, "out.json")
+
+	var output, report bytes.Buffer
+	cli := &CLI{
+		Filename: jsonnetFile,
+		Output:   []string{outputFile, "https://example.com/upload"},
+		DryRun:   true,
+		writer:   &output,
+		plan:     newDryRunPlan(&report),
+	}
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("exec was executed in dry-run mode: %v", err)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Errorf("output file was written in dry-run mode: %v", err)
+	}
+	// Fields are evaluated in sorted order; identical calls are reported once
+	expected := `Dry run: 6 planned side effect(s)
+  http_get: GET http://127.0.0.1:1/status
+  exec_with_env: A=1 TOKEN=x deploy --env "prod env"
+  http_request: POST http://127.0.0.1:1/hook (5 bytes body)
+  exec: touch ` + marker + `
+  output: write ` + outputFile + `
+  output: POST https://example.com/upload
+`
+	if diff := cmp.Diff(expected, report.String()); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDryRunNoSideEffects(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output, report bytes.Buffer
+	cli := &CLI{Filename: jsonnetFile, DryRun: true, writer: &output, plan: newDryRunPlan(&report)}
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := report.String(); got != "Dry run: no side effects planned\n" {
+		t.Errorf("unexpected report: %q", got)
+	}
+	if output.Len() == 0 {
+		t.Error("result was not written to stdout")
+	}
+}
+
+func TestDryRunGitHubAPI(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	code := `
+	local armed = import 'armed.libsonnet';
+	{
+		created: armed.github_api("repos/owner/repo/issues", { method: "post", body: { title: "x" } }),
+		deleted: armed.github_api("repos/owner/repo/labels/old", { method: "DELETE" }),
+	}`
+	if err := os.WriteFile(jsonnetFile, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var output, report bytes.Buffer
+	cli := &CLI{Filename: jsonnetFile, DryRun: true, writer: &output, plan: newDryRunPlan(&report)}
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `Dry run: 2 planned side effect(s)
+  github_api: POST repos/owner/repo/issues
+  github_api: DELETE repos/owner/repo/labels/old
+`
+	if diff := cmp.Diff(expected, report.String()); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+
+	// GET requests are performed; the invalid option fails before sending it
+	if err := os.WriteFile(jsonnetFile, []byte(`std.native("github_api")("repos/owner/repo", { paginate: "yes" })`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cli = &CLI{Filename: jsonnetFile, DryRun: true, writer: &output, plan: newDryRunPlan(&report)}
+	if err := cli.run(t.Context()); err == nil || !strings.Contains(err.Error(), "options.paginate must be a boolean") {
+		t.Errorf("expected the GET request to be performed, got %v", err)
+	}
+}
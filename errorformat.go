@@ -0,0 +1,161 @@
+package armed
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiBlue  = "\x1b[34m"
+)
+
+// errorContextLines is the number of source lines shown before and after
+// the offending line in pretty error reports.
+const errorContextLines = 2
+
+// maxPrettyStackTraceSize is the default limit of the number of stack frames
+// in pretty error reports.
+const maxPrettyStackTraceSize = 20
+
+// locatedError is implemented by jsonnet static (parse/analysis) errors.
+type locatedError interface {
+	error
+	Loc() ast.LocationRange
+}
+
+// prettyErrorFormatter is a jsonnet.ErrorFormatter that shows the offending
+// source line with a caret and surrounding context, like modern compilers.
+type prettyErrorFormatter struct {
+	color bool
+	// maxStackTraceSize limits the number of stack frames; 0 shows all of them
+	maxStackTraceSize int
+}
+
+var _ jsonnet.ErrorFormatter = (*prettyErrorFormatter)(nil)
+
+// newPrettyErrorFormatter returns a pretty error formatter. Color is
+// disabled when the NO_COLOR environment variable is set.
+func newPrettyErrorFormatter() *prettyErrorFormatter {
+	return &prettyErrorFormatter{color: os.Getenv("NO_COLOR") == "", maxStackTraceSize: maxPrettyStackTraceSize}
+}
+
+// Format formats static, runtime, and unexpected errors.
+func (f *prettyErrorFormatter) Format(err error) string {
+	var b strings.Builder
+	switch e := err.(type) {
+	case jsonnet.RuntimeError:
+		b.WriteString(f.paint(ansiBold+ansiRed, e.Error()))
+		b.WriteString("\n")
+		for _, frame := range e.StackTrace {
+			if frame.Loc.WithCode() {
+				f.writeExcerpt(&b, frame.Loc)
+				break
+			}
+		}
+		f.writeStackTrace(&b, e.StackTrace)
+	case locatedError:
+		b.WriteString(f.paint(ansiBold+ansiRed, strings.TrimSpace(e.Error())))
+		b.WriteString("\n")
+		f.writeExcerpt(&b, e.Loc())
+	default:
+		b.WriteString(err.Error())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SetMaxStackTraceSize sets the maximum number of stack frames; 0 shows all of them.
+func (f *prettyErrorFormatter) SetMaxStackTraceSize(size int) {
+	f.maxStackTraceSize = size
+}
+
+// SetColorFormatter is a no-op; colors are controlled by the color field.
+func (f *prettyErrorFormatter) SetColorFormatter(color jsonnet.ColorFormatter) {}
+
+func (f *prettyErrorFormatter) paint(code, s string) string {
+	if !f.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// writeExcerpt writes the source lines around loc with a caret under the
+// offending range.
+func (f *prettyErrorFormatter) writeExcerpt(b *strings.Builder, loc ast.LocationRange) {
+	if !loc.WithCode() || loc.File == nil {
+		return
+	}
+	lines := loc.File.Lines
+	// The source always ends with an extra line for text after the last newline
+	if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "" {
+		lines = lines[:n-1]
+	}
+	line := loc.Begin.Line
+	if line > len(lines) {
+		return
+	}
+	first := max(1, line-errorContextLines)
+	last := min(len(lines), line+errorContextLines)
+	width := len(strconv.Itoa(last))
+	gutter := strings.Repeat(" ", width)
+
+	fmt.Fprintf(b, "%s %s:%d:%d\n", f.paint(ansiBlue, gutter+"-->"), loc.FileName, loc.Begin.Line, loc.Begin.Column)
+	fmt.Fprintf(b, "%s\n", f.paint(ansiBlue, gutter+" |"))
+	for n := first; n <= last; n++ {
+		text := strings.TrimRight(lines[n-1], "\r\n")
+		fmt.Fprintf(b, "%s %s\n", f.paint(ansiBlue, fmt.Sprintf("%*d |", width, n)), text)
+		if n != line {
+			continue
+		}
+		span := 1
+		if loc.End.Line == loc.Begin.Line && loc.End.Column > loc.Begin.Column {
+			span = loc.End.Column - loc.Begin.Column
+		} else if loc.End.Line > loc.Begin.Line {
+			// Underline up to the end of the line for multi-line ranges
+			span = max(1, len(text)-loc.Begin.Column+1)
+		}
+		// Preserve tabs so that the caret lines up with the source
+		var pad strings.Builder
+		for i := 0; i < loc.Begin.Column-1 && i < len(text); i++ {
+			if text[i] == '\t' {
+				pad.WriteByte('\t')
+			} else {
+				pad.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(b, "%s %s%s\n", f.paint(ansiBlue, gutter+" |"), pad.String(), f.paint(ansiBold+ansiRed, strings.Repeat("^", span)))
+	}
+	fmt.Fprintf(b, "%s\n", f.paint(ansiBlue, gutter+" |"))
+}
+
+// writeStackTrace writes the stack trace frames, skipping the middle ones
+// when the trace is longer than maxStackTraceSize.
+func (f *prettyErrorFormatter) writeStackTrace(b *strings.Builder, frames []jsonnet.TraceFrame) {
+	above := f.maxStackTraceSize / 2
+	below := f.maxStackTraceSize - above
+	for i := 0; i < len(frames); i++ {
+		if f.maxStackTraceSize > 0 && len(frames) > f.maxStackTraceSize && i == above {
+			fmt.Fprintf(b, "\t... (skipped %d frames)\n", len(frames)-above-below)
+			i = len(frames) - below - 1
+			continue
+		}
+		fmt.Fprintf(b, "\tat %s\t%s\n", frames[i].Loc.String(), frames[i].Name)
+	}
+}
+
+// isTerminal reports whether f refers to a terminal (character device).
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,113 @@
+package armed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrettyErrorFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonnet  string
+		contains []string
+	}{
+		{
+			name: "runtime error",
+			jsonnet: `local x = 1;
+{
+  a: x,
+  b: error "boom",
+  c: 3,
+}
+`,
+			contains: []string{
+				"RUNTIME ERROR: boom",
+				"--> ",
+				"test.jsonnet:4:6",
+				"2 | {",
+				"4 |   b: error \"boom\",",
+				"  |      ^^^^^^^^^^^^",
+				"6 | }",
+			},
+		},
+		{
+			name: "native function error",
+			jsonnet: `{
+  a: std.native("must_env")("JSONNET_ARMED_TEST_NOT_SET"),
+}
+`,
+			contains: []string{
+				"must_env: JSONNET_ARMED_TEST_NOT_SET is not set",
+				"2 |   a: std.native(\"must_env\")(\"JSONNET_ARMED_TEST_NOT_SET\"),",
+				"^",
+			},
+		},
+		{
+			name: "parse error",
+			jsonnet: `{
+  a: 1
+  b: 2,
+}
+`,
+			contains: []string{
+				"test.jsonnet:3:3",
+				"3 |   b: 2,",
+				"  |   ^",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "1")
+			filename := filepath.Join(t.TempDir(), "test.jsonnet")
+			if err := os.WriteFile(filename, []byte(tt.jsonnet), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cli := &CLI{Filename: filename, prettyErrors: true}
+			_, err := cli.evaluate(context.Background(), "", false)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("error does not contain %q:\n%s", s, err.Error())
+				}
+			}
+			if strings.Contains(err.Error(), "\x1b[") {
+				t.Errorf("error contains ANSI escapes with NO_COLOR set:\n%s", err.Error())
+			}
+		})
+	}
+}
+
+func TestMaxStackAndTrace(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "deep.jsonnet")
+	if err := os.WriteFile(filename, []byte("local f(n) = if n == 0 then 0 else 1 + f(n - 1);\nf(1000)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (&CLI{Filename: filename}).evaluate(context.Background(), "", false); err == nil || !strings.Contains(err.Error(), "max stack frames exceeded") {
+		t.Fatalf("expected the default stack limit to be exceeded, got %v", err)
+	}
+	got, err := (&CLI{Filename: filename, MaxStack: 2000}).evaluate(context.Background(), "", false)
+	if err != nil {
+		t.Fatalf("unexpected error with --max-stack: %v", err)
+	}
+	if strings.TrimSpace(got) != "1000" {
+		t.Errorf("got %q, want 1000", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	for _, pretty := range []bool{false, true} {
+		_, err := (&CLI{Filename: filename, MaxTrace: 4, prettyErrors: pretty}).evaluate(context.Background(), "", false)
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		if n := strings.Count(err.Error(), "function <f>"); n == 0 || n > 4 {
+			t.Errorf("pretty=%v: expected at most 4 frames, got %d:\n%s", pretty, n, err.Error())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package armed
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// Formats of --error-format
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorReport is a failure written to stderr by --error-format json. The
+// location is that of the failing expression of an evaluation error, and
+// is omitted for other failures.
+type errorReport struct {
+	Message    string       `json:"message"`
+	File       string       `json:"file,omitempty"`
+	Line       int          `json:"line,omitempty"`
+	Column     int          `json:"column,omitempty"`
+	StackTrace []errorFrame `json:"stack_trace,omitempty"`
+}
+
+// errorFrame is a frame of the stack trace of a runtime error
+type errorFrame struct {
+	Name   string `json:"name,omitempty"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// evalError is an evaluation error with its structured report
+type evalError struct {
+	report errorReport
+	err    error
+}
+
+func (e *evalError) Error() string { return e.err.Error() }
+func (e *evalError) Unwrap() error { return e.err }
+
+// errorRecorder is a jsonnet.ErrorFormatter that records the structure of
+// the error, which the VM returns only as the formatted text
+type errorRecorder struct {
+	jsonnet.ErrorFormatter
+	report *errorReport
+}
+
+var _ jsonnet.ErrorFormatter = (*errorRecorder)(nil)
+
+func (r *errorRecorder) Format(err error) string {
+	report := errorReport{Message: err.Error()}
+	switch e := err.(type) {
+	case jsonnet.RuntimeError:
+		report.Message = e.Msg
+		// Innermost first, as in the standard Jsonnet error format
+		for _, frame := range slices.Backward(e.StackTrace) {
+			f := errorFrame{Name: strings.TrimSpace(frame.Name)}
+			if frame.Loc.IsSet() {
+				f.File, f.Line, f.Column = diagnosticFileName(frame.Loc), frame.Loc.Begin.Line, frame.Loc.Begin.Column
+				if report.Line == 0 {
+					report.File, report.Line, report.Column = f.File, f.Line, f.Column
+				}
+			} else if f.Name == "" {
+				// Frames without code, e.g. "During evaluation", are described by FileName
+				f.Name = strings.TrimSpace(frame.Loc.FileName)
+			}
+			if f != (errorFrame{}) {
+				report.StackTrace = append(report.StackTrace, f)
+			}
+		}
+	case locatedError:
+		loc := e.Loc()
+		if loc.IsSet() {
+			report.Message = strings.TrimSpace(strings.TrimPrefix(e.Error(), loc.String()))
+			report.File, report.Line, report.Column = diagnosticFileName(loc), loc.Begin.Line, loc.Begin.Column
+		}
+	}
+	r.report = &report
+	return r.ErrorFormatter.Format(err)
+}
+
+// diagnosticFileName returns the file name of loc as shown in error
+// messages, which is set for snippets (stdin, -e/--exec) too
+func diagnosticFileName(loc ast.LocationRange) string {
+	if loc.File != nil && loc.File.DiagnosticFileName != "" {
+		return string(loc.File.DiagnosticFileName)
+	}
+	return loc.FileName
+}
+
+// wrap attaches the recorded report to err, the error of the evaluation
+func (r *errorRecorder) wrap(err error) error {
+	if err == nil || r.report == nil {
+		return err
+	}
+	return &evalError{report: *r.report, err: err}
+}
+
+// reportErrorJSON writes err to stderr as a JSON object for --error-format
+// json, and returns an ExitError with the exit status of err, which is not
+// logged again.
+func (cli *CLI) reportErrorJSON(err error) error {
+	report := errorReport{Message: err.Error()}
+	var ee *evalError
+	if errors.As(err, &ee) {
+		report = ee.report
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if jerr := enc.Encode(report); jerr != nil {
+		return err
+	}
+	w := cli.errorOutput
+	if w == nil {
+		w = os.Stderr
+	}
+	// Error messages may contain secrets, e.g. an exec stderr echoing a token
+	if _, werr := io.WriteString(w, functions.RedactSecrets(b.String())); werr != nil {
+		return err
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.Code, Err: exitErr.Err, Reported: true}
+	}
+	return &ExitError{Code: 1, Err: err, Reported: true}
+}
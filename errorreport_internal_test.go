@@ -0,0 +1,106 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestErrorFormatJSON(t *testing.T) {
+	dir := t.TempDir()
+	runtimeFile := filepath.Join(dir, "runtime.jsonnet")
+	staticFile := filepath.Join(dir, "static.jsonnet")
+	for name, content := range map[string]string{
+		runtimeFile: "local f(x) = error 'deep ' + x;\n{\n  a: f('y'),\n}\n",
+		staticFile:  "{ a: 1\n b: 2 }\n",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cli      *CLI
+		want     errorReport
+		wantCode int
+	}{
+		{
+			name: "runtime error",
+			cli:  &CLI{Filename: runtimeFile},
+			want: errorReport{
+				Message: "deep y",
+				File:    runtimeFile, Line: 1, Column: 14,
+				StackTrace: []errorFrame{
+					{Name: "function <f>", File: runtimeFile, Line: 1, Column: 14},
+					{Name: "object <anonymous>", File: runtimeFile, Line: 3, Column: 6},
+					{Name: `Field "a"`},
+					{Name: "During manifestation"},
+				},
+			},
+			wantCode: 1,
+		},
+		{
+			name:     "static error",
+			cli:      &CLI{Filename: staticFile},
+			want:     errorReport{Message: "Expected a comma before next field", File: staticFile, Line: 2, Column: 2},
+			wantCode: 1,
+		},
+		{
+			name: "snippet",
+			cli:  &CLI{Exec: `std.native("must_env")("JSONNET_ARMED_TEST_NOT_SET")`},
+			want: errorReport{
+				Message: "must_env: JSONNET_ARMED_TEST_NOT_SET is not set",
+				File:    "<cmdline>", Line: 1, Column: 1,
+				StackTrace: []errorFrame{
+					{Name: "$", File: "<cmdline>", Line: 1, Column: 1},
+					{Name: "During evaluation"},
+				},
+			},
+			wantCode: 1,
+		},
+		{
+			name:     "other failure",
+			cli:      &CLI{Filename: runtimeFile, Exec: "1"},
+			want:     errorReport{Message: "-e/--exec cannot be used with <filename>"},
+			wantCode: 1,
+		},
+		{
+			name:     "exit status of --exit-code-error",
+			cli:      &CLI{Filename: staticFile, ExitCodeError: 3},
+			want:     errorReport{Message: "Expected a comma before next field", File: staticFile, Line: 2, Column: 2},
+			wantCode: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			tt.cli.ErrorFormat = errorFormatJSON
+			tt.cli.errorOutput = &stderr
+			tt.cli.SetWriter(io.Discard)
+			err := tt.cli.Run(context.Background())
+
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("expected ExitError, got %v", err)
+			}
+			if !exitErr.Reported || exitErr.Code != tt.wantCode {
+				t.Errorf("got reported=%v code=%d, want reported code %d", exitErr.Reported, exitErr.Code, tt.wantCode)
+			}
+			var got errorReport
+			if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+				t.Fatalf("stderr is not a JSON object: %q", stderr.String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("report mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package armed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// EvalDirCmd evaluates every *.jsonnet file under a directory and writes the
+// results to the mirrored paths under an output directory.
+type EvalDirCmd struct {
+	Output         string            `short:"o" name:"output" required:"" placeholder:"DIR" type:"path" help:"Directory to write the results to, mirroring the paths under <src>."`
+	Extension      string            `name:"extension" default:".json" help:"Extension of the output files, replacing .jsonnet."`
+	WriteIfChanged bool              `name:"write-if-changed" help:"Write each output file only if its content has changed."`
+	OutputMode     string            `name:"output-mode" placeholder:"MODE" help:"Permissions of the output files in octal, e.g. 0600 (default 0644)."`
+	OutputOwner    string            `name:"output-owner" placeholder:"USER[:GROUP]" help:"Change the owner (and group) of the output files, by name or numeric ID."`
+	CompactOutput  bool              `short:"c" name:"compact-output" aliases:"compact" help:"Output compact JSON (no indentation)."`
+	Indent         int               `name:"indent" placeholder:"N" help:"Indent the JSON output by N spaces instead of 3."`
+	SortKeys       bool              `name:"sort-keys" help:"Sort the keys of objects. Accepted for compatibility with jq; the keys are always sorted."`
+	ExtStr         map[string]string `short:"V" name:"ext-str" help:"Set external string variable for all files (can be repeated)."`
+	ExtCode        map[string]string `name:"ext-code" help:"Set external code variable for all files (can be repeated)."`
+	Lib            []string          `short:"J" name:"lib" aliases:"jpath" placeholder:"DIR" type:"path" help:"Make the files in DIR importable by their names (can be repeated)."`
+	Timeout        time.Duration     `short:"t" name:"timeout" help:"Timeout for each file's evaluation (e.g., 30s, 5m)"`
+	Parallel       int               `name:"parallel" placeholder:"N" help:"Evaluate at most N files at the same time (default: the number of CPUs)."`
+	Src            string            `arg:"" name:"src" type:"existingdir" help:"Directory to search for *.jsonnet files"`
+
+	// writer for the report (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+
+	// functions holds additional native functions to be added to the Jsonnet VM
+	functions []*jsonnet.NativeFunction `kong:"-"`
+}
+
+// evalDirResult is the outcome of evaluating a file of the directory
+type evalDirResult struct {
+	output    string
+	unchanged bool
+	err       error
+}
+
+// SetWriter sets the writer for the report
+func (e *EvalDirCmd) SetWriter(w io.Writer) {
+	e.writer = w
+}
+
+// AddFunctions adds custom native functions to the evaluations
+func (e *EvalDirCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
+	e.functions = append(e.functions, funcs...)
+}
+
+// Run evaluates the files and reports the written outputs. It returns an
+// error if any file fails; the other files are written anyway.
+func (e *EvalDirCmd) Run(ctx context.Context) error {
+	if e.writer == nil {
+		e.writer = os.Stdout
+	}
+	if e.Parallel < 0 {
+		return fmt.Errorf("--parallel must not be negative")
+	}
+	if e.Extension == "" || strings.ContainsRune(e.Extension, filepath.Separator) {
+		return fmt.Errorf("invalid --extension %q", e.Extension)
+	}
+	if _, _, _, err := (&CLI{OutputMode: e.OutputMode, OutputOwner: e.OutputOwner}).outputFileAttrs(); err != nil {
+		return err
+	}
+	files, err := e.discover()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.jsonnet files found in %s", e.Src)
+	}
+
+	parallel := e.Parallel
+	if parallel == 0 {
+		parallel = runtime.NumCPU()
+	}
+	results := make([]evalDirResult, len(files))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Go(func() {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				results[i] = e.evaluate(ctx, file)
+			case <-ctx.Done():
+				results[i] = evalDirResult{err: ctx.Err()}
+			}
+		})
+	}
+	wg.Wait()
+
+	var failed, unchanged int
+	for i, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+			fmt.Fprintf(e.writer, "FAIL %s\n    %s\n", files[i], indent(r.err.Error()))
+		case r.unchanged:
+			unchanged++
+			fmt.Fprintf(e.writer, "unchanged %s\n", r.output)
+		default:
+			fmt.Fprintf(e.writer, "wrote %s\n", r.output)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed", failed, len(files))
+	}
+	fmt.Fprintf(e.writer, "ok %d file(s) evaluated, %d written, %d unchanged\n", len(files), len(files)-unchanged, unchanged)
+	return nil
+}
+
+// discover returns the *.jsonnet files under e.Src. Test files
+// (*_test.jsonnet) are cases of the test command, not configurations.
+func (e *EvalDirCmd) discover() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(e.Src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".jsonnet") && !strings.HasSuffix(d.Name(), testFileSuffix) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// outputPath returns the path under e.Output mirroring file under e.Src
+func (e *EvalDirCmd) outputPath(file string) (string, error) {
+	rel, err := filepath.Rel(e.Src, file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(e.Output, strings.TrimSuffix(rel, ".jsonnet")+e.Extension), nil
+}
+
+// evaluate evaluates a file and writes the result to its output path
+func (e *EvalDirCmd) evaluate(ctx context.Context, file string) evalDirResult {
+	output, err := e.outputPath(file)
+	if err != nil {
+		return evalDirResult{err: err}
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return evalDirResult{output: output, err: err}
+	}
+	cli := &CLI{
+		Filename:       file,
+		Output:         []string{output},
+		WriteIfChanged: e.WriteIfChanged,
+		OutputMode:     e.OutputMode,
+		OutputOwner:    e.OutputOwner,
+		CompactOutput:  e.CompactOutput,
+		Indent:         e.Indent,
+		ExtStr:         e.ExtStr,
+		ExtCode:        e.ExtCode,
+		Lib:            e.Lib,
+		Timeout:        e.Timeout,
+		functions:      e.functions,
+		writer:         io.Discard,
+	}
+	if err := cli.Run(ctx); err != nil {
+		return evalDirResult{output: output, err: err}
+	}
+	return evalDirResult{output: output, unchanged: cli.outputUnchanged > 0}
+}
@@ -0,0 +1,93 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestEvalDir(t *testing.T) {
+	src := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(src, "app", "prod"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(src, "common.libsonnet"), `{region: std.extVar("region")}`)
+	writeFile(t, filepath.Join(src, "app", "prod", "api.jsonnet"), `(import '../../common.libsonnet') + {name: "api"}`)
+	writeFile(t, filepath.Join(src, "top.jsonnet"), `{top: true}`)
+	writeFile(t, filepath.Join(src, "top_test.jsonnet"), `{input: "top.jsonnet"}`)
+
+	run := func(writeIfChanged bool) string {
+		t.Helper()
+		var buf bytes.Buffer
+		cmd := &armed.EvalDirCmd{
+			Src:            src,
+			Output:         out,
+			Extension:      ".json",
+			WriteIfChanged: writeIfChanged,
+			ExtStr:         map[string]string{"region": "ap-northeast-1"},
+		}
+		cmd.SetWriter(&buf)
+		if err := cmd.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, buf.String())
+		}
+		return buf.String()
+	}
+
+	report := run(true)
+	if !strings.Contains(report, "ok 2 file(s) evaluated, 2 written, 0 unchanged") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+	b, err := os.ReadFile(filepath.Join(out, "app", "prod", "api.json"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	compareJSON(t, string(b), `{"name": "api", "region": "ap-northeast-1"}`)
+	b, err = os.ReadFile(filepath.Join(out, "top.json"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	compareJSON(t, string(b), `{"top": true}`)
+	if _, err := os.Stat(filepath.Join(out, "top_test.json")); !os.IsNotExist(err) {
+		t.Errorf("test files must not be evaluated: %v", err)
+	}
+
+	report = run(true)
+	if !strings.Contains(report, "ok 2 file(s) evaluated, 0 written, 2 unchanged") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestEvalDirFailure(t *testing.T) {
+	src := t.TempDir()
+	out := t.TempDir()
+	writeFile(t, filepath.Join(src, "good.jsonnet"), `{good: true}`)
+	writeFile(t, filepath.Join(src, "bad.jsonnet"), `error 'broken'`)
+
+	var buf bytes.Buffer
+	cmd := &armed.EvalDirCmd{Src: src, Output: out, Extension: ".json", Parallel: 1}
+	cmd.SetWriter(&buf)
+	err := cmd.Run(t.Context())
+	if err == nil || err.Error() != "1 of 2 file(s) failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "FAIL "+filepath.Join(src, "bad.jsonnet")) {
+		t.Errorf("the failure is not reported:\n%s", buf.String())
+	}
+	// The other files are written anyway
+	if _, err := os.Stat(filepath.Join(out, "good.json")); err != nil {
+		t.Errorf("good.json is not written: %v", err)
+	}
+}
+
+func TestEvalDirNoFiles(t *testing.T) {
+	cmd := &armed.EvalDirCmd{Src: t.TempDir(), Output: t.TempDir(), Extension: ".json"}
+	cmd.SetWriter(&bytes.Buffer{})
+	if err := cmd.Run(t.Context()); err == nil || !strings.Contains(err.Error(), "no *.jsonnet files found") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
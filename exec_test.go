@@ -0,0 +1,85 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIExec(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lib.libsonnet"), `{ name: "lib" }`)
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		cli      armed.CLI
+		expected string
+	}{
+		{
+			name:     "native function",
+			cli:      armed.CLI{Exec: `std.native("sha256")("x")`},
+			expected: `"2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"`,
+		},
+		{
+			name:     "external variable",
+			cli:      armed.CLI{Exec: `{ env: std.extVar("env") }`, ExtStr: map[string]string{"env": "dev"}},
+			expected: `{"env": "dev"}`,
+		},
+		{
+			name:     "import relative to the working directory",
+			cli:      armed.CLI{Exec: `(import "lib.libsonnet") + { armed: (import "armed.libsonnet").base64("a") }`},
+			expected: `{"name": "lib", "armed": "YQ=="}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			tt.cli.SetWriter(&output)
+			if err := tt.cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLIExecCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// The same JSON is valid Jsonnet, so the output equals the code
+	for _, code := range []string{`{"a": 1}`, `{"a": 2}`} {
+		var output bytes.Buffer
+		cli := &armed.CLI{Exec: code, Cache: time.Minute}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, code, output.String())
+	}
+}
+
+func TestRunWithCLIExecErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cli     armed.CLI
+		wantErr string
+	}{
+		{"with filename", armed.CLI{Exec: `{}`, Filename: "app.jsonnet"}, "-e/--exec cannot be used with <filename>"},
+		{"watch", armed.CLI{Exec: `{}`, Watch: true}, "--watch cannot be used with -e/--exec"},
+		{"incremental", armed.CLI{Exec: `{}`, Incremental: true, Output: []string{"out.json"}}, "--incremental cannot be used with -e/--exec"},
+		{"evaluation error", armed.CLI{Exec: `{ a: error "boom" }`}, "<cmdline>:1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package armed
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTimeout is returned by CLI.Run when the evaluation exceeds --timeout.
+var ErrTimeout = errors.New("evaluation timed out")
+
+// ExitError carries the exit status configured by the --exit-code-* flags.
+// Err is nil when a successful run maps to a non-zero status
+// (e.g. --exit-code-changed).
+type ExitError struct {
+	Code int
+	Err  error
+	// Reported is true when Err has already been written to stderr
+	// (--error-format json), so it should not be logged again.
+	Reported bool
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// tracksChanges reports whether file outputs must be compared with the
+// existing files to choose the exit status, run --on-change or publish metrics
+func (cli *CLI) tracksChanges() bool {
+	return cli.ExitCodeChanged != 0 || cli.ExitCodeUnchanged != 0 || cli.OnChange != "" || cli.MetricsDest != ""
+}
+
+// recordChange records whether a file or HTTP(S) output changed
+func (cli *CLI) recordChange(changed bool) {
+	if changed {
+		cli.outputChanged++
+	} else {
+		cli.outputUnchanged++
+	}
+}
+
+// Exit statuses of --check
+const (
+	checkExitChanged = 1
+	checkExitError   = 2
+)
+
+// mapExitCode wraps the result of an evaluation in an ExitError according
+// to the --exit-code-* flags. A zero flag keeps the default behavior.
+func (cli *CLI) mapExitCode(err error) error {
+	if err != nil {
+		code := cli.ExitCodeError
+		if cli.Check && code == 0 {
+			code = checkExitError
+		}
+		switch {
+		case cli.Check && errors.Is(err, ErrDifferencesFound):
+			code = checkExitChanged
+			if cli.ExitCodeChanged != 0 {
+				code = cli.ExitCodeChanged
+			}
+		case (errors.Is(err, ErrTimeout) || errors.Is(err, ErrCPULimit)) && cli.ExitCodeTimeout != 0:
+			code = cli.ExitCodeTimeout
+		case errors.Is(err, ErrAssertionFailed) && cli.ExitCodeAssert != 0:
+			code = cli.ExitCodeAssert
+		}
+		if code == 0 {
+			return err
+		}
+		return &ExitError{Code: code, Err: err}
+	}
+
+	switch {
+	case cli.outputChanged > 0 && cli.ExitCodeChanged != 0:
+		return &ExitError{Code: cli.ExitCodeChanged}
+	case cli.outputChanged == 0 && cli.outputUnchanged > 0 && cli.ExitCodeUnchanged != 0:
+		return &ExitError{Code: cli.ExitCodeUnchanged}
+	}
+	return nil
+}
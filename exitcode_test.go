@@ -0,0 +1,136 @@
+package armed_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIExitCodes(t *testing.T) {
+	codes := func(cli *armed.CLI) *armed.CLI {
+		cli.ExitCodeError = 2
+		cli.ExitCodeTimeout = 4
+		cli.ExitCodeAssert = 5
+		cli.ExitCodeChanged = 3
+		cli.ExitCodeUnchanged = 6
+		return cli
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		cli      *armed.CLI
+		prepare  func(t *testing.T, outputFile string)
+		wantCode int // 0 means no ExitError
+		wantErr  bool
+	}{
+		{
+			name:     "evaluation error",
+			code:     `error "boom"`,
+			cli:      codes(&armed.CLI{}),
+			wantCode: 2,
+			wantErr:  true,
+		},
+		{
+			name:     "timeout",
+			code:     `std.length([x * y for x in std.range(1, 3000) for y in std.range(1, 3000)])`,
+			cli:      codes(&armed.CLI{Timeout: 10 * time.Millisecond}),
+			wantCode: 4,
+			wantErr:  true,
+		},
+		{
+			name:     "assertion failed",
+			code:     `false`,
+			cli:      codes(&armed.CLI{Assert: true}),
+			wantCode: 5,
+			wantErr:  true,
+		},
+		{
+			name:     "output created",
+			code:     `{ a: 1 }`,
+			cli:      codes(&armed.CLI{}),
+			wantCode: 3,
+		},
+		{
+			name: "output changed",
+			code: `{ a: 1 }`,
+			cli:  codes(&armed.CLI{WriteIfChanged: true}),
+			prepare: func(t *testing.T, outputFile string) {
+				writeFile(t, outputFile, `{"a": 0}`)
+			},
+			wantCode: 3,
+		},
+		{
+			name: "output unchanged",
+			code: `{ a: 1 }`,
+			cli:  codes(&armed.CLI{WriteIfChanged: true}),
+			prepare: func(t *testing.T, outputFile string) {
+				writeFile(t, outputFile, "{\n   \"a\": 1\n}\n")
+			},
+			wantCode: 6,
+		},
+		{
+			name:    "default error",
+			code:    `error "boom"`,
+			cli:     &armed.CLI{},
+			wantErr: true,
+		},
+		{
+			name: "default success",
+			code: `{ a: 1 }`,
+			cli:  &armed.CLI{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+			outputFile := filepath.Join(tmpDir, "out.json")
+			writeFile(t, jsonnetFile, tt.code)
+			if tt.prepare != nil {
+				tt.prepare(t, outputFile)
+			}
+
+			cli := tt.cli
+			cli.Filename = jsonnetFile
+			cli.Output = []string{outputFile}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+
+			var exitErr *armed.ExitError
+			if tt.wantCode == 0 {
+				if errors.As(err, &exitErr) {
+					t.Fatalf("unexpected exit error: %v", err)
+				}
+				if tt.wantErr != (err != nil) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("expected exit error, got %v", err)
+			}
+			if exitErr.Code != tt.wantCode {
+				t.Errorf("expected exit code %d, got %d", tt.wantCode, exitErr.Code)
+			}
+			if tt.wantErr != (exitErr.Err != nil) {
+				t.Errorf("unexpected wrapped error: %v", exitErr.Err)
+			}
+		})
+	}
+}
+
+func TestExitErrorUnwrap(t *testing.T) {
+	err := &armed.ExitError{Code: 5, Err: armed.ErrAssertionFailed}
+	if !errors.Is(err, armed.ErrAssertionFailed) {
+		t.Error("ExitError must unwrap to the original error")
+	}
+	if got := (&armed.ExitError{Code: 3}).Error(); got != "exit status 3" {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
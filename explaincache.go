@@ -0,0 +1,100 @@
+package armed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// explainf writes a line of --explain-cache diagnostics
+func (cli *CLI) explainf(format string, args ...any) {
+	fmt.Fprintf(cli.explain, "explain-cache: "+format+"\n", args...)
+}
+
+// shortHash returns an abbreviated hash of b, to compare key inputs between runs
+func shortHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// explainCacheLookup explains the cache decision for key: the inputs of the
+// key and the age of the entry compared with the TTLs. It must be called
+// before the lookup, which removes expired entries.
+func (cli *CLI) explainCacheLookup(cache cacheStore, key string, content []byte) {
+	c, ok := cache.(*Cache)
+	if !ok {
+		return
+	}
+	parts, err := cacheKeyParts(cli, content)
+	if err != nil {
+		cli.explainf("failed to generate cache key: %s", err)
+		return
+	}
+	cachePath := filepath.Join(c.dir, key+".json")
+	cli.explainf("key %s (%s)", key, cachePath)
+	for _, p := range parts {
+		switch p.name {
+		case "flags":
+			cli.explainFlags(p.data)
+		case "path":
+			cli.explainf("  path %s", p.data)
+		default:
+			cli.explainf("  %s %s", p.name, shortHash(p.data))
+		}
+	}
+
+	stat, err := os.Stat(cachePath)
+	if err != nil {
+		cli.explainf("miss: no entry")
+		return
+	}
+	age := time.Since(stat.ModTime()).Truncate(time.Second)
+	switch {
+	case age <= c.ttl:
+		cli.explainf("hit: entry age %s is within --cache %s", age, c.ttl)
+	case c.staleTTL > 0 && age <= c.staleTTL:
+		cli.explainf("stale: entry age %s is beyond --cache %s but within --stale %s; evaluating, and the entry is used only if the evaluation fails", age, c.ttl, c.staleTTL)
+	case c.staleTTL > 0:
+		cli.explainf("miss: entry age %s is beyond --cache %s and --stale %s; the entry is removed", age, c.ttl, c.staleTTL)
+	default:
+		cli.explainf("miss: entry age %s is beyond --cache %s and --stale is not set; the entry is removed", age, c.ttl)
+	}
+}
+
+// explainFlags lists the flags that are part of the key with the hash of
+// their values, so that two runs can be compared without printing values
+// such as secrets passed in external variables
+func (cli *CLI) explainFlags(cliJSON []byte) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(cliJSON, &fields); err != nil {
+		cli.explainf("  flags %s", shortHash(cliJSON))
+		return
+	}
+	cli.explainf("  flags %s", shortHash(cliJSON))
+	names := make([]string, 0, len(fields))
+	for name, v := range fields {
+		if !isZeroJSON(v) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		cli.explainf("    %s %s", name, shortHash(fields[name]))
+	}
+}
+
+// isZeroJSON reports whether v is the encoding of a zero value
+func isZeroJSON(v json.RawMessage) bool {
+	for _, zero := range []string{"null", "false", "0", `""`, "[]", "{}"} {
+		if bytes.Equal(v, []byte(zero)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package armed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	jsonnetFile := filepath.Join(t.TempDir(), "app.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: std.extVar('token') }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// run returns the diagnostics of a run
+	run := func(cli *CLI) string {
+		t.Helper()
+		var explain bytes.Buffer
+		cli.Filename = jsonnetFile
+		cli.ExtStr = map[string]string{"token": "very-secret"}
+		cli.ExplainCache = true
+		cli.writer = &bytes.Buffer{}
+		cli.explain = &explain
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return explain.String()
+	}
+	// age sets the age of the only cache entry
+	age := func(d time.Duration) {
+		t.Helper()
+		entries, err := filepath.Glob(filepath.Join(getCacheDir(), "*.json"))
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("expected 1 cache entry, got %v (%v)", entries, err)
+		}
+		mtime := time.Now().Add(-d)
+		if err := os.Chtimes(entries[0], mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		cli   *CLI
+		setup func()
+		want  []string
+	}{
+		{
+			name: "disabled",
+			cli:  &CLI{},
+			want: []string{"cache is disabled (--cache is not set)"},
+		},
+		{
+			name: "disabled by redact",
+			cli:  &CLI{Cache: time.Minute, Redact: true},
+			want: []string{"cache is disabled by --redact"},
+		},
+		{
+			name: "no entry",
+			cli:  &CLI{Cache: time.Minute, Stale: time.Hour},
+			want: []string{"path " + jsonnetFile, "    ExtStr sha256:", "  content sha256:", "miss: no entry"},
+		},
+		{
+			name: "hit",
+			cli:  &CLI{Cache: time.Minute, Stale: time.Hour},
+			want: []string{"hit: entry age 0s is within --cache 1m0s"},
+		},
+		{
+			name:  "stale",
+			cli:   &CLI{Cache: time.Minute, Stale: time.Hour},
+			setup: func() { age(10 * time.Minute) },
+			want:  []string{"stale: entry age 10m0s is beyond --cache 1m0s but within --stale 1h0m0s"},
+		},
+		{
+			name:  "expired",
+			cli:   &CLI{Cache: time.Minute, Stale: time.Hour},
+			setup: func() { age(2 * time.Hour) },
+			want:  []string{"miss: entry age 2h0m0s is beyond --cache 1m0s and --stale 1h0m0s; the entry is removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			got := run(tt.cli)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("diagnostics do not contain %q:\n%s", want, got)
+				}
+			}
+			if strings.Contains(got, "very-secret") {
+				t.Errorf("diagnostics contain an external variable value:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestExplainCacheDoesNotChangeKey(t *testing.T) {
+	content := []byte(`{}`)
+	key, err := generateCacheKey(&CLI{Filename: "app.jsonnet", Cache: time.Minute}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explainKey, err := generateCacheKey(&CLI{Filename: "app.jsonnet", Cache: time.Minute, ExplainCache: true}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != explainKey {
+		t.Errorf("--explain-cache changed the cache key: %s != %s", key, explainKey)
+	}
+}
@@ -0,0 +1,27 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// loadExtJSON reads a JSON or YAML file given by --ext-json and returns it
+// as JSON, to be bound as an external code variable
+func loadExtJSON(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --ext-json file: %w", err)
+	}
+	if json.Valid(b) {
+		// Keep JSON as is, so numbers are not converted
+		return string(b), nil
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse --ext-json file %s: %w", path, err)
+	}
+	return string(j), nil
+}
@@ -0,0 +1,67 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIExtJSON(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "values.json")
+	writeFile(t, jsonFile, `{"image": "web:1.0", "replicas": 3, "big": 12345678901234567890}`)
+	yamlFile := filepath.Join(dir, "values.yaml")
+	writeFile(t, yamlFile, "image: web:2.0\nreplicas: 2\nports:\n  - 80\n  - 443\n")
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `{ a: std.extVar("a"), b: std.extVar("b"), replicas: std.extVar("a").replicas + std.extVar("b").replicas }`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: file, ExtJSON: map[string]string{"a": jsonFile, "b": yamlFile}}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, `{
+		"a": {"image": "web:1.0", "replicas": 3, "big": 12345678901234567000},
+		"b": {"image": "web:2.0", "replicas": 2, "ports": [80, 443]},
+		"replicas": 5
+	}`, output.String())
+}
+
+func TestRunWithCLIExtJSONErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `std.extVar("vars")`)
+	invalid := filepath.Join(dir, "invalid.yaml")
+	writeFile(t, invalid, "a: [1, 2\n")
+
+	tests := []struct {
+		name    string
+		extJSON map[string]string
+		extCode map[string]string
+		want    string
+	}{
+		{name: "missing file", extJSON: map[string]string{"vars": filepath.Join(dir, "missing.json")}, want: "failed to read --ext-json file"},
+		{name: "invalid file", extJSON: map[string]string{"vars": invalid}, want: "failed to parse --ext-json file"},
+		{
+			name:    "conflict with ext-code",
+			extJSON: map[string]string{"vars": invalid},
+			extCode: map[string]string{"vars": "{}"},
+			want:    `external variable "vars" is set by both --ext-code and --ext-json`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, ExtJSON: tt.extJSON, ExtCode: tt.extCode}
+			cli.SetWriter(&output)
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+// setStdin replaces os.Stdin with a pipe that yields content
+func setStdin(t *testing.T, content string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = oldStdin })
+	go func() {
+		w.Write([]byte(content))
+		w.Close()
+	}()
+}
+
+func TestRunWithCLIExtStrStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `{
+		env: std.extVar("env"),
+		users: std.parseJson(std.extVar("input")).users,
+	}`)
+	setStdin(t, `{"users": ["alice", "bob"]}`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{
+		Filename:    jsonnetFile,
+		ExtStr:      map[string]string{"env": "prod"},
+		ExtStrStdin: "input",
+	}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, `{"env": "prod", "users": ["alice", "bob"]}`, output.String())
+}
+
+func TestRunWithCLIExtStrStdinErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cli     *armed.CLI
+		wantErr string
+	}{
+		{
+			name:    "program from stdin",
+			cli:     &armed.CLI{Filename: "-", ExtStrStdin: "input"},
+			wantErr: "--ext-str-stdin cannot be used when the program is read from stdin",
+		},
+		{
+			name:    "conflict with --ext-str",
+			cli:     &armed.CLI{Filename: "test.jsonnet", ExtStr: map[string]string{"input": "x"}, ExtStrStdin: "input"},
+			wantErr: `external variable "input" is set by both --ext-str and --ext-str-stdin`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
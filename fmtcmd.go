@@ -0,0 +1,122 @@
+package armed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-jsonnet/formatter"
+)
+
+// FmtCmd formats jsonnet files with the formatter of go-jsonnet, the same
+// as jsonnetfmt with its default options.
+type FmtCmd struct {
+	Write bool     `short:"w" name:"write" help:"Write the formatted source back to the files instead of printing it"`
+	Check bool     `name:"check" help:"List the files that are not formatted and fail if there are any, without writing them"`
+	Paths []string `arg:"" name:"path" help:"Files or directories to search for *.jsonnet and *.libsonnet files (default: current directory)" type:"path" optional:""`
+
+	// writer for the formatted sources and reports (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+}
+
+// SetWriter sets the writer for the formatted sources and reports
+func (f *FmtCmd) SetWriter(w io.Writer) {
+	f.writer = w
+}
+
+// Run formats the files. Without --write or --check, the formatted sources
+// are printed.
+func (f *FmtCmd) Run(ctx context.Context) error {
+	if f.writer == nil {
+		f.writer = os.Stdout
+	}
+	if f.Write && f.Check {
+		return fmt.Errorf("--write and --check cannot be used together")
+	}
+	files, err := discoverSources(f.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.jsonnet or *.libsonnet files found")
+	}
+
+	var unformatted int
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		formatted, err := formatter.Format(file, string(b), formatter.DefaultOptions())
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", file, err)
+		}
+		changed := formatted != string(b)
+		switch {
+		case f.Check:
+			if changed {
+				unformatted++
+				fmt.Fprintln(f.writer, file)
+			}
+		case f.Write:
+			if !changed {
+				continue
+			}
+			st, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if err := writeFileAtomic(file, []byte(formatted), st.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			fmt.Fprintf(f.writer, "formatted %s\n", file)
+		default:
+			if _, err := io.WriteString(f.writer, formatted); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted > 0 {
+		return fmt.Errorf("%d of %d file(s) not formatted", unformatted, len(files))
+	}
+	return nil
+}
+
+// discoverSources returns the files given by paths, and the *.jsonnet and
+// *.libsonnet files under the directories. No paths means the current directory.
+func discoverSources(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var files []string
+	for _, p := range paths {
+		st, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (strings.HasSuffix(d.Name(), ".jsonnet") || strings.HasSuffix(d.Name(), ".libsonnet")) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
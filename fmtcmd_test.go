@@ -0,0 +1,117 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+const (
+	unformattedJsonnet = "{a:1,\n  \"b\":   [1,2]}\n"
+	formattedJsonnet   = "{\n  a: 1,\n  b: [1, 2],\n}\n"
+)
+
+func setupFmtDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.jsonnet"), unformattedJsonnet)
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "lib", "common.libsonnet"), formattedJsonnet)
+	writeFile(t, filepath.Join(dir, "README.md"), "not jsonnet {")
+	return dir
+}
+
+func TestFmtCmd(t *testing.T) {
+	dir := setupFmtDir(t)
+	var output bytes.Buffer
+	cmd := &armed.FmtCmd{Paths: []string{filepath.Join(dir, "app.jsonnet")}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.String() != formattedJsonnet {
+		t.Errorf("unexpected output:\n%s", output.String())
+	}
+	// Printing does not modify the file
+	b, err := os.ReadFile(filepath.Join(dir, "app.jsonnet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != unformattedJsonnet {
+		t.Errorf("file is modified: %s", b)
+	}
+}
+
+func TestFmtCmdCheckAndWrite(t *testing.T) {
+	dir := setupFmtDir(t)
+	appFile := filepath.Join(dir, "app.jsonnet")
+
+	var output bytes.Buffer
+	cmd := &armed.FmtCmd{Check: true, Paths: []string{dir}}
+	cmd.SetWriter(&output)
+	err := cmd.Run(t.Context())
+	if err == nil || err.Error() != "1 of 2 file(s) not formatted" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.String() != appFile+"\n" {
+		t.Errorf("unexpected report:\n%s", output.String())
+	}
+
+	output.Reset()
+	cmd = &armed.FmtCmd{Write: true, Paths: []string{dir}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.String() != "formatted "+appFile+"\n" {
+		t.Errorf("unexpected report:\n%s", output.String())
+	}
+	b, err := os.ReadFile(appFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != formattedJsonnet {
+		t.Errorf("file is not formatted:\n%s", b)
+	}
+
+	output.Reset()
+	cmd = &armed.FmtCmd{Check: true, Paths: []string{dir}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error after --write: %v", err)
+	}
+	if output.String() != "" {
+		t.Errorf("unexpected report:\n%s", output.String())
+	}
+}
+
+func TestFmtCmdErrors(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.jsonnet")
+	writeFile(t, broken, "{ a: ")
+
+	tests := []struct {
+		name    string
+		cmd     *armed.FmtCmd
+		wantErr string
+	}{
+		{"write and check", &armed.FmtCmd{Write: true, Check: true, Paths: []string{broken}}, "--write and --check cannot be used together"},
+		{"syntax error", &armed.FmtCmd{Paths: []string{broken}}, "failed to format " + broken},
+		{"no files", &armed.FmtCmd{Paths: []string{t.TempDir()}}, "no *.jsonnet or *.libsonnet files found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmd.SetWriter(&bytes.Buffer{})
+			err := tt.cmd.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
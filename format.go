@@ -0,0 +1,239 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Output formats for --format
+const (
+	formatJSON    = "json"
+	formatMsgpack = "msgpack"
+	formatCBOR    = "cbor"
+	formatTOML    = "toml"
+)
+
+// isNonJSONFormat reports whether the result is encoded in a format other than JSON
+func (cli *CLI) isNonJSONFormat() bool {
+	return cli.Format != "" && cli.Format != formatJSON
+}
+
+// checkFormat rejects options that work on JSON text with other formats
+// and options that conflict with --output-template
+func (cli *CLI) checkFormat() error {
+	switch {
+	case cli.Indent < 0:
+		return fmt.Errorf("--indent must not be negative")
+	case cli.Indent > 0 && cli.CompactOutput:
+		return fmt.Errorf("--indent cannot be used with --compact-output")
+	}
+	if cli.OutputTemplate != "" {
+		switch {
+		case cli.isNonJSONFormat():
+			return fmt.Errorf("--output-template cannot be used with --format %s", cli.Format)
+		case cli.CompactOutput:
+			return fmt.Errorf("--output-template cannot be used with --compact-output")
+		case cli.Indent > 0:
+			return fmt.Errorf("--output-template cannot be used with --indent")
+		case cli.RawOutput:
+			return fmt.Errorf("--output-template cannot be used with --raw-output")
+		case cli.OutputBinary:
+			return fmt.Errorf("--output-template cannot be used with --output-binary")
+		}
+		return nil
+	}
+	if !cli.isNonJSONFormat() {
+		return nil
+	}
+	switch {
+	case cli.CompactOutput:
+		return fmt.Errorf("--format %s cannot be used with --compact-output", cli.Format)
+	case cli.Indent > 0:
+		return fmt.Errorf("--format %s cannot be used with --indent", cli.Format)
+	case cli.RawOutput:
+		return fmt.Errorf("--format %s cannot be used with --raw-output", cli.Format)
+	case cli.OutputBinary:
+		return fmt.Errorf("--format %s cannot be used with --output-binary", cli.Format)
+	case cli.Redact:
+		return fmt.Errorf("--format %s cannot be used with --redact", cli.Format)
+	}
+	return nil
+}
+
+// contentType returns the Content-Type of the output for HTTP(S) destinations
+func (cli *CLI) contentType() string {
+	switch {
+	case cli.OutputBinary:
+		return "application/octet-stream"
+	case cli.OutputTemplate != "":
+		return "text/plain; charset=utf-8"
+	case cli.Format == formatMsgpack:
+		return "application/msgpack"
+	case cli.Format == formatCBOR:
+		return "application/cbor"
+	case cli.Format == formatTOML:
+		return "application/toml"
+	default:
+		return "application/json"
+	}
+}
+
+// encodeFormat encodes the JSON result in another format. Map keys are
+// sorted so that the same result always produces the same bytes.
+func encodeFormat(jsonStr string, format string) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(jsonStr)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("failed to parse result: %w", err)
+	}
+	v = fromJSONNumbers(v)
+
+	switch format {
+	case formatMsgpack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetSortMapKeys(true)
+		enc.UseCompactInts(true)
+		if err := enc.Encode(v); err != nil {
+			return "", fmt.Errorf("failed to encode result as msgpack: %w", err)
+		}
+		return buf.String(), nil
+	case formatCBOR:
+		em, err := cbor.CoreDetEncOptions().EncMode()
+		if err != nil {
+			return "", err
+		}
+		b, err := em.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result as cbor: %w", err)
+		}
+		return string(b), nil
+	case formatTOML:
+		if err := checkTOML(v); err != nil {
+			return "", err
+		}
+		b, err := toml.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result as toml: %w", err)
+		}
+		return string(b), nil
+	default:
+		return "", fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// checkTOML checks that the result can be represented in TOML: the top
+// level must be an object and null values are not allowed anywhere.
+// Nested objects become tables and arrays of objects become arrays of tables.
+func checkTOML(v any) error {
+	if _, ok := v.(map[string]any); !ok {
+		return fmt.Errorf("--format toml requires an object result")
+	}
+	return checkTOMLValue(v, "")
+}
+
+func checkTOMLValue(v any, path string) error {
+	switch v := v.(type) {
+	case nil:
+		return fmt.Errorf("--format toml cannot encode null at %s", path)
+	case []any:
+		for i, e := range v {
+			if err := checkTOMLValue(e, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for k, e := range v {
+			if err := checkTOMLValue(e, path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// templateFuncs are the functions available in --output-template templates
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"jsonIndent": func(v any) (string, error) {
+		b, err := json.MarshalIndent(v, "", "  ")
+		return string(b), err
+	},
+	"join": func(sep string, elems []any) string {
+		s := make([]string, len(elems))
+		for i, e := range elems {
+			s[i] = fmt.Sprint(e)
+		}
+		return strings.Join(s, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// renderTemplate renders the JSON result with the Go template file.
+// Numbers are passed as json.Number, so integers are printed as is.
+func renderTemplate(filename string, jsonStr string) (string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read output template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(filename)).
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Parse(string(b))
+	if err != nil {
+		return "", fmt.Errorf("invalid output template: %w", err)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(jsonStr))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("failed to parse result: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, v); err != nil {
+		return "", fmt.Errorf("failed to render output template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// fromJSONNumbers converts json.Number to int64 for integers and to
+// float64 otherwise, so that integers are encoded compactly.
+func fromJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+		return v
+	case map[string]any:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,327 @@
+package armed_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestRunWithCLIFormat(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{ name: "device", port: 8080, ratio: 0.5, tags: ["a", "b"], enabled: true, extra: null }`)
+
+	tests := []struct {
+		format      string
+		contentType string
+		decode      func([]byte) (any, error)
+		expected    any
+	}{
+		{
+			format:      "msgpack",
+			contentType: "application/msgpack",
+			decode: func(b []byte) (any, error) {
+				var v map[string]any
+				err := msgpack.Unmarshal(b, &v)
+				return v, err
+			},
+			expected: map[string]any{
+				"name": "device", "port": uint16(8080), "ratio": 0.5,
+				"tags": []any{"a", "b"}, "enabled": true, "extra": nil,
+			},
+		},
+		{
+			format:      "cbor",
+			contentType: "application/cbor",
+			decode: func(b []byte) (any, error) {
+				var v map[string]any
+				err := cbor.Unmarshal(b, &v)
+				return v, err
+			},
+			expected: map[string]any{
+				"name": "device", "port": uint64(8080), "ratio": 0.5,
+				"tags": []any{"a", "b"}, "enabled": true, "extra": nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: jsonnetFile, Format: tt.format}
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			v, err := tt.decode(output.Bytes())
+			if err != nil {
+				t.Fatalf("failed to decode output: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, v); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+
+			// Map keys are sorted, so the output is stable
+			var again bytes.Buffer
+			cli = &armed.CLI{Filename: jsonnetFile, Format: tt.format}
+			cli.SetWriter(&again)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(output.Bytes(), again.Bytes()) {
+				t.Error("output is not deterministic")
+			}
+
+			// Round trip through the decode native
+			roundTrip := filepath.Join(t.TempDir(), "roundtrip.jsonnet")
+			writeFile(t, roundTrip, `std.native("`+tt.format+`_decode")(std.extVar("data"))`)
+			var decoded bytes.Buffer
+			cli = &armed.CLI{Filename: roundTrip, ExtStr: map[string]string{"data": base64.StdEncoding.EncodeToString(output.Bytes())}}
+			cli.SetWriter(&decoded)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, `{"enabled": true, "extra": null, "name": "device", "port": 8080, "ratio": 0.5, "tags": ["a", "b"]}`, decoded.String())
+		})
+
+		t.Run(tt.format+" http", func(t *testing.T) {
+			var contentType string
+			var body []byte
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer ts.Close()
+
+			cli := &armed.CLI{Filename: jsonnetFile, Format: tt.format, Output: []string{ts.URL}}
+			cli.SetWriter(&bytes.Buffer{})
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("unexpected Content-Type: %s", contentType)
+			}
+			if _, err := tt.decode(body); err != nil {
+				t.Errorf("failed to decode body: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLIFormatTOML(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{
+  name: "app",
+  port: 8080,
+  ratio: 0.5,
+  tags: ["a", "b"],
+  database: { user: "admin", pool: { size: 10 } },
+  servers: [
+    { host: "a.example.com", tls: { enabled: true } },
+    { host: "b.example.com" },
+  ],
+}`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: jsonnetFile, Format: "toml"}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `name = 'app'
+port = 8080
+ratio = 0.5
+tags = ['a', 'b']
+
+[database]
+user = 'admin'
+
+[database.pool]
+size = 10
+
+[[servers]]
+host = 'a.example.com'
+
+[servers.tls]
+enabled = true
+
+[[servers]]
+host = 'b.example.com'
+`
+	if diff := cmp.Diff(expected, output.String()); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+
+	var v map[string]any
+	if err := toml.Unmarshal(output.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if servers, ok := v["servers"].([]any); !ok || len(servers) != 2 {
+		t.Errorf("servers is not decoded as an array of tables: %#v", v["servers"])
+	}
+
+	t.Run("http", func(t *testing.T) {
+		var contentType string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+		}))
+		defer ts.Close()
+
+		cli := &armed.CLI{Filename: jsonnetFile, Format: "toml", Output: []string{ts.URL}}
+		cli.SetWriter(&bytes.Buffer{})
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contentType != "application/toml" {
+			t.Errorf("unexpected Content-Type: %s", contentType)
+		}
+	})
+}
+
+func TestRunWithCLIFormatTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"not an object", `[1, 2]`, "--format toml requires an object result"},
+		{"null value", `{ a: { b: null } }`, "--format toml cannot encode null at .a.b"},
+		{"null in array", `{ a: [1, null] }`, "--format toml cannot encode null at .a[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+			writeFile(t, jsonnetFile, tt.content)
+			cli := &armed.CLI{Filename: jsonnetFile, Format: "toml"}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLIFormatErrors(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{ a: 1 }`)
+
+	tests := []struct {
+		name    string
+		cli     *armed.CLI
+		wantErr string
+	}{
+		{"compact output", &armed.CLI{CompactOutput: true}, "--format msgpack cannot be used with --compact-output"},
+		{"indent", &armed.CLI{Indent: 2}, "--format msgpack cannot be used with --indent"},
+		{"raw output", &armed.CLI{RawOutput: true}, "--format msgpack cannot be used with --raw-output"},
+		{"output binary", &armed.CLI{OutputBinary: true}, "--format msgpack cannot be used with --output-binary"},
+		{"redact", &armed.CLI{Redact: true}, "--format msgpack cannot be used with --redact"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.Filename = jsonnetFile
+			tt.cli.Format = "msgpack"
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLIOutputTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `{
+		name: "app",
+		port: 8080,
+		ratio: 0.5,
+		servers: [{ host: "a.example.com", weight: 1 }, { host: "b.example.com", weight: 2 }],
+		tags: ["x", "y"],
+	}`)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  string
+	}{
+		{
+			name: "ini",
+			template: `[{{ .name }}]
+port = {{ .port }}
+ratio = {{ .ratio }}
+{{ range $i, $s := .servers -}}
+server{{ $i }} = {{ $s.host }}:{{ $s.weight }}
+{{ end -}}
+tags = {{ join "," .tags }}
+`,
+			expected: `[app]
+port = 8080
+ratio = 0.5
+server0 = a.example.com:1
+server1 = b.example.com:2
+tags = x,y
+`,
+		},
+		{
+			name:     "functions",
+			template: `{{ upper .name }} {{ json .tags }}`,
+			expected: `APP ["x","y"]`,
+		},
+		{
+			name:     "missing key",
+			template: `{{ .nonexistent }}`,
+			wantErr:  "failed to render output template",
+		},
+		{
+			name:     "invalid template",
+			template: `{{ .name `,
+			wantErr:  "invalid output template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmplFile := filepath.Join(tmpDir, tt.name+".gotmpl")
+			writeFile(t, tmplFile, tt.template)
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: jsonnetFile, OutputTemplate: tmplFile}
+			cli.SetWriter(&output)
+			err := cli.Run(t.Context())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, output.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("conflicting options", func(t *testing.T) {
+		tmplFile := filepath.Join(tmpDir, "simple.gotmpl")
+		writeFile(t, tmplFile, `{{ .name }}`)
+		cli := &armed.CLI{Filename: jsonnetFile, OutputTemplate: tmplFile, CompactOutput: true}
+		cli.SetWriter(&bytes.Buffer{})
+		err := cli.Run(t.Context())
+		if err == nil || !strings.Contains(err.Error(), "--output-template cannot be used with --compact-output") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
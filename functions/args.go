@@ -0,0 +1,197 @@
+package functions
+
+import (
+	"fmt"
+)
+
+// nativeArgs provides typed access to the arguments of a native function.
+// Errors name the function, the 1-based argument position and the
+// parameter, e.g. `env: argument #1 (name) must be a string, got number`.
+// Missing arguments are reported as errors instead of causing a panic.
+type nativeArgs struct {
+	fn   string
+	args []any
+}
+
+// newArgs wraps the arguments of the native function fn
+func newArgs(fn string, args []any) nativeArgs {
+	return nativeArgs{fn: fn, args: args}
+}
+
+// errorf returns an error prefixed with the function name and the argument
+func (a nativeArgs) errorf(i int, param, format string, v ...any) error {
+	return fmt.Errorf("%s: argument #%d (%s) %s", a.fn, i+1, param, fmt.Sprintf(format, v...))
+}
+
+// typeError returns an error for an argument of an unexpected type
+func (a nativeArgs) typeError(i int, param, want string) error {
+	return a.errorf(i, param, "must be %s, got %s", want, jsonTypeName(a.args[i]))
+}
+
+// Value returns the i-th argument as it is
+func (a nativeArgs) Value(i int, param string) (any, error) {
+	if i >= len(a.args) {
+		return nil, fmt.Errorf("%s: missing argument #%d (%s)", a.fn, i+1, param)
+	}
+	return a.args[i], nil
+}
+
+// isNull reports whether the i-th argument is missing or null
+func (a nativeArgs) isNull(i int) bool {
+	return i >= len(a.args) || a.args[i] == nil
+}
+
+// String returns the i-th argument as a string
+func (a nativeArgs) String(i int, param string) (string, error) {
+	if _, err := a.Value(i, param); err != nil {
+		return "", err
+	}
+	s, ok := a.args[i].(string)
+	if !ok {
+		return "", a.typeError(i, param, "a string")
+	}
+	return s, nil
+}
+
+// OptionalString returns the i-th argument as a string, or def if it is missing or null
+func (a nativeArgs) OptionalString(i int, param, def string) (string, error) {
+	if a.isNull(i) {
+		return def, nil
+	}
+	s, ok := a.args[i].(string)
+	if !ok {
+		return "", a.typeError(i, param, "a string or null")
+	}
+	return s, nil
+}
+
+// Number returns the i-th argument as a number
+func (a nativeArgs) Number(i int, param string) (float64, error) {
+	if _, err := a.Value(i, param); err != nil {
+		return 0, err
+	}
+	n, ok := a.args[i].(float64)
+	if !ok {
+		return 0, a.typeError(i, param, "a number")
+	}
+	return n, nil
+}
+
+// Bool returns the i-th argument as a boolean
+func (a nativeArgs) Bool(i int, param string) (bool, error) {
+	if _, err := a.Value(i, param); err != nil {
+		return false, err
+	}
+	b, ok := a.args[i].(bool)
+	if !ok {
+		return false, a.typeError(i, param, "a boolean")
+	}
+	return b, nil
+}
+
+// Array returns the i-th argument as an array
+func (a nativeArgs) Array(i int, param string) ([]any, error) {
+	if _, err := a.Value(i, param); err != nil {
+		return nil, err
+	}
+	arr, ok := a.args[i].([]any)
+	if !ok {
+		return nil, a.typeError(i, param, "an array")
+	}
+	return arr, nil
+}
+
+// StringArray returns the i-th argument as an array of strings
+func (a nativeArgs) StringArray(i int, param string) ([]string, error) {
+	arr, err := a.Array(i, param)
+	if err != nil {
+		return nil, err
+	}
+	return a.toStrings(i, param, arr)
+}
+
+// OptionalStringArray returns the i-th argument as an array of strings, or nil if it is missing or null
+func (a nativeArgs) OptionalStringArray(i int, param string) ([]string, error) {
+	if a.isNull(i) {
+		return nil, nil
+	}
+	arr, ok := a.args[i].([]any)
+	if !ok {
+		return nil, a.typeError(i, param, "an array or null")
+	}
+	return a.toStrings(i, param, arr)
+}
+
+func (a nativeArgs) toStrings(i int, param string, arr []any) ([]string, error) {
+	strs := make([]string, len(arr))
+	for j, v := range arr {
+		s, ok := v.(string)
+		if !ok {
+			return nil, a.errorf(i, param, "element at index %d must be a string, got %s", j, jsonTypeName(v))
+		}
+		strs[j] = s
+	}
+	return strs, nil
+}
+
+// Object returns the i-th argument as an object
+func (a nativeArgs) Object(i int, param string) (map[string]any, error) {
+	if _, err := a.Value(i, param); err != nil {
+		return nil, err
+	}
+	obj, ok := a.args[i].(map[string]any)
+	if !ok {
+		return nil, a.typeError(i, param, "an object")
+	}
+	return obj, nil
+}
+
+// OptionalObject returns the i-th argument as an object, or nil if it is missing or null
+func (a nativeArgs) OptionalObject(i int, param string) (map[string]any, error) {
+	if a.isNull(i) {
+		return nil, nil
+	}
+	obj, ok := a.args[i].(map[string]any)
+	if !ok {
+		return nil, a.typeError(i, param, "an object or null")
+	}
+	return obj, nil
+}
+
+// OptionalStringMap returns the i-th argument as an object with string
+// values, or nil if it is missing or null
+func (a nativeArgs) OptionalStringMap(i int, param string) (map[string]string, error) {
+	obj, err := a.OptionalObject(i, param)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(obj))
+	for k, v := range obj {
+		s, ok := v.(string)
+		if !ok {
+			return nil, a.errorf(i, param, "value for %s must be a string, got %s", k, jsonTypeName(v))
+		}
+		m[k] = s
+	}
+	return m, nil
+}
+
+// jsonTypeName returns the Jsonnet type name of a native function argument
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, int:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,112 @@
+package functions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+)
+
+func TestArgumentValidation(t *testing.T) {
+	all := map[string]*jsonnet.NativeFunction{}
+	for _, f := range functions.GenerateAllFunctions(context.Background()) {
+		all[f.Name] = f
+	}
+
+	tests := []struct {
+		name     string
+		function string
+		args     []any
+		wantErr  string
+	}{
+		{
+			name:     "string type mismatch",
+			function: "env",
+			args:     []any{123.0, "default"},
+			wantErr:  "env: argument #1 (name) must be a string, got number",
+		},
+		{
+			name:     "missing argument",
+			function: "env",
+			args:     []any{"JSONNET_ARMED_UNDEFINED_VAR"},
+			wantErr:  "env: missing argument #2 (default)",
+		},
+		{
+			name:     "no arguments",
+			function: "sha256",
+			args:     []any{},
+			wantErr:  "sha256: missing argument #1 (data)",
+		},
+		{
+			name:     "file hash function name",
+			function: "md5_file",
+			args:     []any{nil},
+			wantErr:  "md5_file: argument #1 (filename) must be a string, got null",
+		},
+		{
+			name:     "second argument",
+			function: "regex_replace",
+			args:     []any{"a", true, "abc"},
+			wantErr:  "regex_replace: argument #2 (replacement) must be a string, got boolean",
+		},
+		{
+			name:     "number type mismatch",
+			function: "time_format",
+			args:     []any{"now", "RFC3339"},
+			wantErr:  "time_format: argument #1 (timestamp) must be a number, got string",
+		},
+		{
+			name:     "array element type mismatch",
+			function: "path_join",
+			args:     []any{[]any{"a", 1.0}},
+			wantErr:  "path_join: argument #1 (elements) element at index 1 must be a string, got number",
+		},
+		{
+			name:     "optional array type mismatch",
+			function: "exec",
+			args:     []any{"echo", "hello"},
+			wantErr:  "exec: argument #2 (args) must be an array or null, got string",
+		},
+		{
+			name:     "optional object value type mismatch",
+			function: "exec_with_env",
+			args:     []any{"env", nil, map[string]any{"FOO": 1.0}},
+			wantErr:  "exec_with_env: argument #3 (env_vars) value for FOO must be a string, got number",
+		},
+		{
+			name:     "optional object type mismatch",
+			function: "http_get",
+			args:     []any{"http://example.com", []any{}},
+			wantErr:  "http_get: argument #2 (headers) must be an object or null, got array",
+		},
+		{
+			name:     "optional string type mismatch",
+			function: "http_request",
+			args:     []any{"POST", "http://example.com", nil, map[string]any{}},
+			wantErr:  "http_request: argument #4 (body) must be a string or null, got object",
+		},
+		{
+			name:     "missing value argument",
+			function: "jq",
+			args:     []any{"."},
+			wantErr:  "jq: missing argument #2 (input)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := all[tt.function]
+			if !ok {
+				t.Fatalf("function %s not found", tt.function)
+			}
+			_, err := f.Func(tt.args)
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.wantErr)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package functions
 
 import (
 	"context"
-	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/google/go-jsonnet"
@@ -12,7 +12,7 @@ func GenerateAllFunctions(ctx context.Context) []*jsonnet.NativeFunction {
 	var all []*jsonnet.NativeFunction
 
 	// Add functions from maps
-	for _, f := range EnvFunctions {
+	for _, f := range GenerateEnvFunctions(ctx) {
 		all = append(all, f)
 	}
 	for _, f := range HashFunctions {
@@ -33,7 +33,28 @@ func GenerateAllFunctions(ctx context.Context) []*jsonnet.NativeFunction {
 	for _, f := range GenerateHttpFunctions(ctx) {
 		all = append(all, f)
 	}
-	for _, f := range DnsFunctions {
+	for _, f := range GenerateGitHubFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateOIDCFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateCloudFormationFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateDynamoDBFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateRedisFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateSQLFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateLDAPFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateDnsFunctions(ctx) {
 		all = append(all, f)
 	}
 	for _, f := range RegexpFunctions {
@@ -51,9 +72,53 @@ func GenerateAllFunctions(ctx context.Context) []*jsonnet.NativeFunction {
 	for _, f := range X509Functions {
 		all = append(all, f)
 	}
+	for _, f := range CUEFunctions {
+		all = append(all, f)
+	}
+	for _, f := range ProtoFunctions {
+		all = append(all, f)
+	}
+	for _, f := range BinaryFormatFunctions {
+		all = append(all, f)
+	}
 	for _, f := range PathFunctions {
 		all = append(all, f)
 	}
+	for _, f := range BigNumFunctions {
+		all = append(all, f)
+	}
+	for _, f := range FakeFunctions {
+		all = append(all, f)
+	}
+	for _, f := range PasswordFunctions {
+		all = append(all, f)
+	}
+	for _, f := range TOTPFunctions {
+		all = append(all, f)
+	}
+	for _, f := range CronFunctions {
+		all = append(all, f)
+	}
+	for _, f := range GenerateVersionFunctions(ctx) {
+		all = append(all, f)
+	}
+	for _, f := range GenerateSecretFunctions(ctx) {
+		all = append(all, f)
+	}
+
+	// all is generated for each evaluation, so is the memo
+	memo := newFileMemo()
+	for i, f := range all {
+		if memoizedFileFunctions[f.Name] {
+			all[i] = memo.wrap(f)
+		}
+	}
+
+	for i, f := range all {
+		if message, ok := DeprecatedFunctions[f.Name]; ok {
+			all[i] = deprecate(ctx, f, message)
+		}
+	}
 
 	return all
 }
@@ -63,9 +128,12 @@ func GenerateArmedLib(funcs []*jsonnet.NativeFunction) string {
 	var lines []string
 	lines = append(lines, "{")
 
-	// Add all function definitions
+	// Add all function definitions, sorted by name for a stable output
+	funcs = slices.SortedFunc(slices.Values(funcs), func(a, b *jsonnet.NativeFunction) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 	for _, f := range funcs {
-		lines = append(lines, fmt.Sprintf("  %s: std.native('%s'),", f.Name, f.Name))
+		lines = append(lines, libEntry(f)...)
 	}
 
 	lines = append(lines, "}")
@@ -3,6 +3,9 @@ package functions
 import (
 	"strings"
 	"testing"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
 )
 
 func TestGenerateArmedLib(t *testing.T) {
@@ -11,17 +14,16 @@ func TestGenerateArmedLib(t *testing.T) {
 
 	// Verify it contains expected function definitions
 	expectedFunctions := []string{
-		"env: std.native('env')",
-		"sha256: std.native('sha256')",
-		"file_content: std.native('file_content')",
-		"must_env: std.native('must_env')",
-		"md5: std.native('md5')",
-		"file_stat: std.native('file_stat')",
-		"base64: std.native('base64')",
-		"base64url: std.native('base64url')",
-		"now: std.native('now')",
-		"time_format: std.native('time_format')",
-		"file_exists: std.native('file_exists')",
+		"  // env(name, default=null): Get environment variable with default\n  env(name, default=null): std.native('env')(name, default),",
+		"sha256(data): std.native('sha256')(data),",
+		"file_content(filename): std.native('file_content')(filename),",
+		"must_env(name): std.native('must_env')(name),",
+		"now(): std.native('now')(),",
+		"time_format(timestamp, format='RFC3339'): std.native('time_format')(timestamp, format),",
+		"http_get(url, headers={}): std.native('http_get')(url, headers),",
+		"exec(command, args=[]): std.native('exec')(command, args),",
+		"exec_with_env(command, args=[], env_vars={}): std.native('exec_with_env')(command, args, env_vars),",
+		"dns_lookup(hostname, record_type='A'): std.native('dns_lookup')(hostname, record_type),",
 	}
 
 	for _, expected := range expectedFunctions {
@@ -30,3 +32,18 @@ func TestGenerateArmedLib(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateArmedLibUserFunctions(t *testing.T) {
+	funcs := []*jsonnet.NativeFunction{
+		{Name: "hello", Params: []ast.Identifier{"name"}},
+		{Name: "reserved", Params: []ast.Identifier{"function"}},
+	}
+	result := GenerateArmedLib(funcs)
+	expected := "{\n" +
+		"  hello(name): std.native('hello')(name),\n" +
+		"  reserved: std.native('reserved'),\n" +
+		"}"
+	if result != expected {
+		t.Errorf("unexpected result:\n%s\nwant:\n%s", result, expected)
+	}
+}
@@ -0,0 +1,149 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+)
+
+// libFunction describes how a native function is exposed in armed.libsonnet
+type libFunction struct {
+	// Doc is a one-line description emitted as a comment
+	Doc string
+	// Defaults maps parameter names to default values in Jsonnet syntax
+	Defaults map[string]string
+}
+
+// armedLibFunctions holds documentation and default arguments of the
+// built-in native functions for armed.libsonnet.
+var armedLibFunctions = map[string]libFunction{
+	"env":                     {Doc: "Get environment variable with default", Defaults: map[string]string{"default": "null"}},
+	"must_env":                {Doc: "Get required environment variable"},
+	"secret":                  {Doc: "Mark a value as secret to be masked by --redact"},
+	"env_parse":               {Doc: "Parse .env format string"},
+	"env_all":                 {Doc: "Get environment variables matching a prefix or regex as object"},
+	"now":                     {Doc: "Get current Unix timestamp"},
+	"time_format":             {Doc: "Format timestamp with Go layout", Defaults: map[string]string{"format": "'RFC3339'"}},
+	"base64":                  {Doc: "Standard Base64 encoding"},
+	"base64url":               {Doc: "URL-safe Base64 encoding"},
+	"md5":                     {Doc: "MD5 hash of string"},
+	"sha1":                    {Doc: "SHA-1 hash of string"},
+	"sha256":                  {Doc: "SHA-256 hash of string"},
+	"sha512":                  {Doc: "SHA-512 hash of string"},
+	"md5_file":                {Doc: "MD5 hash of file content"},
+	"sha1_file":               {Doc: "SHA-1 hash of file content"},
+	"sha256_file":             {Doc: "SHA-256 hash of file content"},
+	"sha512_file":             {Doc: "SHA-512 hash of file content"},
+	"uuid_v4":                 {Doc: "Generate random UUID v4"},
+	"uuid_v7":                 {Doc: "Generate time-based UUID v7"},
+	"http_get":                {Doc: "Make HTTP GET request", Defaults: map[string]string{"headers": "{}"}},
+	"http_request":            {Doc: "Make HTTP request with method", Defaults: map[string]string{"headers": "{}", "body": "null"}},
+	"github_api":              {Doc: "Call the GitHub REST API with token handling and pagination", Defaults: map[string]string{"options": "{}"}},
+	"github_release":          {Doc: "Get a GitHub release (latest, or {tag: ...}) with its assets", Defaults: map[string]string{"options": "{}"}},
+	"oidc_token":              {Doc: "Get an OIDC ID token for the audience from the environment"},
+	"dns_lookup":              {Doc: "DNS lookup for various record types", Defaults: map[string]string{"record_type": "'A'"}},
+	"net_port_listening":      {Doc: "Check if a port is listening (Linux only)"},
+	"regex_match":             {Doc: "Check if text matches pattern"},
+	"regex_find":              {Doc: "Find first match"},
+	"regex_find_all":          {Doc: "Find all matches"},
+	"regex_replace":           {Doc: "Replace all matches"},
+	"regex_split":             {Doc: "Split text by pattern"},
+	"regex_captures":          {Doc: "Extract groups of the first match as {match, groups, named}"},
+	"regex_find_all_submatch": {Doc: "Find all matches with their groups"},
+	"cue_validate":            {Doc: "Validate a value against a CUE schema"},
+	"msgpack_decode":          {Doc: "Decode base64-encoded MessagePack data"},
+	"cbor_decode":             {Doc: "Decode base64-encoded CBOR data"},
+	"jq":                      {Doc: "Execute jq query on JSON data"},
+	"exec":                    {Doc: "Execute command with arguments", Defaults: map[string]string{"args": "[]"}},
+	"exec_with_env":           {Doc: "Execute command with custom environment", Defaults: map[string]string{"args": "[]", "env_vars": "{}"}},
+	"file_content":            {Doc: "Read file content as string"},
+	"file_stat":               {Doc: "Get file metadata as object"},
+	"file_exists":             {Doc: "Check if file exists"},
+	"basename":                {Doc: "Get base name of a path"},
+	"dirname":                 {Doc: "Get directory part of a path"},
+	"extname":                 {Doc: "Get file extension (with dot)"},
+	"path_join":               {Doc: "Join path elements into a single path"},
+	"proto_decode":            {Doc: "Decode a base64-encoded protobuf message using a descriptor set file"},
+	"x509_certificate":        {Doc: "Parse X.509 certificate and return detailed information"},
+	"x509_private_key":        {Doc: "Parse private key and return metadata (without exposing the key)"},
+	"bigint_add":              {Doc: "Add two string-encoded integers exactly"},
+	"bigint_sub":              {Doc: "Subtract two string-encoded integers exactly"},
+	"bigint_mul":              {Doc: "Multiply two string-encoded integers exactly"},
+	"bigint_cmp":              {Doc: "Compare two string-encoded integers (-1, 0 or 1)"},
+	"decimal_add":             {Doc: "Add two string-encoded decimals exactly"},
+	"decimal_sub":             {Doc: "Subtract two string-encoded decimals exactly"},
+	"decimal_mul":             {Doc: "Multiply two string-encoded decimals exactly"},
+	"decimal_div":             {Doc: "Divide two string-encoded decimals, rounded to places digits"},
+	"decimal_round":           {Doc: "Round a string-encoded decimal to places digits (halves away from zero)"},
+	"decimal_cmp":             {Doc: "Compare two string-encoded decimals (-1, 0 or 1)"},
+	"fake":                    {Doc: "Generate fake test data of a kind (name, email, ipv4, sentence, ...); the same seed yields the same value", Defaults: map[string]string{"seed": "null"}},
+	"password_generate":       {Doc: "Generate a random password with at least one character of each class (options: length, classes, exclude)", Defaults: map[string]string{"options": "{}"}},
+	"totp_generate":           {Doc: "Generate a TOTP code (RFC 6238) from a base32 secret at timestamp (default now)", Defaults: map[string]string{"timestamp": "null"}},
+	"totp_verify":             {Doc: "Verify a TOTP code, allowing one period of clock drift", Defaults: map[string]string{"timestamp": "null"}},
+	"totp_uri":                {Doc: "Build an otpauth:// URI for authenticator apps and QR codes", Defaults: map[string]string{"issuer": "null"}},
+	"cron_validate":           {Doc: "Validate a cron expression, returning {valid, error, normalized, description}"},
+	"aws_cfn_output":          {Doc: "Get an output value of a CloudFormation stack"},
+	"aws_cfn_outputs":         {Doc: "Get all the outputs of a CloudFormation stack as an object"},
+	"aws_dynamodb_get":        {Doc: "Get an item of a DynamoDB table by key as a plain object (null if not found)"},
+	"redis_get":               {Doc: "Get the string value of a key from the Redis server of --redis-url (null if not found)"},
+	"redis_hgetall":           {Doc: "Get all the fields of a hash from the Redis server of --redis-url as an object"},
+	"sql_query":               {Doc: "Run a query on a PostgreSQL or MySQL database and return the rows as an array of objects", Defaults: map[string]string{"params": "[]"}},
+	"ldap_search":             {Doc: "Search an LDAP directory, binding with JSONNET_ARMED_LDAP_BIND_DN/PASSWORD, and return the entries as objects", Defaults: map[string]string{"attrs": "null"}},
+	"armed_version":           {Doc: "Get the running jsonnet-armed version"},
+	"armed_require":           {Doc: "Fail unless the running version satisfies the constraint"},
+}
+
+// jsonnetKeywords are reserved words that cannot be used as parameter names
+var jsonnetKeywords = map[string]bool{
+	"assert": true, "else": true, "error": true, "false": true, "for": true,
+	"function": true, "if": true, "import": true, "importstr": true, "importbin": true,
+	"in": true, "local": true, "null": true, "self": true, "super": true,
+	"tailstrict": true, "then": true, "true": true,
+}
+
+// isIdentifier reports whether s can be used as a Jsonnet parameter name
+func isIdentifier(s string) bool {
+	if s == "" || jsonnetKeywords[s] {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// libEntry returns the armed.libsonnet entry for f: a documented wrapper
+// function with default arguments, or a bare std.native binding when the
+// parameter names cannot be used as Jsonnet identifiers.
+func libEntry(f *jsonnet.NativeFunction) []string {
+	lf := armedLibFunctions[f.Name]
+	for _, p := range f.Params {
+		if !isIdentifier(string(p)) {
+			return []string{fmt.Sprintf("  %s: std.native('%s'),", f.Name, f.Name)}
+		}
+	}
+
+	params := make([]string, len(f.Params))
+	args := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		args[i] = string(p)
+		params[i] = string(p)
+		if d, ok := lf.Defaults[string(p)]; ok {
+			params[i] += "=" + d
+		}
+	}
+	signature := fmt.Sprintf("%s(%s)", f.Name, strings.Join(params, ", "))
+
+	var lines []string
+	if lf.Doc != "" {
+		lines = append(lines, fmt.Sprintf("  // %s: %s", signature, lf.Doc))
+	}
+	lines = append(lines, fmt.Sprintf("  %s: std.native('%s')(%s),", signature, f.Name, strings.Join(args, ", ")))
+	return lines
+}
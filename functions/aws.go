@@ -0,0 +1,91 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// awsRoleSessionName is the session name used for --aws-assume-role
+const awsRoleSessionName = "jsonnet-armed"
+
+// AWSOptions configures the AWS SDK shared by the aws_* native functions
+// and S3 outputs. Zero values fall back to the SDK defaults (environment
+// variables, shared config files and instance roles).
+type AWSOptions struct {
+	Profile       string
+	Region        string
+	AssumeRoleARN string
+}
+
+type awsOptionsKey struct{}
+
+// WithAWSOptions returns a context that carries the AWS options
+func WithAWSOptions(ctx context.Context, opts AWSOptions) context.Context {
+	return context.WithValue(ctx, awsOptionsKey{}, opts)
+}
+
+// awsOptionsFromContext returns the AWS options carried by ctx
+func awsOptionsFromContext(ctx context.Context) AWSOptions {
+	opts, _ := ctx.Value(awsOptionsKey{}).(AWSOptions)
+	return opts
+}
+
+// awsConfigs caches loaded configurations by options. The credentials of a
+// cached configuration are cached until they expire, so long-lived modes
+// (--watch, serve) do not call STS on every evaluation.
+var awsConfigs = struct {
+	mu      sync.Mutex
+	configs map[AWSOptions]aws.Config
+}{configs: make(map[AWSOptions]aws.Config)}
+
+// LoadAWSConfig returns the AWS configuration for the options carried by ctx.
+// Configurations are loaded on first use and reused afterwards.
+func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
+	opts := awsOptionsFromContext(ctx)
+	awsConfigs.mu.Lock()
+	defer awsConfigs.mu.Unlock()
+	if cfg, ok := awsConfigs.configs[opts]; ok {
+		return cfg, nil
+	}
+	cfg, err := loadAWSConfig(ctx, opts)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	awsConfigs.configs[opts] = cfg
+	return cfg, nil
+}
+
+// ResetAWSConfigs discards the cached configurations and credentials, so
+// that the next use loads them again (e.g. on SIGHUP in server mode)
+func ResetAWSConfigs() {
+	awsConfigs.mu.Lock()
+	defer awsConfigs.mu.Unlock()
+	clear(awsConfigs.configs)
+}
+
+func loadAWSConfig(ctx context.Context, opts AWSOptions) (aws.Config, error) {
+	var loadOpts []func(*config.LoadOptions) error
+	if opts.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(opts.Profile))
+	}
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if opts.AssumeRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = awsRoleSessionName
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,77 @@
+package functions_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestLoadAWSConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFile, []byte("[profile armed-test]\nregion = ap-northeast-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	tests := []struct {
+		name   string
+		opts   functions.AWSOptions
+		region string
+	}{
+		{name: "profile", opts: functions.AWSOptions{Profile: "armed-test"}, region: "ap-northeast-1"},
+		{name: "region overrides profile", opts: functions.AWSOptions{Profile: "armed-test", Region: "us-west-2"}, region: "us-west-2"},
+		{name: "region only", opts: functions.AWSOptions{Region: "eu-west-1"}, region: "eu-west-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := functions.WithAWSOptions(t.Context(), tt.opts)
+			cfg, err := functions.LoadAWSConfig(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Region != tt.region {
+				t.Errorf("expected region %s, got %s", tt.region, cfg.Region)
+			}
+		})
+	}
+
+	t.Run("unknown profile", func(t *testing.T) {
+		ctx := functions.WithAWSOptions(t.Context(), functions.AWSOptions{Profile: "no-such-profile"})
+		if _, err := functions.LoadAWSConfig(ctx); err == nil {
+			t.Error("expected error for unknown profile")
+		}
+	})
+
+	t.Run("assume role", func(t *testing.T) {
+		opts := functions.AWSOptions{Region: "us-east-1", AssumeRoleARN: "arn:aws:iam::123456789012:role/armed"}
+		ctx := functions.WithAWSOptions(t.Context(), opts)
+		cfg, err := functions.LoadAWSConfig(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+		if !ok {
+			t.Fatalf("expected *aws.CredentialsCache, got %T", cfg.Credentials)
+		}
+		if !cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}) {
+			t.Error("expected the assume role provider")
+		}
+		// The configuration and its credentials cache are reused
+		again, err := functions.LoadAWSConfig(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if again.Credentials != cfg.Credentials {
+			t.Error("expected the cached credentials to be reused")
+		}
+	})
+}
@@ -2,7 +2,6 @@ package functions
 
 import (
 	"encoding/base64"
-	"fmt"
 
 	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/ast"
@@ -12,9 +11,9 @@ var Base64Functions = map[string]*jsonnet.NativeFunction{
 	"base64": {
 		Params: []ast.Identifier{"data"},
 		Func: func(args []any) (any, error) {
-			data, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("base64: data must be a string")
+			data, err := newArgs("base64", args).String(0, "data")
+			if err != nil {
+				return nil, err
 			}
 			return base64.StdEncoding.EncodeToString([]byte(data)), nil
 		},
@@ -22,9 +21,9 @@ var Base64Functions = map[string]*jsonnet.NativeFunction{
 	"base64url": {
 		Params: []ast.Identifier{"data"},
 		Func: func(args []any) (any, error) {
-			data, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("base64url: data must be a string")
+			data, err := newArgs("base64url", args).String(0, "data")
+			if err != nil {
+				return nil, err
 			}
 			return base64.URLEncoding.EncodeToString([]byte(data)), nil
 		},
@@ -0,0 +1,220 @@
+package functions
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// Jsonnet numbers are float64, which cannot represent integers beyond 2^53
+// or decimal fractions exactly. These functions take and return numbers
+// encoded as strings, so that 64-bit IDs and monetary values pass through
+// templates exactly.
+
+// bigintArg returns the i-th argument, a string-encoded integer
+func bigintArg(a nativeArgs, i int, param string) (*big.Int, error) {
+	s, err := a.String(i, param)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return nil, a.errorf(i, param, "must be an integer string, got %q", s)
+	}
+	return n, nil
+}
+
+// bigintFunction creates a function of two integers returning an integer
+func bigintFunction(name string, op func(z, x, y *big.Int) *big.Int) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		a := newArgs(name, args)
+		x, err := bigintArg(a, 0, "a")
+		if err != nil {
+			return nil, err
+		}
+		y, err := bigintArg(a, 1, "b")
+		if err != nil {
+			return nil, err
+		}
+		return op(new(big.Int), x, y).String(), nil
+	}
+}
+
+// decimal is an exact decimal number with the number of digits after the point
+type decimal struct {
+	value *big.Rat
+	scale int
+}
+
+func (d decimal) String() string {
+	return d.value.FloatString(d.scale)
+}
+
+// parseDecimal parses a decimal string such as "-12.340". Exponents are not
+// accepted, so that the scale of the result is the one written.
+func parseDecimal(s string) (decimal, bool) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return decimal{}, false
+	}
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return decimal{}, false
+		}
+	}
+	v, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return decimal{}, false
+	}
+	return decimal{value: v, scale: len(fracPart)}, true
+}
+
+// decimalArg returns the i-th argument, a string-encoded decimal number
+func decimalArg(a nativeArgs, i int, param string) (decimal, error) {
+	s, err := a.String(i, param)
+	if err != nil {
+		return decimal{}, err
+	}
+	d, ok := parseDecimal(s)
+	if !ok {
+		return decimal{}, a.errorf(i, param, "must be a decimal string, got %q", s)
+	}
+	return d, nil
+}
+
+// placesArg returns the i-th argument, a number of digits after the point
+func placesArg(a nativeArgs, i int, param string) (int, error) {
+	n, err := a.Number(i, param)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n != float64(int(n)) {
+		return 0, a.errorf(i, param, "must be a non-negative integer, got %v", n)
+	}
+	return int(n), nil
+}
+
+// decimalFunction creates a function of two decimals returning a decimal.
+// scale returns the number of digits after the point of the result.
+func decimalFunction(name string, op func(z, x, y *big.Rat) *big.Rat, scale func(x, y int) int) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		a := newArgs(name, args)
+		x, err := decimalArg(a, 0, "a")
+		if err != nil {
+			return nil, err
+		}
+		y, err := decimalArg(a, 1, "b")
+		if err != nil {
+			return nil, err
+		}
+		return decimal{value: op(new(big.Rat), x.value, y.value), scale: scale(x.scale, y.scale)}.String(), nil
+	}
+}
+
+// maxScale is the scale of a sum or difference
+func maxScale(x, y int) int {
+	return max(x, y)
+}
+
+var BigNumFunctions = map[string]*jsonnet.NativeFunction{
+	"bigint_add": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   bigintFunction("bigint_add", (*big.Int).Add),
+	},
+	"bigint_sub": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   bigintFunction("bigint_sub", (*big.Int).Sub),
+	},
+	"bigint_mul": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   bigintFunction("bigint_mul", (*big.Int).Mul),
+	},
+	"bigint_cmp": {
+		Params: []ast.Identifier{"a", "b"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("bigint_cmp", args)
+			x, err := bigintArg(a, 0, "a")
+			if err != nil {
+				return nil, err
+			}
+			y, err := bigintArg(a, 1, "b")
+			if err != nil {
+				return nil, err
+			}
+			return x.Cmp(y), nil
+		},
+	},
+	"decimal_add": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   decimalFunction("decimal_add", (*big.Rat).Add, maxScale),
+	},
+	"decimal_sub": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   decimalFunction("decimal_sub", (*big.Rat).Sub, maxScale),
+	},
+	"decimal_mul": {
+		Params: []ast.Identifier{"a", "b"},
+		Func:   decimalFunction("decimal_mul", (*big.Rat).Mul, func(x, y int) int { return x + y }),
+	},
+	"decimal_div": {
+		Params: []ast.Identifier{"a", "b", "places"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("decimal_div", args)
+			x, err := decimalArg(a, 0, "a")
+			if err != nil {
+				return nil, err
+			}
+			y, err := decimalArg(a, 1, "b")
+			if err != nil {
+				return nil, err
+			}
+			places, err := placesArg(a, 2, "places")
+			if err != nil {
+				return nil, err
+			}
+			if y.value.Sign() == 0 {
+				return nil, fmt.Errorf("decimal_div: division by zero")
+			}
+			return decimal{value: new(big.Rat).Quo(x.value, y.value), scale: places}.String(), nil
+		},
+	},
+	"decimal_round": {
+		Params: []ast.Identifier{"value", "places"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("decimal_round", args)
+			x, err := decimalArg(a, 0, "value")
+			if err != nil {
+				return nil, err
+			}
+			places, err := placesArg(a, 1, "places")
+			if err != nil {
+				return nil, err
+			}
+			return decimal{value: x.value, scale: places}.String(), nil
+		},
+	},
+	"decimal_cmp": {
+		Params: []ast.Identifier{"a", "b"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("decimal_cmp", args)
+			x, err := decimalArg(a, 0, "a")
+			if err != nil {
+				return nil, err
+			}
+			y, err := decimalArg(a, 1, "b")
+			if err != nil {
+				return nil, err
+			}
+			return x.value.Cmp(y.value), nil
+		},
+	},
+}
+
+func init() {
+	initializeFunctionMap(BigNumFunctions)
+}
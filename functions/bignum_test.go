@@ -0,0 +1,75 @@
+package functions_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBigNumFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		args     []any
+		expected any
+		wantErr  bool
+	}{
+		{name: "add beyond float64 precision", function: "bigint_add", args: []any{"9007199254740993", "1"}, expected: "9007199254740994"},
+		{name: "add 64-bit IDs", function: "bigint_add", args: []any{"18446744073709551615", "1"}, expected: "18446744073709551616"},
+		{name: "add negative", function: "bigint_add", args: []any{"-5", "3"}, expected: "-2"},
+		{name: "sub", function: "bigint_sub", args: []any{"1234567890123456789", "1234567890123456788"}, expected: "1"},
+		{name: "mul", function: "bigint_mul", args: []any{"4294967296", "4294967296"}, expected: "18446744073709551616"},
+		{name: "cmp less", function: "bigint_cmp", args: []any{"9007199254740992", "9007199254740993"}, expected: -1},
+		{name: "cmp equal", function: "bigint_cmp", args: []any{"42", " 42 "}, expected: 0},
+		{name: "cmp greater", function: "bigint_cmp", args: []any{"10", "-10"}, expected: 1},
+		{name: "bigint rejects decimals", function: "bigint_add", args: []any{"1.5", "1"}, wantErr: true},
+		{name: "bigint rejects numbers", function: "bigint_add", args: []any{1.0, "1"}, wantErr: true},
+		{name: "bigint missing argument", function: "bigint_mul", args: []any{"1"}, wantErr: true},
+
+		{name: "decimal add", function: "decimal_add", args: []any{"0.1", "0.2"}, expected: "0.3"},
+		{name: "decimal add keeps scale", function: "decimal_add", args: []any{"1.10", "2.205"}, expected: "3.305"},
+		{name: "decimal add integer", function: "decimal_add", args: []any{"19.99", "1"}, expected: "20.99"},
+		{name: "decimal sub", function: "decimal_sub", args: []any{"100.00", "0.01"}, expected: "99.99"},
+		{name: "decimal sub negative", function: "decimal_sub", args: []any{"0.5", "1.25"}, expected: "-0.75"},
+		{name: "decimal mul", function: "decimal_mul", args: []any{"19.99", "3"}, expected: "59.97"},
+		{name: "decimal mul scale", function: "decimal_mul", args: []any{"1.5", "2.25"}, expected: "3.375"},
+		{name: "decimal div", function: "decimal_div", args: []any{"10", "3", 4.0}, expected: "3.3333"},
+		{name: "decimal div rounds", function: "decimal_div", args: []any{"2", "3", 2.0}, expected: "0.67"},
+		{name: "decimal div by zero", function: "decimal_div", args: []any{"1", "0.00", 2.0}, wantErr: true},
+		{name: "decimal div negative places", function: "decimal_div", args: []any{"1", "3", -1.0}, wantErr: true},
+		{name: "decimal round half away from zero", function: "decimal_round", args: []any{"2.345", 2.0}, expected: "2.35"},
+		{name: "decimal round negative", function: "decimal_round", args: []any{"-2.345", 2.0}, expected: "-2.35"},
+		{name: "decimal round pads", function: "decimal_round", args: []any{"3", 2.0}, expected: "3.00"},
+		{name: "decimal round to integer", function: "decimal_round", args: []any{"0.5", 0.0}, expected: "1"},
+		{name: "decimal round fractional places", function: "decimal_round", args: []any{"0.5", 1.5}, wantErr: true},
+		{name: "decimal cmp", function: "decimal_cmp", args: []any{"1.10", "1.1"}, expected: 0},
+		{name: "decimal cmp less", function: "decimal_cmp", args: []any{"-0.01", "0"}, expected: -1},
+		{name: "decimal leading point", function: "decimal_add", args: []any{".5", "+1.5"}, expected: "2.0"},
+		{name: "decimal rejects exponents", function: "decimal_add", args: []any{"1e3", "1"}, wantErr: true},
+		{name: "decimal rejects garbage", function: "decimal_add", args: []any{"1.2.3", "1"}, wantErr: true},
+		{name: "decimal rejects empty", function: "decimal_add", args: []any{"-", "1"}, wantErr: true},
+		{name: "decimal rejects numbers", function: "decimal_add", args: []any{0.1, "1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := getBigNumFunction(tt.function)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := fn(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// decodeBinaryFormat decodes base64-encoded data with unmarshal and converts
+// the result to JSON-compatible types
+func decodeBinaryFormat(name string, args []any, unmarshal func([]byte, any) error) (any, error) {
+	data, err := newArgs(name, args).String(0, "data_base64")
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decode base64: %w", name, err)
+	}
+	var v any
+	if err := unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: failed to decode data: %w", name, err)
+	}
+	result, err := toJSONCompatible(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return result, nil
+}
+
+// toJSONCompatible converts decoded MessagePack/CBOR values to the types
+// Jsonnet accepts. Numbers become float64, byte strings base64 strings,
+// timestamps RFC3339 strings, and non-string map keys their text form.
+func toJSONCompatible(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, string, float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case big.Int:
+		f, _ := new(big.Float).SetInt(&v).Float64()
+		return f, nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []any:
+		result := make([]any, len(v))
+		for i, e := range v {
+			c, err := toJSONCompatible(e)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = c
+		}
+		return result, nil
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, e := range v {
+			c, err := toJSONCompatible(e)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = c
+		}
+		return result, nil
+	case map[any]any:
+		result := make(map[string]any, len(v))
+		for k, e := range v {
+			c, err := toJSONCompatible(e)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(k)] = c
+		}
+		return result, nil
+	case cbor.Tag:
+		return toJSONCompatible(v.Content)
+	default:
+		return nil, fmt.Errorf("unsupported value of type %T", v)
+	}
+}
+
+// msgpackUnmarshal is msgpack.Unmarshal accepting maps with non-string keys
+func msgpackUnmarshal(b []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(b))
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (any, error) {
+		return d.DecodeUntypedMap()
+	})
+	return dec.Decode(v)
+}
+
+var BinaryFormatFunctions = map[string]*jsonnet.NativeFunction{
+	"msgpack_decode": {
+		Params: []ast.Identifier{"data_base64"},
+		Func: func(args []any) (any, error) {
+			return decodeBinaryFormat("msgpack_decode", args, msgpackUnmarshal)
+		},
+	},
+	"cbor_decode": {
+		Params: []ast.Identifier{"data_base64"},
+		Func: func(args []any) (any, error) {
+			return decodeBinaryFormat("cbor_decode", args, cbor.Unmarshal)
+		},
+	},
+}
+
+func init() {
+	initializeFunctionMap(BinaryFormatFunctions)
+}
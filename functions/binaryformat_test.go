@@ -0,0 +1,175 @@
+package functions_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func encodeBase64(t *testing.T, marshal func(any) ([]byte, error), v any) string {
+	t.Helper()
+	b, err := marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestMsgpackDecodeFunction(t *testing.T) {
+	msgpackDecodeFunc, err := getBinaryFormatFunction("msgpack_decode")
+	if err != nil {
+		t.Fatalf("failed to get msgpack_decode function: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "object",
+			args: []any{encodeBase64(t, msgpack.Marshal, map[string]any{
+				"name": "device", "port": 8080, "ratio": float32(0.5), "tags": []string{"a", "b"}, "on": true, "none": nil,
+			})},
+			expected: map[string]any{
+				"name": "device", "port": float64(8080), "ratio": 0.5, "tags": []any{"a", "b"}, "on": true, "none": nil,
+			},
+		},
+		{
+			name:     "non-string keys",
+			args:     []any{encodeBase64(t, msgpack.Marshal, map[int]string{1: "one"})},
+			expected: map[string]any{"1": "one"},
+		},
+		{
+			name:     "binary as base64",
+			args:     []any{encodeBase64(t, msgpack.Marshal, []byte{0, 1, 2})},
+			expected: "AAEC",
+		},
+		{
+			name:     "timestamp",
+			args:     []any{encodeBase64(t, msgpack.Marshal, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))},
+			expected: "2026-01-02T03:04:05Z",
+		},
+		{
+			name:     "negative integer",
+			args:     []any{encodeBase64(t, msgpack.Marshal, -42)},
+			expected: float64(-42),
+		},
+		{
+			name:        "invalid base64",
+			args:        []any{"!!!"},
+			expectError: true,
+		},
+		{
+			name:        "truncated data",
+			args:        []any{base64.StdEncoding.EncodeToString([]byte{0x92, 0x01})},
+			expectError: true,
+		},
+		{
+			name:        "non-string argument",
+			args:        []any{float64(1)},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := msgpackDecodeFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCBORDecodeFunction(t *testing.T) {
+	cborDecodeFunc, err := getBinaryFormatFunction("cbor_decode")
+	if err != nil {
+		t.Fatalf("failed to get cbor_decode function: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "object",
+			args: []any{encodeBase64(t, cbor.Marshal, map[string]any{
+				"name": "device", "port": 8080, "ratio": 0.5, "tags": []string{"a", "b"}, "on": true, "none": nil,
+			})},
+			expected: map[string]any{
+				"name": "device", "port": float64(8080), "ratio": 0.5, "tags": []any{"a", "b"}, "on": true, "none": nil,
+			},
+		},
+		{
+			name:     "non-string keys",
+			args:     []any{encodeBase64(t, cbor.Marshal, map[int]string{1: "one"})},
+			expected: map[string]any{"1": "one"},
+		},
+		{
+			name:     "byte string as base64",
+			args:     []any{encodeBase64(t, cbor.Marshal, []byte{0, 1, 2})},
+			expected: "AAEC",
+		},
+		{
+			name:     "tagged value",
+			args:     []any{encodeBase64(t, cbor.Marshal, cbor.Tag{Number: 32, Content: "https://example.com"})},
+			expected: "https://example.com",
+		},
+		{
+			name:     "negative integer",
+			args:     []any{encodeBase64(t, cbor.Marshal, -42)},
+			expected: float64(-42),
+		},
+		{
+			name:        "invalid base64",
+			args:        []any{"!!!"},
+			expectError: true,
+		},
+		{
+			name:        "truncated data",
+			args:        []any{base64.StdEncoding.EncodeToString([]byte{0x82, 0x01})},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := cborDecodeFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// cfnStackOutputs looks up the outputs of CloudFormation stacks. Outputs are
+// cached by stack name, so a stack is described once per evaluation.
+type cfnStackOutputs struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	stacks map[string]map[string]any
+}
+
+func (c *cfnStackOutputs) get(fn, stackName string) (map[string]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if outputs, ok := c.stacks[stackName]; ok {
+		return outputs, nil
+	}
+	cfg, err := LoadAWSConfig(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	res, err := cloudformation.NewFromConfig(cfg).DescribeStacks(c.ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to describe stack %s: %w", fn, stackName, err)
+	}
+	if len(res.Stacks) == 0 {
+		return nil, fmt.Errorf("%s: stack %s not found", fn, stackName)
+	}
+	outputs := make(map[string]any, len(res.Stacks[0].Outputs))
+	for _, o := range res.Stacks[0].Outputs {
+		outputs[aws.ToString(o.OutputKey)] = aws.ToString(o.OutputValue)
+	}
+	c.stacks[stackName] = outputs
+	return outputs, nil
+}
+
+func GenerateCloudFormationFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	stacks := &cfnStackOutputs{ctx: ctx, stacks: make(map[string]map[string]any)}
+
+	funcs := map[string]*jsonnet.NativeFunction{
+		"aws_cfn_output": {
+			Params: []ast.Identifier{"stack_name", "output_key"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("aws_cfn_output", args)
+				stackName, err := a.String(0, "stack_name")
+				if err != nil {
+					return nil, err
+				}
+				key, err := a.String(1, "output_key")
+				if err != nil {
+					return nil, err
+				}
+				outputs, err := stacks.get("aws_cfn_output", stackName)
+				if err != nil {
+					return nil, err
+				}
+				value, ok := outputs[key]
+				if !ok {
+					return nil, fmt.Errorf("aws_cfn_output: stack %s has no output %s", stackName, key)
+				}
+				return value, nil
+			},
+		},
+		"aws_cfn_outputs": {
+			Params: []ast.Identifier{"stack_name"},
+			Func: func(args []any) (any, error) {
+				stackName, err := newArgs("aws_cfn_outputs", args).String(0, "stack_name")
+				if err != nil {
+					return nil, err
+				}
+				return stacks.get("aws_cfn_outputs", stackName)
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
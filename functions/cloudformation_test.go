@@ -0,0 +1,95 @@
+package functions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCloudFormationFunctions(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		stackName := r.Form.Get("StackName")
+		mu.Lock()
+		calls[stackName]++
+		mu.Unlock()
+		if r.Form.Get("Action") != "DescribeStacks" || stackName != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>ValidationError</Code><Message>Stack with id %s does not exist</Message></Error></ErrorResponse>`, stackName)
+			return
+		}
+		fmt.Fprint(w, `<DescribeStacksResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+			<DescribeStacksResult><Stacks><member>
+				<StackName>app</StackName>
+				<StackStatus>CREATE_COMPLETE</StackStatus>
+				<CreationTime>2024-01-01T00:00:00Z</CreationTime>
+				<Outputs>
+					<member><OutputKey>BucketName</OutputKey><OutputValue>app-bucket</OutputValue></member>
+					<member><OutputKey>QueueUrl</OutputKey><OutputValue>https://sqs.us-east-1.amazonaws.com/123456789012/app</OutputValue></member>
+				</Outputs>
+			</member></Stacks></DescribeStacksResult>
+		</DescribeStacksResponse>`)
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ENDPOINT_URL_CLOUDFORMATION", ts.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	functions.ResetAWSConfigs()
+	t.Cleanup(functions.ResetAWSConfigs)
+
+	funcs := functions.GenerateCloudFormationFunctions(t.Context())
+	output := funcs["aws_cfn_output"].Func
+	outputs := funcs["aws_cfn_outputs"].Func
+
+	v, err := output([]any{"app", "BucketName"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "app-bucket" {
+		t.Errorf("expected app-bucket, got %v", v)
+	}
+	all, err := outputs([]any{"app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"BucketName": "app-bucket",
+		"QueueUrl":   "https://sqs.us-east-1.amazonaws.com/123456789012/app",
+	}
+	if diff := cmp.Diff(expected, all); diff != "" {
+		t.Errorf("outputs mismatch (-want +got):\n%s", diff)
+	}
+	// The stack is described once per evaluation
+	if calls["app"] != 1 {
+		t.Errorf("expected 1 call, got %d", calls["app"])
+	}
+
+	errorTests := []struct {
+		name string
+		fn   func([]any) (any, error)
+		args []any
+		want string
+	}{
+		{name: "unknown output", fn: output, args: []any{"app", "Missing"}, want: "aws_cfn_output: stack app has no output Missing"},
+		{name: "unknown stack", fn: outputs, args: []any{"missing"}, want: "Stack with id missing does not exist"},
+		{name: "non-string stack name", fn: output, args: []any{1.0, "BucketName"}, want: "argument #1 (stack_name) must be a string"},
+		{name: "missing output key", fn: output, args: []any{"app"}, want: "missing argument #2 (output_key)"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fn(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/robfig/cron/v3"
+)
+
+// cronDescriptors are the expressions of the predefined schedules
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = []string{"", "January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+var cronWeekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// cronField describes a field of a cron expression
+type cronField struct {
+	singular, plural string
+	// names are the names accepted in expressions, indexed by value
+	names []string
+	// display returns the human-readable form of a value
+	display func(n int) string
+}
+
+var cronFields = []cronField{
+	{singular: "minute", plural: "minutes", display: strconv.Itoa},
+	{singular: "hour", plural: "hours", display: strconv.Itoa},
+	{singular: "day-of-month", plural: "days-of-month", display: strconv.Itoa},
+	{
+		singular: "month", plural: "months",
+		names:   []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"},
+		display: func(n int) string { return cronMonthNames[n] },
+	},
+	{
+		singular: "day-of-week", plural: "days-of-week",
+		names:   []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"},
+		display: func(n int) string { return cronWeekdayNames[n] },
+	},
+}
+
+// cronValidateFunction checks a cron expression in the syntax of the cron
+// command, returning its normalized form and a description
+func cronValidateFunction(args []any) (any, error) {
+	a := newArgs("cron_validate", args)
+	expr, err := a.String(0, "expr")
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(err error) map[string]any {
+		return map[string]any{"valid": false, "error": err.Error(), "normalized": nil, "description": nil}
+	}
+	if _, err := cron.ParseStandard(expr); err != nil {
+		return invalid(err), nil
+	}
+
+	spec := strings.Join(strings.Fields(expr), " ")
+	var zone string
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		zone, spec, _ = strings.Cut(spec, " ")
+		_, zone, _ = strings.Cut(zone, "=")
+	}
+
+	var normalized, description string
+	switch {
+	case strings.HasPrefix(spec, "@every "):
+		d, err := time.ParseDuration(strings.TrimPrefix(spec, "@every "))
+		if err != nil {
+			return invalid(err), nil
+		}
+		normalized = "@every " + d.String()
+		description = "Every " + d.String()
+	default:
+		if e, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+			spec = e
+		}
+		fields := strings.Fields(spec)
+		for i, f := range fields {
+			fields[i] = normalizeCronField(f, cronFields[i])
+		}
+		normalized = strings.Join(fields, " ")
+		description = describeCron(fields)
+	}
+	if zone != "" {
+		normalized = "CRON_TZ=" + zone + " " + normalized
+		description += " (" + zone + ")"
+	}
+	return map[string]any{
+		"valid":       true,
+		"error":       nil,
+		"normalized":  normalized,
+		"description": description + ".",
+	}, nil
+}
+
+// normalizeCronField replaces names by numbers and "?" by "*"
+func normalizeCronField(s string, field cronField) string {
+	if s == "?" {
+		return "*"
+	}
+	s = strings.ToLower(s)
+	for n, name := range field.names {
+		if name != "" {
+			s = strings.ReplaceAll(s, name, strconv.Itoa(n))
+		}
+	}
+	return s
+}
+
+// describeCron returns a description of the normalized fields of an expression
+func describeCron(fields []string) string {
+	minute, hour := fields[0], fields[1]
+	var parts []string
+	if isCronNumber(minute) && isCronNumber(hour) {
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		parts = append(parts, fmt.Sprintf("At %02d:%02d", h, m))
+	} else {
+		if minute == "*" {
+			parts = append(parts, "At every minute")
+		} else {
+			parts = append(parts, "At "+describeCronField(minute, cronFields[0]))
+		}
+		if hour != "*" {
+			parts = append(parts, "past "+describeCronField(hour, cronFields[1]))
+		}
+	}
+	dom, month, dow := fields[2], fields[3], fields[4]
+	if dom != "*" {
+		parts = append(parts, "on "+describeCronField(dom, cronFields[2]))
+	}
+	if dow != "*" {
+		// Both days are matched when both are restricted
+		prefix := "on "
+		if dom != "*" {
+			prefix = "and on "
+		}
+		parts = append(parts, prefix+describeCronField(dow, cronFields[4]))
+	}
+	if month != "*" {
+		parts = append(parts, "in "+describeCronField(month, cronFields[3]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeCronField describes a field such as "1-5", "*/15" or "0,30"
+func describeCronField(s string, field cronField) string {
+	items := strings.Split(s, ",")
+	if len(items) == 1 {
+		return describeCronItem(s, field)
+	}
+	values := make([]string, len(items))
+	simple := true
+	for i, item := range items {
+		if !isCronNumber(item) {
+			simple = false
+		}
+		values[i] = describeCronItem(item, field)
+	}
+	if simple {
+		// "minutes 0, 15 and 30" rather than "minute 0, minute 15 and minute 30"
+		for i, item := range items {
+			values[i] = displayCronValue(item, field)
+		}
+		return withUnit(field, joinCronList(values), true)
+	}
+	return joinCronList(values)
+}
+
+func describeCronItem(s string, field cronField) string {
+	rng, step, hasStep := strings.Cut(s, "/")
+	var from, to string
+	switch {
+	case rng == "*":
+	case strings.Contains(rng, "-"):
+		from, to, _ = strings.Cut(rng, "-")
+	default:
+		from = rng
+	}
+	if !hasStep {
+		if to != "" {
+			return withUnit(field, displayCronValue(from, field)+" through "+displayCronValue(to, field), true)
+		}
+		return withUnit(field, displayCronValue(from, field), false)
+	}
+	desc := "every " + ordinal(step) + " " + field.singular
+	if step == "1" {
+		desc = "every " + field.singular
+	}
+	switch {
+	case to != "":
+		desc += " from " + displayCronValue(from, field) + " through " + displayCronValue(to, field)
+	case from != "":
+		desc += " from " + displayCronValue(from, field)
+	}
+	return desc
+}
+
+// withUnit prefixes numeric values with the name of the field
+func withUnit(field cronField, s string, plural bool) string {
+	if field.names != nil {
+		return s
+	}
+	if plural {
+		return field.plural + " " + s
+	}
+	return field.singular + " " + s
+}
+
+func displayCronValue(s string, field cronField) string {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return s
+	}
+	return field.display(n)
+}
+
+func isCronNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func joinCronList(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " and " + values[len(values)-1]
+}
+
+func ordinal(s string) string {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return s
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return s + suffix
+}
+
+var CronFunctions = map[string]*jsonnet.NativeFunction{
+	"cron_validate": {
+		Params: []ast.Identifier{"expr"},
+		Func:   cronValidateFunction,
+	},
+}
+
+func init() {
+	initializeFunctionMap(CronFunctions)
+}
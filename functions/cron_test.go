@@ -0,0 +1,82 @@
+package functions_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCronValidate(t *testing.T) {
+	validate, err := getCronFunction("cron_validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		expr        string
+		normalized  string
+		description string
+	}{
+		{name: "every 5 minutes", expr: "*/5 * * * *", normalized: "*/5 * * * *", description: "At every 5th minute."},
+		{name: "descriptor", expr: "@hourly", normalized: "0 * * * *", description: "At minute 0."},
+		{name: "weekdays with names", expr: "30 9 * * mon-FRI", normalized: "30 9 * * 1-5", description: "At 09:30 on Monday through Friday."},
+		{name: "every 2nd hour", expr: "0 */2 * * *", normalized: "0 */2 * * *", description: "At minute 0 past every 2nd hour."},
+		{name: "list of days", expr: "0 0 1,15 * *", normalized: "0 0 1,15 * *", description: "At 00:00 on days-of-month 1 and 15."},
+		{name: "month names", expr: "0 0 * JAN,JUL SUN", normalized: "0 0 * 1,7 0", description: "At 00:00 on Sunday in January and July."},
+		{name: "both days", expr: "0 0 13 * 5", normalized: "0 0 13 * 5", description: "At 00:00 on day-of-month 13 and on Friday."},
+		{name: "ranges", expr: "0,30  8-18 * * 1-5", normalized: "0,30 8-18 * * 1-5", description: "At minutes 0 and 30 past hours 8 through 18 on Monday through Friday."},
+		{name: "question mark", expr: "5/15 * ? * *", normalized: "5/15 * * * *", description: "At every 15th minute from 5."},
+		{name: "time zone", expr: "TZ=Asia/Tokyo 0 9 * * *", normalized: "CRON_TZ=Asia/Tokyo 0 9 * * *", description: "At 09:00 (Asia/Tokyo)."},
+		{name: "interval", expr: "@every 90s", normalized: "@every 1m30s", description: "Every 1m30s."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validate([]any{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := map[string]any{
+				"valid":       true,
+				"error":       nil,
+				"normalized":  tt.normalized,
+				"description": tt.description,
+			}
+			if diff := cmp.Diff(expected, result); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	invalid := []struct {
+		expr  string
+		error string
+	}{
+		{expr: "* * *", error: "expected exactly 5 fields"},
+		{expr: "61 * * * *", error: "above maximum"},
+		{expr: "0 0 * * 7", error: "above maximum"},
+		{expr: "0 0 * * MOON", error: "failed to parse"},
+		{expr: "CRON_TZ=Nowhere/City 0 0 * * *", error: "provided bad location"},
+		{expr: "@every 5 minutes", error: "failed to parse"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := validate([]any{tt.expr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			m := result.(map[string]any)
+			if m["valid"] != false || m["normalized"] != nil || m["description"] != nil {
+				t.Errorf("expected invalid result, got %v", m)
+			}
+			if msg, _ := m["error"].(string); !strings.Contains(msg, tt.error) {
+				t.Errorf("error %q does not contain %q", msg, tt.error)
+			}
+		})
+	}
+
+	if _, err := validate([]any{5.0}); err == nil {
+		t.Error("expected error for non-string expr")
+	}
+}
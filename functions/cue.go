@@ -0,0 +1,69 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// cueValidateFunction validates a value against a CUE schema and returns
+// {valid, errors}; each error has the path of the invalid field and a message.
+func cueValidateFunction(args []any) (any, error) {
+	a := newArgs("cue_validate", args)
+	schema, err := a.String(0, "schema")
+	if err != nil {
+		return nil, err
+	}
+	value, err := a.Value(1, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	// cue.Context is not safe for concurrent use, so each call has its own
+	cctx := cuecontext.New()
+	s := cctx.CompileString(schema, cue.Filename("schema.cue"))
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("cue_validate: invalid schema: %w", err)
+	}
+	// Jsonnet numbers are float64; going through JSON lets CUE see 80.0 as int
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("cue_validate: failed to encode value: %w", err)
+	}
+	v := cctx.CompileBytes(b, cue.Filename("value.json"))
+	if err := v.Err(); err != nil {
+		return nil, fmt.Errorf("cue_validate: failed to encode value: %w", err)
+	}
+
+	errs := []any{}
+	if err := s.Unify(v).Validate(cue.Concrete(true), cue.All()); err != nil {
+		for _, e := range cueerrors.Errors(err) {
+			format, fargs := e.Msg()
+			errs = append(errs, map[string]any{
+				"path":    strings.Join(e.Path(), "."),
+				"message": fmt.Sprintf(format, fargs...),
+			})
+		}
+	}
+	return map[string]any{
+		"valid":  len(errs) == 0,
+		"errors": errs,
+	}, nil
+}
+
+var CUEFunctions = map[string]*jsonnet.NativeFunction{
+	"cue_validate": {
+		Params: []ast.Identifier{"schema", "value"},
+		Func:   cueValidateFunction,
+	},
+}
+
+func init() {
+	initializeFunctionMap(CUEFunctions)
+}
@@ -0,0 +1,122 @@
+package functions_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCUEValidateFunction(t *testing.T) {
+	cueValidateFunc, err := getCUEFunction("cue_validate")
+	if err != nil {
+		t.Fatalf("failed to get cue_validate function: %v", err)
+	}
+
+	const configSchema = `
+#Config: {
+	port: int & >0 & <65536
+	name: string
+	tags?: [...string]
+}
+#Config
+`
+	valid := map[string]any{"valid": true, "errors": []any{}}
+	invalid := func(errs ...map[string]any) any {
+		e := make([]any, len(errs))
+		for i, err := range errs {
+			e[i] = err
+		}
+		return map[string]any{"valid": false, "errors": e}
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name:     "valid value",
+			args:     []any{configSchema, map[string]any{"port": float64(8080), "name": "app"}},
+			expected: valid,
+		},
+		{
+			name: "valid value with optional field",
+			args: []any{configSchema, map[string]any{
+				"port": float64(80), "name": "app", "tags": []any{"a", "b"},
+			}},
+			expected: valid,
+		},
+		{
+			name: "out of bound",
+			args: []any{configSchema, map[string]any{"port": float64(0), "name": "app"}},
+			expected: invalid(map[string]any{
+				"path": "port", "message": "invalid value 0 (out of bound >0)",
+			}),
+		},
+		{
+			name: "not an integer",
+			args: []any{configSchema, map[string]any{"port": 1.5, "name": "app"}},
+			expected: invalid(map[string]any{
+				"path": "port", "message": "conflicting values 1.5 and int (mismatched types float and int)",
+			}),
+		},
+		{
+			name: "missing field",
+			args: []any{configSchema, map[string]any{"port": float64(80)}},
+			expected: invalid(map[string]any{
+				"path": "name", "message": "incomplete value string",
+			}),
+		},
+		{
+			name: "field not allowed",
+			args: []any{configSchema, map[string]any{"port": float64(80), "name": "app", "extra": true}},
+			expected: invalid(map[string]any{
+				"path": "extra", "message": "field not allowed",
+			}),
+		},
+		{
+			name: "nested path",
+			args: []any{configSchema, map[string]any{"port": float64(80), "name": "app", "tags": []any{"a", float64(1)}}},
+			expected: invalid(map[string]any{
+				"path": "tags.1", "message": "conflicting values 1 and string (mismatched types int and string)",
+			}),
+		},
+		{
+			name:     "open schema",
+			args:     []any{`port: int`, map[string]any{"port": float64(80), "extra": "ok"}},
+			expected: valid,
+		},
+		{
+			name:        "invalid schema",
+			args:        []any{`port: int &`, map[string]any{}},
+			expectError: true,
+		},
+		{
+			name:        "non-string schema",
+			args:        []any{float64(1), map[string]any{}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := cueValidateFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,12 +19,12 @@ var (
 )
 
 // httpsLookup performs HTTPS record lookup using miekg/dns library
-func httpsLookup(hostname string) (any, error) {
-	c := dns.Client{Timeout: DefaultDnsTimeout}
+func httpsLookup(ctx context.Context, hostname string, timeout time.Duration) (any, error) {
+	c := dns.Client{Timeout: timeout}
 	m := dns.Msg{}
 	m.SetQuestion(dns.Fqdn(hostname), dns.TypeHTTPS)
 
-	r, _, err := c.Exchange(&m, "1.1.1.1:53") // Use Cloudflare DNS
+	r, _, err := c.ExchangeContext(ctx, &m, "1.1.1.1:53") // Use Cloudflare DNS
 	if err != nil {
 		return nil, fmt.Errorf("dns_lookup: HTTPS record lookup failed: %w", err)
 	}
@@ -91,9 +92,10 @@ func httpsLookup(hostname string) (any, error) {
 	}, nil
 }
 
-// dnslookup performs DNS lookup for the specified hostname and record type
-func dnslookup(hostname, recordType string) (any, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultDnsTimeout)
+// dnslookup performs DNS lookup for the specified hostname and record type.
+// A and AAAA records of hosts overridden by --resolve are not looked up.
+func dnslookup(ctx context.Context, resolve []ResolveEntry, timeout time.Duration, hostname, recordType string) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	resolver := &net.Resolver{}
@@ -105,6 +107,20 @@ func dnslookup(hostname, recordType string) (any, error) {
 		"success":  true,
 	}
 
+	if recordType == "A" || recordType == "AAAA" {
+		if addrs := lookupResolve(resolve, hostname, ""); len(addrs) > 0 {
+			records := []any{}
+			for _, addr := range addrs {
+				isV4 := net.ParseIP(addr).To4() != nil
+				if isV4 == (recordType == "A") && !slices.Contains(records, any(addr)) {
+					records = append(records, addr)
+				}
+			}
+			result["records"] = records
+			return result, nil
+		}
+	}
+
 	switch recordType {
 	case "A":
 		ips, err := resolver.LookupIPAddr(ctx, hostname)
@@ -187,12 +203,12 @@ func dnslookup(hostname, recordType string) (any, error) {
 		result["records"] = records
 
 	case "HTTPS":
-		return httpsLookup(hostname)
+		return httpsLookup(ctx, hostname, timeout)
 
 	case "SVCB":
 		// SVCB records are similar to HTTPS but for other services
 		// For now, we'll treat them the same as HTTPS records but with different type
-		result, err := httpsLookup(hostname)
+		result, err := httpsLookup(ctx, hostname, timeout)
 		if err != nil {
 			return nil, err
 		}
@@ -208,25 +224,30 @@ func dnslookup(hostname, recordType string) (any, error) {
 	return result, nil
 }
 
-var DnsFunctions = map[string]*jsonnet.NativeFunction{
-	"dns_lookup": {
-		Params: []ast.Identifier{"hostname", "record_type"},
-		Func: func(args []any) (any, error) {
-			hostname, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("dns_lookup: hostname must be a string")
-			}
+// DnsFunctions are the DNS functions without --resolve overrides
+var DnsFunctions = GenerateDnsFunctions(context.Background())
+
+func GenerateDnsFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	resolve := resolveFromContext(ctx)
+	funcs := map[string]*jsonnet.NativeFunction{
+		"dns_lookup": {
+			Params: []ast.Identifier{"hostname", "record_type"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("dns_lookup", args)
+				hostname, err := a.String(0, "hostname")
+				if err != nil {
+					return nil, err
+				}
 
-			recordType, ok := args[1].(string)
-			if !ok {
-				return nil, fmt.Errorf("dns_lookup: record_type must be a string")
-			}
+				recordType, err := a.String(1, "record_type")
+				if err != nil {
+					return nil, err
+				}
 
-			return dnslookup(hostname, recordType)
+				return dnslookup(ctx, resolve, funcTimeout(ctx, "dns_lookup", DefaultDnsTimeout), hostname, recordType)
+			},
 		},
-	},
-}
-
-func init() {
-	initializeFunctionMap(DnsFunctions)
+	}
+	initializeFunctionMap(funcs)
+	return funcs
 }
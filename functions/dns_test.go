@@ -81,7 +81,7 @@ func TestDnsLookup(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := dnslookup(tt.hostname, tt.recordType)
+			result, err := dnslookup(t.Context(), nil, DefaultDnsTimeout, tt.hostname, tt.recordType)
 
 			if tt.expectError {
 				if err == nil {
@@ -0,0 +1,158 @@
+package functions
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// dynamoDBKey converts a Jsonnet object to the key of an item.
+// Key attributes are strings or numbers.
+func dynamoDBKey(key map[string]any) (map[string]types.AttributeValue, error) {
+	av := make(map[string]types.AttributeValue, len(key))
+	for name, v := range key {
+		switch v := v.(type) {
+		case string:
+			av[name] = &types.AttributeValueMemberS{Value: v}
+		case float64:
+			av[name] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(v, 'f', -1, 64)}
+		default:
+			return nil, fmt.Errorf("aws_dynamodb_get: key attribute %s must be a string or a number, got %s", name, jsonTypeName(v))
+		}
+	}
+	return av, nil
+}
+
+// fromAttributeValue converts a DynamoDB attribute value to a JSON-compatible value.
+// Binary values are base64 encoded and sets are converted to sorted arrays.
+func fromAttributeValue(av types.AttributeValue) (any, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value, nil
+	case *types.AttributeValueMemberN:
+		return parseDynamoDBNumber(v.Value)
+	case *types.AttributeValueMemberB:
+		return base64.StdEncoding.EncodeToString(v.Value), nil
+	case *types.AttributeValueMemberBOOL:
+		return v.Value, nil
+	case *types.AttributeValueMemberNULL:
+		return nil, nil
+	case *types.AttributeValueMemberM:
+		m := make(map[string]any, len(v.Value))
+		for k, e := range v.Value {
+			converted, err := fromAttributeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
+		}
+		return m, nil
+	case *types.AttributeValueMemberL:
+		l := make([]any, len(v.Value))
+		for i, e := range v.Value {
+			converted, err := fromAttributeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = converted
+		}
+		return l, nil
+	case *types.AttributeValueMemberSS:
+		ss := append([]string(nil), v.Value...)
+		sort.Strings(ss)
+		l := make([]any, len(ss))
+		for i, s := range ss {
+			l[i] = s
+		}
+		return l, nil
+	case *types.AttributeValueMemberNS:
+		ns := make([]float64, len(v.Value))
+		for i, s := range v.Value {
+			n, err := parseDynamoDBNumber(s)
+			if err != nil {
+				return nil, err
+			}
+			ns[i] = n
+		}
+		sort.Float64s(ns)
+		l := make([]any, len(ns))
+		for i, n := range ns {
+			l[i] = n
+		}
+		return l, nil
+	case *types.AttributeValueMemberBS:
+		bs := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			bs[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		sort.Strings(bs)
+		l := make([]any, len(bs))
+		for i, s := range bs {
+			l[i] = s
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute value %T", av)
+}
+
+func parseDynamoDBNumber(s string) (float64, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %w", s, err)
+	}
+	return n, nil
+}
+
+func GenerateDynamoDBFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	funcs := map[string]*jsonnet.NativeFunction{
+		"aws_dynamodb_get": {
+			Params: []ast.Identifier{"table", "key"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("aws_dynamodb_get", args)
+				table, err := a.String(0, "table")
+				if err != nil {
+					return nil, err
+				}
+				keyObj, err := a.Object(1, "key")
+				if err != nil {
+					return nil, err
+				}
+				key, err := dynamoDBKey(keyObj)
+				if err != nil {
+					return nil, err
+				}
+				cfg, err := LoadAWSConfig(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("aws_dynamodb_get: %w", err)
+				}
+				res, err := dynamodb.NewFromConfig(cfg).GetItem(ctx, &dynamodb.GetItemInput{
+					TableName: aws.String(table),
+					Key:       key,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("aws_dynamodb_get: failed to get an item from %s: %w", table, err)
+				}
+				if res.Item == nil {
+					// No item for the key
+					return nil, nil
+				}
+				item, err := fromAttributeValue(&types.AttributeValueMemberM{Value: res.Item})
+				if err != nil {
+					return nil, fmt.Errorf("aws_dynamodb_get: %w", err)
+				}
+				return item, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
@@ -0,0 +1,107 @@
+package functions_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDynamoDBGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			TableName string
+			Key       map[string]map[string]string
+		}
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Target") != "DynamoDB_20120810.GetItem" || json.Unmarshal(b, &req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if req.TableName != "tenants" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"__type": "com.amazonaws.dynamodb.v20120810#ResourceNotFoundException", "message": "Requested resource not found: Table: %s not found"}`, req.TableName)
+			return
+		}
+		switch {
+		case req.Key["tenant"]["S"] == "acme" && req.Key["version"]["N"] == "2":
+			fmt.Fprint(w, `{"Item": {
+				"tenant": {"S": "acme"},
+				"version": {"N": "2"},
+				"enabled": {"BOOL": true},
+				"ratio": {"N": "0.25"},
+				"note": {"NULL": true},
+				"logo": {"B": "aGVsbG8="},
+				"limits": {"M": {"users": {"N": "100"}, "plan": {"S": "pro"}}},
+				"regions": {"L": [{"S": "us-east-1"}, {"N": "1"}]},
+				"tags": {"SS": ["b", "a"]},
+				"ports": {"NS": ["443", "80"]}
+			}}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ENDPOINT_URL_DYNAMODB", ts.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	functions.ResetAWSConfigs()
+	t.Cleanup(functions.ResetAWSConfigs)
+
+	get := functions.GenerateDynamoDBFunctions(t.Context())["aws_dynamodb_get"].Func
+
+	item, err := get([]any{"tenants", map[string]any{"tenant": "acme", "version": 2.0}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"tenant":  "acme",
+		"version": 2.0,
+		"enabled": true,
+		"ratio":   0.25,
+		"note":    nil,
+		"logo":    "aGVsbG8=",
+		"limits":  map[string]any{"users": 100.0, "plan": "pro"},
+		"regions": []any{"us-east-1", 1.0},
+		"tags":    []any{"a", "b"},
+		"ports":   []any{80.0, 443.0},
+	}
+	if diff := cmp.Diff(expected, item); diff != "" {
+		t.Errorf("item mismatch (-want +got):\n%s", diff)
+	}
+
+	// A missing item is null
+	item, err = get([]any{"tenants", map[string]any{"tenant": "unknown", "version": 1.0}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item != nil {
+		t.Errorf("expected nil, got %v", item)
+	}
+
+	errorTests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{name: "unknown table", args: []any{"missing", map[string]any{"id": "a"}}, want: "Table: missing not found"},
+		{name: "invalid key attribute", args: []any{"tenants", map[string]any{"id": true}}, want: "key attribute id must be a string or a number"},
+		{name: "non-object key", args: []any{"tenants", "acme"}, want: "argument #2 (key) must be an object"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := get(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
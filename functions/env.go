@@ -1,8 +1,10 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/google/go-jsonnet"
@@ -10,54 +12,115 @@ import (
 	"github.com/hashicorp/go-envparse"
 )
 
-var EnvFunctions = map[string]*jsonnet.NativeFunction{
-	"env": {
-		Params: []ast.Identifier{"name", "default"},
-		Func: func(args []any) (any, error) {
-			key, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("env: name must be a string")
-			}
-			if v := os.Getenv(key); v != "" {
-				return v, nil
-			}
-			return args[1], nil
+// PromptFunc asks the user for the value of a missing variable
+type PromptFunc func(name string) (string, error)
+
+type promptKey struct{}
+
+// WithPrompt returns a context whose must_env asks for the values of unset
+// environment variables with prompt instead of failing
+func WithPrompt(ctx context.Context, prompt PromptFunc) context.Context {
+	return context.WithValue(ctx, promptKey{}, prompt)
+}
+
+// EnvFunctions are the environment functions without prompting
+var EnvFunctions = GenerateEnvFunctions(context.Background())
+
+func GenerateEnvFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	prompt, _ := ctx.Value(promptKey{}).(PromptFunc)
+	funcs := map[string]*jsonnet.NativeFunction{
+		"env": {
+			Params: []ast.Identifier{"name", "default"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("env", args)
+				key, err := a.String(0, "name")
+				if err != nil {
+					return nil, err
+				}
+				if v := os.Getenv(key); v != "" {
+					return v, nil
+				}
+				return a.Value(1, "default")
+			},
+		},
+		"must_env": {
+			Params: []ast.Identifier{"name"},
+			Func: func(args []any) (any, error) {
+				key, err := newArgs("must_env", args).String(0, "name")
+				if err != nil {
+					return nil, err
+				}
+				if v, ok := os.LookupEnv(key); ok {
+					// Required variables typically hold credentials
+					RegisterSecret(v)
+					return v, nil
+				}
+				if prompt != nil {
+					v, err := prompt(key)
+					if err != nil {
+						return nil, fmt.Errorf("must_env: %s is not set: %w", key, err)
+					}
+					RegisterSecret(v)
+					return v, nil
+				}
+				return nil, fmt.Errorf("must_env: %s is not set", key)
+			},
 		},
-	},
-	"must_env": {
-		Params: []ast.Identifier{"name"},
-		Func: func(args []any) (any, error) {
-			key, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("must_env: name must be a string")
-			}
-			if v, ok := os.LookupEnv(key); ok {
-				return v, nil
-			}
-			return nil, fmt.Errorf("must_env: %s is not set", key)
+		"env_all": {
+			Params: []ast.Identifier{"pattern"},
+			Func: func(args []any) (any, error) {
+				pattern, err := newArgs("env_all", args).String(0, "pattern")
+				if err != nil {
+					return nil, err
+				}
+				match, err := envNameMatcher(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("env_all: %w", err)
+				}
+				result := make(map[string]any)
+				for _, kv := range os.Environ() {
+					name, value, _ := strings.Cut(kv, "=")
+					if match(name) {
+						result[name] = value
+					}
+				}
+				return result, nil
+			},
 		},
-	},
-	"env_parse": {
-		Params: []ast.Identifier{"content"},
-		Func: func(args []any) (any, error) {
-			content, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("env_parse: content must be a string")
-			}
-			envMap, err := envparse.Parse(strings.NewReader(content))
-			if err != nil {
-				return nil, fmt.Errorf("env_parse: failed to parse: %w", err)
-			}
-			// Convert map[string]string to map[string]any for JSON compatibility
-			result := make(map[string]any)
-			for k, v := range envMap {
-				result[k] = v
-			}
-			return result, nil
+		"env_parse": {
+			Params: []ast.Identifier{"content"},
+			Func: func(args []any) (any, error) {
+				content, err := newArgs("env_parse", args).String(0, "content")
+				if err != nil {
+					return nil, err
+				}
+				envMap, err := envparse.Parse(strings.NewReader(content))
+				if err != nil {
+					return nil, fmt.Errorf("env_parse: failed to parse: %w", err)
+				}
+				// Convert map[string]string to map[string]any for JSON compatibility
+				result := make(map[string]any)
+				for k, v := range envMap {
+					result[k] = v
+				}
+				return result, nil
+			},
 		},
-	},
+	}
+	initializeFunctionMap(funcs)
+	return funcs
 }
 
-func init() {
-	initializeFunctionMap(EnvFunctions)
+// envNameMatcher returns a matcher of environment variable names for
+// env_all. Names consist of letters, digits and underscores, so a pattern
+// with other characters is a regular expression; otherwise it is a prefix.
+func envNameMatcher(pattern string) (func(name string) bool, error) {
+	if regexp.QuoteMeta(pattern) == pattern {
+		return func(name string) bool { return strings.HasPrefix(name, pattern) }, nil
+	}
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
 }
@@ -259,3 +259,73 @@ func TestEnvParseFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvAllFunction(t *testing.T) {
+	envAllFunc, err := getEnvFunction("env_all")
+	if err != nil {
+		t.Fatalf("failed to get env_all function: %v", err)
+	}
+
+	t.Setenv("TEST_ENV_ALL_HOST", "localhost")
+	t.Setenv("TEST_ENV_ALL_PORT", "8080")
+	t.Setenv("TEST_ENV_ALLX", "other")
+	t.Setenv("TEST_ENV_ALL_EMPTY", "")
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "prefix",
+			args: []any{"TEST_ENV_ALL_"},
+			expected: map[string]any{
+				"TEST_ENV_ALL_HOST":  "localhost",
+				"TEST_ENV_ALL_PORT":  "8080",
+				"TEST_ENV_ALL_EMPTY": "",
+			},
+		},
+		{
+			name: "regex",
+			args: []any{"^TEST_ENV_ALL(X|_PORT)$"},
+			expected: map[string]any{
+				"TEST_ENV_ALL_PORT": "8080",
+				"TEST_ENV_ALLX":     "other",
+			},
+		},
+		{
+			name:     "no match",
+			args:     []any{"TEST_ENV_ALL_NONE_"},
+			expected: map[string]any{},
+		},
+		{
+			name:        "invalid regex",
+			args:        []any{"TEST_ENV_ALL_("},
+			expectError: true,
+		},
+		{
+			name:        "non-string pattern",
+			args:        []any{123},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := envAllFunc(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
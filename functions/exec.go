@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/google/go-jsonnet"
@@ -17,70 +18,83 @@ var (
 	DefaultExecTimeout = 30 * time.Second
 )
 
+type execMaxParallelKey struct{}
+
+// WithExecMaxParallel returns a context that limits the number of exec
+// commands running at the same time to n (unlimited if n <= 0)
+func WithExecMaxParallel(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, execMaxParallelKey{}, n)
+}
+
+// execSlots holds a semaphore per limit, shared by all evaluations of the
+// process, so concurrent evaluations (serve, daemon) are bounded together.
+var execSlots = struct {
+	mu    sync.Mutex
+	slots map[int]chan struct{}
+}{slots: make(map[int]chan struct{})}
+
+// acquireExecSlot waits until an exec command can run under the limit
+// carried by ctx and returns the function releasing the slot
+func acquireExecSlot(ctx context.Context) (func(), error) {
+	n, _ := ctx.Value(execMaxParallelKey{}).(int)
+	if n <= 0 {
+		return func() {}, nil
+	}
+	execSlots.mu.Lock()
+	slots, ok := execSlots.slots[n]
+	if !ok {
+		slots = make(chan struct{}, n)
+		execSlots.slots[n] = slots
+	}
+	execSlots.mu.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("command execution was cancelled while waiting for a free slot")
+	}
+}
+
 func GenerateExecFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
 	funcs := map[string]*jsonnet.NativeFunction{
 		"exec": {
 			Params: []ast.Identifier{"command", "args"},
 			Func: func(args []any) (any, error) {
-				command, ok := args[0].(string)
-				if !ok {
-					return nil, fmt.Errorf("exec: command must be a string")
+				a := newArgs("exec", args)
+				command, err := a.String(0, "command")
+				if err != nil {
+					return nil, err
 				}
-				var cmdArgs []string
-				if args[1] != nil {
-					argsSlice, ok := args[1].([]any)
-					if !ok {
-						return nil, fmt.Errorf("exec: args must be an array")
-					}
-					cmdArgs = make([]string, len(argsSlice))
-					for i, arg := range argsSlice {
-						argStr, ok := arg.(string)
-						if !ok {
-							return nil, fmt.Errorf("exec: all arguments must be strings")
-						}
-						cmdArgs[i] = argStr
-					}
+				cmdArgs, err := a.OptionalStringArray(1, "args")
+				if err != nil {
+					return nil, err
 				}
-				return executeCommand(ctx, command, cmdArgs, nil)
+				return executeCommand(ctx, funcTimeout(ctx, "exec", DefaultExecTimeout), command, cmdArgs, nil)
 			},
 		},
 		"exec_with_env": {
 			Params: []ast.Identifier{"command", "args", "env_vars"},
 			Func: func(args []any) (any, error) {
-				command, ok := args[0].(string)
-				if !ok {
-					return nil, fmt.Errorf("exec_with_env: command must be a string")
+				a := newArgs("exec_with_env", args)
+				command, err := a.String(0, "command")
+				if err != nil {
+					return nil, err
+				}
+				cmdArgs, err := a.OptionalStringArray(1, "args")
+				if err != nil {
+					return nil, err
 				}
-				var cmdArgs []string
-				if args[1] != nil {
-					argsSlice, ok := args[1].([]any)
-					if !ok {
-						return nil, fmt.Errorf("exec_with_env: args must be an array")
-					}
-					cmdArgs = make([]string, len(argsSlice))
-					for i, arg := range argsSlice {
-						argStr, ok := arg.(string)
-						if !ok {
-							return nil, fmt.Errorf("exec_with_env: all arguments must be strings")
-						}
-						cmdArgs[i] = argStr
-					}
+				envMap, err := a.OptionalStringMap(2, "env_vars")
+				if err != nil {
+					return nil, err
 				}
 				var envVars []string
-				if args[2] != nil {
-					envMap, ok := args[2].(map[string]any)
-					if !ok {
-						return nil, fmt.Errorf("exec_with_env: env_vars must be an object")
-					}
-					for key, value := range envMap {
-						valueStr, ok := value.(string)
-						if !ok {
-							return nil, fmt.Errorf("exec_with_env: environment variable values must be strings")
-						}
-						envVars = append(envVars, fmt.Sprintf("%s=%s", key, valueStr))
-					}
+				for key, value := range envMap {
+					// Values passed via env_vars typically hold credentials
+					RegisterSecret(value)
+					envVars = append(envVars, fmt.Sprintf("%s=%s", key, value))
 				}
-				return executeCommand(ctx, command, cmdArgs, envVars)
+				return executeCommand(ctx, funcTimeout(ctx, "exec_with_env", DefaultExecTimeout), command, cmdArgs, envVars)
 			},
 		},
 	}
@@ -88,9 +102,16 @@ func GenerateExecFunctions(ctx context.Context) map[string]*jsonnet.NativeFuncti
 	return funcs
 }
 
-func executeCommand(ctx context.Context, command string, args []string, envVars []string) (map[string]any, error) {
+func executeCommand(ctx context.Context, timeout time.Duration, command string, args []string, envVars []string) (map[string]any, error) {
+	// Wait for a slot before starting the timeout, so waiting does not count
+	release, err := acquireExecSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Add timeout to the parent context
-	ctx, cancel := context.WithTimeout(ctx, DefaultExecTimeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, command, args...)
@@ -107,7 +128,7 @@ func executeCommand(ctx context.Context, command string, args []string, envVars
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	exitCode := 0
 
 	// Check for context cancellation/timeout first
@@ -1,7 +1,11 @@
 package functions_test
 
 import (
+	"context"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -294,3 +298,72 @@ func TestExecTimeoutSIGKILL(t *testing.T) {
 		t.Error("expected timeout error but got nil")
 	}
 }
+
+func TestExecMaxParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping shell test on Windows")
+	}
+	ctx := functions.WithExecMaxParallel(t.Context(), 2)
+	execFunc, err := getExecFunction(ctx, "exec")
+	if err != nil {
+		t.Fatalf("failed to get exec function: %v", err)
+	}
+
+	// Each command records the number of commands running alongside it
+	dir := t.TempDir()
+	script := `touch "$1/$$"; ls "$1" | wc -l; sleep 0.2; rm "$1/$$"`
+	var wg sync.WaitGroup
+	results := make([]any, 6)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := execFunc([]any{"sh", []any{"-c", script, "sh", dir}})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	for _, res := range results {
+		m, ok := res.(map[string]any)
+		if !ok {
+			continue
+		}
+		running, err := strconv.Atoi(strings.TrimSpace(m["stdout"].(string)))
+		if err != nil {
+			t.Fatalf("unexpected stdout %q", m["stdout"])
+		}
+		if running > 2 {
+			t.Errorf("%d commands ran at the same time, expected at most 2", running)
+		}
+	}
+}
+
+func TestExecMaxParallelWaitCancelled(t *testing.T) {
+	execFunc, err := getExecFunction(functions.WithExecMaxParallel(t.Context(), 1), "exec")
+	if err != nil {
+		t.Fatalf("failed to get exec function: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		execFunc([]any{"sleep", []any{"1"}})
+	}()
+	defer func() { <-done }()
+	time.Sleep(100 * time.Millisecond)
+
+	// The limit is shared, so another evaluation waits for the running command
+	ctx, cancel := context.WithTimeout(functions.WithExecMaxParallel(t.Context(), 1), 100*time.Millisecond)
+	defer cancel()
+	waitingFunc, err := getExecFunction(ctx, "exec")
+	if err != nil {
+		t.Fatalf("failed to get exec function: %v", err)
+	}
+	_, err = waitingFunc([]any{"true"})
+	if err == nil || !strings.Contains(err.Error(), "waiting for a free slot") {
+		t.Errorf("expected error while waiting for a slot, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package functions
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+var fakeFirstNames = []string{
+	"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry", "Iris", "Jack",
+	"Kate", "Liam", "Mia", "Noah", "Olivia", "Paul", "Quinn", "Ruby", "Sam", "Tina",
+	"Uma", "Victor", "Wendy", "Xavier", "Yuki", "Zoe", "Haruto", "Sakura", "Ren", "Hana",
+}
+
+var fakeLastNames = []string{
+	"Smith", "Johnson", "Brown", "Garcia", "Miller", "Davis", "Wilson", "Taylor", "Clark", "Lewis",
+	"Walker", "Young", "King", "Wright", "Scott", "Green", "Baker", "Adams", "Nelson", "Hill",
+	"Sato", "Suzuki", "Takahashi", "Tanaka", "Watanabe", "Ito", "Yamamoto", "Nakamura", "Kobayashi", "Kato",
+}
+
+var fakeWords = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do",
+	"eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim",
+	"ad", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip",
+	"ex", "ea", "commodo", "consequat", "duis", "aute", "irure", "in", "reprehenderit", "voluptate",
+}
+
+var fakeDomainSuffixes = []string{"example.com", "example.net", "example.org", "example.jp"}
+
+// fakeGenerators generate a value of each kind of fake()
+var fakeGenerators = map[string]func(r *rand.Rand) any{
+	"first_name": func(r *rand.Rand) any { return pick(r, fakeFirstNames) },
+	"last_name":  func(r *rand.Rand) any { return pick(r, fakeLastNames) },
+	"name": func(r *rand.Rand) any {
+		return pick(r, fakeFirstNames) + " " + pick(r, fakeLastNames)
+	},
+	"username": func(r *rand.Rand) any { return fakeUsername(r) },
+	"email": func(r *rand.Rand) any {
+		return fakeUsername(r) + "@" + pick(r, fakeDomainSuffixes)
+	},
+	"domain": func(r *rand.Rand) any {
+		return pick(r, fakeWords) + "-" + pick(r, fakeWords) + "." + pick(r, fakeDomainSuffixes)
+	},
+	// Addresses are in private ranges, so that fixtures never point to real hosts
+	"ipv4": func(r *rand.Rand) any {
+		return netip.AddrFrom4([4]byte{10, byte(r.IntN(256)), byte(r.IntN(256)), byte(1 + r.IntN(254))}).String()
+	},
+	"ipv6": func(r *rand.Rand) any {
+		var b [16]byte
+		b[0] = 0xfd // unique local addresses (fd00::/8)
+		for i := 1; i < 16; i++ {
+			b[i] = byte(r.IntN(256))
+		}
+		return netip.AddrFrom16(b).String()
+	},
+	"port":   func(r *rand.Rand) any { return 1024 + r.IntN(65535-1024+1) },
+	"number": func(r *rand.Rand) any { return r.IntN(1000000) },
+	"word":   func(r *rand.Rand) any { return pick(r, fakeWords) },
+	"sentence": func(r *rand.Rand) any {
+		return fakeSentence(r)
+	},
+	"paragraph": func(r *rand.Rand) any {
+		sentences := make([]string, 3+r.IntN(4))
+		for i := range sentences {
+			sentences[i] = fakeSentence(r)
+		}
+		return strings.Join(sentences, " ")
+	},
+	"uuid": func(r *rand.Rand) any {
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(r.IntN(256))
+		}
+		b[6] = b[6]&0x0f | 0x40 // version 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	},
+}
+
+func pick(r *rand.Rand, list []string) string {
+	return list[r.IntN(len(list))]
+}
+
+func fakeUsername(r *rand.Rand) string {
+	return strings.ToLower(pick(r, fakeFirstNames)) + "." + strings.ToLower(pick(r, fakeLastNames)) + fmt.Sprint(r.IntN(100))
+}
+
+func fakeSentence(r *rand.Rand) string {
+	words := make([]string, 5+r.IntN(8))
+	for i := range words {
+		words[i] = pick(r, fakeWords)
+	}
+	s := strings.Join(words, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// fakeRand returns a random source for kind. The same seed always yields
+// the same sequence; a null seed yields a random one.
+func fakeRand(kind string, seed any) (*rand.Rand, error) {
+	var key string
+	switch s := seed.(type) {
+	case nil:
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), nil
+	case string:
+		key = "s:" + s
+	case float64:
+		key = fmt.Sprintf("n:%v", s)
+	default:
+		return nil, fmt.Errorf("fake: argument #2 (seed) must be a string, a number or null, got %s", jsonTypeName(seed))
+	}
+	sum := sha256.Sum256([]byte(kind + "\x00" + key))
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]))), nil
+}
+
+// fakeKinds returns the kinds supported by fake(), sorted
+func fakeKinds() []string {
+	kinds := make([]string, 0, len(fakeGenerators))
+	for k := range fakeGenerators {
+		kinds = append(kinds, k)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+var FakeFunctions = map[string]*jsonnet.NativeFunction{
+	"fake": {
+		Params: []ast.Identifier{"kind", "seed"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("fake", args)
+			kind, err := a.String(0, "kind")
+			if err != nil {
+				return nil, err
+			}
+			generate, ok := fakeGenerators[kind]
+			if !ok {
+				return nil, a.errorf(0, "kind", "must be one of %s, got %q", strings.Join(fakeKinds(), ", "), kind)
+			}
+			var seed any
+			if len(args) > 1 {
+				seed = args[1]
+			}
+			r, err := fakeRand(kind, seed)
+			if err != nil {
+				return nil, err
+			}
+			return generate(r), nil
+		},
+	},
+}
+
+func init() {
+	initializeFunctionMap(FakeFunctions)
+}
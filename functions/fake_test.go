@@ -0,0 +1,89 @@
+package functions_test
+
+import (
+	"net/netip"
+	"regexp"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	fake, err := getFakeFunction("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kind  string
+		valid func(v any) bool
+	}{
+		{"first_name", matches(`^[A-Z][a-z]+$`)},
+		{"last_name", matches(`^[A-Z][a-z]+$`)},
+		{"name", matches(`^[A-Z][a-z]+ [A-Z][a-z]+$`)},
+		{"username", matches(`^[a-z]+\.[a-z]+[0-9]{1,2}$`)},
+		{"email", matches(`^[a-z]+\.[a-z]+[0-9]{1,2}@example\.(com|net|org|jp)$`)},
+		{"domain", matches(`^[a-z]+-[a-z]+\.example\.(com|net|org|jp)$`)},
+		{"ipv4", func(v any) bool {
+			addr, err := netip.ParseAddr(v.(string))
+			return err == nil && addr.Is4() && addr.IsPrivate()
+		}},
+		{"ipv6", func(v any) bool {
+			addr, err := netip.ParseAddr(v.(string))
+			return err == nil && addr.Is6() && addr.IsPrivate()
+		}},
+		{"port", func(v any) bool { n, ok := v.(int); return ok && n >= 1024 && n <= 65535 }},
+		{"number", func(v any) bool { n, ok := v.(int); return ok && n >= 0 && n < 1000000 }},
+		{"word", matches(`^[a-z]+$`)},
+		{"sentence", matches(`^[A-Z][a-z]*( [a-z]+){4,11}\.$`)},
+		{"paragraph", matches(`^([A-Z][a-z ]+\. ?){3,6}$`)},
+		{"uuid", matches(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			for _, seed := range []any{"user-1", "user-2", 42.0, nil} {
+				v, err := fake([]any{tt.kind, seed})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !tt.valid(v) {
+					t.Errorf("invalid %s with seed %v: %v", tt.kind, seed, v)
+				}
+			}
+
+			// The same seed yields the same value
+			a, _ := fake([]any{tt.kind, "user-1"})
+			b, _ := fake([]any{tt.kind, "user-1"})
+			if a != b {
+				t.Errorf("seeded values differ: %v != %v", a, b)
+			}
+		})
+	}
+
+	// Different seeds yield different values
+	seen := make(map[any]bool)
+	for _, seed := range []any{"a", "b", "c", "d", "e", 1.0, 2.0, 3.0} {
+		v, _ := fake([]any{"uuid", seed})
+		seen[v] = true
+	}
+	if len(seen) != 8 {
+		t.Errorf("expected 8 distinct values, got %d", len(seen))
+	}
+
+	for _, args := range [][]any{
+		{"unknown_kind", "seed"},
+		{123, "seed"},
+		{"name", true},
+		{"name", map[string]any{}},
+	} {
+		if _, err := fake(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func matches(pattern string) func(v any) bool {
+	re := regexp.MustCompile(pattern)
+	return func(v any) bool {
+		s, ok := v.(string)
+		return ok && re.MatchString(s)
+	}
+}
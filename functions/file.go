@@ -13,9 +13,9 @@ var FileFunctions = map[string]*jsonnet.NativeFunction{
 	"file_content": {
 		Params: []ast.Identifier{"filename"},
 		Func: func(args []any) (any, error) {
-			filename, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("file_content: filename must be a string")
+			filename, err := newArgs("file_content", args).String(0, "filename")
+			if err != nil {
+				return nil, err
 			}
 
 			file, err := os.Open(filename)
@@ -35,9 +35,9 @@ var FileFunctions = map[string]*jsonnet.NativeFunction{
 	"file_stat": {
 		Params: []ast.Identifier{"filename"},
 		Func: func(args []any) (any, error) {
-			filename, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("file_stat: filename must be a string")
+			filename, err := newArgs("file_stat", args).String(0, "filename")
+			if err != nil {
+				return nil, err
 			}
 
 			stat, err := os.Stat(filename)
@@ -57,12 +57,12 @@ var FileFunctions = map[string]*jsonnet.NativeFunction{
 	"file_exists": {
 		Params: []ast.Identifier{"filename"},
 		Func: func(args []any) (any, error) {
-			filename, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("file_exists: filename must be a string")
+			filename, err := newArgs("file_exists", args).String(0, "filename")
+			if err != nil {
+				return nil, err
 			}
 
-			_, err := os.Stat(filename)
+			_, err = os.Stat(filename)
 			return err == nil, nil
 		},
 	},
@@ -1,7 +1,6 @@
 package functions
 
 import (
-	"fmt"
 	"path/filepath"
 
 	"github.com/google/go-jsonnet"
@@ -12,9 +11,9 @@ var PathFunctions = map[string]*jsonnet.NativeFunction{
 	"basename": {
 		Params: []ast.Identifier{"path"},
 		Func: func(args []any) (any, error) {
-			path, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("basename: path must be a string")
+			path, err := newArgs("basename", args).String(0, "path")
+			if err != nil {
+				return nil, err
 			}
 			return filepath.Base(path), nil
 		},
@@ -22,9 +21,9 @@ var PathFunctions = map[string]*jsonnet.NativeFunction{
 	"dirname": {
 		Params: []ast.Identifier{"path"},
 		Func: func(args []any) (any, error) {
-			path, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("dirname: path must be a string")
+			path, err := newArgs("dirname", args).String(0, "path")
+			if err != nil {
+				return nil, err
 			}
 			return filepath.Dir(path), nil
 		},
@@ -32,9 +31,9 @@ var PathFunctions = map[string]*jsonnet.NativeFunction{
 	"extname": {
 		Params: []ast.Identifier{"path"},
 		Func: func(args []any) (any, error) {
-			path, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("extname: path must be a string")
+			path, err := newArgs("extname", args).String(0, "path")
+			if err != nil {
+				return nil, err
 			}
 			return filepath.Ext(path), nil
 		},
@@ -42,17 +41,9 @@ var PathFunctions = map[string]*jsonnet.NativeFunction{
 	"path_join": {
 		Params: []ast.Identifier{"elements"},
 		Func: func(args []any) (any, error) {
-			elements, ok := args[0].([]any)
-			if !ok {
-				return nil, fmt.Errorf("path_join: elements must be an array")
-			}
-			parts := make([]string, len(elements))
-			for i, e := range elements {
-				s, ok := e.(string)
-				if !ok {
-					return nil, fmt.Errorf("path_join: element at index %d must be a string", i)
-				}
-				parts[i] = s
+			parts, err := newArgs("path_join", args).StringArray(0, "elements")
+			if err != nil {
+				return nil, err
 			}
 			return filepath.Join(parts...), nil
 		},
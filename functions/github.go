@@ -0,0 +1,357 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// defaultGitHubAPIURL is the base URL of the GitHub REST API
+const defaultGitHubAPIURL = "https://api.github.com"
+
+var (
+	// GitHubMaxRateLimitWait is the longest wait for a GitHub API rate limit
+	// reset before retrying; longer waits fail the request instead
+	GitHubMaxRateLimitWait = 60 * time.Second
+
+	// GitHubMaxPages is the default maximum number of pages fetched by github_api
+	GitHubMaxPages = 10
+)
+
+// githubAPIURL returns the base URL of the GitHub REST API. GITHUB_API_URL,
+// which GitHub Actions sets, overrides it (e.g. for GitHub Enterprise Server).
+func githubAPIURL() string {
+	if u := os.Getenv("GITHUB_API_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return defaultGitHubAPIURL
+}
+
+// githubToken returns the token from GITHUB_TOKEN or GH_TOKEN, or "" for
+// unauthenticated requests. The token is registered as a secret.
+func githubToken() string {
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			RegisterSecret(token)
+			return token
+		}
+	}
+	return ""
+}
+
+// checkGitHubAPIHost returns an error unless the absolute URL u has the same
+// scheme and host as the GitHub API URL
+func checkGitHubAPIHost(u string) error {
+	api, err := url.Parse(githubAPIURL())
+	if err != nil {
+		return fmt.Errorf("invalid GitHub API URL %q: %w", githubAPIURL(), err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", u, err)
+	}
+	if !strings.EqualFold(parsed.Scheme, api.Scheme) || !strings.EqualFold(parsed.Host, api.Host) {
+		return fmt.Errorf("%s is not on the GitHub API host %s", u, api.Host)
+	}
+	return nil
+}
+
+// githubResponse is a response of the GitHub REST API
+type githubResponse struct {
+	header http.Header
+	body   any
+}
+
+// githubRequest sends a request to the GitHub REST API. path is relative to
+// the API URL unless it is an absolute URL, which must point at the API host
+// so that the token is never sent elsewhere. Non-2xx responses are errors.
+func githubRequest(ctx context.Context, resolve []ResolveEntry, name, version, method, path string, body any) (*githubResponse, error) {
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = githubAPIURL() + "/" + strings.TrimPrefix(path, "/")
+	} else if err := checkGitHubAPIHost(path); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to encode request body: %w", name, err)
+		}
+		bodyReader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to create request: %w", name, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := githubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	setDefaultUserAgent(req, version)
+
+	client := newHTTPClient(resolve, DefaultHttpTimeout)
+	resp, b, err := doGitHubRequest(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if wait, limited := githubRateLimitWait(resp, time.Now()); limited {
+		if wait > GitHubMaxRateLimitWait {
+			return nil, fmt.Errorf("%s: %s %s: rate limit exceeded, retry after %s", name, method, req.URL.Path, wait.Round(time.Second))
+		}
+		slog.Warn("GitHub API rate limit exceeded, waiting", "function", name, "wait", wait.Round(time.Second).String())
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %w", name, ctx.Err())
+		case <-time.After(wait):
+		}
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("%s: failed to create request: %w", name, err)
+			}
+		}
+		if resp, b, err = doGitHubRequest(client, retry); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("%s: %s %s: %s (%s)", name, method, req.URL.Path, resp.Status, e.Message)
+		}
+		return nil, fmt.Errorf("%s: %s %s: %s", name, method, req.URL.Path, resp.Status)
+	}
+
+	res := &githubResponse{header: resp.Header}
+	if len(bytes.TrimSpace(b)) > 0 {
+		if err := json.Unmarshal(b, &res.body); err != nil {
+			return nil, fmt.Errorf("%s: failed to parse response: %w", name, err)
+		}
+	}
+	return res, nil
+}
+
+// doGitHubRequest sends the request and reads the response body
+func doGitHubRequest(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp, b, nil
+}
+
+// githubRateLimitWait reports whether the response is rejected by a rate
+// limit and how long to wait before retrying, from Retry-After (secondary
+// rate limits) or X-RateLimit-Reset (primary rate limit).
+func githubRateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if sec, err := strconv.Atoi(s); err == nil {
+			return time.Duration(sec) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+// githubNextPage returns the URL of the next page from the Link header, or ""
+func githubNextPage(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// githubAPIFunction calls the GitHub REST API and returns the decoded
+// response. Paginated GET responses are concatenated.
+func githubAPIFunction(ctx context.Context, resolve []ResolveEntry, version string, args []any) (any, error) {
+	a := newArgs("github_api", args)
+	path, err := a.String(0, "path")
+	if err != nil {
+		return nil, err
+	}
+	options, err := a.OptionalObject(1, "options")
+	if err != nil {
+		return nil, err
+	}
+
+	method := http.MethodGet
+	if m, ok := options["method"]; ok && m != nil {
+		s, ok := m.(string)
+		if !ok {
+			return nil, fmt.Errorf("github_api: options.method must be a string, got %s", jsonTypeName(m))
+		}
+		method = strings.ToUpper(s)
+	}
+	paginate := method == http.MethodGet
+	if p, ok := options["paginate"]; ok && p != nil {
+		b, ok := p.(bool)
+		if !ok {
+			return nil, fmt.Errorf("github_api: options.paginate must be a boolean, got %s", jsonTypeName(p))
+		}
+		paginate = b
+	}
+	maxPages := GitHubMaxPages
+	if n, ok := options["max_pages"]; ok && n != nil {
+		f, ok := n.(float64)
+		if !ok || f < 1 {
+			return nil, fmt.Errorf("github_api: options.max_pages must be a positive number")
+		}
+		maxPages = int(f)
+	}
+
+	res, err := githubRequest(ctx, resolve, "github_api", version, method, path, options["body"])
+	if err != nil {
+		return nil, err
+	}
+	items, ok := res.body.([]any)
+	if !paginate || !ok {
+		return res.body, nil
+	}
+	for page := 1; ; page++ {
+		next := githubNextPage(res.header)
+		if next == "" {
+			break
+		}
+		if page >= maxPages {
+			return nil, fmt.Errorf("github_api: %s has more than %d pages; set options.max_pages to fetch more", path, maxPages)
+		}
+		if res, err = githubRequest(ctx, resolve, "github_api", version, method, next, nil); err != nil {
+			return nil, err
+		}
+		pageItems, ok := res.body.([]any)
+		if !ok {
+			return nil, fmt.Errorf("github_api: unexpected response on page %d", page+1)
+		}
+		items = append(items, pageItems...)
+	}
+	return items, nil
+}
+
+// githubReleaseFunction returns a release of a repository: the latest one
+// by default, or the one with options.tag
+func githubReleaseFunction(ctx context.Context, resolve []ResolveEntry, version string, args []any) (any, error) {
+	a := newArgs("github_release", args)
+	owner, err := a.String(0, "owner")
+	if err != nil {
+		return nil, err
+	}
+	repo, err := a.String(1, "repo")
+	if err != nil {
+		return nil, err
+	}
+	options, err := a.OptionalObject(2, "options")
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/releases/latest", url.PathEscape(owner), url.PathEscape(repo))
+	if tag, ok := options["tag"]; ok && tag != nil {
+		s, ok := tag.(string)
+		if !ok {
+			return nil, fmt.Errorf("github_release: options.tag must be a string, got %s", jsonTypeName(tag))
+		}
+		if latest, _ := options["latest"].(bool); latest {
+			return nil, fmt.Errorf("github_release: options.tag and options.latest are exclusive")
+		}
+		path = fmt.Sprintf("repos/%s/%s/releases/tags/%s", url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(s))
+	}
+
+	res, err := githubRequest(ctx, resolve, "github_release", version, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	release, ok := res.body.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("github_release: unexpected response")
+	}
+
+	assets := []any{}
+	if list, ok := release["assets"].([]any); ok {
+		for _, v := range list {
+			asset, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			assets = append(assets, map[string]any{
+				"name":         asset["name"],
+				"url":          asset["browser_download_url"],
+				"size":         asset["size"],
+				"content_type": asset["content_type"],
+				"digest":       asset["digest"],
+			})
+		}
+	}
+	return map[string]any{
+		"tag":          release["tag_name"],
+		"name":         release["name"],
+		"url":          release["html_url"],
+		"published_at": release["published_at"],
+		"prerelease":   release["prerelease"],
+		"draft":        release["draft"],
+		"assets":       assets,
+	}, nil
+}
+
+func GenerateGitHubFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	version := versionFromContext(ctx)
+	resolve := resolveFromContext(ctx)
+
+	funcs := map[string]*jsonnet.NativeFunction{
+		"github_api": {
+			Params: []ast.Identifier{"path", "options"},
+			Func: func(args []any) (any, error) {
+				return githubAPIFunction(ctx, resolve, version, args)
+			},
+		},
+		"github_release": {
+			Params: []ast.Identifier{"owner", "repo", "options"},
+			Func: func(args []any) (any, error) {
+				return githubReleaseFunction(ctx, resolve, version, args)
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
@@ -0,0 +1,318 @@
+package functions_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testReleaseJSON = `{
+	"tag_name": "v1.2.3",
+	"name": "Release v1.2.3",
+	"html_url": "https://github.com/owner/repo/releases/tag/v1.2.3",
+	"published_at": "2026-01-02T03:04:05Z",
+	"prerelease": false,
+	"draft": false,
+	"assets": [
+		{
+			"name": "tool_linux_amd64.tar.gz",
+			"browser_download_url": "https://github.com/owner/repo/releases/download/v1.2.3/tool_linux_amd64.tar.gz",
+			"size": 1024,
+			"content_type": "application/gzip",
+			"digest": "sha256:0123456789abcdef"
+		}
+	]
+}`
+
+func newGitHubTestServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+}
+
+func TestGitHubReleaseFunction(t *testing.T) {
+	var authorization string
+	newGitHubTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases/latest", "/repos/owner/repo/releases/tags/v1.2.3":
+			fmt.Fprint(w, testReleaseJSON)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	})
+	t.Setenv("GITHUB_TOKEN", "ghp_testtoken")
+
+	githubRelease := functions.GenerateGitHubFunctions(context.Background())["github_release"].Func
+	expected := map[string]any{
+		"tag":          "v1.2.3",
+		"name":         "Release v1.2.3",
+		"url":          "https://github.com/owner/repo/releases/tag/v1.2.3",
+		"published_at": "2026-01-02T03:04:05Z",
+		"prerelease":   false,
+		"draft":        false,
+		"assets": []any{
+			map[string]any{
+				"name":         "tool_linux_amd64.tar.gz",
+				"url":          "https://github.com/owner/repo/releases/download/v1.2.3/tool_linux_amd64.tar.gz",
+				"size":         float64(1024),
+				"content_type": "application/gzip",
+				"digest":       "sha256:0123456789abcdef",
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError string
+	}{
+		{
+			name:     "latest by default",
+			args:     []any{"owner", "repo", map[string]any{}},
+			expected: expected,
+		},
+		{
+			name:     "latest",
+			args:     []any{"owner", "repo", map[string]any{"latest": true}},
+			expected: expected,
+		},
+		{
+			name:     "by tag",
+			args:     []any{"owner", "repo", map[string]any{"tag": "v1.2.3"}},
+			expected: expected,
+		},
+		{
+			name:        "not found",
+			args:        []any{"owner", "repo", map[string]any{"tag": "v9.9.9"}},
+			expectError: "github_release: GET /repos/owner/repo/releases/tags/v9.9.9: 404 Not Found (Not Found)",
+		},
+		{
+			name:        "tag and latest",
+			args:        []any{"owner", "repo", map[string]any{"tag": "v1.2.3", "latest": true}},
+			expectError: "github_release: options.tag and options.latest are exclusive",
+		},
+		{
+			name:        "non-string tag",
+			args:        []any{"owner", "repo", map[string]any{"tag": float64(1)}},
+			expectError: "github_release: options.tag must be a string, got number",
+		},
+		{
+			name:        "missing repo",
+			args:        []any{"owner"},
+			expectError: "github_release: missing argument #2 (repo)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := githubRelease(tt.args)
+
+			if tt.expectError != "" {
+				if err == nil || err.Error() != tt.expectError {
+					t.Fatalf("expected error %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+			if authorization != "Bearer ghp_testtoken" {
+				t.Errorf("unexpected Authorization header: %q", authorization)
+			}
+		})
+	}
+
+	if got := functions.RedactSecrets("token=ghp_testtoken"); got != "token=***" {
+		t.Errorf("token is not registered as a secret: %s", got)
+	}
+}
+
+func TestGitHubAPIFunction(t *testing.T) {
+	var serverURL string
+	var rateLimited bool
+	newGitHubTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/tags":
+			// Three pages of two items
+			page := r.URL.Query().Get("page")
+			switch page {
+			case "", "1":
+				w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/tags?page=2>; rel="next", <%s/repos/owner/repo/tags?page=3>; rel="last"`, serverURL, serverURL))
+				fmt.Fprint(w, `[{"name": "v3"}, {"name": "v2"}]`)
+			case "2":
+				w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/tags?page=3>; rel="next", <%s/repos/owner/repo/tags?page=1>; rel="first"`, serverURL, serverURL))
+				fmt.Fprint(w, `[{"name": "v1"}, {"name": "v0"}]`)
+			default:
+				fmt.Fprint(w, `[{"name": "v0.1"}]`)
+			}
+		case "/repos/owner/repo":
+			fmt.Fprint(w, `{"full_name": "owner/repo", "private": false}`)
+		case "/repos/owner/repo/issues":
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, `{"number": 1, "request": %s}`, body)
+		case "/rate_limited":
+			if !rateLimited {
+				rateLimited = true
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit"}`)
+				return
+			}
+			fmt.Fprint(w, `{"ok": true}`)
+		case "/rate_limit_exhausted":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	})
+	serverURL = os.Getenv("GITHUB_API_URL")
+
+	githubAPI := functions.GenerateGitHubFunctions(context.Background())["github_api"].Func
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError string
+	}{
+		{
+			name:     "object",
+			args:     []any{"/repos/owner/repo", map[string]any{}},
+			expected: map[string]any{"full_name": "owner/repo", "private": false},
+		},
+		{
+			name: "pagination",
+			args: []any{"repos/owner/repo/tags", map[string]any{}},
+			expected: []any{
+				map[string]any{"name": "v3"}, map[string]any{"name": "v2"},
+				map[string]any{"name": "v1"}, map[string]any{"name": "v0"},
+				map[string]any{"name": "v0.1"},
+			},
+		},
+		{
+			name: "pagination disabled",
+			args: []any{"repos/owner/repo/tags", map[string]any{"paginate": false}},
+			expected: []any{
+				map[string]any{"name": "v3"}, map[string]any{"name": "v2"},
+			},
+		},
+		{
+			name:        "too many pages",
+			args:        []any{"repos/owner/repo/tags", map[string]any{"max_pages": float64(2)}},
+			expectError: "github_api: repos/owner/repo/tags has more than 2 pages; set options.max_pages to fetch more",
+		},
+		{
+			name: "post with body",
+			args: []any{"repos/owner/repo/issues", map[string]any{
+				"method": "post",
+				"body":   map[string]any{"title": "hello"},
+			}},
+			expected: map[string]any{"number": float64(1), "request": map[string]any{"title": "hello"}},
+		},
+		{
+			name:     "secondary rate limit is retried",
+			args:     []any{"rate_limited", map[string]any{}},
+			expected: map[string]any{"ok": true},
+		},
+		{
+			name:        "rate limit reset too far",
+			args:        []any{"rate_limit_exhausted", map[string]any{}},
+			expectError: "github_api: GET /rate_limit_exhausted: rate limit exceeded, retry after",
+		},
+		{
+			name:        "not found",
+			args:        []any{"nonexistent", map[string]any{}},
+			expectError: "github_api: GET /nonexistent: 404 Not Found (Not Found)",
+		},
+		{
+			name:        "invalid method",
+			args:        []any{"repos/owner/repo", map[string]any{"method": true}},
+			expectError: "github_api: options.method must be a string, got boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := githubAPI(tt.args)
+
+			if tt.expectError != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.expectError) {
+					t.Fatalf("expected error %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGitHubAPIForeignHost(t *testing.T) {
+	var foreignAuth []string
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignAuth = append(foreignAuth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `[{"name": "stolen"}]`)
+	}))
+	t.Cleanup(foreign.Close)
+
+	var apiAuth string
+	newGitHubTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		apiAuth = r.Header.Get("Authorization")
+		// A next page on a foreign host must not be followed
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/tags?page=2>; rel="next"`, foreign.URL))
+		fmt.Fprint(w, `[{"name": "v1"}]`)
+	})
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	githubAPI := functions.GenerateGitHubFunctions(context.Background())["github_api"].Func
+
+	_, err := githubAPI([]any{foreign.URL + "/repos/owner/repo", map[string]any{}})
+	if err == nil || !strings.Contains(err.Error(), "is not on the GitHub API host") {
+		t.Errorf("expected foreign host error, got %v", err)
+	}
+
+	_, err = githubAPI([]any{"repos/owner/repo/tags", map[string]any{}})
+	if err == nil || !strings.Contains(err.Error(), "is not on the GitHub API host") {
+		t.Errorf("expected foreign host error for next page, got %v", err)
+	}
+	if apiAuth != "Bearer test-token" {
+		t.Errorf("expected the token to be sent to the API host, got %q", apiAuth)
+	}
+	if len(foreignAuth) != 0 {
+		t.Errorf("expected no request to reach the foreign host, got %d with Authorization %q", len(foreignAuth), foreignAuth)
+	}
+}
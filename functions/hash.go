@@ -16,11 +16,11 @@ import (
 )
 
 // hashFunction creates a generic hash function using the hash.Hash interface
-func hashFunction(newHasher func() hash.Hash) func([]any) (any, error) {
+func hashFunction(name string, newHasher func() hash.Hash) func([]any) (any, error) {
 	return func(args []any) (any, error) {
-		data, ok := args[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("data must be a string")
+		data, err := newArgs(name, args).String(0, "data")
+		if err != nil {
+			return nil, err
 		}
 		hasher := newHasher()
 		hasher.Write([]byte(data))
@@ -29,22 +29,22 @@ func hashFunction(newHasher func() hash.Hash) func([]any) (any, error) {
 }
 
 // hashFileFunction creates a generic file hash function using the hash.Hash interface
-func hashFileFunction(newHasher func() hash.Hash) func([]any) (any, error) {
+func hashFileFunction(name string, newHasher func() hash.Hash) func([]any) (any, error) {
 	return func(args []any) (any, error) {
-		filename, ok := args[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("filename must be a string")
+		filename, err := newArgs(name, args).String(0, "filename")
+		if err != nil {
+			return nil, err
 		}
 
 		file, err := os.Open(filename)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
+			return nil, fmt.Errorf("%s: failed to open file %s: %w", name, filename, err)
 		}
 		defer file.Close()
 
 		hasher := newHasher()
 		if _, err := io.Copy(hasher, file); err != nil {
-			return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+			return nil, fmt.Errorf("%s: failed to read file %s: %w", name, filename, err)
 		}
 
 		return hex.EncodeToString(hasher.Sum(nil)), nil
@@ -55,37 +55,37 @@ var HashFunctions = map[string]*jsonnet.NativeFunction{
 	// String hash functions
 	"md5": {
 		Params: []ast.Identifier{"data"},
-		Func:   hashFunction(func() hash.Hash { return md5.New() }),
+		Func:   hashFunction("md5", func() hash.Hash { return md5.New() }),
 	},
 	"sha1": {
 		Params: []ast.Identifier{"data"},
-		Func:   hashFunction(func() hash.Hash { return sha1.New() }),
+		Func:   hashFunction("sha1", func() hash.Hash { return sha1.New() }),
 	},
 	"sha256": {
 		Params: []ast.Identifier{"data"},
-		Func:   hashFunction(func() hash.Hash { return sha256.New() }),
+		Func:   hashFunction("sha256", func() hash.Hash { return sha256.New() }),
 	},
 	"sha512": {
 		Params: []ast.Identifier{"data"},
-		Func:   hashFunction(func() hash.Hash { return sha512.New() }),
+		Func:   hashFunction("sha512", func() hash.Hash { return sha512.New() }),
 	},
 
 	// File hash functions
 	"md5_file": {
 		Params: []ast.Identifier{"filename"},
-		Func:   hashFileFunction(func() hash.Hash { return md5.New() }),
+		Func:   hashFileFunction("md5_file", func() hash.Hash { return md5.New() }),
 	},
 	"sha1_file": {
 		Params: []ast.Identifier{"filename"},
-		Func:   hashFileFunction(func() hash.Hash { return sha1.New() }),
+		Func:   hashFileFunction("sha1_file", func() hash.Hash { return sha1.New() }),
 	},
 	"sha256_file": {
 		Params: []ast.Identifier{"filename"},
-		Func:   hashFileFunction(func() hash.Hash { return sha256.New() }),
+		Func:   hashFileFunction("sha256_file", func() hash.Hash { return sha256.New() }),
 	},
 	"sha512_file": {
 		Params: []ast.Identifier{"filename"},
-		Func:   hashFileFunction(func() hash.Hash { return sha512.New() }),
+		Func:   hashFileFunction("sha512_file", func() hash.Hash { return sha512.New() }),
 	},
 }
 
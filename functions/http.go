@@ -27,13 +27,13 @@ func setDefaultUserAgent(req *http.Request, version string) {
 }
 
 // makeHttpRequest is the shared implementation for HTTP requests
-func makeHttpRequest(method, url string, headers map[string]any, body string, version string) (any, error) {
+func makeHttpRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]any, body string, version string) (any, error) {
 	var bodyReader io.Reader
 	if body != "" {
 		bodyReader = bytes.NewReader([]byte(body))
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("http request: failed to create request: %w", err)
 	}
@@ -50,10 +50,6 @@ func makeHttpRequest(method, url string, headers map[string]any, body string, ve
 	// Set default User-Agent if not specified
 	setDefaultUserAgent(req, version)
 
-	client := &http.Client{
-		Timeout: DefaultHttpTimeout,
-	}
-
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http request: request failed: %w", err)
@@ -86,66 +82,53 @@ func makeHttpRequest(method, url string, headers map[string]any, body string, ve
 }
 
 func GenerateHttpFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
-	version, _ := ctx.Value(versionKey).(string)
-	if version == "" {
-		version = "unknown"
-	}
+	version := versionFromContext(ctx)
+	resolve := resolveFromContext(ctx)
 
 	funcs := map[string]*jsonnet.NativeFunction{
 		"http_request": {
 			Params: []ast.Identifier{"method", "url", "headers", "body"},
 			Func: func(args []any) (any, error) {
-				method, ok := args[0].(string)
-				if !ok {
-					return nil, fmt.Errorf("http_request: method must be a string")
+				a := newArgs("http_request", args)
+				method, err := a.String(0, "method")
+				if err != nil {
+					return nil, err
 				}
 
-				url, ok := args[1].(string)
-				if !ok {
-					return nil, fmt.Errorf("http_request: url must be a string")
+				url, err := a.String(1, "url")
+				if err != nil {
+					return nil, err
 				}
 
-				var headers map[string]any
-				if args[2] != nil {
-					headersMap, ok := args[2].(map[string]any)
-					if !ok {
-						return nil, fmt.Errorf("http_request: headers must be an object or null")
-					}
-					headers = headersMap
+				headers, err := a.OptionalObject(2, "headers")
+				if err != nil {
+					return nil, err
 				}
 
-				var body string
-				if args[3] != nil {
-					bodyStr, ok := args[3].(string)
-					if !ok {
-						return nil, fmt.Errorf("http_request: body must be a string or null")
-					}
-					body = bodyStr
+				body, err := a.OptionalString(3, "body", "")
+				if err != nil {
+					return nil, err
 				}
 
-				return makeHttpRequest(method, url, headers, body, version)
+				return makeHttpRequest(ctx, newHTTPClient(resolve, funcTimeout(ctx, "http_request", DefaultHttpTimeout)), method, url, headers, body, version)
 			},
 		},
 		"http_get": {
 			Params: []ast.Identifier{"url", "headers"},
 			Func: func(args []any) (any, error) {
-				// Validate URL argument
-				url, ok := args[0].(string)
-				if !ok {
-					return nil, fmt.Errorf("http_get: url must be a string")
+				a := newArgs("http_get", args)
+				url, err := a.String(0, "url")
+				if err != nil {
+					return nil, err
 				}
 
-				var headers map[string]any
-				if args[1] != nil {
-					headersMap, ok := args[1].(map[string]any)
-					if !ok {
-						return nil, fmt.Errorf("http_get: headers must be an object or null")
-					}
-					headers = headersMap
+				headers, err := a.OptionalObject(1, "headers")
+				if err != nil {
+					return nil, err
 				}
 
 				// Call shared implementation with GET method and no body
-				return makeHttpRequest("GET", url, headers, "", version)
+				return makeHttpRequest(ctx, newHTTPClient(resolve, funcTimeout(ctx, "http_get", DefaultHttpTimeout)), "GET", url, headers, "", version)
 			},
 		},
 	}
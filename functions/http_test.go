@@ -2,10 +2,12 @@ package functions
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -210,3 +212,29 @@ func TestHttpFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestHttpFunctionsCanceled(t *testing.T) {
+	released := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Block until the client gives up
+		<-r.Context().Done()
+		close(released)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	httpFuncs := GenerateHttpFunctions(ctx)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	_, err := httpFuncs["http_get"].Func([]any{server.URL, nil})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Error("the request was not canceled")
+	}
+}
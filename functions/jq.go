@@ -8,46 +8,73 @@ import (
 	"github.com/itchyny/gojq"
 )
 
+// jqCacheSize is the number of compiled queries kept by the jq function
+const jqCacheSize = 256
+
+// jqCache holds compiled queries, so running the same query over many
+// inputs parses and compiles it only once.
+var jqCache = newLRUCache[string, *gojq.Code](jqCacheSize)
+
+// compileJQ parses and compiles a query. *gojq.Code is safe for concurrent use.
+func compileJQ(query string) (*gojq.Code, error) {
+	q, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("jq: failed to parse query: %v", err)
+	}
+	code, err := gojq.Compile(q)
+	if err != nil {
+		return nil, fmt.Errorf("jq: failed to compile query: %v", err)
+	}
+	return code, nil
+}
+
+// RunJQ runs a query on input, using the cache of compiled queries. A single
+// result is returned as is, several results as an array, and none as nil.
+func RunJQ(query string, input any) (any, error) {
+	code, err := jqCache.getOrCreate(query, compileJQ)
+	if err != nil {
+		return nil, err
+	}
+	iter := code.Run(input)
+	var results []any
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
+				break
+			}
+			return nil, fmt.Errorf("jq: failed to execute query: %v", err)
+		}
+		results = append(results, v)
+	}
+	switch len(results) {
+	case 0:
+		return nil, nil // No results
+	case 1:
+		return results[0], nil // Single result
+	default:
+		return results, nil // Multiple results
+	}
+}
+
 var JQFunctions = map[string]*jsonnet.NativeFunction{
 	"jq": {
 		Params: []ast.Identifier{"query", "input"},
 		Func: func(args []any) (any, error) {
-			if len(args) != 2 {
-				return nil, fmt.Errorf("jq: wrong number of arguments")
-			}
-			query, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("jq: argument must be a string")
-			}
-			input := args[1]
-
-			q, err := gojq.Parse(query)
+			a := newArgs("jq", args)
+			query, err := a.String(0, "query")
 			if err != nil {
-				return nil, fmt.Errorf("jq: failed to parse query: %v", err)
+				return nil, err
 			}
-			iter := q.Run(input)
-			var results []any
-			for {
-				v, ok := iter.Next()
-				if !ok {
-					break
-				}
-				if err, ok := v.(error); ok {
-					if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
-						break
-					}
-					return nil, fmt.Errorf("jq: failed to execute query: %v", err)
-				}
-				results = append(results, v)
-			}
-			switch len(results) {
-			case 0:
-				return nil, nil // No results
-			case 1:
-				return results[0], nil // Single result
-			default:
-				return results, nil // Multiple results
+			input, err := a.Value(1, "input")
+			if err != nil {
+				return nil, err
 			}
+
+			return RunJQ(query, input)
 		},
 	},
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/itchyny/gojq"
 )
 
 func TestJQFunction(t *testing.T) {
@@ -33,6 +34,21 @@ func TestJQFunction(t *testing.T) {
 			args:     []any{".[]", []any{float64(1), float64(2), float64(3)}},
 			expected: []any{float64(1), float64(2), float64(3)},
 		},
+		{
+			name:     "same query again",
+			args:     []any{".a", map[string]any{"a": "cached"}},
+			expected: "cached",
+		},
+		{
+			name:        "invalid query",
+			args:        []any{".[", map[string]any{}},
+			expectError: true,
+		},
+		{
+			name:        "undefined function",
+			args:        []any{"no_such_function", map[string]any{}},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -56,3 +72,43 @@ func TestJQFunction(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkJQFunction compares the jq function, which reuses compiled
+// queries, with parsing and running the query on every call.
+func BenchmarkJQFunction(b *testing.B) {
+	const query = `.items | map(select(.enabled)) | length`
+	input := map[string]any{
+		"items": []any{
+			map[string]any{"enabled": true},
+			map[string]any{"enabled": false},
+		},
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		jqFunc, err := getJQFunction("jq")
+		if err != nil {
+			b.Fatal(err)
+		}
+		args := []any{query, input}
+		for b.Loop() {
+			if _, err := jqFunc(args); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parse", func(b *testing.B) {
+		for b.Loop() {
+			q, err := gojq.Parse(query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			iter := q.Run(input)
+			for {
+				if _, ok := iter.Next(); !ok {
+					break
+				}
+			}
+		}
+	})
+}
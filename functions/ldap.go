@@ -0,0 +1,133 @@
+package functions
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// LDAPSearchMaxEntries is the maximum number of entries ldap_search returns.
+// A search matching more entries fails rather than being silently truncated.
+const LDAPSearchMaxEntries = 1000
+
+// ldapDial connects to the server of rawURL, or to the overridden addresses of
+// its host. TLS still verifies the original host name.
+func ldapDial(rawURL string, resolve []ResolveEntry) (*ldap.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") {
+		return ldap.DialURL(rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"ldap": ldap.DefaultLdapPort, "ldaps": ldap.DefaultLdapsPort}[u.Scheme]
+	}
+	addrs := lookupResolve(resolve, u.Hostname(), port)
+	if len(addrs) == 0 {
+		return ldap.DialURL(rawURL)
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname()}
+	for _, addr := range addrs {
+		u.Host = net.JoinHostPort(addr, port)
+		var conn *ldap.Conn
+		if conn, err = ldap.DialURL(u.String(), ldap.DialWithTLSConfig(tlsConfig)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// ldapSearch binds with the credentials in the environment (anonymously if
+// not set) and returns the entries matching filter under baseDN
+func ldapSearch(ctx context.Context, url, baseDN, filter string, attrs []string) ([]any, error) {
+	conn, err := ldapDial(url, resolveFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	defer conn.Close()
+	// Abort the search when the evaluation is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if bindDN := os.Getenv("JSONNET_ARMED_LDAP_BIND_DN"); bindDN != "" {
+		if err := conn.Bind(bindDN, os.Getenv("JSONNET_ARMED_LDAP_BIND_PASSWORD")); err != nil {
+			return nil, fmt.Errorf("failed to bind as %s: %w", bindDN, err)
+		}
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		LDAPSearchMaxEntries, 0, false, filter, attrs, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || (err == nil && len(res.Entries) > LDAPSearchMaxEntries) {
+		return nil, fmt.Errorf("the search matched more than %d entries; narrow it down with the base DN or the filter", LDAPSearchMaxEntries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", baseDN, err)
+	}
+
+	entries := make([]any, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		attributes := make(map[string]any, len(e.Attributes))
+		for _, attr := range e.Attributes {
+			values := make([]any, len(attr.ByteValues))
+			for i, v := range attr.ByteValues {
+				if utf8.Valid(v) {
+					values[i] = string(v)
+				} else {
+					// Binary values such as objectGUID and jpegPhoto
+					values[i] = base64.StdEncoding.EncodeToString(v)
+				}
+			}
+			attributes[attr.Name] = values
+		}
+		entries = append(entries, map[string]any{
+			"dn":         e.DN,
+			"attributes": attributes,
+		})
+	}
+	return entries, nil
+}
+
+func GenerateLDAPFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	funcs := map[string]*jsonnet.NativeFunction{
+		"ldap_search": {
+			Params: []ast.Identifier{"url", "base_dn", "filter", "attrs"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("ldap_search", args)
+				url, err := a.String(0, "url")
+				if err != nil {
+					return nil, err
+				}
+				baseDN, err := a.String(1, "base_dn")
+				if err != nil {
+					return nil, err
+				}
+				filter, err := a.String(2, "filter")
+				if err != nil {
+					return nil, err
+				}
+				attrs, err := a.OptionalStringArray(3, "attrs")
+				if err != nil {
+					return nil, err
+				}
+				entries, err := ldapSearch(ctx, url, baseDN, filter, attrs)
+				if err != nil {
+					return nil, fmt.Errorf("ldap_search: %w", err)
+				}
+				return entries, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
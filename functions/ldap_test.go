@@ -0,0 +1,181 @@
+package functions_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+)
+
+// startLDAPServer starts an LDAP server with a fixed directory, accepting
+// binds as cn=reader,dc=example,dc=com with the password "secret"
+func startLDAPServer(t *testing.T) string {
+	entries := map[string]map[string][]string{
+		"uid=alice,ou=people,dc=example,dc=com": {"uid": {"alice"}, "mail": {"alice@example.com"}, "memberOf": {"cn=admins,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com"}, "photo": {"\xff\xd8\xff"}},
+		"uid=bob,ou=people,dc=example,dc=com":   {"uid": {"bob"}, "mail": {"bob@example.com"}, "memberOf": {"cn=dev,ou=groups,dc=example,dc=com"}},
+	}
+	mux, err := gldap.NewMux()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Bind(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+		defer w.Write(resp)
+		m, err := r.GetSimpleBindMessage()
+		if err == nil && m.UserName == "cn=reader,dc=example,dc=com" && m.Password == "secret" {
+			resp.SetResultCode(gldap.ResultSuccess)
+		}
+	})
+	mux.Search(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultNoSuchObject))
+		defer w.Write(resp)
+		m, err := r.GetSearchMessage()
+		if err != nil || m.BaseDN != "ou=people,dc=example,dc=com" {
+			return
+		}
+		var matched []string
+		for _, dn := range []string{"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"} {
+			switch m.Filter {
+			case "(objectClass=*)":
+				matched = append(matched, dn)
+			case "(memberOf=cn=admins,ou=groups,dc=example,dc=com)":
+				if strings.HasPrefix(dn, "uid=alice,") {
+					matched = append(matched, dn)
+				}
+			}
+		}
+		if m.SizeLimit > 0 && int64(len(matched)) > m.SizeLimit {
+			resp.SetResultCode(gldap.ResultSizeLimitExceeded)
+			return
+		}
+		for _, dn := range matched {
+			attrs := entries[dn]
+			if len(m.Attributes) > 0 {
+				attrs = make(map[string][]string)
+				for _, name := range m.Attributes {
+					attrs[name] = entries[dn][name]
+				}
+			}
+			w.Write(r.NewSearchResponseEntry(dn, gldap.WithAttributes(attrs)))
+		}
+		resp.SetResultCode(gldap.ResultSuccess)
+	})
+	s, err := gldap.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Router(mux)
+	port := testdirectory.FreePort(t)
+	go s.Run(fmt.Sprintf("127.0.0.1:%d", port))
+	t.Cleanup(func() { s.Stop() })
+	for !s.Ready() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Sprintf("ldap://127.0.0.1:%d", port)
+}
+
+func TestLDAPSearch(t *testing.T) {
+	url := startLDAPServer(t)
+	t.Setenv("JSONNET_ARMED_LDAP_BIND_DN", "cn=reader,dc=example,dc=com")
+	t.Setenv("JSONNET_ARMED_LDAP_BIND_PASSWORD", "secret")
+	search := functions.GenerateLDAPFunctions(t.Context())["ldap_search"].Func
+
+	tests := []struct {
+		name     string
+		args     []any
+		expected []any
+	}{
+		{
+			name: "selected attributes",
+			args: []any{url, "ou=people,dc=example,dc=com", "(memberOf=cn=admins,ou=groups,dc=example,dc=com)", []any{"uid", "mail"}},
+			expected: []any{
+				map[string]any{"dn": "uid=alice,ou=people,dc=example,dc=com", "attributes": map[string]any{"uid": []any{"alice"}, "mail": []any{"alice@example.com"}}},
+			},
+		},
+		{
+			name: "all attributes",
+			args: []any{url, "ou=people,dc=example,dc=com", "(objectClass=*)"},
+			expected: []any{
+				map[string]any{"dn": "uid=alice,ou=people,dc=example,dc=com", "attributes": map[string]any{
+					"uid":      []any{"alice"},
+					"mail":     []any{"alice@example.com"},
+					"memberOf": []any{"cn=admins,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com"},
+					"photo":    []any{"/9j/"},
+				}},
+				map[string]any{"dn": "uid=bob,ou=people,dc=example,dc=com", "attributes": map[string]any{
+					"uid":      []any{"bob"},
+					"mail":     []any{"bob@example.com"},
+					"memberOf": []any{"cn=dev,ou=groups,dc=example,dc=com"},
+				}},
+			},
+		},
+		{
+			name:     "no entries",
+			args:     []any{url, "ou=people,dc=example,dc=com", "(uid=carol)", nil},
+			expected: []any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := search(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, entries); diff != "" {
+				t.Errorf("entries mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLDAPSearchErrors(t *testing.T) {
+	url := startLDAPServer(t)
+	search := functions.GenerateLDAPFunctions(t.Context())["ldap_search"].Func
+
+	tests := []struct {
+		name     string
+		bindDN   string
+		password string
+		args     []any
+		want     string
+	}{
+		{name: "invalid credentials", bindDN: "cn=reader,dc=example,dc=com", password: "wrong", args: []any{url, "ou=people,dc=example,dc=com", "(objectClass=*)"}, want: "ldap_search: failed to bind as cn=reader,dc=example,dc=com"},
+		{name: "unknown base DN", args: []any{url, "ou=unknown,dc=example,dc=com", "(objectClass=*)"}, want: "ldap_search: failed to search ou=unknown,dc=example,dc=com"},
+		{name: "unsupported URL", args: []any{"http://localhost", "dc=example,dc=com", "(objectClass=*)"}, want: "ldap_search: failed to connect to http://localhost"},
+		{name: "non-string attrs", args: []any{url, "dc=example,dc=com", "(objectClass=*)", []any{1.0}}, want: "ldap_search: argument #4 (attrs)"},
+		{name: "missing filter", args: []any{url, "dc=example,dc=com"}, want: "missing argument #3 (filter)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JSONNET_ARMED_LDAP_BIND_DN", tt.bindDN)
+			t.Setenv("JSONNET_ARMED_LDAP_BIND_PASSWORD", tt.password)
+			_, err := search(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLDAPSearchResolve(t *testing.T) {
+	url := startLDAPServer(t)
+	ctx := functions.WithResolve(t.Context(), []functions.ResolveEntry{
+		{Host: "ldap.preview.test", Addresses: []string{"127.0.0.1"}},
+	})
+	search := functions.GenerateLDAPFunctions(ctx)["ldap_search"].Func
+	entries, err := search([]any{
+		strings.Replace(url, "127.0.0.1", "ldap.preview.test", 1),
+		"ou=people,dc=example,dc=com", "(memberOf=cn=admins,ou=groups,dc=example,dc=com)", []any{"uid"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries.([]any)) != 1 {
+		t.Errorf("expected 1 entry, got %v", entries)
+	}
+}
@@ -0,0 +1,69 @@
+package functions
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a fixed-size cache that evicts the least recently used entry.
+// It is safe for concurrent use.
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[K]*list.Element
+	order   *list.List // front is the most recently used
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:    size,
+		entries: make(map[K]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// getOrCreate returns the cached value for key, or creates it with create
+// and caches it. Errors are returned as is and not cached.
+func (c *lruCache[K, V]) getOrCreate(key K, create func(K) (V, error)) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		v := e.Value.(*lruEntry[K, V]).value
+		c.mu.Unlock()
+		return v, nil
+	}
+	c.mu.Unlock()
+
+	// Create outside the lock; concurrent misses of the same key may create
+	// the value twice, which is harmless for immutable values.
+	v, err := create(key)
+	if err != nil {
+		return v, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[K, V]).value, nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: v})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	return v, nil
+}
+
+// len returns the number of cached entries
+func (c *lruCache[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
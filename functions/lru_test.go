@@ -0,0 +1,52 @@
+package functions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache[string, int](2)
+	created := 0
+	create := func(key string) (int, error) {
+		created++
+		return len(key), nil
+	}
+
+	for _, key := range []string{"a", "bb", "a"} {
+		if _, err := c.getOrCreate(key, create); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if created != 2 {
+		t.Errorf("expected 2 creations, got %d", created)
+	}
+
+	// "bb" is the least recently used and is evicted
+	if _, err := c.getOrCreate("ccc", create); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.len())
+	}
+	if v, _ := c.getOrCreate("a", create); v != 1 || created != 3 {
+		t.Errorf("expected cached a=1 after 3 creations, got %d after %d", v, created)
+	}
+	if _, err := c.getOrCreate("bb", create); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 4 {
+		t.Errorf("expected evicted entry to be created again, got %d creations", created)
+	}
+}
+
+func TestLRUCacheError(t *testing.T) {
+	c := newLRUCache[string, int](2)
+	errFailed := errors.New("failed")
+	if _, err := c.getOrCreate("a", func(string) (int, error) { return 0, errFailed }); !errors.Is(err, errFailed) {
+		t.Fatalf("expected error, got %v", err)
+	}
+	if c.len() != 0 {
+		t.Errorf("errors must not be cached, got %d entries", c.len())
+	}
+}
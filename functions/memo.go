@@ -0,0 +1,77 @@
+package functions
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// memoizedFileFunctions lists the functions whose results are memoized per
+// evaluation. Templates often hash the same artifact many times; the file is
+// read once as long as its modification time and size are unchanged.
+var memoizedFileFunctions = map[string]bool{
+	"file_content": true,
+	"file_stat":    true,
+	"md5_file":     true,
+	"sha1_file":    true,
+	"sha256_file":  true,
+	"sha512_file":  true,
+}
+
+type fileMemoKey struct {
+	name    string
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// fileMemo holds the results of file functions during an evaluation.
+// It is safe for concurrent use.
+type fileMemo struct {
+	mu      sync.Mutex
+	results map[fileMemoKey]any
+}
+
+func newFileMemo() *fileMemo {
+	return &fileMemo{results: make(map[fileMemoKey]any)}
+}
+
+// wrap returns a copy of f that returns the memoized result for a file
+// already read. Errors are not memoized.
+func (m *fileMemo) wrap(f *jsonnet.NativeFunction) *jsonnet.NativeFunction {
+	return &jsonnet.NativeFunction{
+		Name:   f.Name,
+		Params: f.Params,
+		Func: func(args []any) (any, error) {
+			if len(args) != 1 {
+				return f.Func(args)
+			}
+			path, ok := args[0].(string)
+			if !ok {
+				return f.Func(args)
+			}
+			stat, err := os.Stat(path)
+			if err != nil {
+				return f.Func(args)
+			}
+			key := fileMemoKey{name: f.Name, path: path, modTime: stat.ModTime(), size: stat.Size()}
+			m.mu.Lock()
+			result, ok := m.results[key]
+			m.mu.Unlock()
+			if ok {
+				return result, nil
+			}
+
+			result, err = f.Func(args)
+			if err != nil {
+				return nil, err
+			}
+			m.mu.Lock()
+			m.results[key] = result
+			m.mu.Unlock()
+			return result, nil
+		},
+	}
+}
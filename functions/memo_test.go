@@ -0,0 +1,84 @@
+package functions_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+)
+
+func TestFileFunctionsMemoized(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "artifact.bin")
+	modTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(testFile, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byName := make(map[string]*jsonnet.NativeFunction)
+	for _, f := range functions.GenerateAllFunctions(t.Context()) {
+		byName[f.Name] = f
+	}
+	call := func(name string) any {
+		t.Helper()
+		result, err := byName[name].Func([]any{testFile})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		return result
+	}
+
+	write("aaaa", modTime)
+	first := call("sha256_file")
+	content := call("file_content")
+
+	// Same size and modification time: the file is not read again
+	write("bbbb", modTime)
+	if got := call("sha256_file"); got != first {
+		t.Errorf("sha256_file: expected memoized %v, got %v", first, got)
+	}
+	if got := call("file_content"); got != content {
+		t.Errorf("file_content: expected memoized %v, got %v", content, got)
+	}
+
+	// A modified file is read again
+	write("bbbb", modTime.Add(time.Second))
+	if got := call("sha256_file"); got == first {
+		t.Errorf("sha256_file: expected a new hash for the modified file, got %v", got)
+	}
+	if got := call("file_content"); got != "bbbb" {
+		t.Errorf("file_content: expected bbbb, got %v", got)
+	}
+
+	// Each evaluation generates the functions, and starts with an empty memo
+	for _, f := range functions.GenerateAllFunctions(t.Context()) {
+		if f.Name != "file_content" {
+			continue
+		}
+		write("cccc", modTime.Add(time.Second))
+		got, err := f.Func([]any{testFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "cccc" {
+			t.Errorf("file_content: expected cccc in a new evaluation, got %v", got)
+		}
+	}
+
+	// A removed file is not served from the memo
+	if err := os.Remove(testFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := byName["sha256_file"].Func([]any{testFile}); err == nil {
+		t.Error("sha256_file: expected an error for a removed file")
+	}
+}
@@ -111,13 +111,18 @@ var NetworkFunctions = map[string]*jsonnet.NativeFunction{
 		Params: []ast.Identifier{"protocol", "port"},
 		Func: func(args []any) (any, error) {
 			// Validate protocol argument
-			protocol, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("net_port_listening: protocol must be a string")
+			a := newArgs("net_port_listening", args)
+			protocol, err := a.String(0, "protocol")
+			if err != nil {
+				return nil, err
 			}
 
 			// Validate and parse port argument
-			port, err := parsePort(args[1])
+			portArg, err := a.Value(1, "port")
+			if err != nil {
+				return nil, err
+			}
+			port, err := parsePort(portArg)
 			if err != nil {
 				return nil, fmt.Errorf("net_port_listening: %w", err)
 			}
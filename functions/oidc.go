@@ -0,0 +1,159 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// oidcMetadataTimeout is the timeout for the GCP metadata server, which is
+// tried last and is usually unreachable outside GCP
+var oidcMetadataTimeout = 2 * time.Second
+
+// oidcTokenSource obtains an ID token for an audience from the environment
+type oidcTokenSource struct {
+	name      string
+	available func() bool
+	fetch     func(ctx context.Context, resolve []ResolveEntry, audience, version string) (string, error)
+}
+
+// oidcTokenSources are tried in order; the first available one is used
+var oidcTokenSources = []oidcTokenSource{
+	{
+		// An issuer configured by the user, speaking the GitHub Actions protocol
+		name:      "JSONNET_ARMED_OIDC_TOKEN_URL",
+		available: func() bool { return os.Getenv("JSONNET_ARMED_OIDC_TOKEN_URL") != "" },
+		fetch: func(ctx context.Context, resolve []ResolveEntry, audience, version string) (string, error) {
+			return fetchActionsStyleToken(ctx, resolve, os.Getenv("JSONNET_ARMED_OIDC_TOKEN_URL"), os.Getenv("JSONNET_ARMED_OIDC_REQUEST_TOKEN"), audience, version)
+		},
+	},
+	{
+		// GitHub Actions with `permissions: id-token: write`
+		name:      "GitHub Actions",
+		available: func() bool { return os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" },
+		fetch: func(ctx context.Context, resolve []ResolveEntry, audience, version string) (string, error) {
+			return fetchActionsStyleToken(ctx, resolve, os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"), audience, version)
+		},
+	},
+	{
+		name:      "GCP metadata server",
+		available: func() bool { return true },
+		fetch:     fetchGCPIdentityToken,
+	},
+}
+
+// fetchActionsStyleToken requests a token with the GitHub Actions OIDC
+// protocol: GET <url>&audience=<audience> returning {"value": "<token>"}
+func fetchActionsStyleToken(ctx context.Context, resolve []ResolveEntry, tokenURL, requestToken, audience, version string) (string, error) {
+	u, err := url.Parse(tokenURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid token URL: %w", err)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if requestToken != "" {
+		RegisterSecret(requestToken)
+		req.Header.Set("Authorization", "Bearer "+requestToken)
+	}
+	req.Header.Set("Accept", "application/json")
+	setDefaultUserAgent(req, version)
+
+	b, err := doOIDCRequest(newHTTPClient(resolve, DefaultHttpTimeout), req)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil || res.Value == "" {
+		return "", fmt.Errorf("unexpected response from %s", u.Host)
+	}
+	return res.Value, nil
+}
+
+// fetchGCPIdentityToken requests a token from the GCP metadata server.
+// GCE_METADATA_HOST overrides the host, as in Google Cloud client libraries.
+func fetchGCPIdentityToken(ctx context.Context, resolve []ResolveEntry, audience, version string) (string, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	u := fmt.Sprintf("http://%s/computeMetadata/v1/instance/service-accounts/default/identity?audience=%s&format=full",
+		host, url.QueryEscape(audience))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	setDefaultUserAgent(req, version)
+
+	b, err := doOIDCRequest(newHTTPClient(resolve, oidcMetadataTimeout), req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func doOIDCRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return b, nil
+}
+
+func GenerateOIDCFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	version := versionFromContext(ctx)
+	resolve := resolveFromContext(ctx)
+
+	funcs := map[string]*jsonnet.NativeFunction{
+		"oidc_token": {
+			Params: []ast.Identifier{"audience"},
+			Func: func(args []any) (any, error) {
+				audience, err := newArgs("oidc_token", args).String(0, "audience")
+				if err != nil {
+					return nil, err
+				}
+				for _, source := range oidcTokenSources {
+					if !source.available() {
+						continue
+					}
+					token, err := source.fetch(ctx, resolve, audience, version)
+					if err != nil {
+						return nil, fmt.Errorf("oidc_token: failed to get a token from %s: %w", source.name, err)
+					}
+					RegisterSecret(token)
+					return token, nil
+				}
+				return nil, fmt.Errorf("oidc_token: no token source available")
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
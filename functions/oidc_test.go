@@ -0,0 +1,105 @@
+package functions_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestOIDCTokenFunction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		audience := r.URL.Query().Get("audience")
+		switch r.URL.Path {
+		case "/actions":
+			if r.Header.Get("Authorization") != "Bearer actions-request-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"count": 1, "value": "actions-token-for-%s"}`, audience)
+		case "/issuer":
+			fmt.Fprintf(w, `{"value": "issuer-token-for-%s"}`, audience)
+		case "/computeMetadata/v1/instance/service-accounts/default/identity":
+			if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("format") != "full" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, "gcp-token-for-%s", audience)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oidcToken := functions.GenerateOIDCFunctions(context.Background())["oidc_token"].Func
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    string
+		expectError string
+	}{
+		{
+			name: "GitHub Actions",
+			env: map[string]string{
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   server.URL + "/actions?api-version=2.0",
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "actions-request-token",
+			},
+			expected: "actions-token-for-sts.amazonaws.com",
+		},
+		{
+			name: "configured issuer takes precedence",
+			env: map[string]string{
+				"JSONNET_ARMED_OIDC_TOKEN_URL":   server.URL + "/issuer",
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   server.URL + "/actions",
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "actions-request-token",
+			},
+			expected: "issuer-token-for-sts.amazonaws.com",
+		},
+		{
+			name:     "GCP metadata server",
+			env:      map[string]string{"GCE_METADATA_HOST": strings.TrimPrefix(server.URL, "http://")},
+			expected: "gcp-token-for-sts.amazonaws.com",
+		},
+		{
+			name: "request rejected",
+			env: map[string]string{
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   server.URL + "/actions",
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "wrong",
+			},
+			expectError: "oidc_token: failed to get a token from GitHub Actions: " + strings.TrimPrefix(server.URL, "http://") + " returned 401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{
+				"JSONNET_ARMED_OIDC_TOKEN_URL", "JSONNET_ARMED_OIDC_REQUEST_TOKEN",
+				"ACTIONS_ID_TOKEN_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_TOKEN", "GCE_METADATA_HOST",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			result, err := oidcToken([]any{"sts.amazonaws.com"})
+			if tt.expectError != "" {
+				if err == nil || err.Error() != tt.expectError {
+					t.Fatalf("expected error %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+			if got := functions.RedactSecrets(tt.expected); got != functions.RedactedValue {
+				t.Errorf("token is not registered as a secret: %s", got)
+			}
+		})
+	}
+}
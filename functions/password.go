@@ -0,0 +1,136 @@
+package functions
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// PasswordDefaultLength is the length of passwords without options.length
+const PasswordDefaultLength = 16
+
+// passwordClasses are the character classes of password_generate
+var passwordClasses = map[string]string{
+	"lower":  "abcdefghijklmnopqrstuvwxyz",
+	"upper":  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digit":  "0123456789",
+	"symbol": "!#$%&()*+,-./:;<=>?@[]^_{|}~",
+}
+
+var passwordDefaultClasses = []string{"lower", "upper", "digit", "symbol"}
+
+// passwordGenerateFunction generates a random password that contains at
+// least one character of each class
+func passwordGenerateFunction(args []any) (any, error) {
+	a := newArgs("password_generate", args)
+	options, err := a.OptionalObject(0, "options")
+	if err != nil {
+		return nil, err
+	}
+
+	length := PasswordDefaultLength
+	if v, ok := options["length"]; ok && v != nil {
+		f, ok := v.(float64)
+		if !ok || f < 1 || f != float64(int(f)) {
+			return nil, fmt.Errorf("password_generate: options.length must be a positive integer")
+		}
+		length = int(f)
+	}
+	classes := passwordDefaultClasses
+	if v, ok := options["classes"]; ok && v != nil {
+		arr, ok := v.([]any)
+		if !ok || len(arr) == 0 {
+			return nil, fmt.Errorf("password_generate: options.classes must be a non-empty array of strings")
+		}
+		classes = make([]string, 0, len(arr))
+		for _, c := range arr {
+			s, ok := c.(string)
+			if !ok {
+				return nil, fmt.Errorf("password_generate: options.classes must be a non-empty array of strings")
+			}
+			if _, ok := passwordClasses[s]; !ok {
+				return nil, fmt.Errorf("password_generate: unknown class %q in options.classes (available: %s)", s, strings.Join(passwordDefaultClasses, ", "))
+			}
+			classes = append(classes, s)
+		}
+	}
+	exclude := ""
+	if v, ok := options["exclude"]; ok && v != nil {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("password_generate: options.exclude must be a string, got %s", jsonTypeName(v))
+		}
+		exclude = s
+	}
+
+	// Characters of each class, without the excluded ones
+	sets := make([]string, 0, len(classes))
+	seen := make(map[string]bool)
+	for _, c := range classes {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		set := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(exclude, r) {
+				return -1
+			}
+			return r
+		}, passwordClasses[c])
+		if set == "" {
+			return nil, fmt.Errorf("password_generate: all characters of class %q are excluded", c)
+		}
+		sets = append(sets, set)
+	}
+	if length < len(sets) {
+		return nil, fmt.Errorf("password_generate: options.length must be at least %d to contain all of the classes", len(sets))
+	}
+
+	// One character of each class, then any characters of all the classes
+	all := strings.Join(sets, "")
+	password := make([]byte, length)
+	for i := range password {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		n, err := randomInt(len(set))
+		if err != nil {
+			return nil, fmt.Errorf("password_generate: %w", err)
+		}
+		password[i] = set[n]
+	}
+	// Shuffle so that the positions of the required classes are unpredictable
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return nil, fmt.Errorf("password_generate: %w", err)
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// randomInt returns a uniform random integer in [0, n) from crypto/rand
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+var PasswordFunctions = map[string]*jsonnet.NativeFunction{
+	"password_generate": {
+		Params: []ast.Identifier{"options"},
+		Func:   passwordGenerateFunction,
+	},
+}
+
+func init() {
+	initializeFunctionMap(PasswordFunctions)
+}
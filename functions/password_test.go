@@ -0,0 +1,108 @@
+package functions_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordGenerate(t *testing.T) {
+	generate, err := getPasswordFunction("password_generate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		lower  = "abcdefghijklmnopqrstuvwxyz"
+		upper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		digit  = "0123456789"
+		symbol = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+	)
+	tests := []struct {
+		name     string
+		args     []any
+		length   int
+		required []string
+		allowed  string
+	}{
+		{
+			name:     "defaults",
+			args:     []any{},
+			length:   16,
+			required: []string{lower, upper, digit, symbol},
+			allowed:  lower + upper + digit + symbol,
+		},
+		{
+			name:     "null options",
+			args:     []any{nil},
+			length:   16,
+			required: []string{lower, upper, digit, symbol},
+			allowed:  lower + upper + digit + symbol,
+		},
+		{
+			name:     "length and classes",
+			args:     []any{map[string]any{"length": 32.0, "classes": []any{"lower", "digit"}}},
+			length:   32,
+			required: []string{lower, digit},
+			allowed:  lower + digit,
+		},
+		{
+			name:     "exclude ambiguous characters",
+			args:     []any{map[string]any{"length": 64.0, "classes": []any{"upper", "digit"}, "exclude": "O0I1"}},
+			length:   64,
+			required: []string{upper, digit},
+			allowed:  "ABCDEFGHJKLMNPQRSTUVWXYZ23456789",
+		},
+		{
+			name:     "as short as the classes",
+			args:     []any{map[string]any{"length": 2.0, "classes": []any{"upper", "symbol"}}},
+			length:   2,
+			required: []string{upper, symbol},
+			allowed:  upper + symbol,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				v, err := generate(tt.args)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				password := v.(string)
+				if len(password) != tt.length {
+					t.Errorf("expected length %d, got %q", tt.length, password)
+				}
+				for _, class := range tt.required {
+					if !strings.ContainsAny(password, class) {
+						t.Errorf("%q has no character of %q", password, class)
+					}
+				}
+				if strings.Trim(password, tt.allowed) != "" {
+					t.Errorf("%q has characters not in %q", password, tt.allowed)
+				}
+			}
+		})
+	}
+
+	a, _ := generate([]any{})
+	b, _ := generate([]any{})
+	if a == b {
+		t.Errorf("generated the same password twice: %v", a)
+	}
+
+	for _, args := range [][]any{
+		{"length"},
+		{map[string]any{"length": 0.0}},
+		{map[string]any{"length": 8.5}},
+		{map[string]any{"length": "16"}},
+		{map[string]any{"classes": []any{}}},
+		{map[string]any{"classes": []any{"emoji"}}},
+		{map[string]any{"classes": "lower"}},
+		{map[string]any{"exclude": 1.0}},
+		{map[string]any{"classes": []any{"digit"}, "exclude": digit}},
+		{map[string]any{"length": 3.0}},
+	} {
+		if _, err := generate(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
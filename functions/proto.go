@@ -0,0 +1,101 @@
+package functions
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoDecodeFunction decodes a base64-encoded protobuf message using a
+// FileDescriptorSet file (protoc --descriptor_set_out) and returns it as an
+// object in the protobuf JSON mapping with the original field names.
+func protoDecodeFunction(args []any) (any, error) {
+	a := newArgs("proto_decode", args)
+	descriptorFile, err := a.String(0, "descriptor_file")
+	if err != nil {
+		return nil, err
+	}
+	messageType, err := a.String(1, "message_type")
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.String(2, "data_base64")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := loadFileDescriptorSet(descriptorFile)
+	if err != nil {
+		return nil, fmt.Errorf("proto_decode: %w", err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(messageType))
+	if err != nil {
+		return nil, fmt.Errorf("proto_decode: message type %s not found: %w", messageType, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("proto_decode: %s is not a message type", messageType)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("proto_decode: failed to decode base64: %w", err)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("proto_decode: failed to unmarshal %s: %w", messageType, err)
+	}
+
+	// Marshal to JSON and back to get JSON-compatible types
+	j, err := protojson.MarshalOptions{
+		UseProtoNames: true,
+		Resolver:      dynamicpb.NewTypes(files),
+	}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("proto_decode: failed to convert %s to JSON: %w", messageType, err)
+	}
+	var result any
+	if err := json.Unmarshal(j, &result); err != nil {
+		return nil, fmt.Errorf("proto_decode: failed to convert %s to JSON: %w", messageType, err)
+	}
+	return result, nil
+}
+
+// loadFileDescriptorSet reads a serialized FileDescriptorSet
+func loadFileDescriptorSet(filename string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor file: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor file %s: %w", filename, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor file %s: %w", filename, err)
+	}
+	return files, nil
+}
+
+var ProtoFunctions = map[string]*jsonnet.NativeFunction{
+	"proto_decode": {
+		Params: []ast.Identifier{"descriptor_file", "message_type", "data_base64"},
+		Func:   protoDecodeFunction,
+	},
+}
+
+func init() {
+	initializeFunctionMap(ProtoFunctions)
+}
@@ -0,0 +1,101 @@
+package functions_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProtoDecodeFunction(t *testing.T) {
+	protoDecodeFunc, err := getProtoFunction("proto_decode")
+	if err != nil {
+		t.Fatalf("failed to get proto_decode function: %v", err)
+	}
+
+	const descriptorFile = "../testdata/event.pb"
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "decode message",
+			args: []any{descriptorFile, "example.Event", "CgVldnQtMRADGgFhGgFiIAEqAggB"},
+			expected: map[string]any{
+				"id":          "evt-1",
+				"retry_count": float64(3),
+				"tags":        []any{"a", "b"},
+				"kind":        "KIND_CREATED",
+				"detail":      map[string]any{"ok": true},
+			},
+		},
+		{
+			name:     "decode nested message",
+			args:     []any{descriptorFile, "example.Event.Detail", "CAE="},
+			expected: map[string]any{"ok": true},
+		},
+		{
+			name:     "empty message",
+			args:     []any{descriptorFile, "example.Event", ""},
+			expected: map[string]any{},
+		},
+		{
+			name:        "unknown message type",
+			args:        []any{descriptorFile, "example.Unknown", ""},
+			expectError: true,
+		},
+		{
+			name:        "not a message type",
+			args:        []any{descriptorFile, "example.Event.Kind", ""},
+			expectError: true,
+		},
+		{
+			name:        "invalid base64",
+			args:        []any{descriptorFile, "example.Event", "!!!"},
+			expectError: true,
+		},
+		{
+			name:        "invalid message data",
+			args:        []any{descriptorFile, "example.Event", "/w=="},
+			expectError: true,
+		},
+		{
+			name:        "nonexistent descriptor file",
+			args:        []any{"../testdata/nonexistent.pb", "example.Event", ""},
+			expectError: true,
+		},
+		{
+			name:        "invalid descriptor file",
+			args:        []any{"../testdata/event.proto", "example.Event", ""},
+			expectError: true,
+		},
+		{
+			name:        "non-string message type",
+			args:        []any{descriptorFile, float64(1), ""},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := protoDecodeFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
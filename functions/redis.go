@@ -0,0 +1,125 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/redis/go-redis/v9"
+)
+
+type redisURLKey struct{}
+
+// WithRedisURL returns a context that carries the URL of the Redis server
+// for the redis_* native functions
+func WithRedisURL(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, redisURLKey{}, url)
+}
+
+// redisURLFromContext returns the Redis URL carried by ctx
+func redisURLFromContext(ctx context.Context) string {
+	url, _ := ctx.Value(redisURLKey{}).(string)
+	return url
+}
+
+// redisClients caches clients by URL and address overrides, so long-lived modes (--watch, serve)
+// reuse the connections between evaluations.
+var redisClients = struct {
+	mu      sync.Mutex
+	clients map[string]*redis.Client
+}{clients: make(map[string]*redis.Client)}
+
+// redisClient returns the client for the Redis URL carried by ctx
+func redisClient(ctx context.Context) (*redis.Client, error) {
+	url := redisURLFromContext(ctx)
+	if url == "" {
+		return nil, errors.New("no Redis server is configured (use --redis-url)")
+	}
+	resolve := resolveFromContext(ctx)
+	key := url
+	if len(resolve) > 0 {
+		key += " " + resolveCacheKey(resolve)
+	}
+	redisClients.mu.Lock()
+	defer redisClients.mu.Unlock()
+	if client, ok := redisClients.clients[key]; ok {
+		return client, nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		// The URL may contain a password, so it is not included in the error
+		return nil, errors.New("invalid Redis URL")
+	}
+	if len(resolve) > 0 {
+		opts.Dialer = resolvingDialer(resolve)
+	}
+	client := redis.NewClient(opts)
+	redisClients.clients[key] = client
+	return client, nil
+}
+
+// CloseRedisClients closes the cached clients, so that the next commands
+// connect again (e.g. on SIGHUP in server mode)
+func CloseRedisClients() {
+	redisClients.mu.Lock()
+	defer redisClients.mu.Unlock()
+	for key, client := range redisClients.clients {
+		client.Close()
+		delete(redisClients.clients, key)
+	}
+}
+
+func GenerateRedisFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	funcs := map[string]*jsonnet.NativeFunction{
+		"redis_get": {
+			Params: []ast.Identifier{"key"},
+			Func: func(args []any) (any, error) {
+				key, err := newArgs("redis_get", args).String(0, "key")
+				if err != nil {
+					return nil, err
+				}
+				client, err := redisClient(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("redis_get: %w", err)
+				}
+				value, err := client.Get(ctx, key).Result()
+				if errors.Is(err, redis.Nil) {
+					// No value for the key
+					return nil, nil
+				}
+				if err != nil {
+					return nil, fmt.Errorf("redis_get: failed to get %s: %w", key, err)
+				}
+				return value, nil
+			},
+		},
+		"redis_hgetall": {
+			Params: []ast.Identifier{"key"},
+			Func: func(args []any) (any, error) {
+				key, err := newArgs("redis_hgetall", args).String(0, "key")
+				if err != nil {
+					return nil, err
+				}
+				client, err := redisClient(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("redis_hgetall: %w", err)
+				}
+				fields, err := client.HGetAll(ctx, key).Result()
+				if err != nil {
+					return nil, fmt.Errorf("redis_hgetall: failed to get %s: %w", key, err)
+				}
+				result := make(map[string]any, len(fields))
+				for k, v := range fields {
+					result[k] = v
+				}
+				return result, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
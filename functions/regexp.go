@@ -8,20 +8,34 @@ import (
 	"github.com/google/go-jsonnet/ast"
 )
 
+// regexpCacheSize is the number of compiled patterns kept by compileRegexp
+const regexpCacheSize = 256
+
+// regexpCache holds compiled patterns shared by the regex_* functions, so
+// applying the same pattern to many values compiles it only once.
+var regexpCache = newLRUCache[string, *regexp.Regexp](regexpCacheSize)
+
+// compileRegexp returns the compiled pattern from the cache, compiling it
+// on a miss. *regexp.Regexp is safe for concurrent use.
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	return regexpCache.getOrCreate(pattern, regexp.Compile)
+}
+
 // regexMatchFunction checks if the text matches the regular expression pattern
 func regexMatchFunction(args []any) (any, error) {
-	pattern, ok := args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("pattern must be a string")
+	a := newArgs("regex_match", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
 	}
-	text, ok := args[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("text must be a string")
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexp(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("regex_match: invalid regex pattern: %w", err)
 	}
 
 	return re.MatchString(text), nil
@@ -29,18 +43,19 @@ func regexMatchFunction(args []any) (any, error) {
 
 // regexFindFunction finds the first match of the regular expression in the text
 func regexFindFunction(args []any) (any, error) {
-	pattern, ok := args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("pattern must be a string")
+	a := newArgs("regex_find", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
 	}
-	text, ok := args[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("text must be a string")
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexp(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("regex_find: invalid regex pattern: %w", err)
 	}
 
 	match := re.FindString(text)
@@ -52,18 +67,19 @@ func regexFindFunction(args []any) (any, error) {
 
 // regexFindAllFunction finds all matches of the regular expression in the text
 func regexFindAllFunction(args []any) (any, error) {
-	pattern, ok := args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("pattern must be a string")
+	a := newArgs("regex_find_all", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
 	}
-	text, ok := args[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("text must be a string")
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexp(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("regex_find_all: invalid regex pattern: %w", err)
 	}
 
 	matches := re.FindAllString(text, -1)
@@ -81,22 +97,23 @@ func regexFindAllFunction(args []any) (any, error) {
 
 // regexReplaceFunction replaces all matches of the regular expression with the replacement string
 func regexReplaceFunction(args []any) (any, error) {
-	pattern, ok := args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("pattern must be a string")
+	a := newArgs("regex_replace", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
 	}
-	replacement, ok := args[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("replacement must be a string")
+	replacement, err := a.String(1, "replacement")
+	if err != nil {
+		return nil, err
 	}
-	text, ok := args[2].(string)
-	if !ok {
-		return nil, fmt.Errorf("text must be a string")
+	text, err := a.String(2, "text")
+	if err != nil {
+		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexp(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("regex_replace: invalid regex pattern: %w", err)
 	}
 
 	return re.ReplaceAllString(text, replacement), nil
@@ -104,18 +121,19 @@ func regexReplaceFunction(args []any) (any, error) {
 
 // regexSplitFunction splits the text by the regular expression pattern
 func regexSplitFunction(args []any) (any, error) {
-	pattern, ok := args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("pattern must be a string")
+	a := newArgs("regex_split", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
 	}
-	text, ok := args[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("text must be a string")
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexp(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("regex_split: invalid regex pattern: %w", err)
 	}
 
 	parts := re.Split(text, -1)
@@ -128,6 +146,82 @@ func regexSplitFunction(args []any) (any, error) {
 	return result, nil
 }
 
+// regexCapturesFunction returns the groups of the first match of the regular
+// expression in the text: {match, groups, named}, or null for no match.
+// Groups that did not participate in the match are null.
+func regexCapturesFunction(args []any) (any, error) {
+	a := newArgs("regex_captures", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("regex_captures: invalid regex pattern: %w", err)
+	}
+
+	loc := re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil, nil // Return null for no match
+	}
+	submatches := submatchValues(text, loc)
+	named := make(map[string]any)
+	for i, name := range re.SubexpNames() {
+		if i > 0 && name != "" {
+			named[name] = submatches[i]
+		}
+	}
+	return map[string]any{
+		"match":  submatches[0],
+		"groups": submatches[1:],
+		"named":  named,
+	}, nil
+}
+
+// regexFindAllSubmatchFunction finds all matches of the regular expression in
+// the text, each as an array of the full match followed by its groups
+func regexFindAllSubmatchFunction(args []any) (any, error) {
+	a := newArgs("regex_find_all_submatch", args)
+	pattern, err := a.String(0, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	text, err := a.String(1, "text")
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("regex_find_all_submatch: invalid regex pattern: %w", err)
+	}
+
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	result := make([]any, len(matches))
+	for i, loc := range matches {
+		result[i] = submatchValues(text, loc)
+	}
+	return result, nil
+}
+
+// submatchValues converts submatch index pairs to strings, with null for
+// groups that did not participate in the match
+func submatchValues(text string, loc []int) []any {
+	values := make([]any, len(loc)/2)
+	for i := range values {
+		if loc[2*i] < 0 {
+			continue
+		}
+		values[i] = text[loc[2*i]:loc[2*i+1]]
+	}
+	return values
+}
+
 var RegexpFunctions = map[string]*jsonnet.NativeFunction{
 	"regex_match": {
 		Params: []ast.Identifier{"pattern", "text"},
@@ -149,6 +243,14 @@ var RegexpFunctions = map[string]*jsonnet.NativeFunction{
 		Params: []ast.Identifier{"pattern", "text"},
 		Func:   regexSplitFunction,
 	},
+	"regex_captures": {
+		Params: []ast.Identifier{"pattern", "text"},
+		Func:   regexCapturesFunction,
+	},
+	"regex_find_all_submatch": {
+		Params: []ast.Identifier{"pattern", "text"},
+		Func:   regexFindAllSubmatchFunction,
+	},
 }
 
 func init() {
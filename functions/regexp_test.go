@@ -1,6 +1,7 @@
 package functions_test
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -345,3 +346,187 @@ func TestRegexSplitFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexCapturesFunction(t *testing.T) {
+	regexCapturesFunc, err := getRegexpFunction("regex_captures")
+	if err != nil {
+		t.Fatalf("failed to get regex_captures function: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "named groups",
+			args: []any{`(?P<key>\w+)=(?P<value>\w+)`, "x a=1 b=2"},
+			expected: map[string]any{
+				"match":  "a=1",
+				"groups": []any{"a", "1"},
+				"named":  map[string]any{"key": "a", "value": "1"},
+			},
+		},
+		{
+			name: "positional groups only",
+			args: []any{`v([0-9]+)\.([0-9]+)`, "version v1.23"},
+			expected: map[string]any{
+				"match":  "v1.23",
+				"groups": []any{"1", "23"},
+				"named":  map[string]any{},
+			},
+		},
+		{
+			name: "unmatched optional group",
+			args: []any{`(?P<major>[0-9]+)(-(?P<pre>\w+))?`, "1"},
+			expected: map[string]any{
+				"match":  "1",
+				"groups": []any{"1", nil, nil},
+				"named":  map[string]any{"major": "1", "pre": nil},
+			},
+		},
+		{
+			name: "empty group",
+			args: []any{`a(b*)c`, "ac"},
+			expected: map[string]any{
+				"match":  "ac",
+				"groups": []any{""},
+				"named":  map[string]any{},
+			},
+		},
+		{
+			name:     "no match",
+			args:     []any{`(\d+)`, "hello"},
+			expected: nil,
+		},
+		{
+			name:        "invalid regex pattern",
+			args:        []any{"(", "text"},
+			expectError: true,
+		},
+		{
+			name:        "non-string text",
+			args:        []any{"a", 1.0},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := regexCapturesFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegexFindAllSubmatchFunction(t *testing.T) {
+	regexFindAllSubmatchFunc, err := getRegexpFunction("regex_find_all_submatch")
+	if err != nil {
+		t.Fatalf("failed to get regex_find_all_submatch function: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    any
+		expectError bool
+	}{
+		{
+			name: "key value pairs",
+			args: []any{`(\w+)=(\w+)`, "a=1 b=2"},
+			expected: []any{
+				[]any{"a=1", "a", "1"},
+				[]any{"b=2", "b", "2"},
+			},
+		},
+		{
+			name: "unmatched optional group",
+			args: []any{`(\d)(x)?`, "1x2"},
+			expected: []any{
+				[]any{"1x", "1", "x"},
+				[]any{"2", "2", nil},
+			},
+		},
+		{
+			name:     "no groups",
+			args:     []any{`\d`, "a1b2"},
+			expected: []any{[]any{"1"}, []any{"2"}},
+		},
+		{
+			name:     "no matches",
+			args:     []any{`(\d+)`, "hello"},
+			expected: []any{},
+		},
+		{
+			name:        "invalid regex pattern",
+			args:        []any{"[", "text"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := regexFindAllSubmatchFunc(tt.args)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// BenchmarkRegexMatch compares regex_match, which reuses compiled patterns,
+// with compiling the pattern on every call.
+func BenchmarkRegexMatch(b *testing.B) {
+	const pattern = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+	const text = "test@example.com"
+
+	b.Run("cached", func(b *testing.B) {
+		regexMatchFunc, err := getRegexpFunction("regex_match")
+		if err != nil {
+			b.Fatal(err)
+		}
+		args := []any{pattern, text}
+		for b.Loop() {
+			if _, err := regexMatchFunc(args); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("compile", func(b *testing.B) {
+		for b.Loop() {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				b.Fatal(err)
+			}
+			re.MatchString(text)
+		}
+	})
+}
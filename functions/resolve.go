@@ -0,0 +1,201 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResolveEntry overrides the addresses of a host and port, as curl's
+// --resolve HOST:PORT:ADDRESS[,ADDRESS]... An empty Port matches any port,
+// as entries of a hosts file.
+type ResolveEntry struct {
+	Host      string
+	Port      string
+	Addresses []string
+}
+
+// ParseResolveEntry parses a HOST:PORT:ADDRESS[,ADDRESS]... entry.
+// IPv6 addresses may be enclosed in brackets.
+func ParseResolveEntry(s string) (ResolveEntry, error) {
+	host, rest, ok1 := strings.Cut(s, ":")
+	port, addrs, ok2 := strings.Cut(rest, ":")
+	if !ok1 || !ok2 || host == "" || addrs == "" {
+		return ResolveEntry{}, fmt.Errorf("invalid resolve entry %q: must be HOST:PORT:ADDRESS", s)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return ResolveEntry{}, fmt.Errorf("invalid resolve entry %q: invalid port %q", s, port)
+	}
+	e := ResolveEntry{Host: strings.ToLower(strings.TrimSuffix(host, ".")), Port: port}
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return ResolveEntry{}, fmt.Errorf("invalid resolve entry %q: invalid address %q", s, addr)
+		}
+		e.Addresses = append(e.Addresses, ip.String())
+	}
+	return e, nil
+}
+
+// String returns the entry in the HOST:PORT:ADDRESS[,ADDRESS]... form
+func (e ResolveEntry) String() string {
+	addrs := make([]string, len(e.Addresses))
+	for i, addr := range e.Addresses {
+		if strings.Contains(addr, ":") {
+			addr = "[" + addr + "]"
+		}
+		addrs[i] = addr
+	}
+	return e.Host + ":" + e.Port + ":" + strings.Join(addrs, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (e ResolveEntry) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so the entries can be
+// given as command line flags
+func (e *ResolveEntry) UnmarshalText(b []byte) error {
+	parsed, err := ParseResolveEntry(string(b))
+	if err != nil {
+		return err
+	}
+	*e = parsed
+	return nil
+}
+
+// LoadHostsFile reads the entries of a file in the /etc/hosts format
+// (ADDRESS NAME [ALIAS]...) as overrides for any port
+func LoadHostsFile(path string) ([]ResolveEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+	var entries []ResolveEntry
+	for i, line := range strings.Split(string(b), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: must be ADDRESS NAME [ALIAS]...", path, i+1)
+		}
+		for _, name := range fields[1:] {
+			entries = append(entries, ResolveEntry{
+				Host:      strings.ToLower(strings.TrimSuffix(name, ".")),
+				Addresses: []string{ip.String()},
+			})
+		}
+	}
+	return entries, nil
+}
+
+type resolveKey struct{}
+
+// WithResolve returns a context that carries the address overrides for the
+// network native functions
+func WithResolve(ctx context.Context, entries []ResolveEntry) context.Context {
+	return context.WithValue(ctx, resolveKey{}, entries)
+}
+
+// resolveFromContext returns the address overrides carried by ctx
+func resolveFromContext(ctx context.Context) []ResolveEntry {
+	entries, _ := ctx.Value(resolveKey{}).([]ResolveEntry)
+	return entries
+}
+
+// lookupResolve returns the overridden addresses of host and port. Entries
+// for the port take precedence over entries for any port. An empty port
+// returns the addresses of all entries of the host.
+func lookupResolve(entries []ResolveEntry, host, port string) []string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var addrs, anyPort []string
+	for _, e := range entries {
+		switch {
+		case e.Host != host:
+		case port == "" || e.Port == port:
+			addrs = append(addrs, e.Addresses...)
+		case e.Port == "":
+			anyPort = append(anyPort, e.Addresses...)
+		}
+	}
+	if len(addrs) == 0 {
+		return anyPort
+	}
+	return addrs
+}
+
+// resolvingDialer returns a dial function that connects to the overridden
+// addresses of the hosts in entries, trying them in order
+func resolvingDialer(entries []ResolveEntry) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs := lookupResolve(entries, host, port)
+		if len(addrs) == 0 {
+			return dialer.DialContext(ctx, network, address)
+		}
+		for _, addr := range addrs {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// resolveCacheKey returns a string identifying entries, for caches of
+// clients by server and overrides
+func resolveCacheKey(entries []ResolveEntry) string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.String()
+	}
+	return strings.Join(keys, " ")
+}
+
+// resolvingTransports caches HTTP transports by address overrides, so
+// calls share their idle connections and long-lived modes (--watch, serve)
+// reuse them between evaluations.
+var resolvingTransports = struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}{transports: make(map[string]*http.Transport)}
+
+// resolvingTransport returns the transport that connects to the overridden
+// addresses of the hosts in entries
+func resolvingTransport(entries []ResolveEntry) *http.Transport {
+	key := resolveCacheKey(entries)
+	resolvingTransports.mu.Lock()
+	defer resolvingTransports.mu.Unlock()
+	if t, ok := resolvingTransports.transports[key]; ok {
+		return t
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = resolvingDialer(entries)
+	resolvingTransports.transports[key] = t
+	return t
+}
+
+// newHTTPClient returns an HTTP client that connects to the overridden
+// addresses of the hosts in entries. TLS still verifies the original host name.
+func newHTTPClient(entries []ResolveEntry, timeout time.Duration) *http.Client {
+	if len(entries) == 0 {
+		return &http.Client{Timeout: timeout}
+	}
+	return &http.Client{Timeout: timeout, Transport: resolvingTransport(entries)}
+}
@@ -0,0 +1,202 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseResolveEntry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ResolveEntry
+		err      string
+	}{
+		{input: "example.com:443:10.0.0.1", expected: ResolveEntry{Host: "example.com", Port: "443", Addresses: []string{"10.0.0.1"}}},
+		{input: "Example.COM.:80:10.0.0.1,[::1]", expected: ResolveEntry{Host: "example.com", Port: "80", Addresses: []string{"10.0.0.1", "::1"}}},
+		{input: "example.com:443:::1", expected: ResolveEntry{Host: "example.com", Port: "443", Addresses: []string{"::1"}}},
+		{input: "example.com:443", err: "must be HOST:PORT:ADDRESS"},
+		{input: ":443:10.0.0.1", err: "must be HOST:PORT:ADDRESS"},
+		{input: "example.com:https:10.0.0.1", err: `invalid port "https"`},
+		{input: "example.com:443:backend", err: `invalid address "backend"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, err := ParseResolveEntry(tt.input)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, e); diff != "" {
+				t.Errorf("entry mismatch (-want +got):\n%s", diff)
+			}
+			// The text form round-trips, e.g. through the daemon protocol
+			var parsed ResolveEntry
+			if err := parsed.UnmarshalText([]byte(e.String())); err != nil {
+				t.Fatalf("failed to parse %q: %v", e.String(), err)
+			}
+			if diff := cmp.Diff(e, parsed); diff != "" {
+				t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveHttp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	ctx := WithResolve(context.Background(), []ResolveEntry{
+		{Host: "staging.example.test", Port: u.Port(), Addresses: []string{"192.0.2.1", "127.0.0.1"}},
+	})
+	httpGet := GenerateHttpFunctions(ctx)["http_get"].Func
+	res, err := httpGet([]any{"http://staging.example.test:" + u.Port() + "/", nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The request is sent to the overridden address with the original Host
+	if body := res.(map[string]any)["body"]; body != "staging.example.test:"+u.Port() {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestResolveHttpReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	ctx := WithResolve(context.Background(), []ResolveEntry{
+		{Host: "reuse.example.test", Port: u.Port(), Addresses: []string{"127.0.0.1"}},
+	})
+	httpGet := GenerateHttpFunctions(ctx)["http_get"].Func
+	for range 3 {
+		if _, err := httpGet([]any{"http://reuse.example.test:" + u.Port() + "/", nil}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected the connection to be reused, got %d connections", n)
+	}
+}
+
+func TestResolveDnsLookup(t *testing.T) {
+	ctx := WithResolve(context.Background(), []ResolveEntry{
+		{Host: "staging.example.test", Port: "443", Addresses: []string{"192.0.2.1", "2001:db8::1"}},
+		{Host: "staging.example.test", Port: "80", Addresses: []string{"192.0.2.1"}},
+	})
+	dnsLookup := GenerateDnsFunctions(ctx)["dns_lookup"].Func
+
+	tests := []struct {
+		recordType string
+		expected   []any
+	}{
+		{recordType: "A", expected: []any{"192.0.2.1"}},
+		{recordType: "AAAA", expected: []any{"2001:db8::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			res, err := dnsLookup([]any{"Staging.Example.Test", tt.recordType})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, res.(map[string]any)["records"]); diff != "" {
+				t.Errorf("records mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	content := `# preview environment
+10.0.0.1  api.preview.test  API.preview.test.
+2001:db8::1 ipv6.preview.test # trailing comment
+
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadHostsFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ResolveEntry{
+		{Host: "api.preview.test", Addresses: []string{"10.0.0.1"}},
+		{Host: "api.preview.test", Addresses: []string{"10.0.0.1"}},
+		{Host: "ipv6.preview.test", Addresses: []string{"2001:db8::1"}},
+	}
+	if diff := cmp.Diff(expected, entries); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := os.WriteFile(path, []byte("10.0.0.1 ok.test\napi.preview.test 10.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHostsFile(path); err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("expected error at line 2, got %v", err)
+	}
+}
+
+func TestLookupResolvePrecedence(t *testing.T) {
+	entries := []ResolveEntry{
+		{Host: "api.test", Port: "443", Addresses: []string{"10.0.0.1"}},
+		{Host: "api.test", Addresses: []string{"10.0.0.2"}},
+	}
+	tests := []struct {
+		port     string
+		expected []string
+	}{
+		{port: "443", expected: []string{"10.0.0.1"}},
+		{port: "80", expected: []string{"10.0.0.2"}},
+		{port: "", expected: []string{"10.0.0.1", "10.0.0.2"}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.expected, lookupResolve(entries, "api.test", tt.port)); diff != "" {
+			t.Errorf("port %q: addresses mismatch (-want +got):\n%s", tt.port, diff)
+		}
+	}
+	if addrs := lookupResolve(entries, "other.test", "443"); addrs != nil {
+		t.Errorf("expected no addresses for other.test, got %v", addrs)
+	}
+}
+
+func TestResolveRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.Set("app:version", "1.2.3")
+	_, port, _ := net.SplitHostPort(mr.Addr())
+	ctx := WithRedisURL(context.Background(), "redis://redis.preview.test:"+port+"/0")
+	ctx = WithResolve(ctx, []ResolveEntry{{Host: "redis.preview.test", Addresses: []string{"127.0.0.1"}}})
+
+	get := GenerateRedisFunctions(ctx)["redis_get"].Func
+	if v, err := get([]any{"app:version"}); err != nil || v != "1.2.3" {
+		t.Errorf("expected 1.2.3, got %v (%v)", v, err)
+	}
+}
@@ -0,0 +1,206 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// RedactedValue replaces secret values in redacted strings
+const RedactedValue = "***"
+
+// minSecretLength is the minimum length of a registered secret. Shorter
+// values (e.g. "1", "on") would redact unrelated text everywhere.
+const minSecretLength = 4
+
+// secretRegistry holds secret values obtained by native functions.
+// It is process-wide because log handlers are, and is safe for concurrent use.
+type secretRegistry struct {
+	mu       sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+var secrets = &secretRegistry{values: make(map[string]struct{})}
+
+// RegisterSecret registers a secret value so that RedactSecrets scrubs it
+// from log messages and error strings. Values shorter than 4 bytes are ignored.
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	if _, ok := secrets.values[value]; ok {
+		return
+	}
+	secrets.values[value] = struct{}{}
+	secrets.replacer = nil
+}
+
+// RedactSecrets replaces registered secret values in s with RedactedValue
+func RedactSecrets(s string) string {
+	secrets.mu.RLock()
+	r := secrets.replacer
+	n := len(secrets.values)
+	secrets.mu.RUnlock()
+	if n == 0 {
+		return s
+	}
+	if r == nil {
+		r = secrets.buildReplacer()
+	}
+	return r.Replace(s)
+}
+
+// buildReplacer builds a replacer that prefers longer secrets, so that a
+// secret containing another one is redacted as a whole.
+func (r *secretRegistry) buildReplacer() *strings.Replacer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replacer != nil {
+		return r.replacer
+	}
+	values := make([]string, 0, len(r.values))
+	for v := range r.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	oldnew := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		oldnew = append(oldnew, v, RedactedValue)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+	return r.replacer
+}
+
+type secretCollectorKey struct{}
+
+// SecretCollector collects the values marked with secret() during an
+// evaluation. It is safe for concurrent use.
+type SecretCollector struct {
+	mu     sync.Mutex
+	values map[string]struct{}
+}
+
+// NewSecretCollector creates an empty SecretCollector
+func NewSecretCollector() *SecretCollector {
+	return &SecretCollector{values: make(map[string]struct{})}
+}
+
+// Add records a secret value. Strings in arrays and objects are recorded individually.
+func (c *SecretCollector) Add(v any) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+		c.mu.Lock()
+		c.values[v] = struct{}{}
+		c.mu.Unlock()
+	case []any:
+		for _, e := range v {
+			c.Add(e)
+		}
+	case map[string]any:
+		for _, e := range v {
+			c.Add(e)
+		}
+	}
+}
+
+// Redact replaces the recorded values in manifested output s with
+// RedactedValue. Both the raw and the JSON-escaped forms are replaced.
+func (c *SecretCollector) Redact(s string) string {
+	c.mu.Lock()
+	values := make([]string, 0, len(c.values)*2)
+	for v := range c.values {
+		values = append(values, v)
+		if escaped := jsonEscape(v); escaped != v {
+			values = append(values, escaped)
+		}
+	}
+	c.mu.Unlock()
+	if len(values) == 0 {
+		return s
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	oldnew := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		oldnew = append(oldnew, v, RedactedValue)
+	}
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
+
+// jsonEscape returns s as it appears inside a JSON string literal
+func jsonEscape(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return s
+	}
+	escaped := strings.TrimSuffix(b.String(), "\n")
+	return escaped[1 : len(escaped)-1]
+}
+
+// WithSecretCollector returns a context that carries the collector.
+// secret() generated with the context records its values to it.
+func WithSecretCollector(ctx context.Context, c *SecretCollector) context.Context {
+	return context.WithValue(ctx, secretCollectorKey{}, c)
+}
+
+func GenerateSecretFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	collector, _ := ctx.Value(secretCollectorKey{}).(*SecretCollector)
+
+	funcs := map[string]*jsonnet.NativeFunction{
+		"secret": {
+			Params: []ast.Identifier{"value"},
+			Func: func(args []any) (any, error) {
+				v, err := newArgs("secret", args).Value(0, "value")
+				if err != nil {
+					return nil, err
+				}
+				registerSecrets(v)
+				if collector != nil {
+					collector.Add(v)
+				}
+				return v, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
+
+// registerSecrets registers strings in v with RegisterSecret
+func registerSecrets(v any) {
+	switch v := v.(type) {
+	case string:
+		RegisterSecret(v)
+	case []any:
+		for _, e := range v {
+			registerSecrets(e)
+		}
+	case map[string]any:
+		for _, e := range v {
+			registerSecrets(e)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package functions_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	functions.RegisterSecret("redact-test-token")
+	functions.RegisterSecret("redact-test-token-extended")
+	functions.RegisterSecret("abc") // too short to be registered
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"token=redact-test-token", "token=***"},
+		{"redact-test-token-extended and redact-test-token", "*** and ***"},
+		{"abc is not a secret", "abc is not a secret"},
+		{"nothing to redact", "nothing to redact"},
+	}
+	for _, tt := range tests {
+		if got := functions.RedactSecrets(tt.input); got != tt.expected {
+			t.Errorf("RedactSecrets(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMustEnvRegistersSecret(t *testing.T) {
+	t.Setenv("REDACT_TEST_MUST_ENV", "must-env-secret-value")
+	mustEnv, err := getEnvFunction("must_env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mustEnv([]any{"REDACT_TEST_MUST_ENV"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := functions.RedactSecrets("value: must-env-secret-value"); got != "value: ***" {
+		t.Errorf("must_env value was not registered as a secret: %q", got)
+	}
+}
+
+func TestSecretFunction(t *testing.T) {
+	collector := functions.NewSecretCollector()
+	ctx := functions.WithSecretCollector(t.Context(), collector)
+	secret := functions.GenerateSecretFunctions(ctx)["secret"]
+
+	value := map[string]any{"user": "admin", "password": "p\"ss\nword"}
+	got, err := secret.Func([]any{value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(value, got); diff != "" {
+		t.Errorf("secret() must return the value as it is (-want +got):\n%s", diff)
+	}
+
+	output := `{"password": "p\"ss\nword", "user": "admin", "count": 1}`
+	expected := `{"password": "***", "user": "***", "count": 1}`
+	if got := collector.Redact(output); got != expected {
+		t.Errorf("Redact() = %q, want %q", got, expected)
+	}
+	if _, err := secret.Func([]any{}); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
@@ -0,0 +1,252 @@
+package functions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// SQLQueryMaxRows is the maximum number of rows sql_query returns. A query
+// returning more rows fails rather than being silently truncated.
+const SQLQueryMaxRows = 1000
+
+// sqlDrivers maps the schemes of DSNs to database/sql drivers
+var sqlDrivers = map[string]string{
+	"postgres":   "pgx",
+	"postgresql": "pgx",
+	"mysql":      "mysql",
+}
+
+// sqlDBs caches database handles by DSN and address overrides, so
+// long-lived modes (--watch, serve) reuse the connections between evaluations.
+var sqlDBs = struct {
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}{dbs: make(map[string]*sql.DB)}
+
+// sqlDB returns the database handle for dsn, connecting to the overridden
+// addresses of resolve. The DSN may contain a password, so it is never
+// included in errors.
+func sqlDB(dsn string, resolve []ResolveEntry) (*sql.DB, error) {
+	key := dsn
+	if len(resolve) > 0 {
+		key += " " + resolveCacheKey(resolve)
+	}
+	sqlDBs.mu.Lock()
+	defer sqlDBs.mu.Unlock()
+	if db, ok := sqlDBs.dbs[key]; ok {
+		return db, nil
+	}
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	driver, supported := sqlDrivers[scheme]
+	if !ok || !supported {
+		return nil, errors.New("DSN must start with postgres:// or mysql://")
+	}
+	dataSource := dsn
+	if driver == "mysql" {
+		// go-sql-driver/mysql takes user:password@tcp(host:port)/dbname.
+		// Times are parsed to be formatted as RFC 3339 like PostgreSQL.
+		cfg, err := mysql.ParseDSN(rest)
+		if err != nil {
+			return nil, errors.New("invalid mysql DSN")
+		}
+		cfg.ParseTime = true
+		if len(resolve) > 0 {
+			cfg.DialFunc = resolvingDialer(resolve)
+			connector, err := mysql.NewConnector(cfg)
+			if err != nil {
+				return nil, errors.New("invalid mysql DSN")
+			}
+			db := sql.OpenDB(connector)
+			sqlDBs.dbs[key] = db
+			return db, nil
+		}
+		dataSource = cfg.FormatDSN()
+	}
+	if driver == "pgx" && len(resolve) > 0 {
+		cfg, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s DSN", scheme)
+		}
+		// Leave the host names to the dialer, which knows the overrides
+		cfg.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+			return []string{host}, nil
+		}
+		cfg.DialFunc = resolvingDialer(resolve)
+		db := stdlib.OpenDB(*cfg)
+		sqlDBs.dbs[key] = db
+		return db, nil
+	}
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s DSN", scheme)
+	}
+	sqlDBs.dbs[key] = db
+	return db, nil
+}
+
+// CloseSQLDBs closes the cached database handles, so that the next queries
+// connect again (e.g. on SIGHUP in server mode). Queries in progress complete.
+func CloseSQLDBs() {
+	sqlDBs.mu.Lock()
+	defer sqlDBs.mu.Unlock()
+	for key, db := range sqlDBs.dbs {
+		db.Close()
+		delete(sqlDBs.dbs, key)
+	}
+}
+
+// sqlParam converts a Jsonnet value to a query parameter
+func sqlParam(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			// Integers are passed as integers for integer columns
+			return int64(v), nil
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("must be a string, a number, a boolean or null, got %s", jsonTypeName(v))
+}
+
+// sqlValue converts a column value to a JSON-compatible value. Integers and
+// floats become numbers, times become RFC 3339 strings, and others (including
+// decimals, to keep their precision) become strings.
+func sqlValue(v any, column *sql.ColumnType) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool, float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []byte:
+		if sqlNumericColumn(column.DatabaseTypeName()) {
+			// MySQL returns numbers as text for queries without parameters
+			n, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in column %s: %w", v, column.Name(), err)
+			}
+			return n, nil
+		}
+		return string(v), nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// sqlNumericColumn reports whether the database type is an integer or a
+// floating point number
+func sqlNumericColumn(typeName string) bool {
+	switch strings.TrimPrefix(strings.ToUpper(typeName), "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR",
+		"INT2", "INT4", "INT8", "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "REAL":
+		return true
+	}
+	return false
+}
+
+// sqlQuery runs query in a read-only transaction and returns the rows as objects
+func sqlQuery(ctx context.Context, dsn, query string, params []any) ([]any, error) {
+	db, err := sqlDB(dsn, resolveFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	queryArgs := make([]any, len(params))
+	for i, p := range params {
+		if queryArgs[i], err = sqlParam(p); err != nil {
+			return nil, fmt.Errorf("params[%d] %w", i, err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin a transaction: %w", err)
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	result := []any{}
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result) == SQLQueryMaxRows {
+			return nil, fmt.Errorf("the query returned more than %d rows; narrow it down with WHERE or LIMIT", SQLQueryMaxRows)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan a row: %w", err)
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			v, err := sqlValue(values[i], column)
+			if err != nil {
+				return nil, err
+			}
+			row[column.Name()] = v
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return result, nil
+}
+
+func GenerateSQLFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	funcs := map[string]*jsonnet.NativeFunction{
+		"sql_query": {
+			Params: []ast.Identifier{"dsn", "query", "params"},
+			Func: func(args []any) (any, error) {
+				a := newArgs("sql_query", args)
+				dsn, err := a.String(0, "dsn")
+				if err != nil {
+					return nil, err
+				}
+				query, err := a.String(1, "query")
+				if err != nil {
+					return nil, err
+				}
+				var params []any
+				if !a.isNull(2) {
+					if params, err = a.Array(2, "params"); err != nil {
+						return nil, err
+					}
+				}
+				rows, err := sqlQuery(ctx, dsn, query, params)
+				if err != nil {
+					return nil, fmt.Errorf("sql_query: %w", err)
+				}
+				return rows, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
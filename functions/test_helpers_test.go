@@ -64,6 +64,30 @@ func getRegexpFunction(name string) (func([]any) (any, error), error) {
 	return f.Func, nil
 }
 
+func getCUEFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.CUEFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("cue function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getProtoFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.ProtoFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("proto function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getBinaryFormatFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.BinaryFormatFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("binary format function %s not found", name)
+	}
+	return f.Func, nil
+}
+
 func getJQFunction(name string) (func([]any) (any, error), error) {
 	f, ok := functions.JQFunctions[name]
 	if !ok {
@@ -79,3 +103,59 @@ func getPathFunction(name string) (func([]any) (any, error), error) {
 	}
 	return f.Func, nil
 }
+
+func getBigNumFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.BigNumFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("bignum function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getFakeFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.FakeFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("fake function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getPasswordFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.PasswordFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("password function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getTOTPFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.TOTPFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("totp function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getCronFunction(name string) (func([]any) (any, error), error) {
+	f, ok := functions.CronFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("cron function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getVersionFunction(ctx context.Context, name string) (func([]any) (any, error), error) {
+	f, ok := functions.GenerateVersionFunctions(ctx)[name]
+	if !ok {
+		return nil, fmt.Errorf("version function %s not found", name)
+	}
+	return f.Func, nil
+}
+
+func getRedisFunction(ctx context.Context, name string) (func([]any) (any, error), error) {
+	f, ok := functions.GenerateRedisFunctions(ctx)[name]
+	if !ok {
+		return nil, fmt.Errorf("redis function %s not found", name)
+	}
+	return f.Func, nil
+}
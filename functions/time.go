@@ -1,7 +1,6 @@
 package functions
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/google/go-jsonnet"
@@ -41,14 +40,15 @@ var TimeFunctions = map[string]*jsonnet.NativeFunction{
 	"time_format": {
 		Params: []ast.Identifier{"timestamp", "format"},
 		Func: func(args []any) (any, error) {
-			timestamp, ok := args[0].(float64)
-			if !ok {
-				return nil, fmt.Errorf("time_format: timestamp must be a number")
+			a := newArgs("time_format", args)
+			timestamp, err := a.Number(0, "timestamp")
+			if err != nil {
+				return nil, err
 			}
 
-			format, ok := args[1].(string)
-			if !ok {
-				return nil, fmt.Errorf("time_format: format must be a string")
+			format, err := a.String(1, "format")
+			if err != nil {
+				return nil, err
 			}
 
 			// Convert float64 timestamp to time.Time
@@ -0,0 +1,28 @@
+package functions
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutFunctions are the native functions whose timeout can be set by
+// WithFuncTimeouts
+var TimeoutFunctions = []string{"dns_lookup", "exec", "exec_with_env", "http_get", "http_request"}
+
+type funcTimeoutsKey struct{}
+
+// WithFuncTimeouts returns a context that overrides the default timeouts of
+// the native functions named by the keys
+func WithFuncTimeouts(ctx context.Context, timeouts map[string]time.Duration) context.Context {
+	return context.WithValue(ctx, funcTimeoutsKey{}, timeouts)
+}
+
+// funcTimeout returns the timeout of the named native function carried by
+// ctx, or def if it is not overridden
+func funcTimeout(ctx context.Context, name string, def time.Duration) time.Duration {
+	timeouts, _ := ctx.Value(funcTimeoutsKey{}).(map[string]time.Duration)
+	if d, ok := timeouts[name]; ok && d > 0 {
+		return d
+	}
+	return def
+}
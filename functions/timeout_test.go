@@ -0,0 +1,64 @@
+package functions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFuncTimeout(t *testing.T) {
+	ctx := WithFuncTimeouts(context.Background(), map[string]time.Duration{"exec": 2 * time.Second, "http_get": 0})
+	tests := []struct {
+		name     string
+		expected time.Duration
+	}{
+		{name: "exec", expected: 2 * time.Second},
+		{name: "exec_with_env", expected: DefaultExecTimeout},
+		{name: "http_get", expected: DefaultHttpTimeout}, // not positive
+	}
+	for _, tt := range tests {
+		if got := funcTimeout(ctx, tt.name, tt.expected); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+	if got := funcTimeout(context.Background(), "exec", DefaultExecTimeout); got != DefaultExecTimeout {
+		t.Errorf("expected the default timeout without overrides, got %s", got)
+	}
+}
+
+func TestFuncTimeoutExec(t *testing.T) {
+	ctx := WithFuncTimeouts(t.Context(), map[string]time.Duration{"exec": 200 * time.Millisecond})
+	start := time.Now()
+	_, err := GenerateExecFunctions(ctx)["exec"].Func([]any{"sleep", []any{"5"}})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the timeout of exec was not applied: took %s", elapsed)
+	}
+}
+
+func TestFuncTimeoutHTTP(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx := WithFuncTimeouts(t.Context(), map[string]time.Duration{"http_get": 200 * time.Millisecond})
+	funcs := GenerateHttpFunctions(ctx)
+	start := time.Now()
+	if _, err := funcs["http_get"].Func([]any{server.URL, nil}); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the timeout of http_get was not applied: took %s", elapsed)
+	}
+}
@@ -0,0 +1,144 @@
+package functions
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// TOTP parameters of RFC 6238, used by most authenticator apps
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is the number of periods before and after the current one
+	// accepted by totp_verify, to tolerate clock drift
+	totpSkew = 1
+)
+
+// decodeTOTPSecret decodes a base32 secret, ignoring spaces, case and padding
+func decodeTOTPSecret(fn, secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%s: argument #1 (secret) must be a base32 encoded string", fn)
+	}
+	return key, nil
+}
+
+// totpCode computes the code of the period containing t (RFC 4226, 6238)
+func totpCode(key []byte, t int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t/totpPeriod))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, n%1000000)
+}
+
+// totpTime returns the i-th argument as a Unix timestamp, or the current time if it is null
+func totpTime(a nativeArgs, i int) (int64, error) {
+	if a.isNull(i) {
+		return time.Now().Unix(), nil
+	}
+	ts, err := a.Number(i, "timestamp")
+	if err != nil {
+		return 0, err
+	}
+	return int64(ts), nil
+}
+
+var TOTPFunctions = map[string]*jsonnet.NativeFunction{
+	"totp_generate": {
+		Params: []ast.Identifier{"secret", "timestamp"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("totp_generate", args)
+			secret, err := a.String(0, "secret")
+			if err != nil {
+				return nil, err
+			}
+			key, err := decodeTOTPSecret("totp_generate", secret)
+			if err != nil {
+				return nil, err
+			}
+			t, err := totpTime(a, 1)
+			if err != nil {
+				return nil, err
+			}
+			return totpCode(key, t), nil
+		},
+	},
+	"totp_verify": {
+		Params: []ast.Identifier{"secret", "code", "timestamp"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("totp_verify", args)
+			secret, err := a.String(0, "secret")
+			if err != nil {
+				return nil, err
+			}
+			code, err := a.String(1, "code")
+			if err != nil {
+				return nil, err
+			}
+			key, err := decodeTOTPSecret("totp_verify", secret)
+			if err != nil {
+				return nil, err
+			}
+			t, err := totpTime(a, 2)
+			if err != nil {
+				return nil, err
+			}
+			for skew := -totpSkew; skew <= totpSkew; skew++ {
+				if hmac.Equal([]byte(totpCode(key, t+int64(skew*totpPeriod))), []byte(code)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	},
+	"totp_uri": {
+		Params: []ast.Identifier{"secret", "account", "issuer"},
+		Func: func(args []any) (any, error) {
+			a := newArgs("totp_uri", args)
+			secret, err := a.String(0, "secret")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := decodeTOTPSecret("totp_uri", secret); err != nil {
+				return nil, err
+			}
+			account, err := a.String(1, "account")
+			if err != nil {
+				return nil, err
+			}
+			issuer, err := a.OptionalString(2, "issuer", "")
+			if err != nil {
+				return nil, err
+			}
+
+			// otpauth://totp/Issuer:account?secret=...&issuer=...
+			label := account
+			q := url.Values{}
+			q.Set("secret", strings.ToUpper(strings.TrimRight(strings.Join(strings.Fields(secret), ""), "=")))
+			if issuer != "" {
+				label = issuer + ":" + account
+				q.Set("issuer", issuer)
+			}
+			u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: q.Encode()}
+			return u.String(), nil
+		},
+	},
+}
+
+func init() {
+	initializeFunctionMap(TOTPFunctions)
+}
@@ -0,0 +1,142 @@
+package functions_test
+
+import (
+	"regexp"
+	"testing"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors ("12345678901234567890")
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPGenerate(t *testing.T) {
+	generate, err := getTOTPFunction("totp_generate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    string
+		expectError bool
+	}{
+		{name: "RFC 6238 T=59", args: []any{rfc6238Secret, 59.0}, expected: "287082"},
+		{name: "RFC 6238 T=1111111109", args: []any{rfc6238Secret, 1111111109.0}, expected: "081804"},
+		{name: "RFC 6238 T=1234567890", args: []any{rfc6238Secret, 1234567890.0}, expected: "005924"},
+		{name: "RFC 6238 T=2000000000", args: []any{rfc6238Secret, 2000000000.0}, expected: "279037"},
+		{name: "lower case with spaces and padding", args: []any{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq====", 59.0}, expected: "287082"},
+		{name: "invalid base32", args: []any{"not base32!", 59.0}, expectError: true},
+		{name: "empty secret", args: []any{"", 59.0}, expectError: true},
+		{name: "non-string secret", args: []any{123.0, 59.0}, expectError: true},
+		{name: "non-number timestamp", args: []any{rfc6238Secret, "59"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := generate(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// The current time is used without timestamp
+	result, err := generate([]any{rfc6238Secret, nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9]{6}$`).MatchString(result.(string)) {
+		t.Errorf("invalid code: %v", result)
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	verify, err := getTOTPFunction("totp_verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    bool
+		expectError bool
+	}{
+		{name: "valid", args: []any{rfc6238Secret, "005924", 1234567890.0}, expected: true},
+		{name: "previous period", args: []any{rfc6238Secret, "005924", 1234567890.0 + 30}, expected: true},
+		{name: "next period", args: []any{rfc6238Secret, "005924", 1234567890.0 - 30}, expected: true},
+		{name: "too old", args: []any{rfc6238Secret, "005924", 1234567890.0 + 90}, expected: false},
+		{name: "wrong code", args: []any{rfc6238Secret, "123456", 1234567890.0}, expected: false},
+		{name: "invalid secret", args: []any{"!", "005924", 1234567890.0}, expectError: true},
+		{name: "non-string code", args: []any{rfc6238Secret, 5924.0, 1234567890.0}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := verify(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := getTOTPFunction("totp_uri")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []any
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "with issuer",
+			args:     []any{"jbsw y3dp ehpk 3pxp", "alice@example.com", "Example Corp"},
+			expected: "otpauth://totp/Example%20Corp:alice@example.com?issuer=Example+Corp&secret=JBSWY3DPEHPK3PXP",
+		},
+		{
+			name:     "without issuer",
+			args:     []any{"JBSWY3DPEHPK3PXP", "alice", nil},
+			expected: "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP",
+		},
+		{name: "invalid secret", args: []any{"!", "alice", nil}, expectError: true},
+		{name: "missing account", args: []any{"JBSWY3DPEHPK3PXP"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := uri(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"golang.org/x/mod/semver"
+)
+
+// versionFromContext returns the jsonnet-armed version carried by ctx, or "unknown"
+func versionFromContext(ctx context.Context) string {
+	version, _ := ctx.Value(versionKey).(string)
+	if version == "" {
+		version = "unknown"
+	}
+	return version
+}
+
+// canonicalVersion returns v with a "v" prefix as required by the semver package
+func canonicalVersion(v string) string {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v
+}
+
+// checkVersionConstraint reports whether version satisfies constraint.
+// A constraint is a comma-separated list of comparisons such as
+// ">=0.5.0, <1.0.0". A version without an operator means ">=".
+func checkVersionConstraint(version, constraint string) (bool, error) {
+	current := canonicalVersion(version)
+	if !semver.IsValid(current) {
+		return false, fmt.Errorf("running version %q is not a valid semantic version", version)
+	}
+	for expr := range strings.SplitSeq(constraint, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return false, fmt.Errorf("invalid constraint %q: empty comparison", constraint)
+		}
+		op := ">="
+		for _, o := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+			if strings.HasPrefix(expr, o) {
+				op = o
+				expr = expr[len(o):]
+				break
+			}
+		}
+		required := canonicalVersion(expr)
+		if !semver.IsValid(required) {
+			return false, fmt.Errorf("invalid constraint %q: %q is not a valid semantic version", constraint, strings.TrimSpace(expr))
+		}
+		c := semver.Compare(current, required)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = c >= 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case "<":
+			ok = c < 0
+		case "=", "==":
+			ok = c == 0
+		case "!=":
+			ok = c != 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func GenerateVersionFunctions(ctx context.Context) map[string]*jsonnet.NativeFunction {
+	version := versionFromContext(ctx)
+
+	funcs := map[string]*jsonnet.NativeFunction{
+		"armed_version": {
+			Params: []ast.Identifier{},
+			Func: func(args []any) (any, error) {
+				return version, nil
+			},
+		},
+		"armed_require": {
+			Params: []ast.Identifier{"constraint"},
+			Func: func(args []any) (any, error) {
+				constraint, err := newArgs("armed_require", args).String(0, "constraint")
+				if err != nil {
+					return nil, err
+				}
+				ok, err := checkVersionConstraint(version, constraint)
+				if err != nil {
+					return nil, fmt.Errorf("armed_require: %w", err)
+				}
+				if !ok {
+					return nil, fmt.Errorf("armed_require: jsonnet-armed %s does not satisfy %q", version, constraint)
+				}
+				return true, nil
+			},
+		},
+	}
+
+	initializeFunctionMap(funcs)
+	return funcs
+}
@@ -0,0 +1,67 @@
+package functions_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestArmedVersionFunction(t *testing.T) {
+	ctx := context.WithValue(t.Context(), "version", "v0.5.2")
+	versionFunc, err := getVersionFunction(ctx, "armed_version")
+	if err != nil {
+		t.Fatalf("failed to get armed_version function: %v", err)
+	}
+	result, err := versionFunc([]any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "v0.5.2" {
+		t.Errorf("expected v0.5.2, got %v", result)
+	}
+}
+
+func TestArmedRequireFunction(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		args        []any
+		expectError bool
+	}{
+		{name: "greater or equal", version: "v0.5.2", args: []any{">=0.5.0"}},
+		{name: "equal boundary", version: "v0.5.0", args: []any{">= 0.5.0"}},
+		{name: "bare version means minimum", version: "v0.5.2", args: []any{"0.5"}},
+		{name: "range", version: "v0.5.2", args: []any{">=0.5.0, <1.0.0"}},
+		{name: "exact", version: "v0.5.2", args: []any{"=v0.5.2"}},
+		{name: "not equal", version: "v0.5.2", args: []any{"!=0.5.1"}},
+		{name: "too old", version: "v0.4.9", args: []any{">=0.5.0"}, expectError: true},
+		{name: "too new", version: "v1.0.0", args: []any{">=0.5.0, <1.0.0"}, expectError: true},
+		{name: "prerelease is older than release", version: "v0.5.0-rc1", args: []any{">=0.5.0"}, expectError: true},
+		{name: "invalid constraint", version: "v0.5.2", args: []any{">=five"}, expectError: true},
+		{name: "empty comparison", version: "v0.5.2", args: []any{">=0.5.0,"}, expectError: true},
+		{name: "unknown running version", version: "", args: []any{">=0.5.0"}, expectError: true},
+		{name: "non-string constraint", version: "v0.5.2", args: []any{1.0}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(t.Context(), "version", tt.version)
+			requireFunc, err := getVersionFunction(ctx, "armed_require")
+			if err != nil {
+				t.Fatalf("failed to get armed_require function: %v", err)
+			}
+			result, err := requireFunc(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected error but got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != true {
+				t.Errorf("expected true, got %v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+type warningCollectorKey struct{}
+
+// Warning is a structured warning emitted by a native function during evaluation.
+type Warning struct {
+	Function string `json:"function"`
+	Message  string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Function, w.Message)
+}
+
+// WarningCollector collects warnings emitted during an evaluation.
+// Identical warnings are recorded only once. It is safe for concurrent use.
+type WarningCollector struct {
+	mu       sync.Mutex
+	seen     map[Warning]struct{}
+	warnings []Warning
+}
+
+// NewWarningCollector creates an empty WarningCollector
+func NewWarningCollector() *WarningCollector {
+	return &WarningCollector{seen: make(map[Warning]struct{})}
+}
+
+// Add records a warning unless an identical one was already recorded
+func (c *WarningCollector) Add(w Warning) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.seen[w]; ok {
+		return
+	}
+	c.seen[w] = struct{}{}
+	c.warnings = append(c.warnings, w)
+}
+
+// Warnings returns the recorded warnings in the order they were first emitted
+func (c *WarningCollector) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
+
+// WithWarningCollector returns a context that carries the collector.
+// Native functions generated with the context report their warnings to it.
+func WithWarningCollector(ctx context.Context, c *WarningCollector) context.Context {
+	return context.WithValue(ctx, warningCollectorKey{}, c)
+}
+
+// Warn reports a warning to the collector carried by ctx.
+// It does nothing if ctx has no collector.
+func Warn(ctx context.Context, function, message string) {
+	if c, ok := ctx.Value(warningCollectorKey{}).(*WarningCollector); ok && c != nil {
+		c.Add(Warning{Function: function, Message: message})
+	}
+}
+
+// DeprecatedFunctions maps names of deprecated native functions to a message
+// describing the replacement. Calling a deprecated function emits a warning.
+var DeprecatedFunctions = map[string]string{}
+
+// deprecate returns a copy of f that emits a deprecation warning to the
+// collector carried by ctx each time it is called.
+func deprecate(ctx context.Context, f *jsonnet.NativeFunction, message string) *jsonnet.NativeFunction {
+	return &jsonnet.NativeFunction{
+		Name:   f.Name,
+		Params: f.Params,
+		Func: func(args []any) (any, error) {
+			Warn(ctx, f.Name, "deprecated: "+message)
+			return f.Func(args)
+		},
+	}
+}
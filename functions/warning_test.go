@@ -0,0 +1,70 @@
+package functions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWarningCollector(t *testing.T) {
+	c := functions.NewWarningCollector()
+	ctx := functions.WithWarningCollector(t.Context(), c)
+
+	functions.Warn(ctx, "foo", "first")
+	functions.Warn(ctx, "bar", "second")
+	functions.Warn(ctx, "foo", "first") // duplicate
+
+	expected := []functions.Warning{
+		{Function: "foo", Message: "first"},
+		{Function: "bar", Message: "second"},
+	}
+	if diff := cmp.Diff(expected, c.Warnings()); diff != "" {
+		t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+	}
+
+	// Warn without a collector is a no-op
+	functions.Warn(context.Background(), "foo", "ignored")
+}
+
+func TestDeprecatedFunctions(t *testing.T) {
+	functions.DeprecatedFunctions["base64"] = "use std.base64 instead"
+	t.Cleanup(func() { delete(functions.DeprecatedFunctions, "base64") })
+
+	c := functions.NewWarningCollector()
+	ctx := functions.WithWarningCollector(t.Context(), c)
+
+	var called bool
+	for _, f := range functions.GenerateAllFunctions(ctx) {
+		if f.Name != "base64" {
+			continue
+		}
+		called = true
+		result, err := f.Func([]any{"hello"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != "aGVsbG8=" {
+			t.Errorf("unexpected result: %v", result)
+		}
+	}
+	if !called {
+		t.Fatal("base64 function not found")
+	}
+
+	expected := []functions.Warning{
+		{Function: "base64", Message: "deprecated: use std.base64 instead"},
+	}
+	if diff := cmp.Diff(expected, c.Warnings()); diff != "" {
+		t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+	}
+
+	// The static function map itself must not be wrapped
+	if _, err := functions.Base64Functions["base64"].Func([]any{"hello"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.Warnings()) != 1 {
+		t.Errorf("static function emitted a warning: %v", c.Warnings())
+	}
+}
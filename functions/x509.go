@@ -268,9 +268,9 @@ var X509Functions = map[string]*jsonnet.NativeFunction{
 	"x509_certificate": {
 		Params: []ast.Identifier{"filename"},
 		Func: func(args []any) (any, error) {
-			filename, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("x509_certificate: filename must be a string")
+			filename, err := newArgs("x509_certificate", args).String(0, "filename")
+			if err != nil {
+				return nil, err
 			}
 			return parseCertificate(filename)
 		},
@@ -278,9 +278,9 @@ var X509Functions = map[string]*jsonnet.NativeFunction{
 	"x509_private_key": {
 		Params: []ast.Identifier{"filename"},
 		Func: func(args []any) (any, error) {
-			filename, ok := args[0].(string)
-			if !ok {
-				return nil, fmt.Errorf("x509_private_key: filename must be a string")
+			filename, err := newArgs("x509_private_key", args).String(0, "filename")
+			if err != nil {
+				return nil, err
 			}
 			return parsePrivateKey(filename)
 		},
@@ -0,0 +1,88 @@
+package armed
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// heartbeat tracks the native function calls of an evaluation, to log them
+// while the evaluation takes long (--heartbeat). It is safe for concurrent use.
+type heartbeat struct {
+	mu      sync.Mutex
+	nextID  int
+	running map[int]runningCall
+}
+
+// runningCall is a native function call in progress
+type runningCall struct {
+	name    string
+	started time.Time
+}
+
+func newHeartbeat() *heartbeat {
+	return &heartbeat{running: make(map[int]runningCall)}
+}
+
+// wrap returns copies of funcs that record their calls while running
+func (h *heartbeat) wrap(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				h.mu.Lock()
+				id := h.nextID
+				h.nextID++
+				h.running[id] = runningCall{name: f.Name, started: time.Now()}
+				h.mu.Unlock()
+				defer func() {
+					h.mu.Lock()
+					delete(h.running, id)
+					h.mu.Unlock()
+				}()
+				return f.Func(args)
+			},
+		}
+	}
+	return wrapped
+}
+
+// start logs the progress of the evaluation every interval, until stop is called
+func (h *heartbeat) start(filename string, interval time.Duration) (stop func()) {
+	started := time.Now()
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.log(filename, time.Since(started))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// log logs the elapsed time and the longest running native function call
+func (h *heartbeat) log(filename string, elapsed time.Duration) {
+	attrs := []any{"filename", filename, "elapsed", elapsed.Round(time.Millisecond).String()}
+	h.mu.Lock()
+	var oldest *runningCall
+	for _, c := range h.running {
+		if oldest == nil || c.started.Before(oldest.started) {
+			oldest = &c
+		}
+	}
+	h.mu.Unlock()
+	if oldest != nil {
+		attrs = append(attrs, "function", oldest.name, "function_elapsed", time.Since(oldest.started).Round(time.Millisecond).String())
+	}
+	slog.Info("Evaluation is still running", attrs...)
+}
@@ -0,0 +1,74 @@
+package armed
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHeartbeat(t *testing.T) {
+	var logs syncBuffer
+	orig := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	h := newHeartbeat()
+	release := make(chan struct{})
+	funcs := h.wrap([]*jsonnet.NativeFunction{{
+		Name: "slow",
+		Func: func(args []any) (any, error) {
+			<-release
+			return "done", nil
+		},
+	}})
+	stop := h.start("app.jsonnet", 10*time.Millisecond)
+	defer stop()
+
+	result := make(chan any)
+	go func() {
+		v, _ := funcs[0].Func(nil)
+		result <- v
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "function=slow") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a heartbeat with the running function, got %q", logs.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	if v := <-result; v != "done" {
+		t.Errorf("expected the result of the wrapped function, got %v", v)
+	}
+
+	// The finished call is not reported any more
+	h.log("app.jsonnet", time.Second)
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	last := lines[len(lines)-1]
+	if !strings.Contains(last, `msg="Evaluation is still running"`) || !strings.Contains(last, "filename=app.jsonnet") || strings.Contains(last, "function=") {
+		t.Errorf("unexpected heartbeat after the call finished: %q", last)
+	}
+}
@@ -0,0 +1,67 @@
+package armed
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// NativeCall is a native function call reported to the OnNativeCall hook
+type NativeCall struct {
+	Name     string
+	Args     []any
+	Duration time.Duration
+	Err      error
+}
+
+// OnNativeCall sets a hook called after each native function call of the
+// evaluations, e.g. to show progress. It may be called concurrently.
+func (cli *CLI) OnNativeCall(fn func(call NativeCall)) {
+	cli.onNativeCall = fn
+}
+
+// OnCacheHit sets a hook called when --cache serves a result instead of
+// evaluating; stale is true for a stale result used after a failure.
+func (cli *CLI) OnCacheHit(fn func(key string, stale bool)) {
+	cli.onCacheHit = fn
+}
+
+// OnResult sets a hook called at the end of each run with the written
+// result, or with the error of the run
+func (cli *CLI) OnResult(fn func(result string, err error)) {
+	cli.onResult = fn
+}
+
+// wrapNativeCalls returns copies of funcs that fail once ctx is done, so
+// that a canceled evaluation stops at its next native function call, and
+// that report their calls to the OnNativeCall hook
+func (cli *CLI) wrapNativeCalls(ctx context.Context, funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if cli.onNativeCall == nil {
+					return f.Func(args)
+				}
+				start := time.Now()
+				result, err := f.Func(args)
+				cli.onNativeCall(NativeCall{Name: f.Name, Args: args, Duration: time.Since(start), Err: err})
+				return result, err
+			},
+		}
+	}
+	return wrapped
+}
+
+// reportResult calls the OnResult hook, if any
+func (cli *CLI) reportResult(result string, err error) {
+	if cli.onResult != nil {
+		cli.onResult(result, err)
+	}
+}
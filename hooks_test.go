@@ -0,0 +1,112 @@
+package armed_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+func TestHooks(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{hash: std.native('sha256')('a'), upper: std.native('regex_replace')('a', 'a', 'A')}`)
+
+	var mu sync.Mutex
+	var calls []string
+	var results []string
+	var hits []bool
+	run := func() {
+		t.Helper()
+		cli := &armed.CLI{Filename: jsonnetFile, Cache: time.Hour}
+		cli.SetWriter(&bytes.Buffer{})
+		cli.OnNativeCall(func(call armed.NativeCall) {
+			mu.Lock()
+			defer mu.Unlock()
+			if call.Err != nil {
+				t.Errorf("unexpected error of %s: %v", call.Name, call.Err)
+			}
+			calls = append(calls, call.Name)
+		})
+		cli.OnCacheHit(func(key string, stale bool) {
+			if key == "" {
+				t.Error("empty cache key")
+			}
+			hits = append(hits, stale)
+		})
+		cli.OnResult(func(result string, err error) {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results = append(results, result)
+		})
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	run()
+	run() // served from the cache
+
+	slices.Sort(calls)
+	if diff := cmp.Diff([]string{"regex_replace", "sha256"}, calls); diff != "" {
+		t.Errorf("unexpected calls (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]bool{false}, hits); diff != "" {
+		t.Errorf("unexpected cache hits (-want +got):\n%s", diff)
+	}
+	if len(results) != 2 || results[0] != results[1] {
+		t.Fatalf("unexpected results: %q", results)
+	}
+	compareJSON(t, results[0], `{"hash": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", "upper": "A"}`)
+}
+
+func TestCancelStopsNativeCalls(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{a: std.native('cancel')(), b: std.native('sha256')('b')}`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cli := &armed.CLI{Filename: jsonnetFile}
+	cli.SetWriter(&bytes.Buffer{})
+	cli.AddFunctions(&jsonnet.NativeFunction{
+		Name:   "cancel",
+		Params: ast.Identifiers{},
+		Func: func(args []any) (any, error) {
+			cancel()
+			return "canceled", nil
+		},
+	})
+	var mu sync.Mutex
+	var calls []string
+	done := make(chan error, 1)
+	cli.OnNativeCall(func(call armed.NativeCall) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call.Name)
+	})
+	cli.OnResult(func(result string, err error) {
+		done <- err
+	})
+	if err := cli.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled for OnResult, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range calls {
+		if name == "sha256" {
+			t.Error("a native function was called after the cancellation")
+		}
+	}
+}
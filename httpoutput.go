@@ -0,0 +1,150 @@
+package armed
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// writeOutputToHTTPIfChanged implements --write-if-changed for HTTP(S)
+// outputs. The current ETag of the resource is fetched with HEAD; when it is
+// the MD5 or SHA-256 hex digest of the content, nothing is sent. Otherwise the
+// content is sent with a conditional PUT (If-Match with the fetched ETag, or
+// If-None-Match: * when the resource does not exist), so that an update by
+// another client in between is not overwritten.
+func (cli *CLI) writeOutputToHTTPIfChanged(ctx context.Context, u string, content string) (changed bool, err error) {
+	head, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if err := cli.setOutputHeaders(head); err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(head)
+	if err != nil {
+		return false, &transientError{fmt.Errorf("failed to send HTTP HEAD request: %w", err)}
+	}
+	resp.Body.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, strings.NewReader(content))
+	if err != nil {
+		return false, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	etag := resp.Header.Get("ETag")
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		req.Header.Set("If-None-Match", "*")
+	case resp.StatusCode >= 200 && resp.StatusCode < 300 && etag != "":
+		if etagMatches(etag, content) {
+			return false, nil
+		}
+		req.Header.Set("If-Match", etag)
+	}
+	// Without an ETag, the content is sent unconditionally
+	req.Header.Set("Content-Type", cli.contentType())
+	if err := cli.setOutputHeaders(req); err != nil {
+		return false, err
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		return false, &transientError{fmt.Errorf("failed to send HTTP request: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return false, fmt.Errorf("HTTP request failed with status %d: the resource was changed by another client", resp.StatusCode)
+	}
+	if err := cli.checkOutputResponse(resp); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// maxErrorBodySize limits the response body included in the error of a
+// failed HTTP(S) output
+const maxErrorBodySize = 4096
+
+// checkHTTPOutputFlags rejects malformed --output-header and
+// --output-expect-status values, and an --output-method that conflicts with
+// the conditional PUT of --write-if-changed
+func (cli *CLI) checkHTTPOutputFlags() error {
+	if _, err := cli.outputHeaders(); err != nil {
+		return err
+	}
+	for _, code := range cli.OutputStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid --output-expect-status %d: must be between 100 and 599", code)
+		}
+	}
+	if cli.WriteIfChanged && cli.OutputMethod != "" && cli.OutputMethod != http.MethodPut {
+		return fmt.Errorf("--output-method %s cannot be used with --write-if-changed, which sends a conditional PUT", cli.OutputMethod)
+	}
+	return nil
+}
+
+// outputHeaders parses the --output-header flags
+func (cli *CLI) outputHeaders() (http.Header, error) {
+	header := http.Header{}
+	for _, h := range cli.OutputHeader {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid --output-header %q: must be NAME: VALUE", h)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// setOutputHeaders sets the User-Agent and the --output-header headers of a
+// request to an HTTP(S) output. The headers replace the defaults of the same
+// name, e.g. Content-Type.
+func (cli *CLI) setOutputHeaders(req *http.Request) error {
+	req.Header.Set("User-Agent", "jsonnet-armed/"+Version)
+	header, err := cli.outputHeaders()
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return nil
+}
+
+// checkOutputResponse returns an error when the status of the response of an
+// HTTP(S) output is not one of --output-expect-status (any 2xx by default).
+// The error contains the beginning of the response body, which usually
+// explains the failure.
+func (cli *CLI) checkOutputResponse(resp *http.Response) error {
+	if len(cli.OutputStatus) > 0 {
+		if slices.Contains(cli.OutputStatus, resp.StatusCode) {
+			return nil
+		}
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	msg := strings.TrimSpace(string(body[:min(len(body), maxErrorBodySize)]))
+	if len(body) > maxErrorBodySize {
+		msg += "..."
+	}
+	err := fmt.Errorf("HTTP request failed with status %d: %s", resp.StatusCode, msg)
+	if isTransientStatus(resp.StatusCode) {
+		return &transientError{err}
+	}
+	return err
+}
+
+// etagMatches reports whether an ETag is the MD5 or SHA-256 hex digest of
+// content, as object stores and content-addressed services compute them
+func etagMatches(etag, content string) bool {
+	tag := strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	md5sum := md5.Sum([]byte(content))
+	sha256sum := sha256.Sum256([]byte(content))
+	return strings.EqualFold(tag, hex.EncodeToString(md5sum[:])) || strings.EqualFold(tag, hex.EncodeToString(sha256sum[:]))
+}
@@ -0,0 +1,155 @@
+package armed
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// vmCache keeps Jsonnet VMs between evaluations of long-lived modes
+// (--watch, cron, serve, daemon), so that imported files are not parsed
+// again while their contents are unchanged. A VM caches the parsed AST of
+// each import by path; cachedImporter keys the contents by hash and flushes
+// the VM when a file changed since the last evaluation.
+//
+// It is safe for concurrent use; each evaluation takes its own VM.
+type vmCache struct {
+	mu   sync.Mutex
+	idle []*cachedVM
+}
+
+func newVMCache() *vmCache {
+	return &vmCache{}
+}
+
+// cachedVM is a VM with the importer that owns its import cache
+type cachedVM struct {
+	vm       *jsonnet.VM
+	importer *cachedImporter
+	// errorFormatter is the default of the VM, restored for each evaluation
+	errorFormatter jsonnet.ErrorFormatter
+}
+
+// get takes a VM for an evaluation that imports files with inner
+func (c *vmCache) get(inner *ArmedImporter) *cachedVM {
+	c.mu.Lock()
+	var v *cachedVM
+	if n := len(c.idle); n > 0 {
+		v = c.idle[n-1]
+		c.idle = c.idle[:n-1]
+	}
+	c.mu.Unlock()
+
+	if v == nil {
+		vm := jsonnet.MakeVM()
+		v = &cachedVM{vm: vm, importer: newCachedImporter(), errorFormatter: vm.ErrorFormatter}
+		v.vm.Importer(v.importer)
+	}
+	v.importer.begin(inner)
+	if !v.importer.valid() {
+		// Parsed ASTs of changed files must not be reused
+		v.importer = newCachedImporter()
+		v.importer.begin(inner)
+		v.vm.Importer(v.importer)
+	}
+	v.vm.ExtReset()
+	v.vm.TLAReset()
+	v.vm.ErrorFormatter = v.errorFormatter
+	return v
+}
+
+// put returns a VM after the evaluation
+func (c *vmCache) put(v *cachedVM) {
+	v.importer.inner = nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.idle = append(c.idle, v)
+}
+
+// importStats counts the imports of an evaluation served from the contents
+// (and thus the parsed ASTs) cached by previous evaluations
+type importStats struct {
+	Hits   int
+	Misses int
+}
+
+type cachedContents struct {
+	contents jsonnet.Contents
+	hash     [sha256.Size]byte
+	// importedFrom and importedPath are used to import the file again
+	importedFrom, importedPath string
+}
+
+// cachedImporter returns the same Contents instance for a path while its
+// content hash is unchanged, which lets the VM reuse the parsed AST.
+type cachedImporter struct {
+	inner   *ArmedImporter
+	entries map[string]*cachedContents
+	// seen are the paths imported by the current evaluation
+	seen  map[string]bool
+	stats importStats
+}
+
+func newCachedImporter() *cachedImporter {
+	return &cachedImporter{entries: make(map[string]*cachedContents)}
+}
+
+// begin starts an evaluation importing with inner
+func (ci *cachedImporter) begin(inner *ArmedImporter) {
+	ci.inner = inner
+	ci.seen = make(map[string]bool)
+	ci.stats = importStats{}
+}
+
+// valid reports whether every cached file still has the same content.
+// The files are imported with a separate importer, so that they are not
+// reported as inputs of the evaluation.
+func (ci *cachedImporter) valid() bool {
+	probe := &ArmedImporter{
+		funcs:          ci.inner.funcs,
+		autoArmedFiles: ci.inner.autoArmedFiles,
+		fileImporter:   jsonnet.FileImporter{JPaths: ci.inner.fileImporter.JPaths},
+		remote:         ci.inner.remote,
+	}
+	for foundAt, e := range ci.entries {
+		contents, newFoundAt, err := probe.Import(e.importedFrom, e.importedPath)
+		if err != nil || newFoundAt != foundAt || sha256.Sum256(contents.Data()) != e.hash {
+			return false
+		}
+	}
+	return true
+}
+
+func (ci *cachedImporter) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	contents, foundAt, err := ci.inner.Import(importedFrom, importedPath)
+	if err != nil {
+		return contents, foundAt, err
+	}
+	if e, ok := ci.entries[foundAt]; ok {
+		if !ci.seen[foundAt] {
+			ci.seen[foundAt] = true
+			ci.stats.Hits++
+		}
+		// The content was validated when the evaluation began
+		return e.contents, foundAt, nil
+	}
+	ci.entries[foundAt] = &cachedContents{
+		contents:     contents,
+		hash:         sha256.Sum256(contents.Data()),
+		importedFrom: importedFrom,
+		importedPath: importedPath,
+	}
+	ci.seen[foundAt] = true
+	ci.stats.Misses++
+	return contents, foundAt, nil
+}
+
+// traceImports logs the import cache statistics of an evaluation for --trace
+func (cli *CLI) traceImports(stats importStats) {
+	if !cli.Trace {
+		return
+	}
+	slog.Info("Import cache", "filename", cli.Filename, "hits", stats.Hits, "misses", stats.Misses)
+}
@@ -0,0 +1,94 @@
+package armed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVMCacheReusesImports(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	libFile := filepath.Join(tmpDir, "lib.libsonnet")
+	write := func(filename, content string) {
+		t.Helper()
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(jsonnetFile, `
+	local armed = import 'armed.libsonnet';
+	{
+		lib: import 'lib.libsonnet',
+		name: std.extVar('name'),
+		hash: armed.sha256('x') != '',
+	}`)
+	write(libFile, `{ v: 1 }`)
+
+	vms := newVMCache()
+	// evaluate returns the result and the import cache statistics of an evaluation
+	evaluate := func(name string) (string, importStats) {
+		t.Helper()
+		var out bytes.Buffer
+		cli := &CLI{Filename: jsonnetFile, ExtStr: map[string]string{"name": name}, CompactOutput: true, writer: &out, vms: vms}
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(vms.idle) != 1 {
+			t.Fatalf("expected 1 idle VM, got %d", len(vms.idle))
+		}
+		return strings.TrimSpace(out.String()), vms.idle[0].importer.stats
+	}
+
+	tests := []struct {
+		name   string
+		change func()
+		want   string
+		stats  importStats
+	}{
+		{
+			name: "first evaluation parses all files",
+			want: `{"hash":true,"lib":{"v":1},"name":"a"}`,
+			// main.jsonnet, armed.libsonnet and lib.libsonnet
+			stats: importStats{Misses: 3},
+		},
+		{
+			name:  "unchanged files are reused",
+			want:  `{"hash":true,"lib":{"v":1},"name":"a"}`,
+			stats: importStats{Hits: 3},
+		},
+		{
+			name:   "changed import is parsed again",
+			change: func() { write(libFile, `{ v: 2 }`) },
+			want:   `{"hash":true,"lib":{"v":2},"name":"a"}`,
+			stats:  importStats{Misses: 3},
+		},
+		{
+			name:  "changed import is cached again",
+			want:  `{"hash":true,"lib":{"v":2},"name":"a"}`,
+			stats: importStats{Hits: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				tt.change()
+			}
+			got, stats := evaluate("a")
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("got stats %+v, want %+v", stats, tt.stats)
+			}
+		})
+	}
+
+	// External variables are set for each evaluation
+	got, _ := evaluate("b")
+	if want := `{"hash":true,"lib":{"v":2},"name":"b"}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
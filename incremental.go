@@ -0,0 +1,341 @@
+package armed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// incrementalPolicy classifies native functions for --incremental
+type incrementalPolicy int
+
+const (
+	// incrementalPure functions depend only on their arguments
+	incrementalPure incrementalPolicy = iota
+	// incrementalTracked functions read external state (files, environment,
+	// network); their calls are recorded and repeated to detect changes
+	incrementalTracked
+)
+
+// incrementalPolicies lists the functions that do not prevent --incremental.
+// Other functions (now, uuid_*, exec, http_request, user-defined ones, ...)
+// are volatile: an evaluation using them is never skipped.
+var incrementalPolicies = map[string]incrementalPolicy{
+	"armed_require":           incrementalPure,
+	"armed_version":           incrementalPure,
+	"base64":                  incrementalPure,
+	"base64url":               incrementalPure,
+	"basename":                incrementalPure,
+	"bigint_add":              incrementalPure,
+	"bigint_cmp":              incrementalPure,
+	"bigint_mul":              incrementalPure,
+	"bigint_sub":              incrementalPure,
+	"cbor_decode":             incrementalPure,
+	"cron_validate":           incrementalPure,
+	"cue_validate":            incrementalPure,
+	"decimal_add":             incrementalPure,
+	"decimal_cmp":             incrementalPure,
+	"decimal_div":             incrementalPure,
+	"decimal_mul":             incrementalPure,
+	"decimal_round":           incrementalPure,
+	"decimal_sub":             incrementalPure,
+	"dirname":                 incrementalPure,
+	"env_parse":               incrementalPure,
+	"extname":                 incrementalPure,
+	"jq":                      incrementalPure,
+	"md5":                     incrementalPure,
+	"msgpack_decode":          incrementalPure,
+	"path_join":               incrementalPure,
+	"regex_captures":          incrementalPure,
+	"regex_find":              incrementalPure,
+	"regex_find_all":          incrementalPure,
+	"regex_find_all_submatch": incrementalPure,
+	"regex_match":             incrementalPure,
+	"regex_replace":           incrementalPure,
+	"regex_split":             incrementalPure,
+	"secret":                  incrementalPure,
+	"sha1":                    incrementalPure,
+	"sha256":                  incrementalPure,
+	"sha512":                  incrementalPure,
+	"time_format":             incrementalPure,
+	"totp_uri":                incrementalPure,
+
+	"aws_cfn_output":     incrementalTracked,
+	"aws_cfn_outputs":    incrementalTracked,
+	"aws_dynamodb_get":   incrementalTracked,
+	"dns_lookup":         incrementalTracked,
+	"env":                incrementalTracked,
+	"env_all":            incrementalTracked,
+	"file_content":       incrementalTracked,
+	"file_exists":        incrementalTracked,
+	"file_stat":          incrementalTracked,
+	"github_release":     incrementalTracked,
+	"http_get":           incrementalTracked,
+	"ldap_search":        incrementalTracked,
+	"md5_file":           incrementalTracked,
+	"must_env":           incrementalTracked,
+	"net_port_listening": incrementalTracked,
+	"proto_decode":       incrementalTracked,
+	"redis_get":          incrementalTracked,
+	"redis_hgetall":      incrementalTracked,
+	"sha1_file":          incrementalTracked,
+	"sha256_file":        incrementalTracked,
+	"sha512_file":        incrementalTracked,
+	"sql_query":          incrementalTracked,
+	"x509_certificate":   incrementalTracked,
+	"x509_private_key":   incrementalTracked,
+}
+
+// incrementalState is what --incremental records about the last run.
+// Only hashes are stored, never contents.
+type incrementalState struct {
+	// Files maps input files (the file, overlays, imports, mock) to content hashes
+	Files map[string]string `json:"files"`
+	// Calls are the calls of tracked native functions and their result hashes
+	Calls []trackedCall `json:"calls,omitempty"`
+	// Outputs maps output files to content hashes
+	Outputs map[string]string `json:"outputs"`
+}
+
+type trackedCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+	Hash string          `json:"hash"`
+}
+
+// inputTracker records the calls of native functions during an evaluation.
+// It is safe for concurrent use.
+type inputTracker struct {
+	mu       sync.Mutex
+	calls    map[string]trackedCall
+	volatile map[string]bool
+}
+
+func newInputTracker() *inputTracker {
+	return &inputTracker{calls: make(map[string]trackedCall), volatile: make(map[string]bool)}
+}
+
+// wrap returns copies of funcs that record calls of tracked and volatile functions
+func (tr *inputTracker) wrap(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		policy, known := incrementalPolicies[f.Name]
+		if known && policy == incrementalPure {
+			wrapped[i] = f
+			continue
+		}
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				result, err := f.Func(args)
+				tr.mu.Lock()
+				defer tr.mu.Unlock()
+				if !known {
+					tr.volatile[f.Name] = true
+					return result, err
+				}
+				key, encodedArgs, kerr := interactionKey(f.Name, args)
+				if kerr != nil {
+					tr.volatile[f.Name] = true
+					return result, err
+				}
+				if _, ok := tr.calls[key]; !ok {
+					tr.calls[key] = trackedCall{Name: f.Name, Args: encodedArgs, Hash: resultHash(result, err)}
+				}
+				return result, err
+			},
+		}
+	}
+	return wrapped
+}
+
+// checkIncrementalFlags checks that --incremental is used with outputs that
+// persist between runs
+func (cli *CLI) checkIncrementalFlags() error {
+	if !cli.Incremental {
+		return nil
+	}
+	switch {
+	case cli.Filename == "-" || cli.ExtStrStdin != "":
+		return fmt.Errorf("--incremental cannot be used with stdin")
+	case isRemoteInput(cli.Filename):
+		return fmt.Errorf("--incremental cannot be used with a remote input")
+	case cli.Exec != "":
+		return fmt.Errorf("--incremental cannot be used with -e/--exec")
+	case len(cli.Output) == 0:
+		return fmt.Errorf("--incremental requires -o/--output")
+	case cli.Record != "" || cli.Replay != "":
+		return fmt.Errorf("--incremental cannot be used with --record or --replay")
+	}
+	return nil
+}
+
+// resultHash hashes the result of a native function call
+func resultHash(result any, err error) string {
+	if err != nil {
+		return hashString("error:" + err.Error())
+	}
+	b, merr := json.Marshal(result)
+	if merr != nil {
+		return hashString("unencodable")
+	}
+	return hashString(string(b))
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// fileHash returns the hash of a file's content, or "" if it does not exist
+func fileHash(filename string) string {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+	return hashString(string(b))
+}
+
+// incrementalStatePath returns the state file of the run. The key covers
+// the flags (including ext vars and outputs) and the file path.
+func (cli *CLI) incrementalStatePath() (string, error) {
+	cliJSON, err := json.Marshal(cli)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal CLI for incremental state: %w", err)
+	}
+	absPath, err := filepath.Abs(cli.Filename)
+	if err != nil {
+		return "", err
+	}
+	key := hashString(string(cliJSON) + "\x00" + absPath)
+	return filepath.Join(getCacheDir(), "incremental", key+".json"), nil
+}
+
+// checkIncremental reports whether the evaluation can be skipped because the
+// inputs and outputs are the same as at the end of the last run
+func (cli *CLI) checkIncremental(ctx context.Context) (bool, error) {
+	path, err := cli.incrementalStatePath()
+	if err != nil {
+		return false, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	var state incrementalState
+	if err := json.Unmarshal(b, &state); err != nil {
+		slog.Warn("Ignoring invalid incremental state", "path", path, "error", err.Error())
+		return false, nil
+	}
+
+	for _, m := range []map[string]string{state.Files, state.Outputs} {
+		for filename, hash := range m {
+			if fileHash(filename) != hash {
+				slog.Info("Input or output changed, evaluating", "filename", cli.Filename, "changed", filename)
+				return false, nil
+			}
+		}
+	}
+	if len(state.Calls) == 0 {
+		return true, nil
+	}
+
+	nctx, err := cli.nativeContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	funcs, err := cli.nativeFunctions(nctx)
+	if err != nil {
+		return false, err
+	}
+	byName := make(map[string]*jsonnet.NativeFunction, len(funcs))
+	for _, f := range funcs {
+		byName[f.Name] = f
+	}
+	for _, c := range state.Calls {
+		f, ok := byName[c.Name]
+		if !ok {
+			return false, nil
+		}
+		var args []any
+		if err := json.Unmarshal(c.Args, &args); err != nil {
+			return false, nil
+		}
+		if resultHash(f.Func(args)) != c.Hash {
+			slog.Info("Input changed, evaluating", "filename", cli.Filename, "changed", fmt.Sprintf("%s(%s)", c.Name, formatArgs(args)))
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// saveIncremental records the state of a successful run. Nothing is recorded
+// (and a previous state is removed) when volatile functions were called.
+func (cli *CLI) saveIncremental() error {
+	path, err := cli.incrementalStatePath()
+	if err != nil {
+		return err
+	}
+	tr := cli.tracker
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.volatile) > 0 {
+		names := make([]string, 0, len(tr.volatile))
+		for name := range tr.volatile {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		slog.Info("Evaluation is not skippable by --incremental", "filename", cli.Filename, "functions", names)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	state := incrementalState{Files: make(map[string]string), Outputs: make(map[string]string)}
+	files := slices.Clone(cli.inputs)
+	files = append(files, cli.Filename, cli.Mock, cli.OutputTemplate)
+	files = append(files, cli.Overlays...)
+	files = append(files, cli.varFiles()...)
+	for _, f := range files {
+		if f != "" {
+			state.Files[f] = fileHash(f)
+		}
+	}
+	for _, out := range cli.fileOutputs() {
+		state.Outputs[out] = fileHash(out)
+	}
+	for _, c := range tr.calls {
+		state.Calls = append(state.Calls, c)
+	}
+	sort.Slice(state.Calls, func(i, j int) bool {
+		if state.Calls[i].Name != state.Calls[j].Name {
+			return state.Calls[i].Name < state.Calls[j].Name
+		}
+		return string(state.Calls[i].Args) < string(state.Calls[j].Args)
+	})
+
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b, 0600)
+}
@@ -0,0 +1,135 @@
+package armed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("ARMED_INCREMENTAL_TEST", "a")
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	libFile := filepath.Join(tmpDir, "lib.libsonnet")
+	dataFile := filepath.Join(tmpDir, "data.txt")
+	outputFile := filepath.Join(tmpDir, "out.json")
+	templateFile := filepath.Join(tmpDir, "out.gotmpl")
+	write := func(filename, content string) {
+		t.Helper()
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(jsonnetFile, `
+	local armed = import 'armed.libsonnet';
+	{
+		lib: import 'lib.libsonnet',
+		data: armed.file_content("`+dataFile+`"),
+		env: armed.env("ARMED_INCREMENTAL_TEST", ""),
+		hash: armed.sha256("x"),
+	}`)
+	write(libFile, `{ v: 1 }`)
+	write(dataFile, "one")
+	write(templateFile, `{"v": {{ .lib.v }}, "data": "{{ .data }}", "env": "{{ .env }}"}`)
+
+	// run reports whether the output file was written by the run
+	run := func() bool {
+		t.Helper()
+		before, _ := os.Stat(outputFile)
+		cli := &CLI{Filename: jsonnetFile, Output: []string{outputFile}, OutputTemplate: templateFile, Incremental: true}
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		after, err := os.Stat(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return before == nil || !os.SameFile(before, after)
+	}
+
+	if !run() {
+		t.Fatal("first run must evaluate")
+	}
+	if run() {
+		t.Error("run with unchanged inputs must be skipped")
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+	}{
+		{name: "imported file", change: func() { write(libFile, `{ v: 2 }`) }},
+		{name: "file read by a native function", change: func() { write(dataFile, "two") }},
+		{name: "environment variable", change: func() { t.Setenv("ARMED_INCREMENTAL_TEST", "b") }},
+		{name: "output template", change: func() {
+			write(templateFile, `{"v": {{ .lib.v }}, "data": "{{ .data }}", "env": "{{ .env }}", "template": 2}`)
+		}},
+		{name: "output file", change: func() { write(outputFile, "{}") }},
+		{name: "removed output file", change: func() { os.Remove(outputFile) }},
+	}
+	for _, step := range steps {
+		step.change()
+		if !run() {
+			t.Errorf("run after changing the %s must evaluate", step.name)
+		}
+		if run() {
+			t.Errorf("run after re-evaluating for the %s must be skipped", step.name)
+		}
+	}
+	b, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"v": 2`, `"data": "two"`, `"env": "b"`, `"template": 2`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("output does not contain %s: %s", want, b)
+		}
+	}
+}
+
+func TestIncrementalVolatile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	outputFile := filepath.Join(tmpDir, "out.json")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ id: std.native("uuid_v4")() }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var outputs []string
+	for range 2 {
+		cli := &CLI{Filename: jsonnetFile, Output: []string{outputFile}, Incremental: true}
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, string(b))
+	}
+	if outputs[0] == outputs[1] {
+		t.Error("evaluation using a volatile function must not be skipped")
+	}
+}
+
+func TestIncrementalFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		cli  CLI
+		want string
+	}{
+		{name: "stdin", cli: CLI{Filename: "-", Output: []string{"out.json"}, Incremental: true}, want: "stdin"},
+		{name: "no output", cli: CLI{Filename: "a.jsonnet", Incremental: true}, want: "requires -o/--output"},
+		{name: "replay", cli: CLI{Filename: "a.jsonnet", Output: []string{"out.json"}, Replay: "c.json", Incremental: true}, want: "--replay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cli.checkIncrementalFlags()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -499,6 +499,31 @@ func TestIntegrationExamples(t *testing.T) {
 				"empty_result":  nil,
 			},
 		},
+		{
+			name: "CUE validation function example",
+			jsonnet: `
+			local cue_validate = std.native("cue_validate");
+			local schema = |||
+				#Config: {
+					port: int & >0 & <65536
+					name: string
+				}
+				#Config
+			|||;
+			{
+				valid: cue_validate(schema, { port: 8080, name: "app" }),
+				invalid: cue_validate(schema, { port: 0, name: "app" }),
+			}`,
+			expected: map[string]any{
+				"valid": map[string]any{"valid": true, "errors": []any{}},
+				"invalid": map[string]any{
+					"valid": false,
+					"errors": []any{
+						map[string]any{"path": "port", "message": "invalid value 0 (out of bound >0)"},
+					},
+				},
+			},
+		},
 		{
 			name: "Network port listening function example",
 			jsonnet: `
@@ -561,6 +586,128 @@ func TestIntegrationExamples(t *testing.T) {
 				"joined_file": "home/user/file.txt",
 			},
 		},
+		{
+			name: "Big number functions example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			{
+				next_id: armed.bigint_add("9007199254740993", "1"),
+				is_newer: armed.bigint_cmp("18446744073709551615", "9007199254740993") > 0,
+				total: armed.decimal_mul("19.99", "3"),
+				with_tax: armed.decimal_round(armed.decimal_mul(armed.decimal_mul("19.99", "3"), "1.1"), 2),
+				per_person: armed.decimal_div("100.00", "3", 2),
+			}`,
+			expected: map[string]any{
+				"next_id":    "9007199254740994",
+				"is_newer":   true,
+				"total":      "59.97",
+				"with_tax":   "65.97",
+				"per_person": "33.33",
+			},
+		},
+		{
+			name: "Fake data function example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			local user(i) = {
+				name: armed.fake("name", "user-%d" % i),
+				email: armed.fake("email", "user-%d" % i),
+			};
+			{
+				reproducible: user(1) == user(1),
+				distinct: user(1) != user(2),
+				private_ip: std.startsWith(armed.fake("ipv4", 1), "10."),
+				uuid_v4: armed.fake("uuid", "user-1"),
+			}`,
+			expected: map[string]any{
+				"reproducible": true,
+				"distinct":     true,
+				"private_ip":   true,
+				"uuid_v4":      "<valid_uuid_v4>",
+			},
+		},
+		{
+			name: "Password functions example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			local pin = armed.password_generate({ length: 6, classes: ['digit'] });
+			{
+				default_length: std.length(armed.password_generate()),
+				pin_is_digits: std.length(pin) == 6 && std.all([std.member('0123456789', c) for c in std.stringChars(pin)]),
+			}`,
+			expected: map[string]any{
+				"default_length": 16.0,
+				"pin_is_digits":  true,
+			},
+		},
+		{
+			name: "TOTP functions example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			local secret = 'GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ';
+			{
+				otp: armed.totp_generate(secret, 1234567890),
+				valid: armed.totp_verify(secret, '005924', 1234567890),
+				now_valid: armed.totp_verify(secret, armed.totp_generate(secret)),
+				uri: armed.totp_uri(secret, 'alice'),
+			}`,
+			expected: map[string]any{
+				"otp":       "005924",
+				"valid":     true,
+				"now_valid": true,
+				"uri":       "otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			},
+		},
+		{
+			name: "Cron functions example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			{
+				backup: armed.cron_validate('30 2 * * mon-fri'),
+				typo: armed.cron_validate('0 25 * * *').valid,
+			}`,
+			expected: map[string]any{
+				"backup": map[string]any{
+					"valid":       true,
+					"error":       nil,
+					"normalized":  "30 2 * * 1-5",
+					"description": "At 02:30 on Monday through Friday.",
+				},
+				"typo": false,
+			},
+		},
+		{
+			name: "Protobuf decoding function example",
+			jsonnet: `
+			local proto_decode = std.native("proto_decode");
+			local event = proto_decode("testdata/event.pb", "example.Event", "CgVldnQtMRADGgFhGgFiIAEqAggB");
+			{
+				id: event.id,
+				retry_count: event.retry_count,
+				kind: event.kind,
+				ok: event.detail.ok,
+			}`,
+			expected: map[string]any{
+				"id":          "evt-1",
+				"retry_count": float64(3),
+				"kind":        "KIND_CREATED",
+				"ok":          true,
+			},
+		},
+		{
+			name: "MessagePack and CBOR functions example",
+			jsonnet: `
+			local msgpack_decode = std.native("msgpack_decode");
+			local cbor_decode = std.native("cbor_decode");
+			{
+				from_msgpack: msgpack_decode("gqJpZAGkbmFtZaZkZXZpY2U="),
+				from_cbor: cbor_decode("omJpZAFkbmFtZWZkZXZpY2U="),
+			}`,
+			expected: map[string]any{
+				"from_msgpack": map[string]any{"id": float64(1), "name": "device"},
+				"from_cbor":    map[string]any{"id": float64(1), "name": "device"},
+			},
+		},
 		{
 			name: "X509 certificate and private key functions example",
 			jsonnet: `
@@ -620,6 +767,35 @@ func TestIntegrationExamples(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "armed.libsonnet default arguments",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			{
+				missing: armed.env('JSONNET_ARMED_NOT_SET'),
+				echo: armed.exec('echo').stdout,
+				formatted: armed.time_format(0),
+				named: armed.time_format(0, format='DateOnly'),
+			}`,
+			expected: map[string]any{
+				"missing":   nil,
+				"echo":      "\n",
+				"formatted": "1970-01-01T00:00:00Z",
+				"named":     "1970-01-01",
+			},
+		},
+		{
+			name: "README version example",
+			jsonnet: `
+			local armed = import 'armed.libsonnet';
+			assert armed.armed_require(">=0.0.1");
+			{
+				version: armed.armed_version(),
+			}`,
+			expected: map[string]any{
+				"version": armed.Version,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,109 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/go-jsonnet"
+)
+
+// LambdaCmd runs jsonnet-armed as an AWS Lambda function handler.
+// Each invocation evaluates the file named by the event.
+type LambdaCmd struct {
+	Timeout time.Duration `short:"t" name:"timeout" help:"Timeout for each invocation's evaluation (e.g., 30s, 5m)"`
+
+	AWSFlags `embed:""`
+
+	// functions holds additional native functions to be added to the Jsonnet VM
+	functions []*jsonnet.NativeFunction `kong:"-"`
+}
+
+// LambdaEvent is the payload of an invocation
+type LambdaEvent struct {
+	// Filename is a path in the function package or a layer (e.g. /opt/config.jsonnet),
+	// or an s3://bucket/key URL.
+	Filename string            `json:"filename"`
+	ExtStr   map[string]string `json:"ext_str,omitempty"`
+	ExtCode  map[string]string `json:"ext_code,omitempty"`
+	// Output lists the destinations of the result, as -o/--output does.
+	// When empty, the result is returned as the invocation response.
+	Output []string `json:"output,omitempty"`
+}
+
+// LambdaOutputResponse is the invocation response when the result was written to outputs
+type LambdaOutputResponse struct {
+	Outputs []string `json:"outputs"`
+}
+
+// AddFunctions adds custom native functions to the evaluations
+func (l *LambdaCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
+	l.functions = append(l.functions, funcs...)
+}
+
+// Run starts the Lambda runtime loop. It does not return unless the runtime fails.
+func (l *LambdaCmd) Run(ctx context.Context) error {
+	lambda.StartWithOptions(l.handle, lambda.WithContext(ctx))
+	return nil
+}
+
+// isLambdaRuntime reports whether the process runs in the AWS Lambda runtime
+func isLambdaRuntime() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != ""
+}
+
+func (l *LambdaCmd) handle(ctx context.Context, event LambdaEvent) (any, error) {
+	if event.Filename == "" {
+		return nil, fmt.Errorf("filename is required in the event")
+	}
+	var buf bytes.Buffer
+	cli := &CLI{
+		Filename:  event.Filename,
+		ExtStr:    event.ExtStr,
+		ExtCode:   event.ExtCode,
+		Output:    event.Output,
+		Timeout:   l.Timeout,
+		AWSFlags:  l.AWSFlags,
+		writer:    &buf,
+		functions: l.functions,
+	}
+	if bucket, key, ok := parseS3URL(event.Filename); ok {
+		filename, cleanup, err := cli.downloadS3File(ctx, bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		cli.Filename = filename
+	}
+	if err := cli.runOnce(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", event.Filename, err)
+	}
+	if len(event.Output) > 0 {
+		return LambdaOutputResponse{Outputs: event.Output}, nil
+	}
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+// downloadS3File saves an S3 object to a temporary directory, keeping its base name
+func (cli *CLI) downloadS3File(ctx context.Context, bucket, key string) (string, func(), error) {
+	b, err := cli.readS3Object(ctx, bucket, key)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "jsonnet-armed-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	filename := filepath.Join(dir, filepath.Base(key))
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return filename, cleanup, nil
+}
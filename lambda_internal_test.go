@@ -0,0 +1,146 @@
+package armed
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLambdaHandle(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "config.jsonnet")
+	code := `{ env: std.extVar("env"), replicas: std.extVar("replicas") }`
+	if err := os.WriteFile(jsonnetFile, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := &LambdaCmd{}
+
+	t.Run("return result", func(t *testing.T) {
+		res, err := l.handle(t.Context(), LambdaEvent{
+			Filename: jsonnetFile,
+			ExtStr:   map[string]string{"env": "prod"},
+			ExtCode:  map[string]string{"replicas": "3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(`{"env":"prod","replicas":3}`, string(b)); diff != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("write output", func(t *testing.T) {
+		outputFile := filepath.Join(tmpDir, "out.json")
+		res, err := l.handle(t.Context(), LambdaEvent{
+			Filename: jsonnetFile,
+			ExtStr:   map[string]string{"env": "dev"},
+			ExtCode:  map[string]string{"replicas": "1"},
+			Output:   []string{outputFile},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(LambdaOutputResponse{Outputs: []string{outputFile}}, res); diff != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", diff)
+		}
+		b, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("{\n   \"env\": \"dev\",\n   \"replicas\": 1\n}\n", string(b)); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := l.handle(t.Context(), LambdaEvent{}); err == nil {
+			t.Error("expected error for missing filename")
+		}
+		if _, err := l.handle(t.Context(), LambdaEvent{Filename: jsonnetFile}); err == nil {
+			t.Error("expected error for missing ext vars")
+		}
+	})
+}
+
+func TestLambdaHandleS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{
+		"/bucket/templates/config.jsonnet": `{ name: std.extVar("name") }`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+			io.WriteString(w, body)
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(b)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", ts.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	l := &LambdaCmd{}
+	res, err := l.handle(t.Context(), LambdaEvent{
+		Filename: "s3://bucket/templates/config.jsonnet",
+		ExtStr:   map[string]string{"name": "app"},
+		Output:   []string{"s3://bucket/outputs/config.json"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(LambdaOutputResponse{Outputs: []string{"s3://bucket/outputs/config.json"}}, res); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+	mu.Lock()
+	got := objects["/bucket/outputs/config.json"]
+	mu.Unlock()
+	if diff := cmp.Diff("{\n   \"name\": \"app\"\n}\n", got); diff != "" {
+		t.Errorf("uploaded object mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := l.handle(t.Context(), LambdaEvent{Filename: "s3://bucket/missing.jsonnet"}); err == nil {
+		t.Error("expected error for missing object")
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	tests := []struct {
+		in          string
+		bucket, key string
+		ok          bool
+	}{
+		{in: "s3://bucket/path/to/file.json", bucket: "bucket", key: "path/to/file.json", ok: true},
+		{in: "s3://bucket/", ok: false},
+		{in: "s3:///key", ok: false},
+		{in: "https://bucket/key", ok: false},
+		{in: "out.json", ok: false},
+	}
+	for _, tt := range tests {
+		bucket, key, ok := parseS3URL(tt.in)
+		if bucket != tt.bucket || key != tt.key || ok != tt.ok {
+			t.Errorf("parseS3URL(%q) = %q, %q, %v; want %q, %q, %v", tt.in, bucket, key, ok, tt.bucket, tt.key, tt.ok)
+		}
+	}
+}
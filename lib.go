@@ -0,0 +1,64 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// libHelpersVar is the name of the ext code variable bound to the helpers
+// object of --lib-helpers
+const libHelpersVar = "helpers"
+
+// checkLibDirs checks that the --lib directories exist
+func (cli *CLI) checkLibDirs() error {
+	for _, dir := range cli.Lib {
+		st, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid --lib: %w", err)
+		}
+		if !st.IsDir() {
+			return fmt.Errorf("invalid --lib: %s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// libHelpersCode returns Jsonnet code of an object importing the *.libsonnet
+// files of the --lib directories, keyed by their names without the extension.
+// As with imports, files of later directories take precedence.
+func (cli *CLI) libHelpersCode() (string, error) {
+	files := make(map[string]string)
+	for _, dir := range cli.Lib {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.libsonnet"))
+		if err != nil {
+			return "", err
+		}
+		for _, m := range matches {
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				return "", err
+			}
+			files[strings.TrimSuffix(filepath.Base(m), ".libsonnet")] = abs
+		}
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("{")
+	for _, name := range names {
+		// JSON strings are valid Jsonnet strings
+		key, _ := json.Marshal(name)
+		path, _ := json.Marshal(files[name])
+		fmt.Fprintf(&b, " %s: import %s,", key, path)
+	}
+	b.WriteString(" }")
+	return b.String(), nil
+}
@@ -0,0 +1,95 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLILib(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared")
+	override := filepath.Join(dir, "override")
+	for _, d := range []string{shared, override} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(shared, "strings.libsonnet"), `{ upper(s):: std.asciiUpper(s) }`)
+	writeFile(t, filepath.Join(shared, "labels.libsonnet"), `{ app(name):: { app: name, team: "shared" } }`)
+	writeFile(t, filepath.Join(override, "labels.libsonnet"), `{ app(name):: { app: name, team: "override" } }`)
+	writeFile(t, filepath.Join(shared, "my-helpers.libsonnet"), `{ answer: 42 }`)
+	writeFile(t, filepath.Join(shared, "README.md"), `not a library`)
+
+	tests := []struct {
+		name       string
+		code       string
+		lib        []string
+		libHelpers bool
+		expected   string
+	}{
+		{
+			name:     "import by name",
+			code:     `local s = import 'strings.libsonnet'; { name: s.upper("web") }`,
+			lib:      []string{shared},
+			expected: `{"name": "WEB"}`,
+		},
+		{
+			name:     "later directories take precedence",
+			code:     `(import 'labels.libsonnet').app("web")`,
+			lib:      []string{shared, override},
+			expected: `{"app": "web", "team": "override"}`,
+		},
+		{
+			name:       "helpers object",
+			code:       `local h = std.extVar('helpers'); { name: h.strings.upper("web"), labels: h.labels.app("web"), answer: h["my-helpers"].answer, keys: std.objectFields(h) }`,
+			lib:        []string{shared, override},
+			libHelpers: true,
+			expected:   `{"name": "WEB", "labels": {"app": "web", "team": "override"}, "answer": 42, "keys": ["labels", "my-helpers", "strings"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "main.jsonnet")
+			writeFile(t, file, tt.code)
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, Lib: tt.lib, LibHelpers: tt.libHelpers}
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLILibErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `{}`)
+
+	tests := []struct {
+		name       string
+		lib        []string
+		libHelpers bool
+		want       string
+	}{
+		{name: "missing directory", lib: []string{filepath.Join(dir, "missing")}, want: "invalid --lib"},
+		{name: "not a directory", lib: []string{file}, want: "is not a directory"},
+		{name: "helpers without lib", libHelpers: true, want: "--lib-helpers requires --lib"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := &armed.CLI{Filename: file, Lib: tt.lib, LibHelpers: tt.libHelpers}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package armed
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/linter"
+)
+
+// Checks reported by the lint command
+const (
+	lintCheckJsonnet       = "jsonnet"
+	lintCheckUnknownNative = "unknown-native"
+	lintCheckDeprecated    = "deprecated"
+)
+
+// LintCmd reports problems in jsonnet files: those found by the linter of
+// go-jsonnet (syntax errors, unused variables, type errors, ...) and calls
+// of native functions that do not exist or are deprecated.
+type LintCmd struct {
+	Lib    []string `short:"J" name:"lib" aliases:"jpath" placeholder:"DIR" type:"path" help:"Make the files in DIR importable by their names (can be repeated)."`
+	Format string   `name:"format" enum:"text,json" default:"text" help:"Output format of the diagnostics: text, or json (one object per line)."`
+	Paths  []string `arg:"" name:"path" help:"Files or directories to search for *.jsonnet and *.libsonnet files (default: current directory)" type:"path" optional:""`
+
+	// writer for the diagnostics (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+
+	// functions holds additional native functions known to the templates
+	functions []*jsonnet.NativeFunction `kong:"-"`
+}
+
+// lintDiagnostic is a problem found by the lint command
+type lintDiagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+func (d lintDiagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.File, d.Line, d.Column, d.Message, d.Check)
+}
+
+// SetWriter sets the writer for the diagnostics
+func (l *LintCmd) SetWriter(w io.Writer) {
+	l.writer = w
+}
+
+// AddFunctions adds custom native functions, so that calling them is not
+// reported as unknown
+func (l *LintCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
+	l.functions = append(l.functions, funcs...)
+}
+
+// Run lints the files and prints the diagnostics. It returns an error if
+// any problem is found.
+func (l *LintCmd) Run(ctx context.Context) error {
+	if l.writer == nil {
+		l.writer = os.Stdout
+	}
+	files, err := discoverSources(l.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.jsonnet or *.libsonnet files found")
+	}
+	funcs := append(functions.GenerateAllFunctions(ctx), l.functions...)
+
+	var diags []lintDiagnostic
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		diags = append(diags, l.lintFile(file, string(b), funcs)...)
+	}
+	slices.SortStableFunc(diags, func(a, b lintDiagnostic) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	for _, d := range diags {
+		line := d.String()
+		if l.Format == "json" {
+			b, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			line = string(b)
+		}
+		if _, err := fmt.Fprintln(l.writer, line); err != nil {
+			return err
+		}
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("%d problem(s) found in %d file(s)", len(diags), len(files))
+	}
+	return nil
+}
+
+// lintFile returns the diagnostics of a file
+func (l *LintCmd) lintFile(file, content string, funcs []*jsonnet.NativeFunction) []lintDiagnostic {
+	collector := &lintCollector{file: file}
+	vm := jsonnet.MakeVM()
+	vm.Importer(&ArmedImporter{funcs: funcs, fileImporter: jsonnet.FileImporter{JPaths: l.Lib}})
+	vm.ErrorFormatter = collector
+	linter.LintSnippet(vm, io.Discard, []linter.Snippet{{FileName: file, Code: content}})
+
+	refs, err := scanSource(file, content)
+	if err != nil {
+		// Reported by the linter as a syntax error
+		return collector.diags
+	}
+	registered := make(map[string]bool, len(funcs))
+	for _, f := range funcs {
+		registered[f.Name] = true
+	}
+	for _, ref := range refs.natives {
+		switch message, deprecated := functions.DeprecatedFunctions[ref.name]; {
+		case !registered[ref.name]:
+			collector.add(lintCheckUnknownNative, ref.loc, fmt.Sprintf("unknown native function %q", ref.name))
+		case deprecated:
+			collector.add(lintCheckDeprecated, ref.loc, fmt.Sprintf("%s is deprecated: %s", ref.name, message))
+		}
+	}
+	return collector.diags
+}
+
+// lintCollector is a jsonnet.ErrorFormatter that collects the errors
+// reported by the linter as diagnostics
+type lintCollector struct {
+	file  string
+	diags []lintDiagnostic
+}
+
+var _ jsonnet.ErrorFormatter = (*lintCollector)(nil)
+
+func (c *lintCollector) add(check string, loc ast.LocationRange, message string) {
+	file := loc.FileName
+	if file == "" {
+		file = c.file
+	}
+	c.diags = append(c.diags, lintDiagnostic{
+		File:    file,
+		Line:    loc.Begin.Line,
+		Column:  loc.Begin.Column,
+		Check:   check,
+		Message: message,
+	})
+}
+
+// Format records the error and returns nothing to print
+func (c *lintCollector) Format(err error) string {
+	var loc ast.LocationRange
+	message := err.Error()
+	if e, ok := err.(locatedError); ok {
+		loc = e.Loc()
+		if loc.IsSet() {
+			message = strings.TrimPrefix(message, loc.String())
+		}
+	}
+	c.add(lintCheckJsonnet, loc, strings.TrimSpace(message))
+	return ""
+}
+
+// SetMaxStackTraceSize is a no-op; lint errors have no stack traces.
+func (c *lintCollector) SetMaxStackTraceSize(size int) {}
+
+// SetColorFormatter is a no-op; diagnostics are not colored.
+func (c *lintCollector) SetColorFormatter(color jsonnet.ColorFormatter) {}
@@ -0,0 +1,113 @@
+package armed_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+func TestLintCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.jsonnet"), `local unused = 1;
+local armed = import 'armed.libsonnet';
+local common = import 'common.libsonnet';
+{
+  a: std.native("sha256")("x"),
+  b: std.native("no_such")("x"),
+  c: armed.sha256("y") + common.name,
+  d: std.length(1, 2),
+  e: std.native("custom")(),
+  f: std.native("md5")("z"),
+}
+`)
+	writeFile(t, filepath.Join(dir, "common.libsonnet"), `{ name: "common" }`)
+	writeFile(t, filepath.Join(dir, "broken.jsonnet"), `{ a: `)
+
+	saved := functions.DeprecatedFunctions
+	functions.DeprecatedFunctions = map[string]string{"md5": "use sha256"}
+	t.Cleanup(func() { functions.DeprecatedFunctions = saved })
+
+	custom := &jsonnet.NativeFunction{
+		Name:   "custom",
+		Params: ast.Identifiers{},
+		Func:   func(args []any) (any, error) { return nil, nil },
+	}
+
+	app := filepath.Join(dir, "app.jsonnet")
+	broken := filepath.Join(dir, "broken.jsonnet")
+	want := []string{
+		app + ":1:7: Unused variable: unused (jsonnet)",
+		app + `:6:6: unknown native function "no_such" (unknown-native)`,
+		app + ":8:20: Too many arguments, there can be at most 1, but 2 provided (jsonnet)",
+		app + ":10:6: md5 is deprecated: use sha256 (deprecated)",
+		broken + ":1:6: Unexpected end of file (jsonnet)",
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var output bytes.Buffer
+		cmd := &armed.LintCmd{Paths: []string{dir}}
+		cmd.SetWriter(&output)
+		cmd.AddFunctions(custom)
+		err := cmd.Run(t.Context())
+		if err == nil || err.Error() != "5 problem(s) found in 3 file(s)" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if got := strings.Split(strings.TrimSpace(output.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("unexpected diagnostics:\n%s\nwant:\n%s", output.String(), strings.Join(want, "\n"))
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var output bytes.Buffer
+		cmd := &armed.LintCmd{Format: "json", Paths: []string{app}}
+		cmd.SetWriter(&output)
+		cmd.AddFunctions(custom)
+		if err := cmd.Run(t.Context()); err == nil {
+			t.Fatal("expected an error")
+		}
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("expected 4 diagnostics, got:\n%s", output.String())
+		}
+		var d struct {
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Column  int    `json:"column"`
+			Check   string `json:"check"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &d); err != nil {
+			t.Fatal(err)
+		}
+		if d.File != app || d.Line != 6 || d.Column != 6 || d.Check != "unknown-native" || d.Message != `unknown native function "no_such"` {
+			t.Errorf("unexpected diagnostic: %+v", d)
+		}
+	})
+}
+
+func TestLintCmdClean(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "lib", "labels.libsonnet"), `{ app: "web" }`)
+	writeFile(t, filepath.Join(dir, "app.jsonnet"), `{ labels: import 'labels.libsonnet', hash: std.native("sha256")("x") }`)
+
+	var output bytes.Buffer
+	cmd := &armed.LintCmd{Lib: []string{filepath.Join(dir, "lib")}, Paths: []string{filepath.Join(dir, "app.jsonnet")}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output.String())
+	}
+	if output.String() != "" {
+		t.Errorf("unexpected diagnostics:\n%s", output.String())
+	}
+}
@@ -0,0 +1,33 @@
+package armed
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Formats of --log-format. By default, logs are written by the default
+// slog handler as human-readable lines.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// setupLogger configures the default slog logger to write the logs at level
+// or above to w in format
+func setupLogger(level, format string, w io.Writer) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid --log-level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case logFormatText:
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case logFormatJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		slog.SetLogLoggerLevel(l)
+	}
+	return nil
+}
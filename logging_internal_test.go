@@ -0,0 +1,55 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetupLogger(t *testing.T) {
+	orig := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(orig)
+		slog.SetLogLoggerLevel(slog.LevelInfo)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := setupLogger("info", logFormatJSON, &buf); err != nil {
+			t.Fatal(err)
+		}
+		slog.Debug("hidden")
+		slog.Warn("Stale cache used", "filename", "app.jsonnet")
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+		}
+		if record["level"] != "WARN" || record["msg"] != "Stale cache used" || record["filename"] != "app.jsonnet" {
+			t.Errorf("unexpected record: %v", record)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := setupLogger("warn", logFormatText, &buf); err != nil {
+			t.Fatal(err)
+		}
+		slog.Info("hidden")
+		slog.Error("failed", "error", "boom")
+		got := buf.String()
+		if strings.Contains(got, "hidden") {
+			t.Errorf("info must not be logged at the warn level: %q", got)
+		}
+		if !strings.Contains(got, `level=ERROR msg=failed error=boom`) {
+			t.Errorf("unexpected log: %q", got)
+		}
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		if err := setupLogger("verbose", "", &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "invalid --log-level") {
+			t.Errorf("expected invalid --log-level, got %v", err)
+		}
+	})
+}
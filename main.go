@@ -2,22 +2,34 @@ package armed
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/fujiwara/jsonnet-armed/functions"
 	"github.com/google/go-jsonnet"
+	"github.com/itchyny/gojq"
+)
+
+// Defaults of --max-stack and --max-trace, the same as go-jsonnet
+const (
+	defaultMaxStack = 500
+	defaultMaxTrace = 20
 )
 
 // SetOutput sets the output destination for jsonnet evaluation results (deprecated)
@@ -38,12 +50,20 @@ func (cli *CLI) AddFunctions(funcs ...*jsonnet.NativeFunction) {
 }
 
 func Run(ctx context.Context) error {
-	root := &rootCLI{Eval: CLI{writer: os.Stdout}}
-	kctx := kong.Parse(root, kong.Vars{"version": fmt.Sprintf("jsonnet-armed %s", Version)})
-	if strings.HasPrefix(kctx.Command(), "serve") {
-		return root.Serve.Run(ctx)
+	// Scrub secret values obtained by native functions from logs
+	log.SetOutput(redactingWriter{w: os.Stderr})
+	// In the AWS Lambda runtime, run the handler when no arguments are given
+	if isLambdaRuntime() && len(os.Args) == 1 {
+		return (&LambdaCmd{}).Run(ctx)
+	}
+	root := &rootCLI{Eval: CLI{writer: os.Stdout, prettyErrors: isTerminal(os.Stderr), stdoutTerminal: isTerminal(os.Stdout)}}
+	kctx := kong.Parse(root, kong.NamedMapper("input", inputMapper), kong.Configuration(loadConfig, defaultConfigFile))
+	if err := setupLogger(root.LogLevel, root.LogFormat, redactingWriter{w: os.Stderr}); err != nil {
+		return err
 	}
-	return root.Eval.run(ctx)
+	// Each command's Run method is called with ctx
+	kctx.BindTo(ctx, (*context.Context)(nil))
+	return kctx.Run()
 }
 
 // Run runs the jsonnet evaluation with the CLI configuration
@@ -52,41 +72,165 @@ func (cli *CLI) Run(ctx context.Context) error {
 	if cli.writer == nil {
 		cli.writer = os.Stdout
 	}
-	return cli.run(ctx)
+	err := cli.run(ctx)
+	if err != nil && cli.ErrorFormat == errorFormatJSON {
+		return cli.reportErrorJSON(err)
+	}
+	return err
 }
 
 func (cli *CLI) run(ctx context.Context) error {
-	// Handle document flags
-	if cli.Document {
-		_, err := io.WriteString(cli.writer, readmeContent)
+	if cli.Version {
+		return cli.printVersion(ctx)
+	}
+	if cli.VersionJSON {
+		return fmt.Errorf("--json requires --version")
+	}
+
+	// Handle document flags, kept for compatibility with the docs command
+	if cli.Document || cli.DocumentToc || cli.DocumentSearch != "" {
+		docs := &DocsCmd{Toc: cli.DocumentToc, Search: cli.DocumentSearch, writer: cli.writer}
+		return docs.Run(ctx)
+	}
+
+	// Filename is required when no document flags are specified
+	if cli.Exec != "" {
+		if cli.Filename != "" {
+			return fmt.Errorf("-e/--exec cannot be used with <filename>")
+		}
+	} else if cli.Filename == "" {
+		return fmt.Errorf("<filename> is required")
+	}
+
+	if err := cli.prepare(); err != nil {
+		return err
+	}
+	if cli.UseDaemon && cli.delegatable() {
+		if handled, err := cli.delegate(ctx); handled {
+			return err
+		}
+	}
+	if cli.Watch {
+		return cli.watch(ctx)
+	}
+	return cli.runOnce(ctx)
+}
+
+// prepare validates the flags and applies the settings from the environment
+// before the first evaluation, which may have side effects
+func (cli *CLI) prepare() error {
+	if err := cli.checkFormat(); err != nil {
+		return err
+	}
+	if err := cli.checkIncrementalFlags(); err != nil {
+		return err
+	}
+	if err := cli.checkDiffFlags(); err != nil {
 		return err
 	}
-	if cli.DocumentToc {
-		_, err := io.WriteString(cli.writer, extractTOC(readmeContent))
+	if err := cli.checkListDepsFlags(); err != nil {
 		return err
 	}
-	if cli.DocumentSearch != "" {
-		results := searchSections(readmeContent, cli.DocumentSearch)
-		if results == "" {
-			_, err := fmt.Fprintf(cli.writer, "No sections found matching: %s\n", cli.DocumentSearch)
+
+	if cli.OnChange != "" && !cli.Watch {
+		return fmt.Errorf("--on-change requires --watch")
+	}
+	if cli.K8sVersion != "" && !cli.ValidateK8s {
+		return fmt.Errorf("--k8s-version requires --validate-k8s")
+	}
+	if cli.Query != "" {
+		// Fail before evaluating, which may have side effects
+		if _, err := gojq.Parse(cli.Query); err != nil {
+			return fmt.Errorf("invalid --query: %w", err)
+		}
+	}
+	if cli.Schema != "" {
+		// Fail before evaluating, which may have side effects
+		if _, err := compileSchema(cli.Schema); err != nil {
 			return err
 		}
-		_, err := io.WriteString(cli.writer, results)
+	}
+	if cli.LibHelpers && len(cli.Lib) == 0 {
+		return fmt.Errorf("--lib-helpers requires --lib")
+	}
+	if err := cli.checkLibDirs(); err != nil {
+		return err
+	}
+	if err := cli.checkVarNames(); err != nil {
+		return err
+	}
+	if err := cli.extVarsFromEnv(os.Environ()); err != nil {
+		return err
+	}
+	if _, err := cli.parseSetOverrides(); err != nil {
+		return err
+	}
+	if err := checkFuncTimeouts(cli.FuncTimeout); err != nil {
+		return err
+	}
+	if err := cli.checkFnPatterns(); err != nil {
+		return err
+	}
+	if cli.Prompt && cli.prompter == nil && stdinIsTerminal() {
+		cli.prompter = newTerminalPrompter()
+	}
+	if cli.MaxStack < 0 {
+		return fmt.Errorf("--max-stack must not be negative")
+	}
+	if cli.MaxTrace < 0 {
+		return fmt.Errorf("--max-trace must not be negative")
+	}
+	if cli.ExecMaxParallel < 0 {
+		return fmt.Errorf("--exec-max-parallel must not be negative")
+	}
+	if cli.OutputRetry < 0 {
+		return fmt.Errorf("--output-retry must not be negative")
+	}
+	if err := cli.checkHTTPOutputFlags(); err != nil {
+		return err
+	}
+	if _, _, _, err := cli.outputFileAttrs(); err != nil {
+		return err
+	}
+	if err := cli.checkBackupFlags(); err != nil {
 		return err
 	}
+	return nil
+}
 
-	// Filename is required when no document flags are specified
-	if cli.Filename == "" {
-		return fmt.Errorf("<filename> is required")
+// runOnce evaluates the file and writes the result
+func (cli *CLI) runOnce(ctx context.Context) error {
+	if cli.DryRun && cli.plan == nil {
+		cli.plan = newDryRunPlan(os.Stderr)
 	}
 
-	// Initialize cache if enabled
+	// Initialize cache if enabled. Not in dry-run mode, whose results are
+	// placeholders, nor with --redact, which needs secrets of the evaluation,
+	// nor with --list-deps, which needs the files read by the evaluation.
 	var cache cacheStore
-	if cli.Cache > 0 {
+	if cli.Cache > 0 && cli.plan == nil && !cli.Redact && !cli.ListDeps {
 		cache = NewCache(cli.Cache, cli.Stale)
 		// Clean expired cache entries (best effort)
 		go cache.Clean()
 	}
+	if cli.PrintChecksum && cli.checksums == nil {
+		cli.checksums = os.Stderr
+	}
+	if cli.ExplainCache {
+		if cli.explain == nil {
+			cli.explain = os.Stderr
+		}
+		switch {
+		case cli.Cache <= 0:
+			cli.explainf("cache is disabled (--cache is not set)")
+		case cli.plan != nil:
+			cli.explainf("cache is disabled by --dry-run")
+		case cli.Redact:
+			cli.explainf("cache is disabled by --redact")
+		case cli.ListDeps:
+			cli.explainf("cache is disabled by --list-deps")
+		}
+	}
 
 	// Apply timeout if specified
 	if cli.Timeout > 0 {
@@ -95,24 +239,71 @@ func (cli *CLI) run(ctx context.Context) error {
 		defer cancel()
 	}
 
+	// Apply CPU time limit if specified. Canceling ctx stops native
+	// functions (exec, http) of the aborted evaluation.
+	var cpuExceeded <-chan struct{}
+	if cli.MaxCPU > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		exceeded, err := watchCPUTime(ctx, cli.MaxCPU)
+		if err != nil {
+			return err
+		}
+		cpuExceeded = exceeded
+	}
+
 	// Create a channel to signal completion
 	resultCh := make(chan result, 1)
+	start := time.Now()
+	cli.cacheStatus = ""
+	cli.tracker = nil
+	cli.meta = nil
+	if (cli.MetaOut != "" || cli.ListDeps) && cli.plan == nil {
+		cli.meta = newRunMeta()
+	}
+	if cli.Incremental && cli.plan == nil {
+		cli.tracker = newInputTracker()
+	}
 
 	// Run all operations in goroutine to enable timeout
 	go func() {
 		res := cli.processRequest(ctx, cache)
+		if cli.plan != nil {
+			if err := cli.plan.report(); err != nil && res.err == nil {
+				res.err = err
+			}
+		}
 		resultCh <- res
 	}()
 
-	// Wait for either completion or timeout
+	// Wait for either completion, timeout or CPU time limit
 	select {
 	case res := <-resultCh:
-		return res.err
+		cli.publishMetrics(ctx, time.Since(start), res.err)
+		cli.writeMeta(start, res.err)
+		cli.reportResult(res.jsonStr, res.err)
+		cli.notifyFailure(ctx, res.err)
+		return cli.mapExitCode(res.err)
+
+	case <-cpuExceeded:
+		err := fmt.Errorf("%w of %v", ErrCPULimit, cli.MaxCPU)
+		cli.publishMetrics(ctx, time.Since(start), err)
+		cli.writeMeta(start, err)
+		cli.reportResult("", err)
+		cli.notifyFailure(ctx, err)
+		return cli.mapExitCode(err)
 
 	case <-ctx.Done():
 		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("evaluation timed out after %v", cli.Timeout)
+			err := fmt.Errorf("%w after %v", ErrTimeout, cli.Timeout)
+			cli.publishMetrics(ctx, time.Since(start), err)
+			cli.writeMeta(start, err)
+			cli.reportResult("", err)
+			cli.notifyFailure(ctx, err)
+			return cli.mapExitCode(err)
 		}
+		cli.reportResult("", ctx.Err())
 		return ctx.Err()
 	}
 }
@@ -129,7 +320,21 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 	var contentBytes []byte
 	var err error
 
-	if cli.Filename == "-" {
+	if cli.ExtStrStdin != "" {
+		if err := cli.bindStdinExtStr(); err != nil {
+			return result{jsonStr: "", err: err}
+		}
+	}
+	if err := cli.promptExtVars(); err != nil {
+		return result{jsonStr: "", err: err}
+	}
+
+	if cli.Exec != "" {
+		// Evaluated as a snippet, like stdin
+		contentBytes = []byte(cli.Exec)
+		inputContent = cli.Exec
+		isStdin = true
+	} else if cli.Filename == "-" {
 		// Read from stdin
 		contentBytes, err = io.ReadAll(os.Stdin)
 		if err != nil {
@@ -137,6 +342,17 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 		}
 		inputContent = string(contentBytes)
 		isStdin = true
+	} else if isRemoteInput(cli.Filename) {
+		// Evaluated as a snippet named by the URL, like stdin
+		contentBytes, err = cli.fetchInput(ctx)
+		if err != nil {
+			return result{jsonStr: "", err: err}
+		}
+		inputContent = string(contentBytes)
+		isStdin = true
+		if cli.meta != nil {
+			cli.meta.addURL(cli.Filename)
+		}
 	} else {
 		// For files, we need content for cache key generation
 		if cache != nil {
@@ -148,6 +364,20 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 		isStdin = false
 	}
 
+	if cli.tracker != nil {
+		skip, err := cli.checkIncremental(ctx)
+		if err != nil {
+			return result{jsonStr: "", err: err}
+		}
+		if skip {
+			slog.Info("Inputs unchanged, skipping evaluation", "filename", cli.Filename)
+			for range cli.fileOutputs() {
+				cli.recordChange(false)
+			}
+			return result{jsonStr: "", err: nil}
+		}
+	}
+
 	// Try to get from cache if enabled
 	var staleContent string
 	if cache != nil {
@@ -157,15 +387,18 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 				"error", err.Error(),
 				"filename", cli.Filename)
 		} else {
+			if cli.ExplainCache {
+				cli.explainCacheLookup(cache, cacheKey, contentBytes)
+			}
+			cli.cacheStatus = cacheStatusMiss
 			if entry, exists := cache.getWithStale(cacheKey); exists {
 				if !entry.isStale {
 					// Use fresh cached result
-					formatted, fErr := cli.formatOutput(entry.content)
-					if fErr != nil {
-						return result{jsonStr: "", err: fErr}
+					cli.cacheStatus = cacheStatusHit
+					if cli.onCacheHit != nil {
+						cli.onCacheHit(cacheKey, false)
 					}
-					err = cli.writeOutput(ctx, formatted)
-					return result{jsonStr: formatted, err: err}
+					return cli.emit(ctx, entry.content)
 				}
 				// Store stale content for potential fallback
 				staleContent = entry.content
@@ -182,12 +415,11 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 			slog.Warn("Evaluation failed, using stale cache",
 				"error", err.Error(),
 				"filename", cli.Filename)
-			formatted, fErr := cli.formatOutput(staleContent)
-			if fErr != nil {
-				return result{jsonStr: "", err: fErr}
+			cli.cacheStatus = cacheStatusStale
+			if cli.onCacheHit != nil {
+				cli.onCacheHit(cli.cacheKey, true)
 			}
-			err = cli.writeOutput(ctx, formatted)
-			return result{jsonStr: formatted, err: err}
+			return cli.emit(ctx, staleContent)
 		}
 		return result{jsonStr: "", err: err}
 	}
@@ -203,56 +435,320 @@ func (cli *CLI) processRequest(ctx context.Context, cache cacheStore) result {
 		}
 	}
 
+	res := cli.emit(ctx, jsonStr)
+	if res.err == nil && cli.tracker != nil {
+		// Best effort; the next run evaluates again without a state
+		if err := cli.saveIncremental(); err != nil {
+			slog.Warn("Failed to save incremental state", "error", err.Error(), "filename", cli.Filename)
+		}
+	}
+	return res
+}
+
+// bindStdinExtStr reads stdin into the external string variable named by
+// --ext-str-stdin. It is added to ExtStr, so the cache key covers it.
+func (cli *CLI) bindStdinExtStr() error {
+	if cli.Filename == "-" {
+		return fmt.Errorf("--ext-str-stdin cannot be used when the program is read from stdin")
+	}
+	if _, ok := cli.ExtStr[cli.ExtStrStdin]; ok {
+		return fmt.Errorf("external variable %q is set by both --ext-str and --ext-str-stdin", cli.ExtStrStdin)
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	cli.ExtStr = merged(cli.ExtStr, map[string]string{cli.ExtStrStdin: string(b)})
+	return nil
+}
+
+// emit filters, formats and writes an evaluation result, or checks it as an
+// assertion when --assert is enabled.
+func (cli *CLI) emit(ctx context.Context, jsonStr string) result {
+	if len(cli.Set) > 0 || len(cli.SetJSON) > 0 {
+		overridden, err := cli.applySetOverrides(jsonStr)
+		if err != nil {
+			return result{jsonStr: "", err: err}
+		}
+		jsonStr = overridden
+	}
+	if cli.Query != "" {
+		filtered, err := cli.applyQuery(jsonStr)
+		if err != nil {
+			return result{jsonStr: "", err: err}
+		}
+		jsonStr = filtered
+	}
+	if cli.Assert {
+		return result{jsonStr: jsonStr, err: cli.assert(jsonStr)}
+	}
+	if cli.ValidateK8s {
+		if err := cli.validateK8s(ctx, jsonStr); err != nil {
+			return result{jsonStr: "", err: err}
+		}
+	}
+	if cli.Schema != "" {
+		if err := cli.validateSchema(jsonStr); err != nil {
+			return result{jsonStr: "", err: err}
+		}
+	}
+	if cli.StrictJSON {
+		if err := checkStrictJSON(jsonStr); err != nil {
+			return result{jsonStr: "", err: err}
+		}
+	}
+
 	// Format output (compact/raw)
 	formatted, err := cli.formatOutput(jsonStr)
 	if err != nil {
 		return result{jsonStr: "", err: err}
 	}
 
+	if cli.Diff || cli.Check {
+		return result{jsonStr: formatted, err: cli.diffOutputs(formatted)}
+	}
+	if cli.ListDeps {
+		return result{jsonStr: formatted, err: cli.writeDeps()}
+	}
+
 	// Write output within the timeout scope
 	err = cli.writeOutput(ctx, formatted)
 	return result{jsonStr: formatted, err: err}
 }
 
-func (cli *CLI) evaluate(ctx context.Context, content string, isStdin bool) (string, error) {
-	vm := jsonnet.MakeVM()
+// checkFuncTimeouts rejects --func-timeout for functions without a timeout
+// and durations that are not positive
+func checkFuncTimeouts(timeouts map[string]time.Duration) error {
+	for name, d := range timeouts {
+		if !slices.Contains(functions.TimeoutFunctions, name) {
+			return fmt.Errorf("invalid --func-timeout: %s has no timeout (supported: %s)", name, strings.Join(functions.TimeoutFunctions, ", "))
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid --func-timeout: the timeout of %s must be positive", name)
+		}
+	}
+	return nil
+}
 
-	// Register native functions
+// nativeContext returns the context for generating native functions.
+// The hosts file is read for each evaluation, so edits apply to the next one.
+func (cli *CLI) nativeContext(ctx context.Context) (context.Context, error) {
+	resolve := cli.Resolve
+	if cli.HostsFile != "" {
+		hosts, err := functions.LoadHostsFile(cli.HostsFile)
+		if err != nil {
+			return nil, err
+		}
+		// --resolve entries are specific to a port, so they take precedence
+		resolve = append(slices.Clone(resolve), hosts...)
+	}
 	ctx = context.WithValue(ctx, "version", Version)
+	ctx = functions.WithRedisURL(ctx, cli.RedisURL)
+	ctx = functions.WithExecMaxParallel(ctx, cli.ExecMaxParallel)
+	ctx = functions.WithFuncTimeouts(ctx, cli.FuncTimeout)
+	if cli.prompter != nil {
+		ctx = functions.WithPrompt(ctx, cli.prompter.prompt)
+	}
+	ctx = functions.WithResolve(ctx, resolve)
+	return functions.WithAWSOptions(ctx, cli.awsOptions()), nil
+}
+
+// nativeFunctions returns the built-in and user-defined native functions,
+// with the functions disabled by the sandbox flags, --allow-fn and --deny-fn
+// failing, and replaced by mocks if any
+func (cli *CLI) nativeFunctions(ctx context.Context) ([]*jsonnet.NativeFunction, error) {
 	funcs := functions.GenerateAllFunctions(ctx)
 	funcs = append(funcs, cli.functions...) // Add user-defined functions
-	for _, f := range funcs {
-		vm.NativeFunction(f)
+	funcs = cli.sandbox(funcs)
+	mocks := cli.mocks
+	if cli.Mock != "" {
+		fileMocks, err := loadMockFile(cli.Mock)
+		if err != nil {
+			return nil, err
+		}
+		mocks = append(slices.Clone(mocks), fileMocks...)
 	}
+	return mockFunctions(funcs, mocks)
+}
 
-	// Add importer for armed.libsonnet
-	vm.Importer(&ArmedImporter{funcs: funcs})
+func (cli *CLI) evaluate(ctx context.Context, content string, isStdin bool) (string, error) {
+	// Register native functions
+	ctx, err := cli.nativeContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	warnings := functions.NewWarningCollector()
+	ctx = functions.WithWarningCollector(ctx, warnings)
+	cli.secrets = functions.NewSecretCollector()
+	ctx = functions.WithSecretCollector(ctx, cli.secrets)
+	funcs, err := cli.nativeFunctions(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cli.tracker != nil {
+		funcs = cli.tracker.wrap(funcs)
+	}
+	if cli.plan != nil {
+		funcs = cli.plan.wrap(funcs)
+	}
+	var recording *cassette
+	switch {
+	case cli.Record != "":
+		recording = newCassette()
+		funcs = recording.record(funcs)
+	case cli.Replay != "":
+		c, err := loadCassette(cli.Replay)
+		if err != nil {
+			return "", err
+		}
+		funcs = c.replay(funcs)
+	}
+	if cli.VerifyNatives {
+		if err := cli.checkNatives(content, isStdin, funcs); err != nil {
+			return "", err
+		}
+	}
+	var usage *functionUsage
+	if cli.ReportFunctions != "" {
+		usage = newFunctionUsage()
+		funcs = usage.wrap(funcs)
+	}
+	if cli.meta != nil {
+		funcs = cli.meta.wrap(funcs)
+	}
+	funcs = cli.wrapNativeCalls(ctx, funcs)
+	if cli.Heartbeat > 0 {
+		h := newHeartbeat()
+		funcs = h.wrap(funcs)
+		stop := h.start(cli.Filename, cli.Heartbeat)
+		defer stop()
+	}
 
-	for k, v := range cli.ExtStr {
-		vm.ExtVar(k, v)
+	// Add importer for armed.libsonnet
+	importer := &ArmedImporter{funcs: funcs, fileImporter: jsonnet.FileImporter{JPaths: cli.Lib}}
+	if isRemoteInput(cli.Filename) {
+		importer.remote = cli.Filename
 	}
-	for k, v := range cli.ExtCode {
-		vm.ExtCode(k, v)
+	if cli.AutoArmed {
+		if !isStdin {
+			importer.autoArmedFiles = append(importer.autoArmedFiles, cli.Filename)
+		}
+		importer.autoArmedFiles = append(importer.autoArmedFiles, cli.Overlays...)
+	}
+	var vm *jsonnet.VM
+	if cli.vms != nil {
+		// Reuse a VM holding the parsed imports of previous evaluations
+		cached := cli.vms.get(importer)
+		defer func() {
+			cli.traceImports(cached.importer.stats)
+			cli.vms.put(cached)
+		}()
+		vm = cached.vm
+	} else {
+		vm = jsonnet.MakeVM()
+		vm.Importer(importer)
+	}
+	if cli.prettyErrors {
+		vm.ErrorFormatter = newPrettyErrorFormatter()
+	}
+	// Set for each evaluation, since cached VMs are shared
+	vm.MaxStack = cmp.Or(cli.MaxStack, defaultMaxStack)
+	vm.ErrorFormatter.SetMaxStackTraceSize(cmp.Or(cli.MaxTrace, defaultMaxTrace))
+	recorder := &errorRecorder{ErrorFormatter: vm.ErrorFormatter}
+	vm.ErrorFormatter = recorder
+	for _, f := range funcs {
+		vm.NativeFunction(f)
+	}
+
+	if err := cli.bindVars(vm); err != nil {
+		return "", err
 	}
 
 	var jsonStr string
-	var err error
+
+	if cli.AutoArmed {
+		vm.ExtCode(armedLibVar, "import 'armed.libsonnet'")
+	}
+	if cli.LibHelpers {
+		code, err := cli.libHelpersCode()
+		if err != nil {
+			return "", err
+		}
+		vm.ExtCode(libHelpersVar, code)
+	}
 
 	if isStdin {
-		jsonStr, err = vm.EvaluateAnonymousSnippet("stdin", content)
+		if cli.AutoArmed {
+			content = autoArmedPrelude + content
+		}
+		jsonStr, err = vm.EvaluateAnonymousSnippet(cli.snippetName(), content)
 	} else {
 		jsonStr, err = vm.EvaluateFile(cli.Filename)
 	}
+	if err == nil && len(cli.Overlays) > 0 {
+		jsonStr, err = cli.evaluateOverlays(vm, jsonStr)
+	}
+	cli.inputs = importer.files
+	if cli.meta != nil {
+		cli.meta.addFiles(importer.files...)
+	}
+	if recording != nil {
+		// Keep interactions recorded before a failure too
+		if rerr := recording.save(cli.Record); rerr != nil {
+			if err == nil {
+				return "", rerr
+			}
+			slog.Warn("Failed to write cassette", "error", rerr.Error())
+		}
+	}
+	if usage != nil {
+		// Report calls made before a failure too; they are still useful for review
+		if rerr := usage.writeReport(cli.ReportFunctions, cli.Filename); rerr != nil {
+			slog.Warn("Failed to write function usage report", "error", rerr.Error())
+		}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to evaluate: %w", err)
+		// Error messages may contain secrets, e.g. an exec stderr echoing a token
+		return "", redactError(fmt.Errorf("failed to evaluate: %w", recorder.wrap(err)))
+	}
+	if err := cli.reportWarnings(warnings.Warnings()); err != nil {
+		return "", redactError(err)
 	}
 
 	return jsonStr, nil
 }
 
+// reportWarnings logs warnings emitted by native functions during the
+// evaluation. With --strict-warnings, any warning fails the evaluation.
+func (cli *CLI) reportWarnings(warnings []functions.Warning) error {
+	for _, w := range warnings {
+		slog.Warn("Native function warning",
+			"function", w.Function,
+			"message", w.Message,
+			"filename", cli.Filename)
+	}
+	if cli.StrictWarnings && len(warnings) > 0 {
+		msgs := make([]string, len(warnings))
+		for i, w := range warnings {
+			msgs[i] = w.String()
+		}
+		return fmt.Errorf("evaluation emitted %d warning(s) with --strict-warnings: %s", len(warnings), strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
 // formatOutput applies compact and raw output formatting to JSON string.
 func (cli *CLI) formatOutput(jsonStr string) (string, error) {
-	if !cli.CompactOutput && !cli.RawOutput {
+	if cli.OutputBinary {
+		return decodeBinaryOutput(jsonStr)
+	}
+	if cli.isNonJSONFormat() {
+		return encodeFormat(jsonStr, cli.Format)
+	}
+	if cli.OutputTemplate != "" {
+		return renderTemplate(cli.OutputTemplate, jsonStr)
+	}
+	if !cli.CompactOutput && !cli.RawOutput && cli.Indent == 0 {
 		return jsonStr, nil
 	}
 
@@ -275,44 +771,64 @@ func (cli *CLI) formatOutput(jsonStr string) (string, error) {
 		}
 		return buf.String() + "\n", nil
 	}
+	if cli.Indent > 0 {
+		// Numbers and the order of keys are kept as manifested
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(trimmed), "", strings.Repeat(" ", cli.Indent)); err != nil {
+			return "", fmt.Errorf("failed to indent JSON: %w", err)
+		}
+		return buf.String() + "\n", nil
+	}
 
 	return jsonStr, nil
 }
 
-func (cli *CLI) writeOutputToHTTP(ctx context.Context, u string, jsonStr string) error {
-	// Warn if --write-if-changed is used with HTTP output
-	if cli.WriteIfChanged {
-		fmt.Fprintf(os.Stderr, "Warning: --write-if-changed has no effect when outputting to HTTP(S) URL\n")
+// decodeBinaryOutput decodes a result that is a base64 encoded string into raw bytes
+func decodeBinaryOutput(jsonStr string) (string, error) {
+	var s string
+	if err := json.Unmarshal([]byte(jsonStr), &s); err != nil {
+		return "", fmt.Errorf("--output-binary requires the result to be a string")
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("--output-binary requires the result to be a base64 string: %w", err)
 	}
+	return string(b), nil
+}
 
+func (cli *CLI) writeOutputToHTTP(ctx context.Context, u string, jsonStr string) error {
 	// Write to HTTP(S) URL
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(jsonStr))
+	req, err := http.NewRequestWithContext(ctx, cmp.Or(cli.OutputMethod, http.MethodPost), u, strings.NewReader(jsonStr))
 	if err != nil {
 		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "jsonnet-armed/"+Version)
+	req.Header.Set("Content-Type", cli.contentType())
+	if err := cli.setOutputHeaders(req); err != nil {
+		return err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send HTTP request: %w", err)
+		return &transientError{fmt.Errorf("failed to send HTTP request: %w", err)}
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("HTTP request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-	return nil
+	return cli.checkOutputResponse(resp)
 }
 
 func (cli *CLI) writeOutput(ctx context.Context, jsonStr string) error {
 	if len(cli.Output) == 0 {
-		_, err := io.WriteString(cli.writer, jsonStr)
-		return err
+		preview := cli.preview(jsonStr)
+		if _, err := io.WriteString(cli.writer, preview); err != nil {
+			return err
+		}
+		cli.printChecksum("-", preview, nil)
+		return nil
 	}
 
 	// Also write to stdout if enabled
 	if cli.Stdout {
-		io.WriteString(os.Stdout, jsonStr)
+		preview := cli.preview(jsonStr)
+		io.WriteString(cli.writer, preview)
+		cli.printChecksum("-", preview, nil)
 	}
 
 	var errs []error
@@ -324,19 +840,89 @@ func (cli *CLI) writeOutput(ctx context.Context, jsonStr string) error {
 	return errors.Join(errs...)
 }
 
+// preview returns the output for stdout, with secrets masked when --redact
+// is enabled and highlighted by --color
+func (cli *CLI) preview(jsonStr string) string {
+	if cli.Redact && cli.secrets != nil {
+		jsonStr = cli.secrets.Redact(jsonStr)
+	}
+	if cli.useColor() {
+		jsonStr = colorizeJSON(jsonStr)
+	}
+	return jsonStr
+}
+
 func (cli *CLI) writeToDestination(ctx context.Context, out string, jsonStr string) error {
+	if cli.plan != nil {
+		cli.plan.addOutput(out)
+		return nil
+	}
+
 	// Check if output is an HTTP(S) URL
 	u, err := url.Parse(out)
 	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
-		return cli.writeOutputToHTTP(ctx, out, jsonStr)
+		if cli.WriteIfChanged {
+			var changed bool
+			err := cli.retryOutput(ctx, out, func() (err error) {
+				changed, err = cli.writeOutputToHTTPIfChanged(ctx, out, jsonStr)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			cli.recordChange(changed)
+			cli.printChecksum(out, jsonStr, &changed)
+			return nil
+		}
+		if err := cli.retryOutput(ctx, out, func() error {
+			return cli.writeOutputToHTTP(ctx, out, jsonStr)
+		}); err != nil {
+			return err
+		}
+		cli.printChecksum(out, jsonStr, nil)
+		return nil
+	}
+	if bucket, key, ok := parseS3URL(out); ok {
+		if err := cli.retryOutput(ctx, out, func() error {
+			return cli.writeOutputToS3(ctx, bucket, key, jsonStr)
+		}); err != nil {
+			return err
+		}
+		cli.printChecksum(out, jsonStr, nil)
+		return nil
 	}
 
 	// Write to file
 	data := []byte(jsonStr)
-	if cli.WriteIfChanged && shouldSkipWrite(out, data) {
-		return nil
+	var changed *bool
+	if cli.WriteIfChanged || cli.Backup || cli.tracksChanges() || cli.PrintChecksum || cli.meta != nil {
+		fileChanged := !shouldSkipWrite(out, data)
+		cli.recordChange(fileChanged)
+		changed = &fileChanged
+		if !fileChanged && cli.WriteIfChanged {
+			// The content is kept, but the mode and owner follow the flags
+			if err := cli.setOutputFileAttrs(out); err != nil {
+				return err
+			}
+			cli.printChecksum(out, jsonStr, changed)
+			return nil
+		}
+	}
+	mode, uid, gid, err := cli.outputFileAttrs()
+	if err != nil {
+		return err
+	}
+	// Unchanged files are not backed up, so that the backups keep older contents
+	if cli.Backup && *changed {
+		if err := cli.backupOutput(out); err != nil {
+			return fmt.Errorf("failed to back up: %w", err)
+		}
+	}
+	if err := writeFileAtomicOwned(out, data, mode, uid, gid); err != nil {
+		return err
 	}
-	return writeFileAtomic(out, data, 0644)
+	cli.printChecksum(out, jsonStr, changed)
+	return nil
 }
 
 // shouldSkipWrite checks if the file write should be skipped because content hasn't changed
@@ -379,6 +965,12 @@ func shouldSkipWrite(filename string, newData []byte) bool {
 // writeFileAtomic writes data to the named file atomically.
 // It writes to a temporary file first, then renames it to the target file.
 func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicOwned(filename, data, perm, -1, -1)
+}
+
+// writeFileAtomicOwned is writeFileAtomic that also changes the owner of the
+// file to uid and gid (-1 leaves either unchanged) before it is renamed.
+func writeFileAtomicOwned(filename string, data []byte, perm os.FileMode, uid, gid int) error {
 	dir := filepath.Dir(filename)
 	base := filepath.Base(filename)
 
@@ -407,6 +999,12 @@ func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
 		return err
 	}
 
+	if uid >= 0 || gid >= 0 {
+		if err := tmpfile.Chown(uid, gid); err != nil {
+			return err
+		}
+	}
+
 	// Set the correct permissions
 	if err := tmpfile.Chmod(perm); err != nil {
 		return err
@@ -430,16 +1028,52 @@ func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
 // ArmedImporter provides virtual file system for armed.libsonnet
 type ArmedImporter struct {
 	funcs []*jsonnet.NativeFunction
+
+	// autoArmedFiles are the input files to which autoArmedPrelude is prepended (--auto-armed)
+	autoArmedFiles []string
+
+	// The VM requires the same Contents instance for the same foundAt,
+	// so generated and file contents are kept for the lifetime of the importer.
+	armedLib     *jsonnet.Contents
+	autoArmed    map[string]*jsonnet.Contents
+	fileImporter jsonnet.FileImporter
+
+	// files are the paths of the imported files, including the input files
+	files []string
+
+	// remote is the URL of a remote input, evaluated as a snippet whose
+	// imports come with an empty importedFrom
+	remote string
 }
 
 func (ai *ArmedImporter) Import(importedFrom, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
 	if importedPath == "armed.libsonnet" {
-		// Generate the library content dynamically
-		content := functions.GenerateArmedLib(ai.funcs)
-		return jsonnet.MakeContents(content), "armed.libsonnet", nil
+		if ai.armedLib == nil {
+			// Generate the library content dynamically
+			c := jsonnet.MakeContents(functions.GenerateArmedLib(ai.funcs))
+			ai.armedLib = &c
+		}
+		return *ai.armedLib, "armed.libsonnet", nil
 	}
 
 	// Fall back to default file system import
-	importer := &jsonnet.FileImporter{}
-	return importer.Import(importedFrom, importedPath)
+	if importedFrom == "" && ai.remote != "" {
+		contents, foundAt, err = ai.importFromRemote(importedPath)
+	} else {
+		contents, foundAt, err = ai.fileImporter.Import(importedFrom, importedPath)
+	}
+	if err == nil && !slices.Contains(ai.files, foundAt) {
+		ai.files = append(ai.files, foundAt)
+	}
+	if err == nil && importedFrom == "" && slices.Contains(ai.autoArmedFiles, importedPath) {
+		if ai.autoArmed == nil {
+			ai.autoArmed = make(map[string]*jsonnet.Contents)
+		}
+		if ai.autoArmed[foundAt] == nil {
+			c := jsonnet.MakeContents(autoArmedPrelude + contents.String())
+			ai.autoArmed[foundAt] = &c
+		}
+		contents = *ai.autoArmed[foundAt]
+	}
+	return contents, foundAt, err
 }
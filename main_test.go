@@ -2,6 +2,7 @@ package armed_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -1288,6 +1290,63 @@ func TestRunWithCLICompactOutput(t *testing.T) {
 	}
 }
 
+func TestRunWithCLIIndent(t *testing.T) {
+	ctx := t.Context()
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ b: [1, { c: 1.50 }], a: "x", e: {} }`), 0644); err != nil {
+		t.Fatalf("failed to write jsonnet file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cli      *armed.CLI
+		expected string
+	}{
+		{
+			name:     "indent 2",
+			cli:      &armed.CLI{Indent: 2},
+			expected: "{\n  \"a\": \"x\",\n  \"b\": [\n    1,\n    {\n      \"c\": 1.5\n    }\n  ],\n  \"e\": {}\n}\n",
+		},
+		{
+			name:     "raw output of a non-string",
+			cli:      &armed.CLI{Indent: 1, RawOutput: true, Query: ".b"},
+			expected: "[\n 1,\n {\n  \"c\": 1.5\n }\n]\n",
+		},
+		{
+			name:     "sort keys is accepted",
+			cli:      &armed.CLI{SortKeys: true, CompactOutput: true},
+			expected: `{"a":"x","b":[1,{"c":1.5}],"e":{}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			tt.cli.Filename = jsonnetFile
+			tt.cli.SetWriter(&output)
+			if err := tt.cli.Run(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, output.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		cli     *armed.CLI
+		wantErr string
+	}{
+		{&armed.CLI{Indent: -1}, "--indent must not be negative"},
+		{&armed.CLI{Indent: 2, CompactOutput: true}, "--indent cannot be used with --compact-output"},
+	} {
+		tc.cli.Filename = jsonnetFile
+		tc.cli.SetWriter(&bytes.Buffer{})
+		if err := tc.cli.Run(ctx); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+		}
+	}
+}
+
 func TestRunWithCLIRawOutput(t *testing.T) {
 	ctx := t.Context()
 
@@ -1570,6 +1629,219 @@ func TestRunWithCLIMultipleOutputFileAndHTTP(t *testing.T) {
 	compareJSON(t, string(receivedBody), `{"mixed": "output"}`)
 }
 
+func TestRunWithCLIWriteIfChangedHTTP(t *testing.T) {
+	ctx := t.Context()
+	tmpDir := t.TempDir()
+
+	// A store serving the SHA256 of the stored content as its ETag
+	var mu sync.Mutex
+	var stored []byte
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := ""
+		if stored != nil {
+			etag = fmt.Sprintf(`"%x"`, sha256.Sum256(stored))
+		}
+		requests = append(requests, r.Method+" "+r.Header.Get("If-Match")+r.Header.Get("If-None-Match"))
+		switch r.Method {
+		case http.MethodHead:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		case http.MethodPut:
+			if m := r.Header.Get("If-Match"); m != "" && m != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			if r.Header.Get("If-None-Match") == "*" && stored != nil {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			stored, _ = io.ReadAll(r.Body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	run := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(jsonnetFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write jsonnet file: %v", err)
+		}
+		cli := &armed.CLI{
+			Filename:       jsonnetFile,
+			Output:         []string{server.URL},
+			WriteIfChanged: true,
+		}
+		if err := cli.Run(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	run(`{version: 1}`)
+	run(`{version: 1}`)
+	run(`{version: 2}`)
+
+	mu.Lock()
+	defer mu.Unlock()
+	compareJSON(t, string(stored), `{"version": 2}`)
+	v1 := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte("{\n   \"version\": 1\n}\n")))
+	expected := []string{
+		"HEAD ",
+		"PUT *",
+		"HEAD ",
+		"HEAD ",
+		"PUT " + v1,
+	}
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunWithCLIWriteIfChangedHTTPConflict(t *testing.T) {
+	ctx := t.Context()
+	tmpDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"other"`)
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+	defer server.Close()
+
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{a: 1}`), 0644); err != nil {
+		t.Fatalf("failed to write jsonnet file: %v", err)
+	}
+	cli := &armed.CLI{
+		Filename:       jsonnetFile,
+		Output:         []string{server.URL},
+		WriteIfChanged: true,
+	}
+	err := cli.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "changed by another client") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestRunWithCLIOutputHTTPOptions(t *testing.T) {
+	var method string
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, header = r.Method, r.Header
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/large":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(strings.Repeat("x", 5000)))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{a: 1}`), 0644); err != nil {
+		t.Fatalf("failed to write jsonnet file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cli     armed.CLI
+		path    string
+		wantErr string
+	}{
+		{
+			name: "method and headers",
+			cli: armed.CLI{
+				OutputMethod: "PATCH",
+				OutputHeader: []string{"Authorization: Bearer secret", "Content-Type: application/merge-patch+json", "X-Tag: a", "X-Tag: b"},
+			},
+		},
+		{
+			name: "expected status",
+			cli:  armed.CLI{OutputStatus: []int{201}},
+			path: "/created",
+		},
+		{
+			name:    "unexpected 2xx status",
+			cli:     armed.CLI{OutputStatus: []int{201}},
+			wantErr: "status 200",
+		},
+		{
+			name:    "response body is truncated",
+			path:    "/large",
+			wantErr: "status 400: " + strings.Repeat("x", 4096) + "...",
+		},
+		{
+			name:    "invalid header",
+			cli:     armed.CLI{OutputHeader: []string{"Authorization"}},
+			wantErr: `invalid --output-header "Authorization"`,
+		},
+		{
+			name:    "invalid status",
+			cli:     armed.CLI{OutputStatus: []int{99}},
+			wantErr: "invalid --output-expect-status 99",
+		},
+		{
+			name:    "method with write-if-changed",
+			cli:     armed.CLI{OutputMethod: "POST", WriteIfChanged: true},
+			wantErr: "--output-method POST cannot be used with --write-if-changed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, header = "", nil
+			cli := tt.cli
+			cli.Filename = jsonnetFile
+			cli.Output = []string{server.URL + tt.path}
+			err := cli.Run(t.Context())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if strings.HasSuffix(tt.wantErr, "...") && strings.Contains(err.Error(), strings.Repeat("x", 4097)) {
+					t.Errorf("the response body is not truncated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := tt.cli.OutputMethod
+			if want == "" {
+				want = http.MethodPost
+			}
+			if method != want {
+				t.Errorf("method: got %s, want %s", method, want)
+			}
+			if len(tt.cli.OutputHeader) == 0 {
+				return
+			}
+			if got := header.Get("Authorization"); got != "Bearer secret" {
+				t.Errorf("Authorization: got %q", got)
+			}
+			if got := header.Get("Content-Type"); got != "application/merge-patch+json" {
+				t.Errorf("Content-Type: got %q", got)
+			}
+			if got := header.Values("X-Tag"); !slices.Equal(got, []string{"a", "b"}) {
+				t.Errorf("X-Tag: got %q", got)
+			}
+			if ua := header.Get("User-Agent"); !strings.HasPrefix(ua, "jsonnet-armed/") {
+				t.Errorf("User-Agent: got %q", ua)
+			}
+		})
+	}
+}
+
 func TestRunWithCLIMultipleOutputWithStdout(t *testing.T) {
 	ctx := t.Context()
 	tmpDir := t.TempDir()
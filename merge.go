@@ -0,0 +1,100 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+)
+
+// Merge strategies for --merge-strategy
+const (
+	mergeDeep    = "deep"
+	mergeAppend  = "append"
+	mergeShallow = "shallow"
+)
+
+// mergeValues merges overlay into base according to strategy.
+//
+//   - deep: objects are merged recursively; other values are replaced.
+//   - append: like deep, but arrays are concatenated.
+//   - shallow: top-level fields of overlay replace those of base.
+//
+// If either value is not an object, overlay replaces base.
+func mergeValues(strategy string, base, overlay any) any {
+	b, ok := base.(map[string]any)
+	if !ok {
+		return overlay
+	}
+	o, ok := overlay.(map[string]any)
+	if !ok {
+		return overlay
+	}
+	merged := make(map[string]any, len(b)+len(o))
+	for k, v := range b {
+		merged[k] = v
+	}
+	for k, v := range o {
+		bv, exists := merged[k]
+		switch {
+		case !exists || strategy == mergeShallow:
+			merged[k] = v
+		case strategy == mergeAppend && isArray(bv) && isArray(v):
+			merged[k] = append(append([]any{}, bv.([]any)...), v.([]any)...)
+		default:
+			merged[k] = mergeValues(strategy, bv, v)
+		}
+	}
+	return merged
+}
+
+func isArray(v any) bool {
+	_, ok := v.([]any)
+	return ok
+}
+
+// mergeResults deep-merges the manifested results left to right and
+// manifests the merged value in the jsonnet output style.
+func mergeResults(strategy string, results []string) (string, error) {
+	if strategy == "" {
+		strategy = mergeDeep
+	}
+	var merged any
+	for i, r := range results {
+		dec := json.NewDecoder(strings.NewReader(r))
+		dec.UseNumber() // keep numbers as they were manifested
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", fmt.Errorf("failed to parse result #%d: %w", i, err)
+		}
+		if i == 0 {
+			merged = v
+			continue
+		}
+		merged = mergeValues(strategy, merged, v)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(merged); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// evaluateOverlays evaluates the overlay files with vm and merges their
+// results over base, left to right.
+func (cli *CLI) evaluateOverlays(vm *jsonnet.VM, base string) (string, error) {
+	results := []string{base}
+	for _, f := range cli.Overlays {
+		r, err := vm.EvaluateFile(f)
+		if err != nil {
+			return "", err
+		}
+		results = append(results, r)
+	}
+	return mergeResults(cli.MergeStrategy, results)
+}
@@ -0,0 +1,92 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIOverlays(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "base.jsonnet")
+	overlay := filepath.Join(tmpDir, "overlay.jsonnet")
+	prod := filepath.Join(tmpDir, "prod.jsonnet")
+	writeFile(t, base, `{
+		name: "app",
+		replicas: 1,
+		labels: { app: "app", tier: "web" },
+		args: ["--verbose"],
+		big: 12345678901234567890,
+	}`)
+	writeFile(t, overlay, `{ labels: { team: "sre" }, args: ["--port=8080"] }`)
+	writeFile(t, prod, `{ replicas: 3, labels: { tier: "frontend" } }`)
+
+	tests := []struct {
+		name     string
+		strategy string
+		expected string
+	}{
+		{
+			name:     "default (deep)",
+			strategy: "",
+			expected: `{
+				"name": "app",
+				"replicas": 3,
+				"labels": { "app": "app", "tier": "frontend", "team": "sre" },
+				"args": ["--port=8080"],
+				"big": 12345678901234567000
+			}`,
+		},
+		{
+			name:     "append",
+			strategy: "append",
+			expected: `{
+				"name": "app",
+				"replicas": 3,
+				"labels": { "app": "app", "tier": "frontend", "team": "sre" },
+				"args": ["--verbose", "--port=8080"],
+				"big": 12345678901234567000
+			}`,
+		},
+		{
+			name:     "shallow",
+			strategy: "shallow",
+			expected: `{
+				"name": "app",
+				"replicas": 3,
+				"labels": { "tier": "frontend" },
+				"args": ["--port=8080"],
+				"big": 12345678901234567000
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: base, Overlays: []string{overlay, prod}, MergeStrategy: tt.strategy}
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLIOverlayAutoArmed(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "base.jsonnet")
+	overlay := filepath.Join(tmpDir, "overlay.jsonnet")
+	writeFile(t, base, `{ a: armed.base64("a") }`)
+	writeFile(t, overlay, `{ b: armed.base64("b") }`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: base, Overlays: []string{overlay}, AutoArmed: true}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, `{"a": "YQ==", "b": "Yg=="}`, output.String())
+}
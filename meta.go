@@ -0,0 +1,199 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// metaFileFunctions are the native functions reading the file given as
+// their first argument
+var metaFileFunctions = map[string]bool{
+	"file_content": true,
+	"file_exists":  true,
+	"file_stat":    true,
+	"md5_file":     true,
+	"sha1_file":    true,
+	"sha256_file":  true,
+	"sha512_file":  true,
+}
+
+// runMeta is the summary of a run written by --meta-out.
+// It is safe for concurrent use.
+type runMeta struct {
+	mu       sync.Mutex
+	files    []string
+	urls     []string
+	commands [][]string
+	outputs  []checksumRecord
+}
+
+// runMetaReport is the JSON written by --meta-out
+type runMetaReport struct {
+	Filename   string           `json:"filename"`
+	Version    string           `json:"version"`
+	StartedAt  time.Time        `json:"started_at"`
+	DurationMS float64          `json:"duration_ms"`
+	Success    bool             `json:"success"`
+	Error      string           `json:"error,omitempty"`
+	Cache      string           `json:"cache,omitempty"`
+	Inputs     runMetaInputs    `json:"inputs"`
+	Outputs    []checksumRecord `json:"outputs"`
+}
+
+type runMetaInputs struct {
+	Files    []string   `json:"files"`
+	URLs     []string   `json:"urls"`
+	Commands [][]string `json:"commands"`
+}
+
+func newRunMeta() *runMeta {
+	return &runMeta{}
+}
+
+// wrap returns copies of funcs that record the files, URLs and commands
+// they read
+func (m *runMeta) wrap(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		var record func(args []any)
+		switch {
+		case metaFileFunctions[f.Name]:
+			record = func(args []any) { m.addFiles(stringArg(args, 0)) }
+		case f.Name == "http_get":
+			record = func(args []any) { m.addURL(stringArg(args, 0)) }
+		case f.Name == "http_request":
+			record = func(args []any) { m.addURL(stringArg(args, 1)) }
+		case f.Name == "exec" || f.Name == "exec_with_env":
+			record = func(args []any) { m.addCommand(commandArgs(args)) }
+		default:
+			wrapped[i] = f
+			continue
+		}
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				record(args)
+				return f.Func(args)
+			},
+		}
+	}
+	return wrapped
+}
+
+// stringArg returns args[i] if it is a string, or ""
+func stringArg(args []any, i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	s, _ := args[i].(string)
+	return s
+}
+
+// commandArgs returns the command line of an exec call
+func commandArgs(args []any) []string {
+	command := []string{stringArg(args, 0)}
+	if len(args) > 1 {
+		if list, ok := args[1].([]any); ok {
+			for _, a := range list {
+				command = append(command, fmt.Sprint(a))
+			}
+		}
+	}
+	return command
+}
+
+func (m *runMeta) addFiles(files ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range files {
+		if f != "" && !slices.Contains(m.files, f) {
+			m.files = append(m.files, f)
+		}
+	}
+}
+
+func (m *runMeta) addURL(u string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u != "" && !slices.Contains(m.urls, u) {
+		m.urls = append(m.urls, u)
+	}
+}
+
+func (m *runMeta) addCommand(command []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.ContainsFunc(m.commands, func(c []string) bool { return slices.Equal(c, command) }) {
+		m.commands = append(m.commands, command)
+	}
+}
+
+func (m *runMeta) addOutput(record checksumRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputs = append(m.outputs, record)
+}
+
+// writeMeta writes the --meta-out summary of a run that started at start
+// and ended with runErr. Failures are logged and do not change the result.
+func (cli *CLI) writeMeta(start time.Time, runErr error) {
+	if cli.meta == nil || cli.MetaOut == "" {
+		return
+	}
+	m := cli.meta
+	m.addFiles(cli.varFiles()...)
+	m.mu.Lock()
+	report := runMetaReport{
+		Filename:   cli.Filename,
+		Version:    Version,
+		StartedAt:  start,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Success:    runErr == nil,
+		Cache:      cli.cacheStatus,
+		Inputs: runMetaInputs{
+			Files:    slices.Sorted(slices.Values(m.files)),
+			URLs:     slices.Clone(m.urls),
+			Commands: slices.Clone(m.commands),
+		},
+		Outputs: slices.Clone(m.outputs),
+	}
+	m.mu.Unlock()
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	// Keep empty lists as [] for consumers
+	if report.Inputs.Files == nil {
+		report.Inputs.Files = []string{}
+	}
+	if report.Inputs.URLs == nil {
+		report.Inputs.URLs = []string{}
+	}
+	if report.Inputs.Commands == nil {
+		report.Inputs.Commands = [][]string{}
+	}
+	if report.Outputs == nil {
+		report.Outputs = []checksumRecord{}
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		slog.Warn("Failed to write metadata", "error", err.Error())
+		return
+	}
+	b = append(b, '\n')
+	if cli.MetaOut == "-" {
+		os.Stderr.Write(b)
+		return
+	}
+	if err := writeFileAtomic(cli.MetaOut, b, 0644); err != nil {
+		slog.Warn("Failed to write metadata", "file", cli.MetaOut, "error", err.Error())
+	}
+}
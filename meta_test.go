@@ -0,0 +1,111 @@
+package armed_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetaOut(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "main.jsonnet")
+	libFile := filepath.Join(dir, "lib.libsonnet")
+	dataFile := filepath.Join(dir, "data.txt")
+	outFile := filepath.Join(dir, "out.json")
+	metaFile := filepath.Join(dir, "meta.json")
+	writeFile(t, libFile, `{name: "lib"}`)
+	writeFile(t, dataFile, "data")
+	writeFile(t, jsonnetFile, `
+local lib = import 'lib.libsonnet';
+{
+  lib: lib.name,
+  data: std.native('file_content')(std.extVar('data')),
+  echo: std.native('exec')('echo', ['hello', 'world']).stdout,
+}`)
+
+	run := func() map[string]any {
+		t.Helper()
+		cli := &armed.CLI{
+			Filename: jsonnetFile,
+			Output:   []string{outFile},
+			ExtStr:   map[string]string{"data": dataFile},
+			MetaOut:  metaFile,
+		}
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := os.ReadFile(metaFile)
+		if err != nil {
+			t.Fatalf("failed to read metadata: %v", err)
+		}
+		var meta map[string]any
+		if err := json.Unmarshal(b, &meta); err != nil {
+			t.Fatalf("invalid metadata %s: %v", b, err)
+		}
+		return meta
+	}
+
+	meta := run()
+	if meta["filename"] != jsonnetFile || meta["success"] != true {
+		t.Errorf("unexpected metadata: %v", meta)
+	}
+	if _, ok := meta["duration_ms"].(float64); !ok {
+		t.Errorf("duration_ms is missing: %v", meta)
+	}
+	inputs := meta["inputs"].(map[string]any)
+	expectedInputs := map[string]any{
+		"files":    []any{dataFile, libFile, jsonnetFile},
+		"urls":     []any{},
+		"commands": []any{[]any{"echo", "hello", "world"}},
+	}
+	if diff := cmp.Diff(expectedInputs, inputs); diff != "" {
+		t.Errorf("unexpected inputs (-want +got):\n%s", diff)
+	}
+	outputs := meta["outputs"].([]any)
+	if len(outputs) != 1 {
+		t.Fatalf("expected 1 output, got %v", outputs)
+	}
+	output := outputs[0].(map[string]any)
+	if output["output"] != outFile || output["changed"] != true || len(output["sha256"].(string)) != 64 {
+		t.Errorf("unexpected output: %v", output)
+	}
+
+	// The second run writes the same content
+	meta = run()
+	output = meta["outputs"].([]any)[0].(map[string]any)
+	if output["changed"] != false {
+		t.Errorf("expected an unchanged output, got %v", output)
+	}
+}
+
+func TestMetaOutFailure(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "main.jsonnet")
+	metaFile := filepath.Join(dir, "meta.json")
+	writeFile(t, jsonnetFile, `error 'broken'`)
+
+	cli := &armed.CLI{Filename: jsonnetFile, MetaOut: metaFile}
+	cli.SetWriter(&strings.Builder{})
+	if err := cli.Run(t.Context()); err == nil {
+		t.Fatal("expected an error")
+	}
+	b, err := os.ReadFile(metaFile)
+	if err != nil {
+		t.Fatalf("failed to read metadata: %v", err)
+	}
+	var meta struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		t.Fatalf("invalid metadata %s: %v", b, err)
+	}
+	if meta.Success || !strings.Contains(meta.Error, "broken") {
+		t.Errorf("unexpected metadata: %s", b)
+	}
+}
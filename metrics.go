@@ -0,0 +1,195 @@
+package armed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+const (
+	metricsDestinationCloudWatch = "cloudwatch"
+	metricsDestinationDatadog    = "datadog"
+	metricsDestinationStatsd     = "statsd"
+
+	// metricsNamespace is the CloudWatch namespace and the statsd prefix
+	metricsNamespace = "jsonnet_armed"
+
+	defaultStatsdAddress = "127.0.0.1:8125"
+
+	// metricsTimeout bounds the publication, which runs after a timed-out evaluation too
+	metricsTimeout = 5 * time.Second
+)
+
+// Cache statuses of a run
+const (
+	cacheStatusHit   = "hit"
+	cacheStatusStale = "stale"
+	cacheStatusMiss  = "miss"
+)
+
+// metric is a value published by --metrics-destination
+type metric struct {
+	name   string
+	value  float64
+	timing bool // milliseconds; otherwise a gauge
+}
+
+// runMetrics returns the metrics of a run that took elapsed and ended with err
+func (cli *CLI) runMetrics(elapsed time.Duration, err error) []metric {
+	success := 0.0
+	if err == nil {
+		success = 1
+	}
+	metrics := []metric{
+		{name: "evaluation.duration", value: float64(elapsed.Microseconds()) / 1000, timing: true},
+		{name: "evaluation.success", value: success},
+		{name: "evaluation.failure", value: 1 - success},
+	}
+	if cli.cacheStatus != "" {
+		hit := 0.0
+		if cli.cacheStatus == cacheStatusHit || cli.cacheStatus == cacheStatusStale {
+			hit = 1
+		}
+		metrics = append(metrics, metric{name: "cache.hit", value: hit})
+	}
+	if cli.outputChanged+cli.outputUnchanged > 0 {
+		changed := 0.0
+		if cli.outputChanged > 0 {
+			changed = 1
+		}
+		metrics = append(metrics, metric{name: "output.changed", value: changed})
+	}
+	return metrics
+}
+
+// metricsTags returns the tags of the metrics: the file name and --metrics-tag
+func (cli *CLI) metricsTags() map[string]string {
+	tags := map[string]string{"file": filepath.Base(cli.Filename)}
+	maps.Copy(tags, cli.MetricsTag)
+	return tags
+}
+
+// publishMetrics publishes the metrics of a run to --metrics-destination.
+// Failures are logged and do not change the result of the run.
+func (cli *CLI) publishMetrics(ctx context.Context, elapsed time.Duration, runErr error) {
+	if cli.MetricsDest == "" || cli.plan != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), metricsTimeout)
+	defer cancel()
+
+	metrics := cli.runMetrics(elapsed, runErr)
+	tags := cli.metricsTags()
+	var err error
+	switch cli.MetricsDest {
+	case metricsDestinationCloudWatch:
+		err = cli.putCloudWatchMetrics(ctx, metrics, tags)
+	case metricsDestinationDatadog:
+		err = sendStatsd(ctx, cli.statsdAddress(), formatStatsd(metrics, tags))
+	case metricsDestinationStatsd:
+		err = sendStatsd(ctx, cli.statsdAddress(), formatStatsd(metrics, nil))
+	}
+	if err != nil {
+		slog.Warn("Failed to publish metrics", "destination", cli.MetricsDest, "error", err.Error())
+	}
+}
+
+// statsdAddress returns --metrics-address, or the DogStatsD agent address
+// set by DD_AGENT_HOST and DD_DOGSTATSD_PORT for datadog
+func (cli *CLI) statsdAddress() string {
+	if cli.MetricsAddress != "" {
+		return cli.MetricsAddress
+	}
+	if cli.MetricsDest == metricsDestinationDatadog {
+		if host := os.Getenv("DD_AGENT_HOST"); host != "" {
+			port := os.Getenv("DD_DOGSTATSD_PORT")
+			if port == "" {
+				port = "8125"
+			}
+			return net.JoinHostPort(host, port)
+		}
+	}
+	return defaultStatsdAddress
+}
+
+// formatStatsd formats metrics in the statsd line protocol. Tags are
+// appended in the DogStatsD format when given.
+func formatStatsd(metrics []metric, tags map[string]string) string {
+	var suffix string
+	if len(tags) > 0 {
+		keys := slices.Sorted(maps.Keys(tags))
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + ":" + tags[k]
+		}
+		suffix = "|#" + strings.Join(pairs, ",")
+	}
+	lines := make([]string, len(metrics))
+	for i, m := range metrics {
+		typ := "g"
+		if m.timing {
+			typ = "ms"
+		}
+		lines[i] = fmt.Sprintf("%s.%s:%g|%s%s", metricsNamespace, m.name, m.value, typ, suffix)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sendStatsd sends a statsd payload over UDP
+func sendStatsd(ctx context.Context, address, payload string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(payload))
+	return err
+}
+
+// cloudWatchMetricData converts metrics to CloudWatch datums with the tags as dimensions
+func cloudWatchMetricData(metrics []metric, tags map[string]string, now time.Time) []types.MetricDatum {
+	dimensions := make([]types.Dimension, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		dimensions = append(dimensions, types.Dimension{Name: aws.String(k), Value: aws.String(tags[k])})
+	}
+	data := make([]types.MetricDatum, len(metrics))
+	for i, m := range metrics {
+		unit := types.StandardUnitNone
+		if m.timing {
+			unit = types.StandardUnitMilliseconds
+		}
+		data[i] = types.MetricDatum{
+			MetricName: aws.String(m.name),
+			Value:      aws.Float64(m.value),
+			Unit:       unit,
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(now),
+		}
+	}
+	return data
+}
+
+func (cli *CLI) putCloudWatchMetrics(ctx context.Context, metrics []metric, tags map[string]string) error {
+	cfg, err := functions.LoadAWSConfig(functions.WithAWSOptions(ctx, cli.awsOptions()))
+	if err != nil {
+		return err
+	}
+	_, err = cloudwatch.NewFromConfig(cfg).PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(metricsNamespace),
+		MetricData: cloudWatchMetricData(metrics, tags, time.Now()),
+	})
+	return err
+}
@@ -0,0 +1,132 @@
+package armed
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+// listenStatsd returns a UDP listener and a function receiving one payload
+// with the duration value replaced by DURATION
+func listenStatsd(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	durationRe := regexp.MustCompile(`duration:[0-9.e+-]+\|`)
+	return conn.LocalAddr().String(), func() string {
+		t.Helper()
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to receive metrics: %v", err)
+		}
+		return durationRe.ReplaceAllString(string(buf[:n]), "duration:DURATION|")
+	}
+}
+
+func TestPublishMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "app.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	addr, receive := listenStatsd(t)
+
+	t.Run("datadog", func(t *testing.T) {
+		cli := &CLI{
+			Filename:       jsonnetFile,
+			Output:         []string{filepath.Join(tmpDir, "out.json")},
+			MetricsDest:    metricsDestinationDatadog,
+			MetricsAddress: addr,
+			MetricsTag:     map[string]string{"env": "prod"},
+		}
+		if err := cli.run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := strings.Join([]string{
+			"jsonnet_armed.evaluation.duration:DURATION|ms|#env:prod,file:app.jsonnet",
+			"jsonnet_armed.evaluation.success:1|g|#env:prod,file:app.jsonnet",
+			"jsonnet_armed.evaluation.failure:0|g|#env:prod,file:app.jsonnet",
+			"jsonnet_armed.output.changed:1|g|#env:prod,file:app.jsonnet",
+		}, "\n")
+		if diff := cmp.Diff(expected, receive()); diff != "" {
+			t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("statsd with cache", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		for _, want := range []string{"0", "1"} {
+			var buf bytes.Buffer
+			cli := &CLI{
+				Filename:       jsonnetFile,
+				Cache:          time.Minute,
+				MetricsDest:    metricsDestinationStatsd,
+				MetricsAddress: addr,
+				MetricsTag:     map[string]string{"ignored": "by statsd"},
+				writer:         &buf,
+			}
+			if err := cli.run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := strings.Join([]string{
+				"jsonnet_armed.evaluation.duration:DURATION|ms",
+				"jsonnet_armed.evaluation.success:1|g",
+				"jsonnet_armed.evaluation.failure:0|g",
+				"jsonnet_armed.cache.hit:" + want + "|g",
+			}, "\n")
+			if diff := cmp.Diff(expected, receive()); diff != "" {
+				t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+			}
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		cli := &CLI{
+			Filename:       filepath.Join(tmpDir, "missing.jsonnet"),
+			MetricsDest:    metricsDestinationStatsd,
+			MetricsAddress: addr,
+			writer:         &bytes.Buffer{},
+		}
+		if err := cli.run(t.Context()); err == nil {
+			t.Fatal("expected error")
+		}
+		got := receive()
+		if !strings.Contains(got, "jsonnet_armed.evaluation.failure:1|g") {
+			t.Errorf("failure metric not found in %q", got)
+		}
+	})
+}
+
+func TestCloudWatchMetricData(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	metrics := []metric{
+		{name: "evaluation.duration", value: 12.5, timing: true},
+		{name: "evaluation.success", value: 1},
+	}
+	dimensions := []types.Dimension{
+		{Name: aws.String("env"), Value: aws.String("prod")},
+		{Name: aws.String("file"), Value: aws.String("app.jsonnet")},
+	}
+	expected := []types.MetricDatum{
+		{MetricName: aws.String("evaluation.duration"), Value: aws.Float64(12.5), Unit: types.StandardUnitMilliseconds, Dimensions: dimensions, Timestamp: aws.Time(now)},
+		{MetricName: aws.String("evaluation.success"), Value: aws.Float64(1), Unit: types.StandardUnitNone, Dimensions: dimensions, Timestamp: aws.Time(now)},
+	}
+	got := cloudWatchMetricData(metrics, map[string]string{"file": "app.jsonnet", "env": "prod"}, now)
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(types.Dimension{}, types.MetricDatum{})); diff != "" {
+		t.Errorf("metric data mismatch (-want +got):\n%s", diff)
+	}
+}
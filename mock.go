@@ -0,0 +1,174 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+)
+
+// mockRule replaces calls to the native function Name. Args, if set, are matched
+// against the leading arguments of a call; string patterns may contain
+// `*` (any characters) and `?` (any single character) wildcards.
+// A matching call returns Result, or fails with the message Fail if it is set.
+type mockRule struct {
+	Name   string `json:"name"`
+	Args   []any  `json:"args,omitempty"`
+	Result any    `json:"result"`
+	Fail   string `json:"fail,omitempty"`
+}
+
+// parseMocks parses mock definitions. An object maps function names to
+// results returned regardless of the arguments; an array holds mock rules,
+// where the first matching rule wins.
+func parseMocks(data []byte) ([]mockRule, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '[' {
+		var rules []mockRule
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("invalid mock rules: %w", err)
+		}
+		for i, r := range rules {
+			if r.Name == "" {
+				return nil, fmt.Errorf("mock rule #%d: name is required", i)
+			}
+		}
+		return rules, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid mocks: %w", err)
+	}
+	rules := make([]mockRule, 0, len(values))
+	for name, v := range values {
+		rules = append(rules, mockRule{Name: name, Result: v})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// loadMockFile evaluates a mock file (JSON or Jsonnet) and parses its mock definitions
+func loadMockFile(filename string) ([]mockRule, error) {
+	vm := jsonnet.MakeVM()
+	jsonStr, err := vm.EvaluateFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate mock file %s: %w", filename, err)
+	}
+	rules, err := parseMocks([]byte(jsonStr))
+	if err != nil {
+		return nil, fmt.Errorf("mock file %s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+// mockFunctions returns funcs with the functions named in mocks replaced by
+// stubs that answer with the first matching rule. A call that matches no
+// rule fails, so that mocked functions never reach real systems.
+// It fails if a mock refers to a function that does not exist.
+func mockFunctions(funcs []*jsonnet.NativeFunction, mocks []mockRule) ([]*jsonnet.NativeFunction, error) {
+	if len(mocks) == 0 {
+		return funcs, nil
+	}
+	rulesByName := make(map[string][]mockRule, len(mocks))
+	for _, r := range mocks {
+		rulesByName[r.Name] = append(rulesByName[r.Name], r)
+	}
+	mocked := slices.Clone(funcs)
+	found := make(map[string]bool, len(rulesByName))
+	for i, f := range mocked {
+		rules, ok := rulesByName[f.Name]
+		if !ok {
+			continue
+		}
+		found[f.Name] = true
+		name := f.Name
+		mocked[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				for _, r := range rules {
+					if !r.matches(args) {
+						continue
+					}
+					if r.Fail != "" {
+						return nil, fmt.Errorf("%s: %s", name, r.Fail)
+					}
+					return r.Result, nil
+				}
+				return nil, fmt.Errorf("%s: no mock matches the arguments %s", name, formatArgs(args))
+			},
+		}
+	}
+	var unknown []string
+	for name := range rulesByName {
+		if !found[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("cannot mock unknown native function(s): %v", unknown)
+	}
+	return mocked, nil
+}
+
+// matches reports whether the leading arguments of a call match r.Args
+func (r mockRule) matches(args []any) bool {
+	if len(r.Args) > len(args) {
+		return false
+	}
+	for i, want := range r.Args {
+		if !matchMockArg(want, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchMockArg(want, got any) bool {
+	if pattern, ok := want.(string); ok {
+		s, ok := got.(string)
+		return ok && globMatch(pattern, s)
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// globMatch matches s against a pattern where `*` matches any characters
+// (including `/`) and `?` matches any single character.
+func globMatch(pattern, s string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == s
+	}
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String()).MatchString(s)
+}
+
+// formatArgs formats native function arguments as a JSON array for error messages
+func formatArgs(args []any) string {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	return string(b)
+}
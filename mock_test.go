@@ -0,0 +1,122 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIMock(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `
+	local armed = import 'armed.libsonnet';
+	{
+		api: armed.http_get("https://api.example.com/v1/users").body,
+		other: armed.http_get("https://other.example.com/").status_code,
+		token: armed.must_env("TOKEN"),
+		user: armed.env("USER", "nobody"),
+		host: armed.exec("hostname").stdout,
+	}`)
+	mockFile := filepath.Join(tmpDir, "mocks.jsonnet")
+	writeFile(t, mockFile, `[
+		{ name: "http_get", args: ["https://api.example.com/*"], result: { status_code: 200, body: "users" } },
+		{ name: "http_get", result: { status_code: 404, body: "" } },
+		{ name: "must_env", args: ["TOKEN"], result: "dummy" },
+		{ name: "env", args: ["USER"], result: "alice" },
+		{ name: "exec", args: ["hostname", []], result: { stdout: "mock-host", stderr: "", exit_code: 0 } },
+	]`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: jsonnetFile, Mock: mockFile}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{
+		"api": "users",
+		"other": 404,
+		"token": "dummy",
+		"user": "alice",
+		"host": "mock-host"
+	}`
+	compareJSON(t, expected, output.String())
+}
+
+func TestRunWithCLIMockErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		mocks   string
+		wantErr string
+	}{
+		{
+			name:    "no matching rule",
+			code:    `std.native("env")("HOME", "")`,
+			mocks:   `[{ name: "env", args: ["USER"], result: "alice" }]`,
+			wantErr: `env: no mock matches the arguments ["HOME",""]`,
+		},
+		{
+			name:    "mocked error",
+			code:    `std.native("must_env")("TOKEN")`,
+			mocks:   `[{ name: "must_env", fail: "TOKEN is not set" }]`,
+			wantErr: "must_env: TOKEN is not set",
+		},
+		{
+			name:    "unknown function",
+			code:    `{}`,
+			mocks:   `{ no_such_function: 1 }`,
+			wantErr: "cannot mock unknown native function(s): [no_such_function]",
+		},
+		{
+			name:    "missing function name",
+			code:    `{}`,
+			mocks:   `[{ result: 1 }]`,
+			wantErr: "mock rule #0: name is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+			writeFile(t, jsonnetFile, tt.code)
+			mockFile := filepath.Join(tmpDir, "mocks.json")
+			writeFile(t, mockFile, tt.mocks)
+
+			cli := &armed.CLI{Filename: jsonnetFile, Mock: mockFile}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestCmdMockFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.jsonnet"), `{
+		token: std.native("must_env")("TOKEN"),
+		region: std.native("must_env")("REGION"),
+	}`)
+	writeFile(t, filepath.Join(tmpDir, "app_test.jsonnet"), `{
+		input: "app.jsonnet",
+		mocks: [{ name: "must_env", args: ["TOKEN"], result: "case-token" }],
+		expected: { token: "case-token", region: "ap-northeast-1" },
+	}`)
+	mockFile := filepath.Join(tmpDir, "mocks.json")
+	writeFile(t, mockFile, `{ "must_env": "ap-northeast-1" }`)
+
+	var output bytes.Buffer
+	cmd := &armed.TestCmd{Paths: []string{tmpDir}, Mock: mockFile}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output.String())
+	}
+}
@@ -0,0 +1,168 @@
+package armed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/toolutils"
+)
+
+// nativeRef is a std.native("name") call found in the source
+type nativeRef struct {
+	name string
+	loc  ast.LocationRange
+}
+
+// sourceRefs are the references found in a jsonnet source
+type sourceRefs struct {
+	natives []nativeRef // std.native calls with a literal name
+	extVars []string    // std.extVar calls with a literal name
+	imports []string    // literal paths of code imports
+}
+
+// scanSource parses a jsonnet snippet and returns its references
+func scanSource(filename, content string) (sourceRefs, error) {
+	node, err := jsonnet.SnippetToAST(filename, content)
+	if err != nil {
+		return sourceRefs{}, err
+	}
+	var refs sourceRefs
+	var walk func(n ast.Node)
+	walk = func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Apply:
+			if name, ok := stdCallName(n, "native"); ok {
+				refs.natives = append(refs.natives, nativeRef{name: name, loc: *n.Loc()})
+			}
+			if name, ok := stdCallName(n, "extVar"); ok {
+				refs.extVars = append(refs.extVars, name)
+			}
+		case *ast.Import:
+			refs.imports = append(refs.imports, n.File.Value)
+		}
+		for _, c := range toolutils.Children(n) {
+			walk(c)
+		}
+	}
+	walk(node)
+	return refs, nil
+}
+
+// stdCallName returns the literal argument of a std.<fn>("name") call
+func stdCallName(a *ast.Apply, fn string) (string, bool) {
+	idx, ok := a.Target.(*ast.Index)
+	if !ok {
+		return "", false
+	}
+	v, ok := idx.Target.(*ast.Var)
+	if !ok || v.Id != "std" {
+		return "", false
+	}
+	field, ok := idx.Index.(*ast.LiteralString)
+	if !ok || field.Value != fn {
+		return "", false
+	}
+	if len(a.Arguments.Positional) != 1 {
+		return "", false
+	}
+	name, ok := a.Arguments.Positional[0].Expr.(*ast.LiteralString)
+	if !ok {
+		return "", false
+	}
+	return name.Value, true
+}
+
+// walkSources calls fn with the references of a jsonnet source and of the
+// files it imports. Imports are resolved as by ArmedImporter: relative to
+// the importing file, then in the lib directories from the last one.
+// Sources that cannot be read or parsed are skipped; evaluation reports them.
+func walkSources(filename, content string, lib []string, fn func(refs sourceRefs)) {
+	visited := map[string]bool{}
+	var walk func(filename, content string)
+	walk = func(filename, content string) {
+		refs, err := scanSource(filename, content)
+		if err != nil {
+			return
+		}
+		fn(refs)
+		for _, imp := range refs.imports {
+			if imp == "armed.libsonnet" {
+				continue
+			}
+			path, b, ok := findImport(filepath.Dir(filename), imp, lib)
+			if !ok || visited[path] {
+				continue
+			}
+			visited[path] = true
+			walk(path, string(b))
+		}
+	}
+	visited[filename] = true
+	walk(filename, content)
+}
+
+// findImport returns the path and the content of the file imported as imp
+// from a file in dir
+func findImport(dir, imp string, lib []string) (string, []byte, bool) {
+	candidates := []string{resolvePath(dir, imp)}
+	if !filepath.IsAbs(imp) {
+		for _, d := range slices.Backward(lib) {
+			candidates = append(candidates, filepath.Join(d, imp))
+		}
+	}
+	for _, path := range candidates {
+		if b, err := os.ReadFile(path); err == nil {
+			return path, b, true
+		}
+	}
+	return "", nil, false
+}
+
+// verifyNatives checks that every std.native("name") call in the input
+// and the files it imports refers to a registered native function.
+func verifyNatives(filename, content string, lib []string, funcs []*jsonnet.NativeFunction) error {
+	registered := make(map[string]bool, len(funcs))
+	for _, f := range funcs {
+		registered[f.Name] = true
+	}
+
+	var errs []error
+	walkSources(filename, content, lib, func(refs sourceRefs) {
+		for _, ref := range refs.natives {
+			if !registered[ref.name] {
+				errs = append(errs, fmt.Errorf("%s:%d:%d: unknown native function %q",
+					ref.loc.FileName, ref.loc.Begin.Line, ref.loc.Begin.Column, ref.name))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("verify natives: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// checkNatives runs verifyNatives on the input of cli
+func (cli *CLI) checkNatives(content string, isStdin bool, funcs []*jsonnet.NativeFunction) error {
+	filename := cli.snippetName()
+	if !isStdin {
+		b, err := os.ReadFile(cli.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		filename, content = cli.Filename, string(b)
+	}
+	errs := []error{verifyNatives(filename, content, cli.Lib, funcs)}
+	for _, overlay := range cli.Overlays {
+		b, err := os.ReadFile(overlay)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		errs = append(errs, verifyNatives(overlay, string(b), cli.Lib, funcs))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIVerifyNatives(t *testing.T) {
+	tests := []struct {
+		name        string
+		jsonnet     string
+		lib         string
+		errContains []string
+	}{
+		{
+			name: "all known",
+			jsonnet: `local sha256 = std.native("sha256");
+{ a: sha256("a"), b: std.native('env')("HOME", "") }`,
+		},
+		{
+			name: "unknown in unevaluated branch",
+			jsonnet: `local typo = std.native("sha265");
+{
+  a: if false then typo("a") else "never called",
+}`,
+			errContains: []string{`test.jsonnet:1:14: unknown native function "sha265"`},
+		},
+		{
+			name:    "unknown in imported file",
+			jsonnet: `(import 'lib.libsonnet') + { b: std.native("no_such_b") }`,
+			lib: `{
+  a: std.native("no_such_a"),
+}`,
+			errContains: []string{
+				`lib.libsonnet:2:6: unknown native function "no_such_a"`,
+				`test.jsonnet:1:33: unknown native function "no_such_b"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+			writeFile(t, jsonnetFile, tt.jsonnet)
+			if tt.lib != "" {
+				writeFile(t, filepath.Join(tmpDir, "lib.libsonnet"), tt.lib)
+			}
+
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: jsonnetFile, VerifyNatives: true}
+			cli.SetWriter(&output)
+			err := cli.Run(t.Context())
+			if len(tt.errContains) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			for _, s := range tt.errContains {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("error does not contain %q:\n%s", s, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestRunWithCLIVerifyNativesLib(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `(import 'common.libsonnet') + { b: 1 }`)
+	// Later --lib directories take precedence, as in the evaluation
+	first, second := filepath.Join(tmpDir, "first"), filepath.Join(tmpDir, "second")
+	for _, dir := range []string{first, second} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(first, "common.libsonnet"), `{ a: std.native("shadowed") }`)
+	writeFile(t, filepath.Join(second, "common.libsonnet"), `{ a: std.native("no_such_in_lib") }`)
+
+	cli := &armed.CLI{Filename: jsonnetFile, Lib: []string{first, second}, VerifyNatives: true}
+	cli.SetWriter(&bytes.Buffer{})
+	err := cli.Run(t.Context())
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	want := filepath.Join(second, "common.libsonnet") + `:1:6: unknown native function "no_such_in_lib"`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error does not contain %q:\n%s", want, err.Error())
+	}
+	if strings.Contains(err.Error(), "shadowed") {
+		t.Errorf("a shadowed library must not be verified:\n%s", err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+// notifyTimeout bounds the failure notification, which runs after a timed-out evaluation too
+const notifyTimeout = 10 * time.Second
+
+// notifyMessage is the payload of --notify-url, compatible with Slack incoming webhooks
+type notifyMessage struct {
+	Text string `json:"text"`
+}
+
+// failureText formats the notification of a failed run. Secret values
+// obtained by native functions are redacted from the error.
+func (cli *CLI) failureText(runErr error) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	filename := cli.Filename
+	if filename == "-" {
+		filename = "(stdin)"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "jsonnet-armed failed\n")
+	fmt.Fprintf(&b, "file: %s\n", filename)
+	fmt.Fprintf(&b, "host: %s\n", host)
+	fmt.Fprintf(&b, "error: ```%s```", functions.RedactSecrets(runErr.Error()))
+	return b.String()
+}
+
+// notifyFailure posts the error of a failed run to --notify-url.
+// Failures of the notification itself are logged.
+func (cli *CLI) notifyFailure(ctx context.Context, runErr error) {
+	if cli.NotifyURL == "" || runErr == nil || cli.plan != nil {
+		return
+	}
+	// Webhook URLs embed credentials; keep them out of logged errors
+	functions.RegisterSecret(cli.NotifyURL)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	if err := postNotification(ctx, cli.NotifyURL, cli.failureText(runErr)); err != nil {
+		slog.Warn("Failed to send failure notification", "error", err.Error())
+	}
+}
+
+func postNotification(ctx context.Context, u string, text string) error {
+	body, err := json.Marshal(notifyMessage{Text: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jsonnet-armed/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP request failed with status %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
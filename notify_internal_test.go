@@ -0,0 +1,68 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotifyFailure(t *testing.T) {
+	var messages []notifyMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected Content-Type: %s", ct)
+		}
+		var m notifyMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		messages = append(messages, m)
+	}))
+	defer ts.Close()
+
+	tmpDir := t.TempDir()
+	okFile := filepath.Join(tmpDir, "ok.jsonnet")
+	if err := os.WriteFile(okFile, []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ngFile := filepath.Join(tmpDir, "ng.jsonnet")
+	if err := os.WriteFile(ngFile, []byte(`error "broken config"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &CLI{Filename: okFile, NotifyURL: ts.URL, writer: &bytes.Buffer{}}
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected no notification on success, got %v", messages)
+	}
+
+	cli = &CLI{Filename: ngFile, NotifyURL: ts.URL, writer: &bytes.Buffer{}}
+	if err := cli.run(t.Context()); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(messages))
+	}
+	host, _ := os.Hostname()
+	for _, want := range []string{"jsonnet-armed failed", "file: " + ngFile, "host: " + host, "broken config"} {
+		if !strings.Contains(messages[0].Text, want) {
+			t.Errorf("notification does not contain %q: %s", want, messages[0].Text)
+		}
+	}
+
+	// Output writing failures are notified too
+	cli = &CLI{Filename: okFile, Output: []string{filepath.Join(tmpDir, "missing", "out.json")}, NotifyURL: ts.URL}
+	if err := cli.run(t.Context()); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(messages))
+	}
+}
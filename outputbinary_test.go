@@ -0,0 +1,95 @@
+package armed_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIOutputBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	// PNG signature followed by a NUL byte
+	writeFile(t, jsonnetFile, `std.base64([137, 80, 78, 71, 13, 10, 26, 10, 0])`)
+	expected := []byte{137, 80, 78, 71, 13, 10, 26, 10, 0}
+
+	t.Run("file", func(t *testing.T) {
+		outputFile := filepath.Join(tmpDir, "out.png")
+		cli := &armed.CLI{Filename: jsonnetFile, OutputBinary: true, Output: []string{outputFile}}
+		cli.SetWriter(&bytes.Buffer{})
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expected) {
+			t.Errorf("got %v, want %v", b, expected)
+		}
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile, OutputBinary: true}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(output.Bytes(), expected) {
+			t.Errorf("got %v, want %v", output.Bytes(), expected)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		var contentType string
+		var body []byte
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			body, _ = io.ReadAll(r.Body)
+		}))
+		defer ts.Close()
+
+		cli := &armed.CLI{Filename: jsonnetFile, OutputBinary: true, Output: []string{ts.URL}}
+		cli.SetWriter(&bytes.Buffer{})
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contentType != "application/octet-stream" {
+			t.Errorf("unexpected Content-Type: %s", contentType)
+		}
+		if !bytes.Equal(body, expected) {
+			t.Errorf("got %v, want %v", body, expected)
+		}
+	})
+}
+
+func TestRunWithCLIOutputBinaryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr string
+	}{
+		{"not a string", `{ a: 1 }`, "--output-binary requires the result to be a string"},
+		{"not base64", `"not base64!"`, "--output-binary requires the result to be a base64 string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+			writeFile(t, jsonnetFile, tt.code)
+			cli := &armed.CLI{Filename: jsonnetFile, OutputBinary: true}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package armed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Formats of --diff-format
+const (
+	diffFormatUnified    = "unified"
+	diffFormatStructural = "structural"
+)
+
+// checkDiffFlags checks that --diff and --check compare with local files
+func (cli *CLI) checkDiffFlags() error {
+	if !cli.Diff && cli.DiffFormat != "" && cli.DiffFormat != diffFormatUnified {
+		return fmt.Errorf("--diff-format requires --diff")
+	}
+	var flag string
+	switch {
+	case cli.Check:
+		flag = "--check"
+	case cli.Diff:
+		flag = "--diff"
+	default:
+		return nil
+	}
+	switch {
+	case len(cli.Output) == 0:
+		return fmt.Errorf("%s requires -o/--output", flag)
+	case len(cli.fileOutputs()) != len(cli.Output):
+		return fmt.Errorf("%s supports file outputs only", flag)
+	case cli.Watch:
+		return fmt.Errorf("%s cannot be used with --watch", flag)
+	case cli.DryRun:
+		return fmt.Errorf("%s cannot be used with --dry-run", flag)
+	case cli.Diff && cli.DiffFormat == diffFormatStructural && (cli.isNonJSONFormat() || cli.OutputTemplate != "" || cli.RawOutput || cli.OutputBinary):
+		return fmt.Errorf("--diff-format structural requires JSON output")
+	}
+	return nil
+}
+
+// diffOutputs compares the formatted result with the current content of
+// each output file. With --diff the differences are printed to the writer,
+// otherwise (--check) the files that would change are logged. Nothing is
+// written. It fails with ErrDifferencesFound when a file differs.
+func (cli *CLI) diffOutputs(content string) error {
+	var differ int
+	for _, out := range cli.Output {
+		current, err := os.ReadFile(out)
+		exists := err == nil
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("output %s: %w", out, err)
+		}
+		var diff string
+		if cli.DiffFormat == diffFormatStructural {
+			diff, err = structuralOutputDiff(out, current, exists, content)
+			if err != nil {
+				return fmt.Errorf("output %s: %w", out, err)
+			}
+		} else {
+			nameA := out
+			if !exists {
+				nameA = "/dev/null"
+			}
+			diff = lineDiff(nameA, out, string(current), content)
+		}
+		if diff == "" {
+			continue
+		}
+		differ++
+		if !cli.Diff {
+			slog.Info("Output would change", "output", out)
+			continue
+		}
+		if _, err := io.WriteString(cli.writer, diff); err != nil {
+			return err
+		}
+	}
+	if differ > 0 {
+		return fmt.Errorf("%w: %d of %d output(s) differ", ErrDifferencesFound, differ, len(cli.Output))
+	}
+	return nil
+}
+
+// structuralOutputDiff returns the changes from the JSON in an output file
+// to the result, formatted like the diff command. A missing file is
+// reported as the whole result added.
+func structuralOutputDiff(out string, current []byte, exists bool, content string) (string, error) {
+	var b any
+	if err := json.Unmarshal([]byte(content), &b); err != nil {
+		return "", fmt.Errorf("failed to parse result: %w", err)
+	}
+	var changes []valueChange
+	if exists {
+		var a any
+		if err := json.Unmarshal(current, &a); err != nil {
+			return "", fmt.Errorf("failed to parse the current content as JSON: %w", err)
+		}
+		changes = structuralDiff(a, b)
+	} else {
+		changes = []valueChange{{Op: "+", Path: ".", New: b}}
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+	nameA := out
+	if !exists {
+		nameA = "/dev/null"
+	}
+	diff := fmt.Sprintf("--- %s\n+++ %s\n", nameA, out)
+	for _, c := range changes {
+		diff += c.String() + "\n"
+	}
+	return diff, nil
+}
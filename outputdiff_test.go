@@ -0,0 +1,168 @@
+package armed_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIDiff(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	writeFile(t, jsonnetFile, `{ replicas: 3, debug: false }`)
+	outFile := filepath.Join(dir, "app.json")
+
+	run := func(cli *armed.CLI) (string, error) {
+		t.Helper()
+		var output bytes.Buffer
+		cli.Filename = jsonnetFile
+		cli.Output = []string{outFile}
+		cli.SetWriter(&output)
+		err := cli.Run(t.Context())
+		return output.String(), err
+	}
+
+	// A missing file differs from any result
+	out, err := run(&armed.CLI{Diff: true})
+	if !errors.Is(err, armed.ErrDifferencesFound) {
+		t.Fatalf("expected ErrDifferencesFound, got %v", err)
+	}
+	if !strings.HasPrefix(out, "--- /dev/null\n+++ "+outFile+"\n+{\n") {
+		t.Errorf("unexpected diff:\n%s", out)
+	}
+	if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+		t.Fatalf("--diff must not write the output: %v", err)
+	}
+
+	if _, err := run(&armed.CLI{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err = run(&armed.CLI{Diff: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "" {
+		t.Errorf("expected no diff, got:\n%s", out)
+	}
+
+	writeFile(t, jsonnetFile, `{ replicas: 5, debug: false, image: "app:v2" }`)
+	before, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = run(&armed.CLI{Diff: true})
+	if !errors.Is(err, armed.ErrDifferencesFound) {
+		t.Fatalf("expected ErrDifferencesFound, got %v", err)
+	}
+	for _, line := range []string{"--- " + outFile, `-   "replicas": 3`, `+   "replicas": 5`, `+   "image": "app:v2",`} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("diff does not contain %q:\n%s", line, out)
+		}
+	}
+
+	out, err = run(&armed.CLI{Diff: true, DiffFormat: "structural"})
+	if !errors.Is(err, armed.ErrDifferencesFound) {
+		t.Fatalf("expected ErrDifferencesFound, got %v", err)
+	}
+	expected := "--- " + outFile + "\n+++ " + outFile + "\n" +
+		`+ .image: "app:v2"` + "\n" +
+		"~ .replicas: 3 -> 5\n"
+	if out != expected {
+		t.Errorf("unexpected structural diff:\n%s", out)
+	}
+
+	after, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("--diff must not write the output")
+	}
+}
+
+func TestRunWithCLIDiffErrors(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "app.jsonnet")
+	writeFile(t, jsonnetFile, `{}`)
+
+	tests := []struct {
+		name    string
+		cli     armed.CLI
+		wantErr string
+	}{
+		{"no output", armed.CLI{Diff: true}, "--diff requires -o/--output"},
+		{"http output", armed.CLI{Diff: true, Output: []string{"https://example.com/app.json"}}, "--diff supports file outputs only"},
+		{"watch", armed.CLI{Diff: true, Output: []string{"out.json"}, Watch: true}, "--diff cannot be used with --watch"},
+		{"structural with msgpack", armed.CLI{Diff: true, DiffFormat: "structural", Output: []string{"out.json"}, Format: "msgpack"}, "--diff-format structural requires JSON output"},
+		{"check without output", armed.CLI{Check: true}, "--check requires -o/--output"},
+		{"check with s3 output", armed.CLI{Check: true, Output: []string{"s3://bucket/app.json"}}, "--check supports file outputs only"},
+		{"diff format without diff", armed.CLI{DiffFormat: "structural"}, "--diff-format requires --diff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.Filename = jsonnetFile
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLICheck(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	outFile := filepath.Join(dir, "app.json")
+
+	run := func(content string) (string, error) {
+		t.Helper()
+		writeFile(t, jsonnetFile, content)
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile, Output: []string{outFile}, Check: true}
+		cli.SetWriter(&output)
+		return output.String(), cli.Run(t.Context())
+	}
+	exitCode := func(err error) int {
+		t.Helper()
+		if err == nil {
+			return 0
+		}
+		var exitErr *armed.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected ExitError, got %v", err)
+		}
+		return exitErr.Code
+	}
+
+	out, err := run(`{ replicas: 3 }`)
+	if code := exitCode(err); code != 1 {
+		t.Errorf("missing output: expected exit status 1, got %d (%v)", code, err)
+	}
+	if out != "" {
+		t.Errorf("--check must not print the result: %s", out)
+	}
+	if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+		t.Fatalf("--check must not write the output: %v", err)
+	}
+
+	cli := &armed.CLI{Filename: jsonnetFile, Output: []string{outFile}}
+	cli.SetWriter(&bytes.Buffer{})
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := run(`{ replicas: 3 }`); exitCode(err) != 0 {
+		t.Errorf("unchanged output: expected exit status 0, got %v", err)
+	}
+	if _, err := run(`{ replicas: 5 }`); exitCode(err) != 1 {
+		t.Errorf("changed output: expected exit status 1, got %v", err)
+	}
+	if _, err := run(`{ replicas: error "boom" }`); exitCode(err) != 2 {
+		t.Errorf("evaluation error: expected exit status 2, got %v", err)
+	}
+}
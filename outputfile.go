@@ -0,0 +1,73 @@
+package armed
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// defaultOutputMode is the permissions of output files without --output-mode
+const defaultOutputMode os.FileMode = 0644
+
+// outputFileAttrs returns the permissions of output files given by
+// --output-mode, and the owner and group given by --output-owner (-1 when
+// unchanged).
+func (cli *CLI) outputFileAttrs() (mode os.FileMode, uid, gid int, err error) {
+	mode, uid, gid = defaultOutputMode, -1, -1
+	if cli.OutputMode != "" {
+		m, err := strconv.ParseUint(cli.OutputMode, 8, 32)
+		if err != nil || m > 0777 {
+			return 0, 0, 0, fmt.Errorf("invalid --output-mode %q: must be octal permissions, e.g. 0600", cli.OutputMode)
+		}
+		mode = os.FileMode(m)
+	}
+	if cli.OutputOwner != "" {
+		name, group, _ := strings.Cut(cli.OutputOwner, ":")
+		if name != "" {
+			if uid, err = lookupID(name, user.Lookup, func(u *user.User) string { return u.Uid }); err != nil {
+				return 0, 0, 0, fmt.Errorf("invalid --output-owner %q: %w", cli.OutputOwner, err)
+			}
+		}
+		if group != "" {
+			if gid, err = lookupID(group, user.LookupGroup, func(g *user.Group) string { return g.Gid }); err != nil {
+				return 0, 0, 0, fmt.Errorf("invalid --output-owner %q: %w", cli.OutputOwner, err)
+			}
+		}
+	}
+	return mode, uid, gid, nil
+}
+
+// lookupID returns the numeric ID of a user or group given by name or ID
+func lookupID[T any](name string, lookup func(string) (T, error), id func(T) string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 {
+		return n, nil
+	}
+	v, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id(v))
+}
+
+// setOutputFileAttrs applies --output-mode and --output-owner to an existing
+// output file, which is not rewritten by --write-if-changed
+func (cli *CLI) setOutputFileAttrs(filename string) error {
+	if cli.OutputMode == "" && cli.OutputOwner == "" {
+		return nil
+	}
+	mode, uid, gid, err := cli.outputFileAttrs()
+	if err != nil {
+		return err
+	}
+	if uid >= 0 || gid >= 0 {
+		if err := os.Chown(filename, uid, gid); err != nil {
+			return err
+		}
+	}
+	if cli.OutputMode != "" {
+		return os.Chmod(filename, mode)
+	}
+	return nil
+}
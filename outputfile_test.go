@@ -0,0 +1,89 @@
+package armed_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestOutputMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on windows")
+	}
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "secret.jsonnet")
+	writeFile(t, jsonnetFile, `{password: "s3cr3t"}`)
+	out := filepath.Join(dir, "secret.json")
+
+	run := func(t *testing.T, cli *armed.CLI) {
+		t.Helper()
+		cli.Filename = jsonnetFile
+		cli.Output = []string{out}
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertMode := func(t *testing.T, want os.FileMode) {
+		t.Helper()
+		st, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := st.Mode().Perm(); got != want {
+			t.Errorf("mode: got %#o, want %#o", got, want)
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		run(t, &armed.CLI{})
+		assertMode(t, 0644)
+	})
+	t.Run("output-mode", func(t *testing.T) {
+		run(t, &armed.CLI{OutputMode: "0600"})
+		assertMode(t, 0600)
+	})
+	t.Run("unchanged file with write-if-changed", func(t *testing.T) {
+		run(t, &armed.CLI{OutputMode: "640", WriteIfChanged: true})
+		assertMode(t, 0640)
+	})
+	t.Run("output-owner", func(t *testing.T) {
+		// Changing to the current owner is permitted without privileges
+		run(t, &armed.CLI{OutputMode: "0600", OutputOwner: fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())})
+		assertMode(t, 0600)
+	})
+}
+
+func TestOutputModeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cli  armed.CLI
+		want string
+	}{
+		{"not octal", armed.CLI{OutputMode: "0644x"}, `invalid --output-mode "0644x"`},
+		{"too large", armed.CLI{OutputMode: "1777"}, `invalid --output-mode "1777"`},
+		{"unknown user", armed.CLI{OutputOwner: "jsonnet-armed-no-such-user"}, `invalid --output-owner "jsonnet-armed-no-such-user"`},
+		{"unknown group", armed.CLI{OutputOwner: ":jsonnet-armed-no-such-group"}, `invalid --output-owner ":jsonnet-armed-no-such-group"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			jsonnetFile := filepath.Join(dir, "test.jsonnet")
+			writeFile(t, jsonnetFile, `{a: 1}`)
+			cli := tt.cli
+			cli.Filename = jsonnetFile
+			cli.Output = []string{filepath.Join(dir, "out.json")}
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, err := os.Stat(cli.Output[0]); !os.IsNotExist(err) {
+				t.Errorf("the output must not be written: %v", err)
+			}
+		})
+	}
+}
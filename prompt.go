@@ -0,0 +1,124 @@
+package armed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+	"golang.org/x/term"
+)
+
+// secretNamePattern matches the names of variables whose values are read
+// without echo by --prompt
+var secretNamePattern = regexp.MustCompile(`(?i)(pass|secret|token|key|credential|private|auth)`)
+
+// prompter asks for the values of missing variables (--prompt). Answers are
+// kept, so a variable is asked once. It is safe for concurrent use.
+type prompter struct {
+	mu      sync.Mutex
+	in      *bufio.Reader
+	out     io.Writer
+	hidden  func() (string, error) // reads a line without echo
+	answers map[string]string
+}
+
+// stdinIsTerminal reports whether stdin is a terminal, which --prompt requires
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// newTerminalPrompter returns a prompter reading from stdin, which must be a
+// terminal, and writing the prompts to stderr
+func newTerminalPrompter() *prompter {
+	return &prompter{
+		in:  bufio.NewReader(os.Stdin),
+		out: os.Stderr,
+		hidden: func() (string, error) {
+			b, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			return string(b), err
+		},
+		answers: make(map[string]string),
+	}
+}
+
+// prompt asks for the value of the variable name. Values of names matching
+// secretNamePattern are read without echo and scrubbed from logs.
+func (p *prompter) prompt(name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if v, ok := p.answers[name]; ok {
+		return v, nil
+	}
+	secret := secretNamePattern.MatchString(name)
+	if secret {
+		fmt.Fprintf(p.out, "%s (hidden): ", name)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", name)
+	}
+	var v string
+	var err error
+	if secret {
+		v, err = p.hidden()
+	} else {
+		v, err = p.in.ReadString('\n')
+		if err == io.EOF && v != "" {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	v = strings.TrimRight(v, "\r\n")
+	if secret {
+		functions.RegisterSecret(v)
+	}
+	p.answers[name] = v
+	return v, nil
+}
+
+// promptExtVars asks for the external variables referenced by the input
+// files with std.extVar("name") and not given by flags. They are added to
+// ExtStr, so the cache key covers them.
+func (cli *CLI) promptExtVars() error {
+	if cli.prompter == nil || cli.Filename == "-" {
+		return nil
+	}
+	var names []string
+	for _, f := range append([]string{cli.Filename}, cli.Overlays...) {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			// Reported by the evaluation
+			continue
+		}
+		walkSources(f, string(b), cli.Lib, func(refs sourceRefs) {
+			names = append(names, refs.extVars...)
+		})
+	}
+	for _, name := range names {
+		if cli.hasExtVar(name) {
+			continue
+		}
+		v, err := cli.prompter.prompt(name)
+		if err != nil {
+			return err
+		}
+		cli.ExtStr = merged(cli.ExtStr, map[string]string{name: v})
+	}
+	return nil
+}
+
+// hasExtVar reports whether the external variable name is given by flags
+func (cli *CLI) hasExtVar(name string) bool {
+	for _, f := range cli.extVarFlags() {
+		if _, ok := f.values[name]; ok {
+			return true
+		}
+	}
+	return (cli.AutoArmed && name == armedLibVar) || (cli.LibHelpers && name == libHelpersVar)
+}
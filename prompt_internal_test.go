@@ -0,0 +1,90 @@
+package armed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrompt(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.libsonnet")
+	if err := os.WriteFile(lib, []byte(`{ region: std.extVar("region") }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`
+		local must_env = std.native("must_env");
+		{
+			env: std.extVar("env"),
+			region: (import "lib.libsonnet").region,
+			given: std.extVar("given"),
+			user: must_env("PROMPT_TEST_USER"),
+			again: must_env("PROMPT_TEST_USER"),
+			token: must_env("PROMPT_TEST_TOKEN"),
+		}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"PROMPT_TEST_USER", "PROMPT_TEST_TOKEN"} {
+		t.Setenv(name, "") // restored after the test
+		os.Unsetenv(name)
+	}
+
+	var prompts, output bytes.Buffer
+	hiddenReads := 0
+	cli := &CLI{
+		Filename: jsonnetFile,
+		ExtStr:   map[string]string{"given": "flag"},
+		Prompt:   true,
+		writer:   &output,
+		prompter: &prompter{
+			in:  bufio.NewReader(strings.NewReader("production\nus-west-2\nalice\n")),
+			out: &prompts,
+			hidden: func() (string, error) {
+				hiddenReads++
+				return "s3cr3t", nil
+			},
+			answers: make(map[string]string),
+		},
+	}
+	if err := cli.run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(output.Bytes(), &got); err != nil {
+		t.Fatalf("invalid output %q: %v", output.String(), err)
+	}
+	want := map[string]string{"env": "production", "region": "us-west-2", "given": "flag", "user": "alice", "again": "alice", "token": "s3cr3t"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+	if p := prompts.String(); p != "env: region: PROMPT_TEST_USER: PROMPT_TEST_TOKEN (hidden): " {
+		t.Errorf("unexpected prompts %q", p)
+	}
+	if hiddenReads != 1 {
+		t.Errorf("expected 1 hidden read, got %d", hiddenReads)
+	}
+}
+
+func TestPromptNotTerminal(t *testing.T) {
+	if stdinIsTerminal() {
+		t.Skip("stdin is a terminal")
+	}
+	jsonnetFile := filepath.Join(t.TempDir(), "app.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ env: std.extVar("env") }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Without a prompter (stdin is not a terminal), the evaluation fails as usual
+	cli := &CLI{Filename: jsonnetFile, Prompt: true, writer: &bytes.Buffer{}}
+	err := cli.run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "Undefined external variable: env") {
+		t.Errorf("expected an undefined external variable error, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+// applyQuery runs the jq filter of --query on an evaluation result and
+// manifests the filtered value in the jsonnet output style. As with the jq
+// native function, several results become an array and none becomes null.
+func (cli *CLI) applyQuery(jsonStr string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(jsonStr))
+	dec.UseNumber() // keep numbers as they were manifested
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("failed to parse the result for --query: %w", err)
+	}
+	filtered, err := functions.RunJQ(cli.Query, v)
+	if err != nil {
+		return "", fmt.Errorf("--query: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(filtered); err != nil {
+		return "", fmt.Errorf("--query: failed to encode the result: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,80 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIQuery(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "services.jsonnet")
+	writeFile(t, file, `{
+		services: [
+			{ name: "web", enabled: true, port: 80 },
+			{ name: "db", enabled: false, port: 5432 },
+			{ name: "api", enabled: true, port: 8080 },
+		],
+		big: 12345678901234567890,
+	}`)
+
+	tests := []struct {
+		name     string
+		query    string
+		raw      bool
+		expected string
+	}{
+		{name: "sub-document", query: ".services[0]", expected: `{"name": "web", "enabled": true, "port": 80}`},
+		{name: "several results", query: ".services[] | select(.enabled) | .name", expected: `["web", "api"]`},
+		{name: "no results", query: ".services[] | select(.port > 10000)", expected: `null`},
+		{name: "reshape", query: "[.services[] | {(.name): .port}] | add", expected: `{"web": 80, "db": 5432, "api": 8080}`},
+		{name: "numbers are kept", query: ".big", expected: `12345678901234567000`},
+		{name: "raw output", query: ".services[1].name", raw: true, expected: "db\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, Query: tt.query, RawOutput: tt.raw}
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.raw {
+				if output.String() != tt.expected {
+					t.Errorf("expected %q, got %q", tt.expected, output.String())
+				}
+				return
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLIQueryErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.jsonnet")
+	writeFile(t, file, `{ a: "x" }`)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: ".[", want: "invalid --query"},
+		{query: ".a + 1", want: "--query: jq: failed to execute query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, Query: tt.query}
+			cli.SetWriter(&output)
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if output.Len() != 0 {
+				t.Errorf("expected no output, got %q", output.String())
+			}
+		})
+	}
+}
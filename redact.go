@@ -0,0 +1,43 @@
+package armed
+
+import (
+	"io"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+// redactedError is an error whose message has secret values scrubbed.
+// It unwraps to the original error, so errors.Is keeps working.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError scrubs registered secret values from the message of err
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := functions.RedactSecrets(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// redactingWriter scrubs registered secret values from everything written
+// to w. It is installed as the output of the standard logger, which the
+// default slog handler writes to.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (rw redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, functions.RedactSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
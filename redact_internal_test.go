@@ -0,0 +1,55 @@
+package armed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestRedactError(t *testing.T) {
+	functions.RegisterSecret("redact-error-secret")
+
+	err := redactError(fmt.Errorf("wrapped: %w: token redact-error-secret", ErrAssertionFailed))
+	if got, want := err.Error(), "wrapped: assertion failed: token ***"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrAssertionFailed) {
+		t.Error("redacted error does not unwrap to the original error")
+	}
+
+	plain := errors.New("no secrets here")
+	if redactError(plain) != plain {
+		t.Error("error without secrets should be returned as it is")
+	}
+	if redactError(nil) != nil {
+		t.Error("nil error should stay nil")
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	functions.RegisterSecret("redact-writer-secret")
+
+	var buf bytes.Buffer
+	logger := log.New(redactingWriter{w: &buf}, "", 0)
+	logger.Print("failed: redact-writer-secret")
+	if got, want := buf.String(), "failed: ***\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEvaluateRedactsSecretsInErrors(t *testing.T) {
+	t.Setenv("REDACT_TEST_TOKEN", "evaluate-secret-token")
+	cli := &CLI{Filename: "-"}
+	_, err := cli.evaluate(t.Context(), `error "failed with " + std.native("must_env")("REDACT_TEST_TOKEN")`, true)
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if strings.Contains(err.Error(), "evaluate-secret-token") {
+		t.Errorf("secret is not redacted: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLIRedact(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `
+	local armed = import 'armed.libsonnet';
+	{
+		user: "app",
+		password: armed.secret("hunter2-password"),
+		dsn: "postgres://app:" + self.password + "@db/app",
+	}`)
+
+	t.Run("stdout is masked", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile, Redact: true}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, `{"user": "app", "password": "***", "dsn": "postgres://app:***@db/app"}`, output.String())
+	})
+
+	t.Run("output files get real values", func(t *testing.T) {
+		outputFile := filepath.Join(tmpDir, "out.json")
+		cli := &armed.CLI{Filename: jsonnetFile, Redact: true, Output: []string{outputFile}}
+		cli.SetWriter(&bytes.Buffer{})
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareJSON(t, `{"user": "app", "password": "hunter2-password", "dsn": "postgres://app:hunter2-password@db/app"}`, string(b))
+	})
+
+	t.Run("without --redact", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, `{"user": "app", "password": "hunter2-password", "dsn": "postgres://app:hunter2-password@db/app"}`, output.String())
+	})
+}
@@ -0,0 +1,96 @@
+package armed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+
+	"github.com/alecthomas/kong"
+	"github.com/google/go-jsonnet"
+)
+
+// isRemoteInput reports whether filename is an http(s) or s3:// URL to be
+// fetched and evaluated
+func isRemoteInput(filename string) bool {
+	if _, _, ok := parseS3URL(filename); ok {
+		return true
+	}
+	u, err := url.Parse(filename)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// inputMapper is the kong mapper of <filename>: a path like type:"path",
+// except that "-" and remote URLs are kept as they are
+var inputMapper = kong.MapperFunc(func(ctx *kong.DecodeContext, target reflect.Value) error {
+	var filename string
+	if err := ctx.Scan.PopValueInto("file", &filename); err != nil {
+		return err
+	}
+	if filename != "-" && !isRemoteInput(filename) {
+		filename = kong.ExpandPath(filename)
+	}
+	target.SetString(filename)
+	return nil
+})
+
+// fetchInput returns the content of a remote input
+func (cli *CLI) fetchInput(ctx context.Context) ([]byte, error) {
+	if bucket, key, ok := parseS3URL(cli.Filename); ok {
+		return cli.readS3Object(ctx, bucket, key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cli.Filename, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("User-Agent", "jsonnet-armed/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", cli.Filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", cli.Filename, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", cli.Filename, err)
+	}
+	return b, nil
+}
+
+// snippetName returns the name of the input evaluated as a snippet, shown in
+// error messages: "<cmdline>" for -e/--exec, "stdin" or the URL of a remote input
+func (cli *CLI) snippetName() string {
+	if cli.Exec != "" {
+		return "<cmdline>"
+	}
+	if isRemoteInput(cli.Filename) {
+		return cli.Filename
+	}
+	return "stdin"
+}
+
+// importFromRemote imports a file for a remote input. A remote template
+// must not read files next to the working directory, so its imports are
+// resolved in the --lib directories only, later directories first.
+func (ai *ArmedImporter) importFromRemote(importedPath string) (jsonnet.Contents, string, error) {
+	jpaths := ai.fileImporter.JPaths
+	if len(jpaths) == 0 {
+		return jsonnet.Contents{}, "", fmt.Errorf("cannot import %q from the remote input %s: imports of remote inputs are resolved in --lib directories only", importedPath, ai.remote)
+	}
+	// FileImporter tries the directory of importedFrom, then JPaths from the last
+	contents, foundAt, err := ai.fileImporter.Import(filepath.Join(jpaths[len(jpaths)-1], "remote.jsonnet"), importedPath)
+	if err != nil {
+		return jsonnet.Contents{}, "", err
+	}
+	for _, dir := range jpaths {
+		if rel, err := filepath.Rel(dir, foundAt); err == nil && filepath.IsLocal(rel) {
+			return contents, foundAt, nil
+		}
+	}
+	return jsonnet.Contents{}, "", fmt.Errorf("cannot import %q from the remote input %s: %s is outside the --lib directories", importedPath, ai.remote, foundAt)
+}
@@ -0,0 +1,122 @@
+package armed
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestInputMapper(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"config.jsonnet", filepath.Join(wd, "config.jsonnet")},
+		{"-", "-"},
+		{"https://example.com/templates/app.jsonnet", "https://example.com/templates/app.jsonnet"},
+		{"s3://bucket/templates/app.jsonnet", "s3://bucket/templates/app.jsonnet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			root := &rootCLI{}
+			parser, err := kong.New(root, kong.Vars{"version": "test"}, kong.NamedMapper("input", inputMapper))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.Parse([]string{tt.arg}); err != nil {
+				t.Fatal(err)
+			}
+			if root.Eval.Filename != tt.want {
+				t.Errorf("got %q, want %q", root.Eval.Filename, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.jsonnet":
+			w.Write([]byte(`{env: std.extVar('env'), version: (import 'armed.libsonnet').armed_version() != ''}`))
+		case "/lib.jsonnet":
+			w.Write([]byte(`(import 'common.libsonnet') + {app: true}`))
+		case "/local.jsonnet":
+			w.Write([]byte(`import 'remote_internal_test.go'`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	libDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(libDir, "common.libsonnet"), []byte(`{common: true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cli     *CLI
+		want    string
+		wantErr string
+	}{
+		{
+			name: "evaluated with ext vars and armed.libsonnet",
+			cli:  &CLI{Filename: server.URL + "/app.jsonnet", ExtStr: map[string]string{"env": "prod"}},
+			want: `"env": "prod"`,
+		},
+		{
+			name: "imports from --lib",
+			cli:  &CLI{Filename: server.URL + "/lib.jsonnet", Lib: []string{libDir}},
+			want: `"common": true`,
+		},
+		{
+			name:    "no local imports",
+			cli:     &CLI{Filename: server.URL + "/local.jsonnet"},
+			wantErr: "imports of remote inputs are resolved in --lib directories only",
+		},
+		{
+			name:    "not found",
+			cli:     &CLI{Filename: server.URL + "/missing.jsonnet"},
+			wantErr: "status 404",
+		},
+		{
+			name:    "no --watch",
+			cli:     &CLI{Filename: server.URL + "/app.jsonnet", Watch: true},
+			wantErr: "--watch cannot be used with a remote input",
+		},
+		{
+			name:    "no --incremental",
+			cli:     &CLI{Filename: server.URL + "/app.jsonnet", Incremental: true, Output: []string{filepath.Join(t.TempDir(), "out.json")}},
+			wantErr: "--incremental cannot be used with a remote input",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.cli.SetWriter(&buf)
+			err := tt.cli.Run(t.Context())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("expected output containing %q, got %s", tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// defaultOutputRetryWait is the wait before the first retry of --output-retry
+const defaultOutputRetryWait = time.Second
+
+// maxOutputRetryWait caps the exponential backoff of --output-retry
+const maxOutputRetryWait = time.Minute
+
+// transientError marks an output write failure that may succeed on a retry,
+// such as a network error or a 5xx or 429 response
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// isTransient reports whether a failed output write is worth retrying
+func isTransient(err error) bool {
+	var te *transientError
+	if errors.As(err, &te) {
+		return true
+	}
+	// S3 errors, classified as the AWS SDK does for its own retries
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// isTransientStatus reports whether an HTTP status is worth retrying
+func isTransientStatus(code int) bool {
+	return code >= 500 || code == 429
+}
+
+// retryOutput calls write, retrying transient failures up to --output-retry
+// times with exponential backoff starting at --output-retry-wait
+func (cli *CLI) retryOutput(ctx context.Context, out string, write func() error) error {
+	wait := cli.OutputRetryWait
+	if wait <= 0 {
+		wait = defaultOutputRetryWait
+	}
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || attempt > cli.OutputRetry || !isTransient(err) {
+			if err != nil && attempt > 1 {
+				return fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return err
+		}
+		slog.Warn("Retrying output", "output", out, "attempt", attempt, "wait", wait, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxOutputRetryWait)
+	}
+}
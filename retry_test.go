@@ -0,0 +1,95 @@
+package armed_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestOutputRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		retry        int
+		statuses     []int
+		wantAttempts int32
+		wantErr      string
+	}{
+		{
+			name:         "succeeds after transient failures",
+			retry:        3,
+			statuses:     []int{503, 429, 200},
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after retries",
+			retry:        1,
+			statuses:     []int{503, 502, 200},
+			wantAttempts: 2,
+			wantErr:      "status 502: unavailable (after 2 attempts)",
+		},
+		{
+			name:         "no retry by default",
+			statuses:     []int{503, 200},
+			wantAttempts: 1,
+			wantErr:      "status 503",
+		},
+		{
+			name:         "client errors are not retried",
+			retry:        3,
+			statuses:     []int{400, 200},
+			wantAttempts: 1,
+			wantErr:      "status 400",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				status := tt.statuses[n-1]
+				w.WriteHeader(status)
+				if status != 200 {
+					w.Write([]byte("unavailable"))
+				}
+			}))
+			defer server.Close()
+
+			jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+			writeFile(t, jsonnetFile, `{a: 1}`)
+			cli := &armed.CLI{
+				Filename:        jsonnetFile,
+				Output:          []string{server.URL},
+				OutputRetry:     tt.retry,
+				OutputRetryWait: time.Millisecond,
+			}
+			err := cli.Run(t.Context())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestOutputRetryNegative(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{}`)
+	cli := &armed.CLI{
+		Filename:    jsonnetFile,
+		OutputRetry: -1,
+	}
+	if err := cli.Run(t.Context()); err == nil || !strings.Contains(err.Error(), "--output-retry must not be negative") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+// parseS3URL splits an s3://bucket/key URL. ok is false for other URLs.
+func parseS3URL(s string) (bucket, key string, ok bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", false
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	if key == "" {
+		return "", "", false
+	}
+	return u.Host, key, true
+}
+
+// s3Client returns an S3 client configured with the --aws-* flags
+func (cli *CLI) s3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := functions.LoadAWSConfig(functions.WithAWSOptions(ctx, cli.awsOptions()))
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+func (cli *CLI) writeOutputToS3(ctx context.Context, bucket, key string, data string) error {
+	// Warn if --write-if-changed is used with S3 output
+	if cli.WriteIfChanged {
+		fmt.Fprintf(os.Stderr, "Warning: --write-if-changed has no effect when outputting to S3\n")
+	}
+
+	client, err := cli.s3Client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader([]byte(data)),
+		ContentType: aws.String(cli.contentType()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// readS3Object returns the content of an S3 object
+func (cli *CLI) readS3Object(ctx context.Context, bucket, key string) ([]byte, error) {
+	client, err := cli.s3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+	b, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	return b, nil
+}
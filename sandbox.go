@@ -0,0 +1,112 @@
+package armed
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/google/go-jsonnet"
+)
+
+// sandboxGroups are the native functions disabled by --no-exec, --no-net
+// and --no-fs (all of them by --sandbox)
+var sandboxGroups = map[string][]string{
+	"--no-exec": {"exec", "exec_with_env"},
+	"--no-net": {
+		"aws_cfn_output", "aws_cfn_outputs", "aws_dynamodb_get",
+		"dns_lookup",
+		"github_api", "github_release",
+		"http_get", "http_request",
+		"ldap_search",
+		"net_port_listening",
+		"oidc_token",
+		"redis_get", "redis_hgetall",
+		"sql_query",
+	},
+	"--no-fs": {
+		"file_content", "file_exists", "file_stat",
+		"md5_file", "sha1_file", "sha256_file", "sha512_file",
+		"proto_decode",
+		"x509_certificate", "x509_private_key",
+	},
+}
+
+// disabledFunctions returns the names of the native functions disabled by
+// the sandbox flags, with the flag disabling each of them
+func (cli *CLI) disabledFunctions() map[string]string {
+	disabled := map[string]string{}
+	for group, enabled := range map[string]bool{"--no-exec": cli.NoExec, "--no-net": cli.NoNet, "--no-fs": cli.NoFS} {
+		flag := group
+		switch {
+		case cli.Sandbox:
+			flag = "--sandbox"
+		case !enabled:
+			continue
+		}
+		for _, name := range sandboxGroups[group] {
+			disabled[name] = flag
+		}
+	}
+	return disabled
+}
+
+// checkFnPatterns rejects malformed --allow-fn and --deny-fn patterns
+func (cli *CLI) checkFnPatterns() error {
+	for flag, patterns := range map[string][]string{"--allow-fn": cli.AllowFn, "--deny-fn": cli.DenyFn} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", flag, p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// blockedBy returns why the native function name is blocked by --allow-fn
+// or --deny-fn, or "" if it is not. --deny-fn takes precedence.
+func (cli *CLI) blockedBy(name string) string {
+	for _, p := range cli.DenyFn {
+		if ok, _ := path.Match(p, name); ok {
+			return fmt.Sprintf("disabled by --deny-fn %q", p)
+		}
+	}
+	if len(cli.AllowFn) == 0 {
+		return ""
+	}
+	for _, p := range cli.AllowFn {
+		if ok, _ := path.Match(p, name); ok {
+			return ""
+		}
+	}
+	return "not allowed by --allow-fn"
+}
+
+// sandbox returns copies of funcs in which the functions disabled by the
+// sandbox flags, --allow-fn and --deny-fn fail without running. They are
+// kept in the list, so that armed.libsonnet has the same fields and mocks
+// can replace them.
+func (cli *CLI) sandbox(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	disabled := cli.disabledFunctions()
+	if len(disabled) == 0 && len(cli.AllowFn) == 0 && len(cli.DenyFn) == 0 {
+		return funcs
+	}
+	funcs = slices.Clone(funcs)
+	for i, f := range funcs {
+		reason := cli.blockedBy(f.Name)
+		if flag, ok := disabled[f.Name]; ok {
+			reason = "disabled by " + flag
+		}
+		if reason == "" {
+			continue
+		}
+		name := f.Name
+		funcs[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				return nil, fmt.Errorf("%s is %s", name, reason)
+			},
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,33 @@
+package armed
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestSandboxGroupsAreFunctions(t *testing.T) {
+	names := map[string]bool{}
+	for _, f := range functions.GenerateAllFunctions(t.Context()) {
+		names[f.Name] = true
+	}
+	for flag, group := range sandboxGroups {
+		for _, name := range group {
+			if !names[name] {
+				t.Errorf("%s disables %s, which is not a native function", flag, name)
+			}
+		}
+	}
+}
+
+func TestSandboxNoFSCoversFileParams(t *testing.T) {
+	for _, f := range functions.GenerateAllFunctions(t.Context()) {
+		for _, p := range f.Params {
+			if (p == "filename" || strings.HasSuffix(string(p), "_file")) && !slices.Contains(sandboxGroups["--no-fs"], f.Name) {
+				t.Errorf("%s reads the file %s, but is not disabled by --no-fs", f.Name, p)
+			}
+		}
+	}
+}
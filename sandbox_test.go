@@ -0,0 +1,111 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+func TestRunWithCLISandbox(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataFile := filepath.Join(tmpDir, "data.txt")
+	writeFile(t, dataFile, "data")
+
+	tests := []struct {
+		name    string
+		cli     *armed.CLI
+		code    string
+		wantErr string
+	}{
+		{"no-exec", &armed.CLI{NoExec: true}, `std.native("exec")("true", [])`, "exec is disabled by --no-exec"},
+		{"no-net", &armed.CLI{NoNet: true}, `std.native("http_get")("http://127.0.0.1:1/", {})`, "http_get is disabled by --no-net"},
+		{"no-net dns", &armed.CLI{NoNet: true}, `std.native("dns_lookup")("example.com", "A")`, "dns_lookup is disabled by --no-net"},
+		{"no-fs", &armed.CLI{NoFS: true}, `std.native("file_content")("` + dataFile + `")`, "file_content is disabled by --no-fs"},
+		{"no-fs hash", &armed.CLI{NoFS: true}, `std.native("sha256_file")("` + dataFile + `")`, "sha256_file is disabled by --no-fs"},
+		{"sandbox", &armed.CLI{Sandbox: true}, `std.native("exec")("true", [])`, "exec is disabled by --sandbox"},
+		{"sandbox via armed.libsonnet", &armed.CLI{Sandbox: true}, `(import 'armed.libsonnet').file_exists("` + dataFile + `")`, "file_exists is disabled by --sandbox"},
+		{"other groups are enabled", &armed.CLI{NoNet: true}, `std.native("file_content")("` + dataFile + `")`, ""},
+		{"pure functions are enabled", &armed.CLI{Sandbox: true}, `std.native("sha256")("data")`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.Exec = tt.code
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLISandboxMock(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockFile := filepath.Join(tmpDir, "mocks.json")
+	writeFile(t, mockFile, `[{"name": "http_get", "result": {"status_code": 200, "body": "mocked"}}]`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{
+		Exec:    `std.native("http_get")("https://api.example.com/", {}).body`,
+		Mock:    mockFile,
+		Sandbox: true,
+	}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(output.String()); got != `"mocked"` {
+		t.Errorf("got %s, want \"mocked\"", got)
+	}
+}
+
+func TestRunWithCLIAllowDenyFn(t *testing.T) {
+	tests := []struct {
+		name    string
+		cli     *armed.CLI
+		code    string
+		wantErr string
+	}{
+		{"deny", &armed.CLI{DenyFn: []string{"http_*"}}, `std.native("http_get")("http://127.0.0.1:1/", {})`, `http_get is disabled by --deny-fn "http_*"`},
+		{"deny others", &armed.CLI{DenyFn: []string{"http_*"}}, `std.native("sha256")("data")`, ""},
+		{"allow", &armed.CLI{AllowFn: []string{"sha*", "base64"}}, `std.native("base64")(std.native("sha1")("data"))`, ""},
+		{"not allowed", &armed.CLI{AllowFn: []string{"sha*"}}, `std.native("md5")("data")`, "md5 is not allowed by --allow-fn"},
+		{"deny takes precedence", &armed.CLI{AllowFn: []string{"sha*"}, DenyFn: []string{"sha512"}}, `std.native("sha512")("data")`, `sha512 is disabled by --deny-fn "sha512"`},
+		{"user-defined functions", &armed.CLI{DenyFn: []string{"my_*"}}, `std.native("my_hello")("world")`, `my_hello is disabled by --deny-fn "my_*"`},
+		{"invalid pattern", &armed.CLI{DenyFn: []string{"exec["}}, `1`, `invalid --deny-fn pattern "exec["`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.Exec = tt.code
+			tt.cli.AddFunctions(&jsonnet.NativeFunction{
+				Name:   "my_hello",
+				Params: []ast.Identifier{"name"},
+				Func: func(args []any) (any, error) {
+					return "hello " + args[0].(string), nil
+				},
+			})
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
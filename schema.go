@@ -0,0 +1,68 @@
+package armed
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ErrSchemaValidation is returned by CLI.Run when --schema finds values
+// that do not conform to the JSON Schema.
+var ErrSchemaValidation = errors.New("JSON Schema validation failed")
+
+// compileSchema compiles the JSON Schema file. Draft 2020-12 is used unless
+// the schema declares another one with $schema. References to other local
+// files are resolved relative to the file.
+func compileSchema(filename string) (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	schema, err := c.Compile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --schema %s: %w", filename, err)
+	}
+	return schema, nil
+}
+
+// validateSchema validates an evaluation result against the --schema file.
+// Each violation is reported with the JSON pointer of the offending value.
+func (cli *CLI) validateSchema(jsonStr string) error {
+	schema, err := compileSchema(cli.Schema)
+	if err != nil {
+		return err
+	}
+	v, err := jsonschema.UnmarshalJSON(strings.NewReader(jsonStr))
+	if err != nil {
+		return fmt.Errorf("failed to parse the result for --schema: %w", err)
+	}
+	err = schema.Validate(v)
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	problems := schemaProblems(verr.DetailedOutput(), nil)
+	// Keywords are validated in map order, so sort for a stable report
+	slices.Sort(problems)
+	return fmt.Errorf("%w:\n  %s", ErrSchemaValidation, strings.Join(problems, "\n  "))
+}
+
+// schemaProblems returns the innermost errors of an output unit, which
+// locate the violations most precisely
+func schemaProblems(unit *jsonschema.OutputUnit, problems []string) []string {
+	if unit.Error != nil {
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return append(problems, fmt.Sprintf("%s: %s", location, unit.Error))
+	}
+	for i := range unit.Errors {
+		problems = schemaProblems(&unit.Errors[i], problems)
+	}
+	return problems
+}
@@ -0,0 +1,120 @@
+package armed_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["name", "replicas"],
+  "properties": {
+    "name": {"type": "string"},
+    "replicas": {"type": "integer", "minimum": 1},
+    "ports": {"type": "array", "items": {"$ref": "port.schema.json"}},
+    "env": {"$ref": "#/$defs/env"}
+  },
+  "additionalProperties": false,
+  "$defs": {"env": {"enum": ["dev", "prod"]}}
+}`
+
+func TestRunWithCLISchema(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "app.schema.json")
+	writeFile(t, schemaFile, testSchema)
+	writeFile(t, filepath.Join(dir, "port.schema.json"), `{"type": "object", "properties": {"port": {"type": "integer", "maximum": 65535}}}`)
+
+	tests := []struct {
+		name     string
+		content  string
+		problems []string
+	}{
+		{
+			name:    "valid",
+			content: `{ name: "app", replicas: 2, ports: [{ port: 80 }], env: "dev" }`,
+		},
+		{
+			name:    "violations",
+			content: `{ name: 1, replicas: 0, ports: [{ port: 80 }, { port: 70000 }], env: "stg", extra: true }`,
+			problems: []string{
+				"  /: additional properties 'extra' not allowed",
+				"  /env: value must be one of 'dev', 'prod'",
+				"  /name: got number, want string",
+				"  /ports/1/port: maximum: got 70,000, want 65,535",
+				"  /replicas: minimum: got 0, want 1",
+			},
+		},
+		{
+			name:     "missing property",
+			content:  `{ name: "app" }`,
+			problems: []string{"  /: missing property 'replicas'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonnetFile := filepath.Join(t.TempDir(), "app.jsonnet")
+			writeFile(t, jsonnetFile, tt.content)
+			outFile := filepath.Join(t.TempDir(), "app.json")
+			cli := &armed.CLI{Filename: jsonnetFile, Schema: schemaFile, Output: []string{outFile}}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, err := os.Stat(outFile); err != nil {
+					t.Errorf("output is not written: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, armed.ErrSchemaValidation) {
+				t.Fatalf("expected ErrSchemaValidation, got %v", err)
+			}
+			want := "JSON Schema validation failed:\n" + strings.Join(tt.problems, "\n")
+			if err.Error() != want {
+				t.Errorf("unexpected error:\n%s\nwant:\n%s", err, want)
+			}
+			if _, err := os.Stat(outFile); !os.IsNotExist(err) {
+				t.Errorf("output must not be written on violations: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLISchemaErrors(t *testing.T) {
+	dir := t.TempDir()
+	jsonnetFile := filepath.Join(dir, "app.jsonnet")
+	// The evaluation must not run with an unusable schema
+	marker := filepath.Join(dir, "evaluated")
+	writeFile(t, jsonnetFile, `std.native("exec")("touch", ["`+marker+`"])`)
+	invalid := filepath.Join(dir, "invalid.json")
+	writeFile(t, invalid, `{"type": 1}`)
+
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{"missing file", filepath.Join(dir, "missing.json"), "invalid --schema"},
+		{"invalid schema", invalid, "invalid --schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := &armed.CLI{Filename: jsonnetFile, Schema: tt.schema}
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if _, err := os.Stat(marker); !os.IsNotExist(err) {
+				t.Error("the file is evaluated with an unusable schema")
+			}
+		})
+	}
+}
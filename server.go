@@ -11,12 +11,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/fujiwara/jsonnet-armed/functions"
 	"github.com/google/go-jsonnet"
 )
 
@@ -25,18 +29,31 @@ const serverShutdownTimeout = 5 * time.Second
 // ServeCmd runs an HTTP server that evaluates jsonnet files under Dir
 // and returns the results as JSON.
 type ServeCmd struct {
-	Listen  string            `name:"listen" default:"localhost:9898" help:"Listen address (host:port)"`
-	Timeout time.Duration     `short:"t" name:"timeout" help:"Timeout for each request's evaluation (e.g., 30s, 5m)"`
-	ExtStr  map[string]string `short:"V" name:"ext-str" help:"Default external string variables (overridden by query parameters)"`
-	Cache   time.Duration     `name:"cache" help:"Cache evaluation results in memory for specified duration (e.g., 5m, 1h)"`
-	Stale   time.Duration     `name:"stale" help:"Maximum duration to serve stale cache when evaluation fails (e.g., 10m, 2h)"`
-	Dir     string            `arg:"" name:"dir" help:"Directory containing .jsonnet files to serve" type:"existingdir"`
+	Listen          string                   `name:"listen" default:"localhost:9898" help:"Listen address (host:port)"`
+	Timeout         time.Duration            `short:"t" name:"timeout" help:"Timeout for each request's evaluation (e.g., 30s, 5m)"`
+	ExtStr          map[string]string        `short:"V" name:"ext-str" help:"Default external string variables (overridden by query parameters)"`
+	Cache           time.Duration            `name:"cache" help:"Cache evaluation results in memory for specified duration (e.g., 5m, 1h)"`
+	Stale           time.Duration            `name:"stale" help:"Maximum duration to serve stale cache when evaluation fails (e.g., 10m, 2h)"`
+	Trace           bool                     `name:"trace" help:"Log import cache statistics after each evaluation."`
+	RedisURL        string                   `name:"redis-url" placeholder:"URL" env:"JSONNET_ARMED_REDIS_URL" help:"Redis server for redis_get and redis_hgetall (e.g., redis://localhost:6379/0)."`
+	Resolve         []functions.ResolveEntry `name:"resolve" placeholder:"HOST:PORT:ADDRESS" help:"Connect to ADDRESS for HOST:PORT in http functions, and return ADDRESS for HOST in dns_lookup (can be repeated)."`
+	HostsFile       string                   `name:"hosts-file" placeholder:"FILE" type:"path" help:"Resolve the host names in FILE (in the /etc/hosts format) to its addresses in network functions."`
+	FuncTimeout     map[string]time.Duration `name:"func-timeout" placeholder:"FUNC=DURATION" mapsep:"," help:"Override the timeout of exec, exec_with_env, http_get, http_request and dns_lookup (e.g., exec=10s,http_get=5s)."`
+	ExecMaxParallel int                      `name:"exec-max-parallel" placeholder:"N" help:"Run at most N exec commands at the same time across all requests (default unlimited)."`
+	Dir             string                   `arg:"" name:"dir" help:"Directory containing .jsonnet files to serve" type:"existingdir"`
+
+	AWSFlags `embed:""`
 
 	// functions holds additional native functions to be added to the Jsonnet VM
 	functions []*jsonnet.NativeFunction `kong:"-"`
 
 	// cache holds the in-memory cache for evaluation results
 	cache cacheStore `kong:"-"`
+
+	// vms keeps VMs with parsed imports between requests
+	vms *vmCache `kong:"-"`
+	// vmsMu guards vms, which Reload replaces
+	vmsMu sync.Mutex `kong:"-"`
 }
 
 // AddFunctions adds custom native functions to the server
@@ -46,6 +63,9 @@ func (s *ServeCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
 
 // Run listens on s.Listen and serves HTTP until ctx is cancelled
 func (s *ServeCmd) Run(ctx context.Context) error {
+	if err := checkFuncTimeouts(s.FuncTimeout); err != nil {
+		return err
+	}
 	ln, err := net.Listen("tcp", s.Listen)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", s.Listen, err)
@@ -65,8 +85,11 @@ func (s *ServeCmd) Serve(ctx context.Context, ln net.Listener) error {
 		errCh <- srv.Serve(ln)
 	}()
 	slog.Info("jsonnet-armed server starting", "addr", ln.Addr().String(), "dir", s.Dir)
+	stopping := systemdReady(ctx, "Listening on "+ln.Addr().String(), httpAlive(ln))
+	go reloadOnSignal(ctx, s.Reload)
 	select {
 	case <-ctx.Done():
+		stopping()
 		sctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
 		defer cancel()
 		if err := srv.Shutdown(sctx); err != nil {
@@ -75,15 +98,66 @@ func (s *ServeCmd) Serve(ctx context.Context, ln net.Listener) error {
 		}
 		return nil
 	case err := <-errCh:
+		stopping()
 		return err
 	}
 }
 
+// reloadOnSignal calls reload on each SIGHUP until ctx is cancelled
+func reloadOnSignal(ctx context.Context, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload()
+		}
+	}
+}
+
+// resetSharedState discards the state that native functions share between
+// evaluations: AWS credentials and database and Redis connections
+func resetSharedState() {
+	functions.ResetAWSConfigs()
+	functions.CloseSQLDBs()
+	functions.CloseRedisClients()
+}
+
+// Reload discards the in-memory state of the server: cached results, VMs
+// with parsed imports, AWS credentials and database and Redis connections.
+// In-flight requests are not interrupted; files are read again by each
+// evaluation.
+func (s *ServeCmd) Reload() {
+	if c, ok := s.cache.(*memoryCache); ok {
+		c.clear()
+	}
+	s.vmsMu.Lock()
+	s.vms = newVMCache()
+	s.vmsMu.Unlock()
+	resetSharedState()
+	slog.Info("jsonnet-armed server reloaded", "dir", s.Dir)
+}
+
+// vmCache returns the current VM cache
+func (s *ServeCmd) vmCache() *vmCache {
+	s.vmsMu.Lock()
+	defer s.vmsMu.Unlock()
+	return s.vms
+}
+
 // Handler returns the HTTP handler of the server
 func (s *ServeCmd) Handler() http.Handler {
 	if s.Cache > 0 && s.cache == nil {
 		s.cache = newMemoryCache(s.Cache, s.Stale)
 	}
+	s.vmsMu.Lock()
+	if s.vms == nil {
+		s.vms = newVMCache()
+	}
+	s.vmsMu.Unlock()
 	return http.HandlerFunc(s.handleRequest)
 }
 
@@ -129,9 +203,17 @@ func (s *ServeCmd) processHTTPRequest(w http.ResponseWriter, r *http.Request) in
 	}
 
 	cli := &CLI{
-		Filename:  filename,
-		ExtStr:    s.mergeQueryVars(r.URL.Query()),
-		functions: s.functions,
+		Filename:        filename,
+		ExtStr:          s.mergeQueryVars(r.URL.Query()),
+		AWSFlags:        s.AWSFlags,
+		RedisURL:        s.RedisURL,
+		ExecMaxParallel: s.ExecMaxParallel,
+		FuncTimeout:     s.FuncTimeout,
+		Resolve:         s.Resolve,
+		HostsFile:       s.HostsFile,
+		Trace:           s.Trace,
+		functions:       s.functions,
+		vms:             s.vmCache(),
 	}
 
 	var cacheKey, staleContent string
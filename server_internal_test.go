@@ -0,0 +1,13 @@
+package armed
+
+import "testing"
+
+func TestServeReloadVMs(t *testing.T) {
+	s := &ServeCmd{Dir: "testdata/server"}
+	s.Handler()
+	vms := s.vmCache()
+	s.Reload()
+	if got := s.vmCache(); got == nil || got == vms {
+		t.Error("expected Reload to discard the cached VMs")
+	}
+}
@@ -621,3 +621,26 @@ func TestServerGracefulShutdown(t *testing.T) {
 		t.Error("Serve did not return within 2s after context cancel")
 	}
 }
+
+func TestServerReload(t *testing.T) {
+	s := &armed.ServeCmd{Dir: "testdata/server", Cache: time.Minute}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	_, body1, cache1 := getWithCacheStatus(t, ts.URL+"/uuid.jsonnet")
+	if cache1 != "MISS" {
+		t.Errorf("first request: got X-Cache=%q, want MISS", cache1)
+	}
+	if _, _, cache2 := getWithCacheStatus(t, ts.URL+"/uuid.jsonnet"); cache2 != "HIT" {
+		t.Errorf("second request: got X-Cache=%q, want HIT", cache2)
+	}
+
+	s.Reload()
+	_, body3, cache3 := getWithCacheStatus(t, ts.URL+"/uuid.jsonnet")
+	if cache3 != "MISS" {
+		t.Errorf("after reload: got X-Cache=%q, want MISS", cache3)
+	}
+	if body1 == body3 {
+		t.Errorf("response after reload should be re-evaluated, got same body %q", body3)
+	}
+}
@@ -0,0 +1,211 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// setOverride is a value set by --set or --set-json at a path of the result
+type setOverride struct {
+	flag  string
+	path  []any // string keys and int indexes
+	value any
+}
+
+// parseSetOverrides parses the PATH=VALUE arguments of --set (string values)
+// and --set-json (JSON values). --set-json is applied after --set.
+func (cli *CLI) parseSetOverrides() ([]setOverride, error) {
+	var overrides []setOverride
+	for _, arg := range cli.Set {
+		p, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q: must be PATH=VALUE", arg)
+		}
+		path, err := parseSetPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --set %q: %w", arg, err)
+		}
+		overrides = append(overrides, setOverride{flag: "--set", path: path, value: value})
+	}
+	for _, arg := range cli.SetJSON {
+		p, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set-json %q: must be PATH=JSON", arg)
+		}
+		path, err := parseSetPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --set-json %q: %w", arg, err)
+		}
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid --set-json %q: %w", arg, err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("invalid --set-json %q: trailing data after the value", arg)
+		}
+		overrides = append(overrides, setOverride{flag: "--set-json", path: path, value: v})
+	}
+	return overrides, nil
+}
+
+// parseSetPath parses a path such as a.b[0].c or .labels["app.kubernetes.io/name"],
+// in the form of the paths reported by the diff command
+func parseSetPath(s string) ([]any, error) {
+	var path []any
+	rest := strings.TrimPrefix(s, ".")
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			// A quoted key, ending at the first unescaped quote
+			end := 2
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) || !strings.HasPrefix(rest[end:], `"]`) {
+				return nil, fmt.Errorf("unterminated key in path %q", s)
+			}
+			key, err := strconv.Unquote(rest[1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid key in path %q: %w", s, err)
+			}
+			path = append(path, key)
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			index, after, ok := strings.Cut(rest[1:], "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index in path %q", s)
+			}
+			path = append(path, n)
+			rest = after
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path %q", s)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("empty key in path %q", s)
+			}
+		} else if rest != "" && !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("invalid path %q", s)
+		}
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return path, nil
+}
+
+// applySetOverrides sets the values of --set and --set-json in an evaluation
+// result and manifests it in the jsonnet output style. Missing objects on
+// the paths are created; array indexes must exist.
+func (cli *CLI) applySetOverrides(jsonStr string) (string, error) {
+	overrides, err := cli.parseSetOverrides()
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(strings.NewReader(jsonStr))
+	dec.UseNumber() // keep numbers as they were manifested
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("failed to parse the result for --set: %w", err)
+	}
+	for _, o := range overrides {
+		if v, err = setValue(v, o.path, o.value); err != nil {
+			return "", fmt.Errorf("%s %s: %w", o.flag, formatSetPath(o.path), err)
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// setValue returns v with value set at path
+func setValue(v any, path []any, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	switch elem := path[0].(type) {
+	case string:
+		if v == nil {
+			v = map[string]any{}
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot set key %q of %s", elem, valueKind(v))
+		}
+		child, err := setValue(obj[elem], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[elem] = child
+		return obj, nil
+	default:
+		index := elem.(int)
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot set index %d of %s", index, valueKind(v))
+		}
+		if index >= len(arr) {
+			return nil, fmt.Errorf("index %d is out of range (length %d)", index, len(arr))
+		}
+		child, err := setValue(arr[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		arr[index] = child
+		return arr, nil
+	}
+}
+
+// formatSetPath formats a path like the paths reported by the diff command
+func formatSetPath(path []any) string {
+	var b strings.Builder
+	for _, elem := range path {
+		switch elem := elem.(type) {
+		case string:
+			b.WriteString(pathKey(elem))
+		case int:
+			fmt.Fprintf(&b, "[%d]", elem)
+		}
+	}
+	return b.String()
+}
+
+// valueKind returns the JSON type of a value decoded with UseNumber
+func valueKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
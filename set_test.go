@@ -0,0 +1,94 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLISet(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deployment.jsonnet")
+	writeFile(t, file, `{
+		metadata: { labels: { "app.kubernetes.io/name": "web" } },
+		spec: { replicas: 1, containers: [{ name: "web", image: "web:1.0" }] },
+	}`)
+
+	tests := []struct {
+		name     string
+		set      []string
+		setJSON  []string
+		query    string
+		expected string
+	}{
+		{
+			name:     "string value",
+			set:      []string{"spec.containers[0].image=web:1.1"},
+			expected: `{"metadata": {"labels": {"app.kubernetes.io/name": "web"}}, "spec": {"replicas": 1, "containers": [{"name": "web", "image": "web:1.1"}]}}`,
+		},
+		{
+			name:     "json value",
+			setJSON:  []string{"spec.replicas=3", `.metadata.labels["app.kubernetes.io/name"]="api"`},
+			expected: `{"metadata": {"labels": {"app.kubernetes.io/name": "api"}}, "spec": {"replicas": 3, "containers": [{"name": "web", "image": "web:1.0"}]}}`,
+		},
+		{
+			name:     "missing objects are created",
+			set:      []string{"metadata.annotations.owner=team-a"},
+			query:    ".metadata",
+			expected: `{"labels": {"app.kubernetes.io/name": "web"}, "annotations": {"owner": "team-a"}}`,
+		},
+		{
+			name:     "set-json after set",
+			set:      []string{"spec.replicas=2"},
+			setJSON:  []string{"spec.replicas=2"},
+			query:    ".spec.replicas",
+			expected: `2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, Set: tt.set, SetJSON: tt.setJSON, Query: tt.query}
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLISetErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.jsonnet")
+	writeFile(t, file, `{ a: "x", list: [1] }`)
+
+	tests := []struct {
+		name    string
+		set     []string
+		setJSON []string
+		want    string
+	}{
+		{name: "no value", set: []string{"a"}, want: `invalid --set "a": must be PATH=VALUE`},
+		{name: "empty key", set: []string{"a..b=1"}, want: "empty key"},
+		{name: "bad index", set: []string{"list[x]=1"}, want: "invalid index"},
+		{name: "bad json", setJSON: []string{"a=x"}, want: `invalid --set-json "a=x"`},
+		{name: "index out of range", set: []string{"list[1]=2"}, want: "--set .list[1]: index 1 is out of range (length 1)"},
+		{name: "not an object", set: []string{"a.b=1"}, want: `--set .a.b: cannot set key "b" of a string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := &armed.CLI{Filename: file, Set: tt.set, SetJSON: tt.setJSON}
+			cli.SetWriter(&output)
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if output.Len() != 0 {
+				t.Errorf("expected no output, got %q", output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package armed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrStrictJSON is returned by CLI.Run when --strict-json finds numbers that
+// JSON parsers may not read as they are written
+var ErrStrictJSON = errors.New("the result is not strict JSON")
+
+// maxSafeInteger is the largest integer n such that float64 (and so
+// JavaScript and many JSON parsers) represents n and n+1 exactly. Larger
+// numbers may have been rounded already, as 2^53+1 is output as 2^53.
+const maxSafeInteger = 1<<53 - 1
+
+// checkStrictJSON rejects NaN, Infinity and numbers beyond ±(2^53-1) in an
+// evaluation result, reporting the paths of the offending values
+func checkStrictJSON(jsonStr string) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(jsonStr)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("failed to parse the result for --strict-json: %w", err)
+	}
+	problems := strictJSONProblems(v, "$")
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrStrictJSON, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func strictJSONProblems(v any, path string) []string {
+	var problems []string
+	switch v := v.(type) {
+	case []any:
+		for i, e := range v {
+			problems = append(problems, strictJSONProblems(e, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			problems = append(problems, strictJSONProblems(v[k], jsonPath(path, k))...)
+		}
+	case json.Number:
+		// Numbers too large for float64, such as 1e999, parse as Infinity
+		f, _ := strconv.ParseFloat(string(v), 64)
+		switch {
+		case math.IsNaN(f) || math.IsInf(f, 0):
+			problems = append(problems, fmt.Sprintf("%s: %s is not a finite number", path, v))
+		case math.Abs(f) > maxSafeInteger:
+			n := string(v)
+			if len(n) > 20 {
+				// Jsonnet writes large numbers with all their digits
+				n = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s is beyond ±(2^53-1) and may lose precision; use a string instead", path, n))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,78 @@
+package armed
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckStrictJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		errors []string
+	}{
+		{name: "safe numbers", json: `{"a": [0, -1.5, 9007199254740991, -9007199254740991, 1e-300], "b": "9007199254740993"}`},
+		{
+			name: "beyond 2^53",
+			json: `{"ids": [1, 9007199254740992], "nested": {"big-int": -1234567890123456789}}`,
+			errors: []string{
+				`$.ids[1]: 9007199254740992 is beyond ±(2^53-1) and may lose precision; use a string instead`,
+				`$.nested["big-int"]: -1234567890123456789 is beyond ±(2^53-1) and may lose precision; use a string instead`,
+			},
+		},
+		{
+			name:   "large float",
+			json:   `[1e300, 100000000000000000000000000000]`,
+			errors: []string{`$[0]: 1e300 is beyond`, `$[1]: 1e+29 is beyond`},
+		},
+		{
+			name:   "infinity",
+			json:   `{"x": 1e999}`,
+			errors: []string{`$.x: 1e999 is not a finite number`},
+		},
+		{name: "scalar", json: `42`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStrictJSON(tt.json)
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrStrictJSON) {
+				t.Fatalf("expected ErrStrictJSON, got %v", err)
+			}
+			lines := strings.Split(err.Error(), "\n")[1:]
+			if len(lines) != len(tt.errors) {
+				t.Fatalf("expected %d problems, got %v", len(tt.errors), err)
+			}
+			for i, want := range tt.errors {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("problem %d: expected %q, got %q", i, want, lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunStrictJSON(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "ids.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ count: 3, id: std.pow(2, 60) }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	cli := &CLI{Filename: jsonnetFile, StrictJSON: true, writer: &out}
+	err := cli.Run(t.Context())
+	if !errors.Is(err, ErrStrictJSON) || !strings.Contains(err.Error(), "$.id: ") {
+		t.Fatalf("expected ErrStrictJSON for $.id, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
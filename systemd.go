@@ -0,0 +1,135 @@
+package armed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// sdNotify sends a state to the systemd notification socket ($NOTIFY_SOCKET).
+// It does nothing when the process is not run by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// Abstract namespace socket
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns the interval of watchdog pings, half of
+// WatchdogSec= ($WATCHDOG_USEC), or 0 if the watchdog is not enabled for this process.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// systemdReady notifies systemd that the service is ready and pings the
+// watchdog until ctx is canceled, each time alive reports that the service
+// is responsive; a service that stops responding is restarted by systemd.
+// The returned function notifies systemd that the service is stopping; call
+// it when shutting down.
+func systemdReady(ctx context.Context, status string, alive func(context.Context) error) (stopping func()) {
+	if err := sdNotify("READY=1\nSTATUS=" + status); err != nil {
+		slog.Warn("Failed to notify systemd", "error", err.Error())
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					actx, acancel := context.WithTimeout(ctx, interval)
+					err := alive(actx)
+					acancel()
+					if ctx.Err() != nil {
+						return
+					}
+					if err != nil {
+						slog.Warn("Service is not responding, skipping systemd watchdog ping", "error", err.Error())
+						continue
+					}
+					if err := sdNotify("WATCHDOG=1"); err != nil {
+						slog.Warn("Failed to ping systemd watchdog", "error", err.Error())
+					}
+				}
+			}
+		}()
+	}
+	return func() {
+		cancel()
+		if err := sdNotify("STOPPING=1"); err != nil {
+			slog.Warn("Failed to notify systemd", "error", err.Error())
+		}
+	}
+}
+
+// httpAlive returns a liveness check of the HTTP server serving on ln. It
+// sends "OPTIONS *", which net/http answers without calling the handler, so
+// it passes while the server accepts and serves connections.
+func httpAlive(ln net.Listener) func(context.Context) error {
+	addr := ln.Addr()
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, addr.Network(), addr.String())
+			},
+			DisableKeepAlives: true,
+		},
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodOptions, "http://localhost/", nil)
+		if err != nil {
+			return err
+		}
+		req.URL.Opaque = "*"
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// loopProbe checks that a loop is responsive. The loop receives from it
+// while waiting for its next run, so a loop stuck in a run does not answer.
+type loopProbe chan struct{}
+
+// alive waits for the loop to receive a probe
+func (p loopProbe) alive(ctx context.Context) error {
+	select {
+	case p <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("the loop is busy: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,164 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listenNotify listens on a systemd notification socket with a watchdog
+// interval of 20ms and returns a function receiving the next notification
+func listenNotify(t *testing.T) (receive func(timeout time.Duration) (string, bool)) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd notification is not available on windows")
+	}
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "40000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	return func(timeout time.Duration) (string, bool) {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return "", false
+		}
+		return string(buf[:n]), true
+	}
+}
+
+func TestSystemdReady(t *testing.T) {
+	receiveNotify := listenNotify(t)
+	receive := func() string {
+		t.Helper()
+		got, ok := receiveNotify(5 * time.Second)
+		if !ok {
+			t.Fatal("failed to receive notification")
+		}
+		return got
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	stopping := systemdReady(ctx, "Watching main.jsonnet", func(context.Context) error { return nil })
+	if got := receive(); got != "READY=1\nSTATUS=Watching main.jsonnet" {
+		t.Errorf("unexpected notification: %q", got)
+	}
+	if got := receive(); got != "WATCHDOG=1" {
+		t.Errorf("unexpected notification: %q", got)
+	}
+	stopping()
+	// Drain pings sent before stopping
+	for {
+		got := receive()
+		if got == "STOPPING=1" {
+			break
+		}
+		if got != "WATCHDOG=1" {
+			t.Fatalf("unexpected notification: %q", got)
+		}
+	}
+}
+
+func TestSystemdWatchdogNotResponding(t *testing.T) {
+	receive := listenNotify(t)
+
+	var responding atomic.Bool
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	stopping := systemdReady(ctx, "Scheduled main.jsonnet", func(context.Context) error {
+		if !responding.Load() {
+			return errors.New("busy")
+		}
+		return nil
+	})
+	defer stopping()
+	if got, _ := receive(5 * time.Second); got != "READY=1\nSTATUS=Scheduled main.jsonnet" {
+		t.Fatalf("unexpected notification: %q", got)
+	}
+
+	// No pings while the service is not responding
+	if got, ok := receive(200 * time.Millisecond); ok {
+		t.Fatalf("unexpected notification: %q", got)
+	}
+
+	responding.Store(true)
+	if got, _ := receive(5 * time.Second); got != "WATCHDOG=1" {
+		t.Errorf("unexpected notification: %q", got)
+	}
+}
+
+func TestHTTPAlive(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the handler must not be called: %s %s", r.Method, r.RequestURI)
+	})}
+	go srv.Serve(ln)
+
+	alive := httpAlive(ln)
+	if err := alive(t.Context()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	srv.Close()
+	if err := alive(t.Context()); err == nil {
+		t.Error("expected an error after the server is closed")
+	}
+}
+
+func TestLoopProbe(t *testing.T) {
+	probe := make(loopProbe)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := probe.alive(ctx); err == nil {
+		t.Error("expected an error while the loop does not receive")
+	}
+
+	go func() { <-probe }()
+	if err := probe.alive(t.Context()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSdWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{usec: "", want: 0},
+		{usec: "invalid", want: 0},
+		{usec: "10000000", want: 5 * time.Second},
+		{usec: "10000000", pid: "1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := sdWatchdogInterval(); got != tt.want {
+			t.Errorf("WATCHDOG_USEC=%q WATCHDOG_PID=%q: expected %v, got %v", tt.usec, tt.pid, tt.want, got)
+		}
+	}
+}
+
+func TestSdNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
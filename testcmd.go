@@ -0,0 +1,295 @@
+package armed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+const testFileSuffix = "_test.jsonnet"
+
+// TestCmd runs test cases defined in *_test.jsonnet files and compares
+// the evaluated results against expected values or golden files.
+type TestCmd struct {
+	Update  bool          `name:"update" help:"Update golden files with the actual results"`
+	Timeout time.Duration `short:"t" name:"timeout" help:"Timeout for each test case's evaluation (e.g., 30s, 5m)"`
+	Mock    string        `name:"mock" placeholder:"FILE" type:"path" help:"Mock file applied to all test cases after their own mocks"`
+	Paths   []string      `arg:"" name:"path" help:"Test files or directories to search for *_test.jsonnet files (default: current directory)" type:"path" optional:""`
+
+	// writer for test reports (not exposed to CLI, used internally)
+	writer io.Writer `kong:"-"`
+
+	// functions holds additional native functions to be added to the Jsonnet VM
+	functions []*jsonnet.NativeFunction `kong:"-"`
+
+	// mocks holds the mock rules loaded from the mock file
+	mocks []mockRule `kong:"-"`
+}
+
+// testCase is a single test case defined in a test file.
+type testCase struct {
+	Name     string            `json:"name"`
+	Input    string            `json:"input"`
+	ExtStr   map[string]string `json:"ext_str"`
+	ExtCode  map[string]string `json:"ext_code"`
+	Mocks    json.RawMessage   `json:"mocks"`
+	Expected json.RawMessage   `json:"expected"`
+	Golden   string            `json:"golden"`
+}
+
+// SetWriter sets the writer for test reports
+func (t *TestCmd) SetWriter(w io.Writer) {
+	t.writer = w
+}
+
+// AddFunctions adds custom native functions to the test runner
+func (t *TestCmd) AddFunctions(funcs ...*jsonnet.NativeFunction) {
+	t.functions = append(t.functions, funcs...)
+}
+
+// Run discovers and runs all test cases. It returns an error if any test case fails.
+func (t *TestCmd) Run(ctx context.Context) error {
+	if t.writer == nil {
+		t.writer = os.Stdout
+	}
+	if t.Mock != "" {
+		mocks, err := loadMockFile(t.Mock)
+		if err != nil {
+			return err
+		}
+		t.mocks = mocks
+	}
+	files, err := t.discover()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no %s files found", testFileSuffix)
+	}
+
+	var total, failed int
+	for _, file := range files {
+		cases, err := t.loadCases(ctx, file)
+		if err != nil {
+			fmt.Fprintf(t.writer, "FAIL %s\n    %s\n", file, indent(err.Error()))
+			total++
+			failed++
+			continue
+		}
+		for _, tc := range cases {
+			total++
+			name := file
+			if tc.Name != "" {
+				name += ":" + tc.Name
+			}
+			if err := t.runCase(ctx, tc); err != nil {
+				failed++
+				fmt.Fprintf(t.writer, "FAIL %s\n    %s\n", name, indent(err.Error()))
+				continue
+			}
+			fmt.Fprintf(t.writer, "PASS %s\n", name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test(s) failed", failed, total)
+	}
+	fmt.Fprintf(t.writer, "ok %d test(s) passed\n", total)
+	return nil
+}
+
+// discover returns test files from t.Paths. Directories are searched
+// recursively for *_test.jsonnet files; files are used as they are.
+func (t *TestCmd) discover() ([]string, error) {
+	paths := t.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var files []string
+	for _, p := range paths {
+		st, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), testFileSuffix) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// loadCases evaluates a test file, which must produce a test case object
+// or an array of test case objects. Relative paths in test cases are
+// resolved against the directory of the test file.
+func (t *TestCmd) loadCases(ctx context.Context, file string) ([]*testCase, error) {
+	cli := &CLI{Filename: file, functions: t.functions}
+	jsonStr, err := cli.evaluate(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []*testCase
+	trimmed := bytes.TrimSpace([]byte(jsonStr))
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &cases); err != nil {
+			return nil, fmt.Errorf("invalid test cases: %w", err)
+		}
+	} else {
+		var tc testCase
+		if err := json.Unmarshal(trimmed, &tc); err != nil {
+			return nil, fmt.Errorf("invalid test case: %w", err)
+		}
+		cases = []*testCase{&tc}
+	}
+
+	dir := filepath.Dir(file)
+	base := strings.TrimSuffix(file, ".jsonnet")
+	for i, tc := range cases {
+		if tc.Input == "" {
+			return nil, fmt.Errorf("test case #%d: input is required", i)
+		}
+		if tc.Name == "" && len(cases) > 1 {
+			tc.Name = strconv.Itoa(i)
+		}
+		tc.Input = resolvePath(dir, tc.Input)
+		switch {
+		case tc.Golden != "":
+			tc.Golden = resolvePath(dir, tc.Golden)
+		case len(cases) == 1:
+			tc.Golden = base + ".golden.json"
+		default:
+			tc.Golden = base + "." + tc.Name + ".golden.json"
+		}
+	}
+	return cases, nil
+}
+
+// runCase evaluates a test case and compares the result with the
+// expected value or the golden file.
+func (t *TestCmd) runCase(ctx context.Context, tc *testCase) error {
+	mocks, err := parseMocks(tc.Mocks)
+	if err != nil {
+		return err
+	}
+	cli := &CLI{
+		Filename:  tc.Input,
+		ExtStr:    tc.ExtStr,
+		ExtCode:   tc.ExtCode,
+		functions: t.functions,
+		mocks:     append(mocks, t.mocks...),
+	}
+	actual, err := evaluateWithTimeout(ctx, cli, t.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if tc.Expected != nil {
+		expected, err := indentJSON(tc.Expected)
+		if err != nil {
+			return fmt.Errorf("invalid expected value: %w", err)
+		}
+		return compareResult("expected", expected, actual)
+	}
+
+	if t.Update {
+		if err := os.MkdirAll(filepath.Dir(tc.Golden), 0755); err != nil {
+			return err
+		}
+		return writeFileAtomic(tc.Golden, []byte(actual), 0644)
+	}
+	golden, err := os.ReadFile(tc.Golden)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("golden file %s not found (run with --update to create it)", tc.Golden)
+		}
+		return err
+	}
+	return compareResult(tc.Golden, string(golden), actual)
+}
+
+// evaluateWithTimeout evaluates cli within timeout (if positive), even if
+// a native function blocks.
+func evaluateWithTimeout(ctx context.Context, cli *CLI, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		jsonStr, err := cli.evaluate(ctx, "", false)
+		resultCh <- result{jsonStr: jsonStr, err: err}
+	}()
+	select {
+	case res := <-resultCh:
+		return res.jsonStr, res.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("evaluation timed out after %v", timeout)
+		}
+		return "", ctx.Err()
+	}
+}
+
+// compareResult compares two JSON documents structurally and returns an
+// error with a line diff if they differ.
+func compareResult(name, expected, actual string) error {
+	var e, a any
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		return fmt.Errorf("failed to parse actual result: %w", err)
+	}
+	if reflect.DeepEqual(e, a) {
+		return nil
+	}
+	ej, _ := indentJSON([]byte(expected))
+	aj, _ := indentJSON([]byte(actual))
+	return fmt.Errorf("result mismatch:\n%s", lineDiff(name, "actual", ej, aj))
+}
+
+// indentJSON re-indents a JSON document in the jsonnet output style
+func indentJSON(data []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "   "); err != nil {
+		return "", err
+	}
+	return buf.String() + "\n", nil
+}
+
+// resolvePath resolves p against dir unless p is absolute
+func resolvePath(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// indent indents continuation lines of a multi-line message for test reports
+func indent(s string) string {
+	return strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n    ")
+}
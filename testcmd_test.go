@@ -0,0 +1,119 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestTestCmd(t *testing.T) {
+	var output bytes.Buffer
+	cmd := &armed.TestCmd{Paths: []string{"testdata/testcmd"}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output.String())
+	}
+	for _, s := range []string{
+		"PASS testdata/testcmd/app_test.jsonnet\n",
+		"PASS testdata/testcmd/cases_test.jsonnet:development\n",
+		"PASS testdata/testcmd/cases_test.jsonnet:staging\n",
+		"ok 3 test(s) passed\n",
+	} {
+		if !strings.Contains(output.String(), s) {
+			t.Errorf("output does not contain %q:\n%s", s, output.String())
+		}
+	}
+}
+
+func TestTestCmdFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.jsonnet"), `{ name: std.extVar("name") }`)
+	writeFile(t, filepath.Join(tmpDir, "app_test.jsonnet"), `{
+		input: "app.jsonnet",
+		ext_str: { name: "bar" },
+		expected: { name: "foo" },
+	}`)
+
+	var output bytes.Buffer
+	cmd := &armed.TestCmd{Paths: []string{tmpDir}}
+	cmd.SetWriter(&output)
+	err := cmd.Run(t.Context())
+	if err == nil {
+		t.Fatalf("expected error but got nil\n%s", output.String())
+	}
+	if err.Error() != "1 of 1 test(s) failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, s := range []string{"FAIL ", `-   "name": "foo"`, `+   "name": "bar"`} {
+		if !strings.Contains(output.String(), s) {
+			t.Errorf("output does not contain %q:\n%s", s, output.String())
+		}
+	}
+}
+
+func TestTestCmdUpdate(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.jsonnet"), `{ name: std.extVar("name") }`)
+	writeFile(t, filepath.Join(tmpDir, "app_test.jsonnet"), `{
+		input: "app.jsonnet",
+		ext_str: { name: "foo" },
+	}`)
+	golden := filepath.Join(tmpDir, "app_test.golden.json")
+
+	// Missing golden file fails without --update
+	cmd := &armed.TestCmd{Paths: []string{tmpDir}}
+	cmd.SetWriter(&bytes.Buffer{})
+	if err := cmd.Run(t.Context()); err == nil {
+		t.Fatal("expected error for missing golden file")
+	}
+
+	// --update creates the golden file
+	cmd = &armed.TestCmd{Paths: []string{tmpDir}, Update: true}
+	cmd.SetWriter(&bytes.Buffer{})
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	compareJSON(t, string(b), `{"name": "foo"}`)
+
+	// Now the test passes without --update
+	cmd = &armed.TestCmd{Paths: []string{tmpDir}}
+	cmd.SetWriter(&bytes.Buffer{})
+	if err := cmd.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTestCmdUnknownMock(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.jsonnet"), `{}`)
+	writeFile(t, filepath.Join(tmpDir, "app_test.jsonnet"), `{
+		input: "app.jsonnet",
+		mocks: { no_such_function: 1 },
+		expected: {},
+	}`)
+
+	var output bytes.Buffer
+	cmd := &armed.TestCmd{Paths: []string{tmpDir}}
+	cmd.SetWriter(&output)
+	if err := cmd.Run(t.Context()); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if !strings.Contains(output.String(), "no_such_function") {
+		t.Errorf("output does not mention the unknown mock:\n%s", output.String())
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
@@ -0,0 +1,69 @@
+package armed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// functionUsage counts native function calls during an evaluation.
+// It is safe for concurrent use.
+type functionUsage struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newFunctionUsage() *functionUsage {
+	return &functionUsage{counts: make(map[string]int)}
+}
+
+// wrap returns copies of funcs that count their calls
+func (u *functionUsage) wrap(funcs []*jsonnet.NativeFunction) []*jsonnet.NativeFunction {
+	wrapped := make([]*jsonnet.NativeFunction, len(funcs))
+	for i, f := range funcs {
+		wrapped[i] = &jsonnet.NativeFunction{
+			Name:   f.Name,
+			Params: f.Params,
+			Func: func(args []any) (any, error) {
+				u.mu.Lock()
+				u.counts[f.Name]++
+				u.mu.Unlock()
+				return f.Func(args)
+			},
+		}
+	}
+	return wrapped
+}
+
+// functionUsageReport is the JSON report written by --report-functions.
+type functionUsageReport struct {
+	Filename  string         `json:"filename"`
+	Functions map[string]int `json:"functions"`
+}
+
+// writeReport writes the usage report as JSON to filename ("-" for stderr)
+func (u *functionUsage) writeReport(filename, evaluated string) error {
+	u.mu.Lock()
+	report := functionUsageReport{Filename: evaluated, Functions: make(map[string]int, len(u.counts))}
+	for name, n := range u.counts {
+		report.Functions[name] = n
+	}
+	u.mu.Unlock()
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if filename == "-" {
+		_, err = os.Stderr.Write(b)
+		return err
+	}
+	if err := writeFileAtomic(filename, b, 0644); err != nil {
+		return fmt.Errorf("failed to write function usage report: %w", err)
+	}
+	return nil
+}
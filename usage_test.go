@@ -0,0 +1,53 @@
+package armed_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunWithCLIReportFunctions(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	writeFile(t, jsonnetFile, `
+	local armed = import 'armed.libsonnet';
+	{
+		a: armed.sha256("a"),
+		b: std.native("sha256")("b"),
+		c: armed.base64("c"),
+		d: [armed.env("HOME", "") != null for i in std.range(1, 3)],
+	}`)
+	reportFile := filepath.Join(tmpDir, "report.json")
+
+	var output bytes.Buffer
+	cli := &armed.CLI{Filename: jsonnetFile, ReportFunctions: reportFile}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("failed to parse report: %v\n%s", err, b)
+	}
+	expected := map[string]any{
+		"filename": jsonnetFile,
+		"functions": map[string]any{
+			"sha256": float64(2),
+			"base64": float64(1),
+			"env":    float64(3),
+		},
+	}
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+}
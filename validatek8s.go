@@ -0,0 +1,363 @@
+package armed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrK8sValidation is returned by CLI.Run when --validate-k8s finds objects
+// that do not conform to the Kubernetes API schema.
+var ErrK8sValidation = errors.New("kubernetes schema validation failed")
+
+// builtinK8sSchema is the schema of Kubernetes k8sBuiltinVersion, trimmed by
+// trimK8sSchema. Regenerate it from the file cached by --k8s-version:
+//
+//	gzip -9 -n -c $XDG_CACHE_HOME/jsonnet-armed/k8s-schema/v1.31.0.json > k8s-schema.json.gz
+//
+//go:embed k8s-schema.json.gz
+var builtinK8sSchema []byte
+
+const k8sBuiltinVersion = "1.31.0"
+
+// k8sSchemaURL is the URL of the OpenAPI v2 schema of a Kubernetes version
+var k8sSchemaURL = "https://raw.githubusercontent.com/kubernetes/kubernetes/v%s/api/openapi-spec/swagger.json"
+
+const (
+	k8sQuantityRef = "io.k8s.apimachinery.pkg.api.resource.Quantity"
+	k8sRefPrefix   = "#/definitions/"
+)
+
+// k8sDefinition is a definition of the OpenAPI v2 schema. Only the fields
+// used for validation are kept.
+type k8sDefinition struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Ref                  string                    `json:"$ref,omitempty"`
+	Items                *k8sDefinition            `json:"items,omitempty"`
+	Properties           map[string]*k8sDefinition `json:"properties,omitempty"`
+	AdditionalProperties *k8sDefinition            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	PreserveUnknown      bool                      `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	GroupVersionKind     []k8sGVK                  `json:"x-kubernetes-group-version-kind,omitempty"`
+}
+
+type k8sGVK struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// k8sSchema holds the definitions and the definitions of each apiVersion and kind
+type k8sSchema struct {
+	Definitions map[string]*k8sDefinition `json:"definitions"`
+	kinds       map[k8sGVK]*k8sDefinition
+}
+
+// parseK8sSchema parses an OpenAPI v2 schema
+func parseK8sSchema(b []byte) (*k8sSchema, error) {
+	s := &k8sSchema{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid Kubernetes schema: %w", err)
+	}
+	if len(s.Definitions) == 0 {
+		return nil, fmt.Errorf("invalid Kubernetes schema: no definitions")
+	}
+	s.kinds = make(map[k8sGVK]*k8sDefinition)
+	for _, def := range s.Definitions {
+		for _, gvk := range def.GroupVersionKind {
+			s.kinds[gvk] = def
+		}
+	}
+	return s, nil
+}
+
+// trimK8sSchema removes descriptions and paths, which are not used for validation
+func trimK8sSchema(b []byte) ([]byte, error) {
+	s, err := parseK8sSchema(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+var k8sVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// loadK8sSchema returns the built-in schema, or the schema of version that
+// is fetched from GitHub and cached.
+func loadK8sSchema(ctx context.Context, version string) (*k8sSchema, error) {
+	if version != "" {
+		version = strings.TrimPrefix(version, "v")
+		if strings.Count(version, ".") == 1 {
+			version += ".0"
+		}
+		if !k8sVersionPattern.MatchString(version) {
+			return nil, fmt.Errorf("invalid --k8s-version %q: must be MAJOR.MINOR or MAJOR.MINOR.PATCH", version)
+		}
+	}
+	if version == "" || version == k8sBuiltinVersion {
+		r, err := gzip.NewReader(bytes.NewReader(builtinK8sSchema))
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return parseK8sSchema(b)
+	}
+
+	cacheFile := filepath.Join(getCacheDir(), "k8s-schema", "v"+version+".json")
+	if b, err := os.ReadFile(cacheFile); err == nil {
+		return parseK8sSchema(b)
+	}
+
+	u := fmt.Sprintf(k8sSchemaURL, version)
+	slog.Info("Fetching Kubernetes schema", "version", version, "url", u)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "jsonnet-armed/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Kubernetes %s schema: %w", version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Kubernetes %s schema: HTTP status %d", version, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Kubernetes %s schema: %w", version, err)
+	}
+	trimmed, err := trimK8sSchema(b)
+	if err != nil {
+		return nil, err
+	}
+	// Best effort; the schema is fetched again next time
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err == nil {
+		if err := writeFileAtomic(cacheFile, trimmed, 0644); err != nil {
+			slog.Warn("Failed to cache Kubernetes schema", "error", err.Error())
+		}
+	}
+	return parseK8sSchema(trimmed)
+}
+
+// validateK8s validates the Kubernetes objects in an evaluation result.
+// Objects are found at the top level, in arrays and objects at any depth,
+// and in the items of List kinds.
+func (cli *CLI) validateK8s(ctx context.Context, jsonStr string) error {
+	if cli.k8sSchema == nil {
+		schema, err := loadK8sSchema(ctx, cli.K8sVersion)
+		if err != nil {
+			return err
+		}
+		cli.k8sSchema = schema
+	}
+	var v any
+	if err := json.Unmarshal([]byte(jsonStr), &v); err != nil {
+		return fmt.Errorf("failed to parse the result for --validate-k8s: %w", err)
+	}
+	problems := cli.k8sSchema.validateObjects(v, "$")
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrK8sValidation, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// validateObjects finds Kubernetes objects in v at path and validates them
+func (s *k8sSchema) validateObjects(v any, path string) []string {
+	var problems []string
+	switch v := v.(type) {
+	case []any:
+		for i, e := range v {
+			problems = append(problems, s.validateObjects(e, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case map[string]any:
+		apiVersion, _ := v["apiVersion"].(string)
+		kind, _ := v["kind"].(string)
+		if apiVersion == "" || kind == "" {
+			for _, k := range sortedKeys(v) {
+				problems = append(problems, s.validateObjects(v[k], jsonPath(path, k))...)
+			}
+			break
+		}
+		if items, ok := v["items"].([]any); ok && strings.HasSuffix(kind, "List") {
+			problems = append(problems, s.validateObjects(items, path+".items")...)
+			break
+		}
+		gvk := k8sGVK{Kind: kind, Version: apiVersion}
+		if group, version, ok := strings.Cut(apiVersion, "/"); ok {
+			gvk.Group, gvk.Version = group, version
+		}
+		def, ok := s.kinds[gvk]
+		if !ok {
+			slog.Warn("No Kubernetes schema for the object, skipped", "path", path, "apiVersion", apiVersion, "kind", kind)
+			break
+		}
+		name := kind
+		if metadata, ok := v["metadata"].(map[string]any); ok {
+			if n, ok := metadata["name"].(string); ok {
+				name += " " + n
+			}
+		}
+		for _, p := range s.validate(v, def, "") {
+			problems = append(problems, fmt.Sprintf("%s (%s): %s", path, name, p))
+		}
+	}
+	return problems
+}
+
+// validate validates v against def, returning field errors prefixed by path
+func (s *k8sSchema) validate(v any, def *k8sDefinition, path string) []string {
+	if def.Ref != "" {
+		name := strings.TrimPrefix(def.Ref, k8sRefPrefix)
+		if name == k8sQuantityRef {
+			// Quantities are strings such as "500m", or numbers
+			switch v.(type) {
+			case string, float64, nil:
+				return nil
+			}
+			return []string{fieldError(path, "must be a quantity (string or number), got %s", k8sTypeName(v))}
+		}
+		resolved, ok := s.Definitions[name]
+		if !ok {
+			return nil
+		}
+		def = resolved
+	}
+	if v == nil {
+		// null means unset in Kubernetes
+		return nil
+	}
+
+	switch def.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []string{fieldError(path, "must be an object, got %s", k8sTypeName(v))}
+		}
+		var problems []string
+		for _, name := range def.Required {
+			if obj[name] == nil {
+				problems = append(problems, fieldError(fieldPath(path, name), "is required"))
+			}
+		}
+		for _, k := range sortedKeys(obj) {
+			child := fieldPath(path, k)
+			switch {
+			case def.Properties[k] != nil:
+				problems = append(problems, s.validate(obj[k], def.Properties[k], child)...)
+			case def.AdditionalProperties != nil:
+				problems = append(problems, s.validate(obj[k], def.AdditionalProperties, child)...)
+			case len(def.Properties) > 0 && !def.PreserveUnknown:
+				problems = append(problems, fieldError(child, "unknown field"))
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return []string{fieldError(path, "must be an array, got %s", k8sTypeName(v))}
+		}
+		if def.Items == nil {
+			return nil
+		}
+		var problems []string
+		for i, e := range arr {
+			problems = append(problems, s.validate(e, def.Items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "string":
+		if def.Format == "int-or-string" {
+			if _, ok := v.(string); ok || isInteger(v) {
+				return nil
+			}
+			return []string{fieldError(path, "must be an integer or a string, got %s", k8sTypeName(v))}
+		}
+		if _, ok := v.(string); !ok {
+			return []string{fieldError(path, "must be a string, got %s", k8sTypeName(v))}
+		}
+	case "integer":
+		if !isInteger(v) {
+			return []string{fieldError(path, "must be an integer, got %s", k8sTypeName(v))}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{fieldError(path, "must be a number, got %s", k8sTypeName(v))}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fieldError(path, "must be a boolean, got %s", k8sTypeName(v))}
+		}
+	}
+	return nil
+}
+
+func isInteger(v any) bool {
+	f, ok := v.(float64)
+	return ok && f == float64(int64(f))
+}
+
+// k8sTypeName returns the type of v with the value for scalars, e.g. `string "3"`
+func k8sTypeName(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case float64:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	}
+	return "null"
+}
+
+func fieldError(path, format string, args ...any) string {
+	if path == "" {
+		return fmt.Sprintf(format, args...)
+	}
+	return path + ": " + fmt.Sprintf(format, args...)
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPath appends a key to a path of the result, e.g. $.a or $["a-b"]
+func jsonPath(path, key string) string {
+	if identPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package armed
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateK8s(t *testing.T) {
+	tests := []struct {
+		name    string
+		jsonnet string
+		errors  []string
+	}{
+		{
+			name: "valid objects",
+			jsonnet: `[
+				{
+					apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web' },
+					spec: {
+						replicas: 2,
+						selector: { matchLabels: { app: 'web' } },
+						template: {
+							metadata: { labels: { app: 'web' } },
+							spec: { containers: [{
+								name: 'web', image: 'nginx',
+								ports: [{ containerPort: 80 }],
+								resources: { limits: { cpu: '500m', memory: 1073741824 } },
+							}] },
+						},
+					},
+				},
+				{
+					apiVersion: 'v1', kind: 'Service', metadata: { name: 'web', annotations: null },
+					spec: { ports: [{ port: 80, targetPort: 'http' }, { port: 443, targetPort: 8443 }] },
+				},
+			]`,
+		},
+		{
+			name:    "not kubernetes objects",
+			jsonnet: `{ a: 1, b: [{ kind: 'no apiVersion' }] }`,
+		},
+		{
+			name: "custom resources are skipped",
+			jsonnet: `{
+				apiVersion: 'example.com/v1', kind: 'Widget', metadata: { name: 'w' }, spec: { anything: true },
+			}`,
+		},
+		{
+			name: "unknown field",
+			jsonnet: `{
+				apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web' },
+				spec: { replica: 2, selector: {}, template: {} },
+			}`,
+			errors: []string{`$ (Deployment web): spec.replica: unknown field`},
+		},
+		{
+			name: "objects in a map",
+			jsonnet: `{
+				deployment: {
+					apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web' },
+					spec: { replicas: '2', selector: {}, template: { spec: { containers: [{ image: 'nginx' }] } } },
+				},
+				'config-map': { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'c' }, data: { port: 80 } },
+			}`,
+			errors: []string{
+				`$["config-map"] (ConfigMap c): data.port: must be a string, got number 80`,
+				`$.deployment (Deployment web): spec.replicas: must be an integer, got string "2"`,
+				`$.deployment (Deployment web): spec.template.spec.containers[0].name: is required`,
+			},
+		},
+		{
+			name: "items of a list",
+			jsonnet: `{
+				apiVersion: 'v1', kind: 'List',
+				items: [
+					{ apiVersion: 'v1', kind: 'Service', spec: { ports: [{ port: 80.5, targetPort: true }] } },
+				],
+			}`,
+			errors: []string{
+				`$.items[0] (Service): spec.ports[0].port: must be an integer, got number 80.5`,
+				`$.items[0] (Service): spec.ports[0].targetPort: must be an integer or a string, got boolean true`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonnetFile := filepath.Join(t.TempDir(), "k8s.jsonnet")
+			if err := os.WriteFile(jsonnetFile, []byte(tt.jsonnet), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			cli := &CLI{Filename: jsonnetFile, ValidateK8s: true, writer: &out}
+			err := cli.run(t.Context())
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out.Len() == 0 {
+					t.Error("no output")
+				}
+				return
+			}
+			if !errors.Is(err, ErrK8sValidation) {
+				t.Fatalf("expected ErrK8sValidation, got %v", err)
+			}
+			got := strings.Split(err.Error(), "\n  ")[1:]
+			if strings.Join(got, "\n") != strings.Join(tt.errors, "\n") {
+				t.Errorf("unexpected errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.errors, "\n"))
+			}
+			if out.Len() != 0 {
+				t.Errorf("unexpected output: %s", out.String())
+			}
+		})
+	}
+}
+
+func TestValidateK8sFetchSchema(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path != "/v1.29.0/swagger.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"swagger": "2.0", "paths": {}, "definitions": {
+			"io.k8s.api.core.v1.ConfigMap": {
+				"description": "ConfigMap holds configuration data.",
+				"type": "object",
+				"properties": {
+					"apiVersion": {"type": "string"},
+					"kind": {"type": "string"},
+					"immutable": {"type": "boolean"}
+				},
+				"x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]
+			}
+		}}`))
+	}))
+	defer ts.Close()
+	defer func(u string) { k8sSchemaURL = u }(k8sSchemaURL)
+	k8sSchemaURL = ts.URL + "/v%s/swagger.json"
+
+	for range 2 {
+		schema, err := loadK8sSchema(t.Context(), "v1.29")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		problems := schema.validateObjects(map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "data": map[string]any{}}, "$")
+		if len(problems) != 1 || problems[0] != "$ (ConfigMap): data: unknown field" {
+			t.Errorf("unexpected problems: %v", problems)
+		}
+	}
+	// The schema is fetched once, and then read from the cache
+	if len(requested) != 1 || requested[0] != "/v1.29.0/swagger.json" {
+		t.Errorf("unexpected requests: %v", requested)
+	}
+	b, err := os.ReadFile(filepath.Join(getCacheDir(), "k8s-schema", "v1.29.0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("description")) {
+		t.Errorf("cached schema is not trimmed: %s", b)
+	}
+
+	// The built-in version is not fetched
+	if _, err := loadK8sSchema(t.Context(), k8sBuiltinVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 1 {
+		t.Errorf("unexpected requests: %v", requested)
+	}
+
+	for _, version := range []string{"latest", "1", "1.2.3.4"} {
+		if _, err := loadK8sSchema(t.Context(), version); err == nil {
+			t.Errorf("expected error for version %q", version)
+		}
+	}
+	if _, err := loadK8sSchema(t.Context(), "1.99"); err == nil || !strings.Contains(err.Error(), "HTTP status 404") {
+		t.Errorf("unexpected error for an unknown version: %v", err)
+	}
+}
+
+func TestValidateK8sFlags(t *testing.T) {
+	cli := &CLI{Filename: "a.jsonnet", K8sVersion: "1.30", writer: &bytes.Buffer{}}
+	err := cli.run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "--k8s-version requires --validate-k8s") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
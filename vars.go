@@ -0,0 +1,164 @@
+package armed
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+)
+
+// varFlag is a flag setting external variables or top-level arguments
+type varFlag struct {
+	name   string
+	values map[string]string
+}
+
+// extVarFlags returns the flags setting external variables
+func (cli *CLI) extVarFlags() []varFlag {
+	return []varFlag{
+		{"--ext-str", cli.ExtStr},
+		{"--ext-code", cli.ExtCode},
+		{"--ext-json", cli.ExtJSON},
+		{"--ext-str-file", cli.ExtStrFile},
+		{"--ext-code-file", cli.ExtCodeFile},
+	}
+}
+
+// tlaFlags returns the flags setting top-level arguments
+func (cli *CLI) tlaFlags() []varFlag {
+	return []varFlag{
+		{"--tla-str", cli.TLAStr},
+		{"--tla-code", cli.TLACode},
+		{"--tla-str-file", cli.TLAStrFile},
+		{"--tla-code-file", cli.TLACodeFile},
+	}
+}
+
+// checkVarNames rejects a variable set by more than one flag
+func (cli *CLI) checkVarNames() error {
+	for _, group := range []struct {
+		kind  string
+		flags []varFlag
+	}{
+		{"external variable", cli.extVarFlags()},
+		{"top-level argument", cli.tlaFlags()},
+	} {
+		setBy := make(map[string]string)
+		for _, f := range group.flags {
+			for _, name := range slices.Sorted(maps.Keys(f.values)) {
+				if prev, ok := setBy[name]; ok {
+					return fmt.Errorf("%s %q is set by both %s and %s", group.kind, name, prev, f.name)
+				}
+				setBy[name] = f.name
+			}
+		}
+	}
+	return nil
+}
+
+// Environment variables setting external variables, e.g.
+// JSONNET_ARMED_EXT_STR_env=production works as --ext-str env=production
+const (
+	extStrEnvPrefix  = "JSONNET_ARMED_EXT_STR_"
+	extCodeEnvPrefix = "JSONNET_ARMED_EXT_CODE_"
+)
+
+// extVarsFromEnv adds the external variables set by environment variables
+// to ExtStr and ExtCode. A variable set by a flag takes precedence over the
+// environment. The maps are copied before adding, as they may be shared.
+func (cli *CLI) extVarsFromEnv(environ []string) error {
+	setByFlag := make(map[string]bool)
+	for _, f := range cli.extVarFlags() {
+		for name := range f.values {
+			setByFlag[name] = true
+		}
+	}
+	setByEnv := make(map[string]string)
+	extStr, extCode := make(map[string]string), make(map[string]string)
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		var name string
+		var dst map[string]string
+		switch {
+		case strings.HasPrefix(key, extStrEnvPrefix):
+			name, dst = strings.TrimPrefix(key, extStrEnvPrefix), extStr
+		case strings.HasPrefix(key, extCodeEnvPrefix):
+			name, dst = strings.TrimPrefix(key, extCodeEnvPrefix), extCode
+		default:
+			continue
+		}
+		if name == "" || setByFlag[name] {
+			continue
+		}
+		if prev, ok := setByEnv[name]; ok {
+			return fmt.Errorf("external variable %q is set by both %s and %s", name, prev, key)
+		}
+		setByEnv[name] = key
+		dst[name] = value
+	}
+	if len(extStr) > 0 {
+		cli.ExtStr = merged(cli.ExtStr, extStr)
+	}
+	if len(extCode) > 0 {
+		cli.ExtCode = merged(cli.ExtCode, extCode)
+	}
+	return nil
+}
+
+// varFiles returns the files read into external variables and top-level
+// arguments, which are inputs of the evaluation
+func (cli *CLI) varFiles() []string {
+	var files []string
+	for _, m := range []map[string]string{cli.ExtJSON, cli.ExtStrFile, cli.ExtCodeFile, cli.TLAStrFile, cli.TLACodeFile} {
+		for _, name := range slices.Sorted(maps.Keys(m)) {
+			files = append(files, m[name])
+		}
+	}
+	return files
+}
+
+// bindVars binds the external variables and top-level arguments to vm.
+// Files are read for each evaluation, so edits apply to the next one.
+func (cli *CLI) bindVars(vm *jsonnet.VM) error {
+	for k, v := range cli.ExtStr {
+		vm.ExtVar(k, v)
+	}
+	for k, v := range cli.ExtCode {
+		vm.ExtCode(k, v)
+	}
+	for k, f := range cli.ExtJSON {
+		code, err := loadExtJSON(f)
+		if err != nil {
+			return err
+		}
+		vm.ExtCode(k, code)
+	}
+	for k, v := range cli.TLAStr {
+		vm.TLAVar(k, v)
+	}
+	for k, v := range cli.TLACode {
+		vm.TLACode(k, v)
+	}
+	for _, b := range []struct {
+		flag  string
+		files map[string]string
+		bind  func(k, v string)
+	}{
+		{"--ext-str-file", cli.ExtStrFile, vm.ExtVar},
+		{"--ext-code-file", cli.ExtCodeFile, vm.ExtCode},
+		{"--tla-str-file", cli.TLAStrFile, vm.TLAVar},
+		{"--tla-code-file", cli.TLACodeFile, vm.TLACode},
+	} {
+		for k, f := range b.files {
+			content, err := os.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("failed to read %s file: %w", b.flag, err)
+			}
+			b.bind(k, string(content))
+		}
+	}
+	return nil
+}
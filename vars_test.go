@@ -0,0 +1,227 @@
+package armed_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+func TestRunWithCLITopLevelArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `function(name, replicas=1, config={}, cert="") { name: name, replicas: replicas, config: config, cert: cert }`)
+	certFile := filepath.Join(dir, "cert.pem")
+	writeFile(t, certFile, "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+	configFile := filepath.Join(dir, "config.jsonnet")
+	writeFile(t, configFile, `{ debug: true, level: 1 + 2 }`)
+
+	tests := []struct {
+		name     string
+		cli      armed.CLI
+		expected string
+	}{
+		{
+			name:     "string and code",
+			cli:      armed.CLI{TLAStr: map[string]string{"name": "web"}, TLACode: map[string]string{"replicas": "3"}},
+			expected: `{"name": "web", "replicas": 3, "config": {}, "cert": ""}`,
+		},
+		{
+			name: "files",
+			cli: armed.CLI{
+				TLAStr:      map[string]string{"name": "web"},
+				TLAStrFile:  map[string]string{"cert": certFile},
+				TLACodeFile: map[string]string{"config": configFile},
+			},
+			expected: `{"name": "web", "replicas": 1, "config": {"debug": true, "level": 3}, "cert": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			cli := tt.cli
+			cli.Filename = file
+			cli.SetWriter(&output)
+			if err := cli.Run(t.Context()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareJSON(t, tt.expected, output.String())
+		})
+	}
+}
+
+func TestRunWithCLIExtVarFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `{ motd: std.extVar("motd"), limits: std.extVar("limits") }`)
+	motdFile := filepath.Join(dir, "motd.txt")
+	writeFile(t, motdFile, "line 1\nline 2\n")
+	limitsFile := filepath.Join(dir, "limits.jsonnet")
+	writeFile(t, limitsFile, `{ cpu: "%dm" % (250 * 2) }`)
+
+	var output bytes.Buffer
+	cli := &armed.CLI{
+		Filename:    file,
+		ExtStrFile:  map[string]string{"motd": motdFile},
+		ExtCodeFile: map[string]string{"limits": limitsFile},
+	}
+	cli.SetWriter(&output)
+	if err := cli.Run(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compareJSON(t, `{"motd": "line 1\nline 2\n", "limits": {"cpu": "500m"}}`, output.String())
+}
+
+func TestRunWithCLIVarFilesCacheKey(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	tests := []struct {
+		name     string
+		template string
+		contents [2]string
+		cli      func(payloadFile string) *armed.CLI
+	}{
+		{
+			name:     "ext-str-file",
+			template: `{ payload: std.extVar("payload") }`,
+			contents: [2]string{"v1", "v2"},
+			cli: func(f string) *armed.CLI {
+				return &armed.CLI{ExtStrFile: map[string]string{"payload": f}}
+			},
+		},
+		{
+			name:     "ext-code-file",
+			template: `{ payload: std.extVar("payload") }`,
+			contents: [2]string{`"v1"`, `"v2"`},
+			cli: func(f string) *armed.CLI {
+				return &armed.CLI{ExtCodeFile: map[string]string{"payload": f}}
+			},
+		},
+		{
+			name:     "tla-str-file",
+			template: `function(payload) { payload: payload }`,
+			contents: [2]string{"v1", "v2"},
+			cli: func(f string) *armed.CLI {
+				return &armed.CLI{TLAStrFile: map[string]string{"payload": f}}
+			},
+		},
+		{
+			name:     "tla-code-file",
+			template: `function(payload) { payload: payload }`,
+			contents: [2]string{`"v1"`, `"v2"`},
+			cli: func(f string) *armed.CLI {
+				return &armed.CLI{TLACodeFile: map[string]string{"payload": f}}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "main.jsonnet")
+			writeFile(t, file, tt.template)
+			payloadFile := filepath.Join(dir, "payload.txt")
+
+			run := func() string {
+				t.Helper()
+				var output bytes.Buffer
+				cli := tt.cli(payloadFile)
+				cli.Filename = file
+				cli.Cache = time.Minute
+				cli.SetWriter(&output)
+				if err := cli.Run(t.Context()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return output.String()
+			}
+			writeFile(t, payloadFile, tt.contents[0])
+			compareJSON(t, `{"payload": "v1"}`, run())
+			// A cached result must not be used for another content of the file
+			writeFile(t, payloadFile, tt.contents[1])
+			compareJSON(t, `{"payload": "v2"}`, run())
+		})
+	}
+}
+
+func TestRunWithCLIVarErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.jsonnet")
+	writeFile(t, file, `function(a="") a`)
+	tests := []struct {
+		name string
+		cli  armed.CLI
+		want string
+	}{
+		{
+			name: "ext var set twice",
+			cli:  armed.CLI{ExtStr: map[string]string{"a": "x"}, ExtStrFile: map[string]string{"a": file}},
+			want: `external variable "a" is set by both --ext-str and --ext-str-file`,
+		},
+		{
+			name: "tla set twice",
+			cli:  armed.CLI{TLAStr: map[string]string{"a": "x"}, TLACode: map[string]string{"a": "1"}},
+			want: `top-level argument "a" is set by both --tla-str and --tla-code`,
+		},
+		{
+			name: "missing file",
+			cli:  armed.CLI{TLAStrFile: map[string]string{"a": filepath.Join(dir, "missing.txt")}},
+			want: "failed to read --tla-str-file file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := tt.cli
+			cli.Filename = file
+			cli.SetWriter(&bytes.Buffer{})
+			err := cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunWithCLIExtVarsFromEnv(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "main.jsonnet")
+	writeFile(t, file, `{ env: std.extVar("env"), replicas: std.extVar("replicas") }`)
+
+	run := func(cli *armed.CLI) string {
+		t.Helper()
+		var output bytes.Buffer
+		cli.Filename = file
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return output.String()
+	}
+
+	t.Setenv("JSONNET_ARMED_EXT_STR_env", "dev")
+	t.Setenv("JSONNET_ARMED_EXT_CODE_replicas", "1 + 2")
+	compareJSON(t, `{"env": "dev", "replicas": 3}`, run(&armed.CLI{}))
+
+	// Flags take precedence over the environment and are not modified
+	extStr := map[string]string{"env": "prod"}
+	compareJSON(t, `{"env": "prod", "replicas": 3}`, run(&armed.CLI{ExtStr: extStr}))
+	if len(extStr) != 1 {
+		t.Errorf("the map of the flag is modified: %v", extStr)
+	}
+	compareJSON(t, `{"env": "dev", "replicas": 5}`, run(&armed.CLI{ExtCode: map[string]string{"replicas": "5"}}))
+
+	// The values are part of the cache key
+	compareJSON(t, `{"env": "dev", "replicas": 3}`, run(&armed.CLI{Cache: time.Minute}))
+	t.Setenv("JSONNET_ARMED_EXT_STR_env", "stg")
+	compareJSON(t, `{"env": "stg", "replicas": 3}`, run(&armed.CLI{Cache: time.Minute}))
+
+	t.Run("set twice", func(t *testing.T) {
+		t.Setenv("JSONNET_ARMED_EXT_CODE_env", `"x"`)
+		cli := &armed.CLI{Filename: file}
+		cli.SetWriter(&bytes.Buffer{})
+		err := cli.Run(t.Context())
+		if err == nil || !strings.Contains(err.Error(), `external variable "env" is set by both`) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package armed_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+	"github.com/fujiwara/jsonnet-armed/functions"
+)
+
+func TestRunWithCLIStrictWarnings(t *testing.T) {
+	functions.DeprecatedFunctions["base64"] = "use std.base64 instead"
+	t.Cleanup(func() { delete(functions.DeprecatedFunctions, "base64") })
+
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "test.jsonnet")
+	if err := os.WriteFile(jsonnetFile, []byte(`{ a: std.native("base64")("hello") }`), 0644); err != nil {
+		t.Fatalf("failed to write jsonnet file: %v", err)
+	}
+
+	t.Run("warnings are logged", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile}
+		cli.SetWriter(&output)
+		if err := cli.Run(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compareJSON(t, output.String(), `{"a": "aGVsbG8="}`)
+	})
+
+	t.Run("strict warnings fail", func(t *testing.T) {
+		var output bytes.Buffer
+		cli := &armed.CLI{Filename: jsonnetFile, StrictWarnings: true}
+		cli.SetWriter(&output)
+		err := cli.Run(t.Context())
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		if !strings.Contains(err.Error(), "base64: deprecated: use std.base64 instead") {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if output.Len() != 0 {
+			t.Errorf("unexpected output: %s", output.String())
+		}
+	})
+}
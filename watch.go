@@ -0,0 +1,160 @@
+package armed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultWatchInterval is the interval for checking the input files in --watch mode
+const defaultWatchInterval = time.Second
+
+// fileState is the state of a watched file; a missing file has the zero value
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watch evaluates the file, then evaluates it again whenever the input files
+// change until ctx is canceled. Failed evaluations are logged and do not stop watching.
+func (cli *CLI) watch(ctx context.Context) error {
+	if cli.Filename == "-" || cli.ExtStrStdin != "" {
+		return fmt.Errorf("--watch cannot be used with stdin")
+	}
+	if isRemoteInput(cli.Filename) {
+		return fmt.Errorf("--watch cannot be used with a remote input")
+	}
+	if cli.Exec != "" {
+		return fmt.Errorf("--watch cannot be used with -e/--exec")
+	}
+	if strings.Contains(cli.OnChange, "{}") && len(cli.fileOutputs()) == 0 {
+		return fmt.Errorf("--on-change with {} requires a file output (-o)")
+	}
+	interval := cli.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	if cli.vms == nil {
+		cli.vms = newVMCache()
+	}
+
+	var stopping func()
+	probe := make(loopProbe)
+	for {
+		cli.outputChanged, cli.outputUnchanged = 0, 0
+		err := cli.runOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if stopping == nil {
+			// Ready after the first evaluation, successful or not
+			stopping = systemdReady(ctx, "Watching "+cli.Filename, probe.alive)
+			defer stopping()
+		}
+		// Exit statuses do not apply while watching
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			err = exitErr.Err
+		}
+		if err != nil {
+			slog.Error(err.Error())
+		} else if cli.OnChange != "" && (cli.outputChanged > 0 || len(cli.fileOutputs()) == 0) {
+			if err := cli.runOnChange(ctx); err != nil {
+				slog.Error("on-change command failed", "error", err.Error())
+			}
+		}
+
+		// Keep watching the input files even if the evaluation failed
+		files := slices.Clone(cli.inputs)
+		for _, f := range slices.Concat([]string{cli.Filename, cli.Mock, cli.OutputTemplate}, cli.Overlays, cli.varFiles()) {
+			if f != "" && !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+		if err := waitForChange(ctx, files, interval, probe); err != nil {
+			return nil // canceled
+		}
+		slog.Info("Input changed, evaluating again", "filename", cli.Filename)
+	}
+}
+
+// fileOutputs returns the -o/--output destinations that are files
+func (cli *CLI) fileOutputs() []string {
+	var files []string
+	for _, out := range cli.Output {
+		if u, err := url.Parse(out); err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "s3") {
+			continue
+		}
+		files = append(files, out)
+	}
+	return files
+}
+
+// runOnChange runs the --on-change command with sh. When the command
+// contains {}, it runs once for each output file with {} replaced by its path.
+func (cli *CLI) runOnChange(ctx context.Context) error {
+	commands := []string{cli.OnChange}
+	if strings.Contains(cli.OnChange, "{}") {
+		commands = nil
+		for _, out := range cli.fileOutputs() {
+			commands = append(commands, strings.ReplaceAll(cli.OnChange, "{}", singleQuote(out)))
+		}
+	}
+	var errs []error
+	for _, command := range commands {
+		slog.Info("Running on-change command", "command", command)
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		// Keep stdout for evaluation results
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", command, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// singleQuote quotes s for sh
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// waitForChange polls files until any of them is modified, created or
+// removed, and answers probe meanwhile
+func waitForChange(ctx context.Context, files []string, interval time.Duration, probe loopProbe) error {
+	initial := statFiles(files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-probe:
+		case <-ticker.C:
+			current := statFiles(files)
+			for i := range files {
+				if current[i] != initial[i] {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func statFiles(files []string) []fileState {
+	states := make([]fileState, len(files))
+	for i, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			states[i] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
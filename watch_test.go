@@ -0,0 +1,165 @@
+package armed_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	armed "github.com/fujiwara/jsonnet-armed"
+)
+
+// waitFor polls cond until it returns true or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWithCLIWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	libFile := filepath.Join(tmpDir, "lib.libsonnet")
+	outputFile := filepath.Join(tmpDir, "out.json")
+	hookLog := filepath.Join(tmpDir, "hook.log")
+	writeFile(t, jsonnetFile, `{ value: (import 'lib.libsonnet').value }`)
+	writeFile(t, libFile, `{ value: 1 }`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cli := &armed.CLI{
+		Filename:       jsonnetFile,
+		Output:         []string{outputFile},
+		WriteIfChanged: true,
+		Watch:          true,
+		WatchInterval:  10 * time.Millisecond,
+		OnChange:       "echo {} >> " + hookLog,
+	}
+	cli.SetWriter(&bytes.Buffer{})
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.Run(ctx)
+	}()
+
+	readOutput := func() string {
+		b, _ := os.ReadFile(outputFile)
+		return string(b)
+	}
+	hookCalls := func() []string {
+		b, _ := os.ReadFile(hookLog)
+		return strings.Fields(string(b))
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return strings.Contains(readOutput(), `"value": 1`) })
+	waitFor(t, 5*time.Second, func() bool { return len(hookCalls()) == 1 })
+
+	// Changing an imported file triggers a regeneration and the hook
+	time.Sleep(20 * time.Millisecond) // ensure a different modification time
+	writeFile(t, libFile, `{ value: 2 }`)
+	waitFor(t, 5*time.Second, func() bool { return strings.Contains(readOutput(), `"value": 2`) })
+	waitFor(t, 5*time.Second, func() bool { return len(hookCalls()) == 2 })
+
+	// A regeneration with the same output does not run the hook
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, libFile, `{ value: 1 + 1 }`)
+	time.Sleep(200 * time.Millisecond)
+
+	// A failed evaluation does not stop watching
+	writeFile(t, libFile, `{ value: error "broken" }`)
+	time.Sleep(100 * time.Millisecond)
+	writeFile(t, libFile, `{ value: 3 }`)
+	waitFor(t, 5*time.Second, func() bool { return strings.Contains(readOutput(), `"value": 3`) })
+	waitFor(t, 5*time.Second, func() bool { return len(hookCalls()) == 3 })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after cancel")
+	}
+	for _, call := range hookCalls() {
+		if call != outputFile {
+			t.Errorf("unexpected hook argument: %s", call)
+		}
+	}
+}
+
+func TestRunWithCLIWatchOutputTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonnetFile := filepath.Join(tmpDir, "main.jsonnet")
+	templateFile := filepath.Join(tmpDir, "out.gotmpl")
+	outputFile := filepath.Join(tmpDir, "out.txt")
+	writeFile(t, jsonnetFile, `{ name: "app" }`)
+	writeFile(t, templateFile, `name={{ .name }}`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cli := &armed.CLI{
+		Filename:       jsonnetFile,
+		Output:         []string{outputFile},
+		OutputTemplate: templateFile,
+		Watch:          true,
+		WatchInterval:  10 * time.Millisecond,
+	}
+	cli.SetWriter(&bytes.Buffer{})
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.Run(ctx)
+	}()
+
+	readOutput := func() string {
+		b, _ := os.ReadFile(outputFile)
+		return string(b)
+	}
+	waitFor(t, 5*time.Second, func() bool { return readOutput() == "name=app" })
+
+	// Changing the template renders the output again
+	time.Sleep(20 * time.Millisecond) // ensure a different modification time
+	writeFile(t, templateFile, `NAME={{ .name }}`)
+	waitFor(t, 5*time.Second, func() bool { return readOutput() == "NAME=app" })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after cancel")
+	}
+}
+
+func TestRunWithCLIWatchErrors(t *testing.T) {
+	jsonnetFile := filepath.Join(t.TempDir(), "test.jsonnet")
+	writeFile(t, jsonnetFile, `{}`)
+
+	tests := []struct {
+		name    string
+		cli     *armed.CLI
+		wantErr string
+	}{
+		{"on-change without watch", &armed.CLI{Filename: jsonnetFile, OnChange: "true"}, "--on-change requires --watch"},
+		{"stdin", &armed.CLI{Filename: "-", Watch: true}, "--watch cannot be used with stdin"},
+		{"on-change placeholder without output", &armed.CLI{Filename: jsonnetFile, Watch: true, OnChange: "cat {}"}, "--on-change with {} requires a file output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cli.SetWriter(&bytes.Buffer{})
+			err := tt.cli.Run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}